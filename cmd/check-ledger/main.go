@@ -0,0 +1,55 @@
+// Command check-ledger verifies the ledger's invariants: every journal sums to zero, the ledger as a
+// whole sums to zero in each currency, and every capture and refund that moved money has been posted.
+// It exits non-zero when any check fails, so it can run from cron or CI.
+//
+//	APP_ENV=production go run ./cmd/check-ledger
+package main
+
+import (
+	"dfood/internal/config"
+	"dfood/internal/database"
+	"dfood/internal/repository"
+	"dfood/internal/service"
+	"dfood/pkg/logger"
+	"log"
+	"os"
+)
+
+func main() {
+	cfg, err := config.New()
+	if err != nil {
+		logger.Error("Failed to initialize config", "error", err)
+		log.Fatal("Failed to initialize config:", err)
+	}
+	logger.Init(cfg.Env)
+
+	if err := database.InitDatabase(cfg, nil); err != nil {
+		logger.Error("Failed to initialize database", "error", err)
+		log.Fatal("Failed to initialize database:", err)
+	}
+
+	ledgerService := service.NewLedgerService(repository.NewLedgerRepository(), repository.NewOrderRepository(), repository.NewPayoutRepository(), cfg.Ledger)
+	check, err := ledgerService.CheckInvariants()
+	if closeErr := database.CloseDB(); closeErr != nil {
+		logger.Error("Error closing database", "error", closeErr)
+	}
+	if err != nil {
+		logger.Error("Ledger check failed to run", "error", err)
+		log.Fatal("Ledger check failed to run:", err)
+	}
+
+	for _, imbalance := range check.Imbalances {
+		if imbalance.JournalID == "" {
+			logger.Error("Ledger does not sum to zero", "amount", imbalance.Amount.String())
+		} else {
+			logger.Error("Journal does not sum to zero", "journal_id", imbalance.JournalID, "amount", imbalance.Amount.String())
+		}
+	}
+	for _, transactionID := range check.UnpostedTransactions {
+		logger.Error("Payment transaction missing from the ledger", "transaction_id", transactionID)
+	}
+	if !check.Balanced || len(check.UnpostedTransactions) > 0 {
+		os.Exit(1)
+	}
+	logger.Info("Ledger is balanced", "journals", check.Journals)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"dfood/internal/api/routes"
 	"dfood/internal/config"
 	"dfood/internal/database"
@@ -9,6 +10,8 @@ import (
 	"dfood/pkg/logger"
 	"fmt"
 	"log"
+	"time"
+	_ "time/tzdata" // Restaurant time zones resolve even on hosts without a zoneinfo database
 )
 
 func main() {
@@ -21,7 +24,14 @@ func main() {
 	logger.Init(cfg.Env)
 	logger.Info("Starting API server", "env", cfg.Env, "port", cfg.Port)
 
-	if err := database.InitDatabase(cfg); err != nil {
+	paymentGateway, err := service.NewPaymentGateway(cfg.Payment)
+	if err != nil {
+		logger.Error("Failed to configure payment gateway", "error", err)
+		log.Fatal("Failed to configure payment gateway:", err)
+	}
+
+	// Cards saved before tokenization are exchanged for gateway tokens while migrating
+	if err := database.InitDatabase(cfg, service.LegacyCardTokenizer(paymentGateway)); err != nil {
 		logger.Error("Failed to initialize database", "error", err)
 		log.Fatal("Failed to initialize database:", err)
 	}
@@ -41,19 +51,51 @@ func main() {
 	addressRepo := repository.NewAddressRepository()
 	favoritesRepo := repository.NewFavoritesRepository()
 	notificationRepo := repository.NewNotificationRepository()
+	taxRuleRepo := repository.NewTaxRuleRepository()
+	deliveryRepo := repository.NewDeliveryRepository()
+	orderQuoteRepo := repository.NewOrderQuoteRepository()
+	orderTemplateRepo := repository.NewOrderTemplateRepository()
+	groupOrderRepo := repository.NewGroupOrderRepository()
+	paymentRepo := repository.NewPaymentRepository()
+	paymentWebhookRepo := repository.NewPaymentWebhookRepository()
+	ledgerRepo := repository.NewLedgerRepository()
+	payoutRepo := repository.NewPayoutRepository()
+	promotionRepo := repository.NewPromotionRepository()
+	loyaltyRepo := repository.NewLoyaltyRepository()
+	referralRepo := repository.NewReferralRepository()
+	giftCardRepo := repository.NewGiftCardRepository()
+	membershipRepo := repository.NewMembershipRepository()
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo)
 	userService := service.NewUserService(userRepo)
-	restaurantService := service.NewRestaurantService(restaurantRepo, foodRepo)
+	restaurantService := service.NewRestaurantService(restaurantRepo, foodRepo, userRepo)
 	foodService := service.NewFoodService(foodRepo)
-	orderService := service.NewOrderService(orderRepo, userRepo, restaurantRepo, foodRepo)
-	paymentService := service.NewPaymentService()
+	taxService := service.NewTaxService(taxRuleRepo, cfg.Tax)
+	deliveryService := service.NewDeliveryService(deliveryRepo)
+	ledgerService := service.NewLedgerService(ledgerRepo, orderRepo, payoutRepo, cfg.Ledger)
+	referralService := service.NewReferralService(referralRepo, userRepo, addressRepo, paymentRepo, ledgerService, cfg.Referral)
+	authService := service.NewAuthService(userRepo, referralService)
+	paymentService := service.NewPaymentService(paymentRepo, paymentWebhookRepo, orderRepo, giftCardRepo, userRepo, paymentGateway, ledgerService, cfg.Payment, cfg.GiftCard)
+	giftCardService := service.NewGiftCardService(giftCardRepo, userRepo, ledgerService, cfg.GiftCard)
+	promotionService := service.NewPromotionService(promotionRepo, orderRepo, restaurantRepo)
+	loyaltyService := service.NewLoyaltyService(loyaltyRepo, userRepo, restaurantRepo, cfg.Loyalty)
+	notificationService := service.NewNotificationService(notificationRepo, userRepo)
+	membershipService := service.NewMembershipService(membershipRepo, userRepo, paymentRepo, paymentService, notificationService, cfg.Membership)
+	orderService := service.NewOrderService(orderRepo, userRepo, restaurantRepo, foodRepo, addressRepo, taxService, deliveryService, orderQuoteRepo, orderTemplateRepo, groupOrderRepo, paymentService, promotionService, loyaltyService, referralService, membershipService, cfg.Cancellation, cfg.Tip, cfg.ServiceFee)
+	groupOrderService := service.NewGroupOrderService(groupOrderRepo, userRepo, restaurantRepo, foodRepo, orderService, paymentService)
 	addressService := service.NewAddressService(addressRepo, userRepo)
 	favoritesService := service.NewFavoritesService(favoritesRepo, userRepo, foodRepo, restaurantRepo)
 	chatService := service.NewChatService()
-	notificationService := service.NewNotificationService(notificationRepo, userRepo)
 	uploadService := service.NewUploadService()
+	payoutService := service.NewPayoutService(payoutRepo, restaurantRepo, orderRepo, userRepo, ledgerRepo, ledgerService)
+	orderScheduler := service.NewOrderScheduler(orderRepo)
+	membershipBiller := service.NewMembershipBiller(membershipService)
+
+	// Release scheduled orders into the kitchen queue and renew memberships in the background
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	go orderScheduler.Start(schedulerCtx, time.Minute)
+	go membershipBiller.Start(schedulerCtx, time.Hour)
 
 	deps := &routes.Dependencies{
 		AuthService:         authService,
@@ -61,7 +103,15 @@ func main() {
 		RestaurantService:   restaurantService,
 		FoodService:         foodService,
 		OrderService:        orderService,
+		GroupOrderService:   groupOrderService,
 		PaymentService:      paymentService,
+		LedgerService:       ledgerService,
+		PayoutService:       payoutService,
+		PromotionService:    promotionService,
+		LoyaltyService:      loyaltyService,
+		ReferralService:     referralService,
+		GiftCardService:     giftCardService,
+		MembershipService:   membershipService,
 		AddressService:      addressService,
 		FavoritesService:    favoritesService,
 		ChatService:         chatService,
@@ -0,0 +1,44 @@
+// Command rotate-keys re-wraps every encrypted column under the active master key.
+//
+// To rotate, add a new key under encryption.master_keys, point encryption.active_key_id at it,
+// run this command, then remove the old key. Running it after encrypting a new column also
+// encrypts the plaintext written before, and it always refreshes blind indexes.
+//
+//	APP_ENV=production go run ./cmd/rotate-keys
+package main
+
+import (
+	"dfood/internal/config"
+	"dfood/internal/database"
+	"dfood/pkg/logger"
+	"log"
+)
+
+func main() {
+	cfg, err := config.New()
+	if err != nil {
+		logger.Error("Failed to initialize config", "error", err)
+		log.Fatal("Failed to initialize config:", err)
+	}
+	logger.Init(cfg.Env)
+
+	if err := database.InitDatabase(cfg, nil); err != nil {
+		logger.Error("Failed to initialize database", "error", err)
+		log.Fatal("Failed to initialize database:", err)
+	}
+	defer func() {
+		if err := database.CloseDB(); err != nil {
+			logger.Error("Error closing database", "error", err)
+		}
+	}()
+
+	changed, err := database.ReencryptAll(database.DB)
+	for table, count := range changed {
+		logger.Info("Re-encrypted table", "table", table, "rows", count, "active_key_id", cfg.Encryption.ActiveKeyID)
+	}
+	if err != nil {
+		logger.Error("Key rotation failed", "error", err)
+		log.Fatal("Key rotation failed:", err)
+	}
+	logger.Info("Key rotation complete")
+}
@@ -0,0 +1,64 @@
+// Command settle-payouts runs a payout settlement: every restaurant's orders delivered or cancelled, and
+// refunds made, before the period end that no earlier run settled are turned into payout statements.
+// Run it from cron at the end of each payout period; a run with nothing new to settle creates nothing.
+//
+//	APP_ENV=production go run ./cmd/settle-payouts -period-end 2026-10-19T00:00:00Z
+package main
+
+import (
+	"dfood/internal/config"
+	"dfood/internal/database"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/internal/service"
+	"dfood/pkg/logger"
+	"flag"
+	"log"
+	"time"
+)
+
+func main() {
+	periodEnd := flag.String("period-end", "", "settle everything finished before this RFC 3339 time (default now)")
+	restaurantID := flag.String("restaurant", "", "settle only this restaurant")
+	flag.Parse()
+
+	request := &models.SettlePayoutsRequest{RestaurantID: *restaurantID}
+	if *periodEnd != "" {
+		end, err := time.Parse(time.RFC3339, *periodEnd)
+		if err != nil {
+			log.Fatal("Invalid -period-end:", err)
+		}
+		request.PeriodEnd = &end
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		logger.Error("Failed to initialize config", "error", err)
+		log.Fatal("Failed to initialize config:", err)
+	}
+	logger.Init(cfg.Env)
+
+	if err := database.InitDatabase(cfg, nil); err != nil {
+		logger.Error("Failed to initialize database", "error", err)
+		log.Fatal("Failed to initialize database:", err)
+	}
+
+	ledgerRepo := repository.NewLedgerRepository()
+	orderRepo := repository.NewOrderRepository()
+	payoutRepo := repository.NewPayoutRepository()
+	ledgerService := service.NewLedgerService(ledgerRepo, orderRepo, payoutRepo, cfg.Ledger)
+	payoutService := service.NewPayoutService(payoutRepo, repository.NewRestaurantRepository(), orderRepo, repository.NewUserRepository(), ledgerRepo, ledgerService)
+
+	payouts, err := payoutService.RunSettlement(request)
+	if closeErr := database.CloseDB(); closeErr != nil {
+		logger.Error("Error closing database", "error", closeErr)
+	}
+	for _, payout := range payouts {
+		logger.Info("Payout issued", "payout_id", payout.ID, "restaurant_id", payout.RestaurantID, "orders", payout.OrderCount, "net", payout.Net.String())
+	}
+	if err != nil {
+		logger.Error("Settlement run failed", "error", err)
+		log.Fatal("Settlement run failed:", err)
+	}
+	logger.Info("Settlement run complete", "payouts", len(payouts))
+}
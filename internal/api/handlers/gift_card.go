@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+
+	"dfood/internal/models"
+	"dfood/internal/service"
+	"dfood/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GiftCardHandler struct {
+	giftCardService service.GiftCardService
+	paymentService  service.PaymentService
+}
+
+func NewGiftCardHandler(giftCardService service.GiftCardService, paymentService service.PaymentService) *GiftCardHandler {
+	return &GiftCardHandler{
+		giftCardService: giftCardService,
+		paymentService:  paymentService,
+	}
+}
+
+func (h *GiftCardHandler) PurchaseGiftCard(c *gin.Context) {
+	var purchaseRequest models.PurchaseGiftCardRequest
+	if err := c.ShouldBindJSON(&purchaseRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for gift card purchase",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.paymentService.PurchaseGiftCard(&purchaseRequest)
+		},
+		"purchasing gift card",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *GiftCardHandler) GetGiftCard(c *gin.Context) {
+	var codeRequest models.GiftCardCodeRequest
+	if err := c.ShouldBindJSON(&codeRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for gift card balance",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.giftCardService.GetGiftCard(codeRequest.Code)
+		},
+		"fetching gift card",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *GiftCardHandler) RedeemGiftCard(c *gin.Context) {
+	var redeemRequest models.RedeemGiftCardRequest
+	if err := c.ShouldBindJSON(&redeemRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for gift card redemption",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.giftCardService.RedeemGiftCard(&redeemRequest)
+		},
+		"redeeming gift card",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *GiftCardHandler) IssueGiftCards(c *gin.Context) {
+	var issueRequest models.IssueGiftCardsRequest
+	if err := c.ShouldBindJSON(&issueRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for gift card issue",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.giftCardService.IssueGiftCards(&issueRequest)
+		},
+		"issuing gift cards",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *GiftCardHandler) GetUserGiftCards(c *gin.Context) {
+	userID := c.Param("userId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.giftCardService.GetUserGiftCards(userID)
+		},
+		"fetching gift cards",
+	)
+	result.RespondWithJSON(c)
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"net/http"
+
+	"dfood/internal/models"
+	"dfood/internal/service"
+	"dfood/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupOrderHandler struct {
+	groupOrderService service.GroupOrderService
+}
+
+func NewGroupOrderHandler(groupOrderService service.GroupOrderService) *GroupOrderHandler {
+	return &GroupOrderHandler{
+		groupOrderService: groupOrderService,
+	}
+}
+
+func (h *GroupOrderHandler) CreateGroupOrder(c *gin.Context) {
+	var createRequest models.CreateGroupOrderRequest
+	if err := c.ShouldBindJSON(&createRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for new group order",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.groupOrderService.CreateGroupOrder(&createRequest)
+		},
+		"creating group order",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *GroupOrderHandler) GetGroupOrder(c *gin.Context) {
+	groupOrderID := c.Param("groupOrderId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.groupOrderService.GetGroupOrder(groupOrderID)
+		},
+		"fetching group order",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *GroupOrderHandler) JoinGroupOrder(c *gin.Context) {
+	var joinRequest models.JoinGroupOrderRequest
+	if err := c.ShouldBindJSON(&joinRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for joining group order",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.groupOrderService.JoinGroupOrder(&joinRequest)
+		},
+		"joining group order",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *GroupOrderHandler) UpdateItems(c *gin.Context) {
+	groupOrderID := c.Param("groupOrderId")
+
+	var itemsRequest models.UpdateGroupOrderItemsRequest
+	if err := c.ShouldBindJSON(&itemsRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for group order items",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.groupOrderService.UpdateItems(groupOrderID, &itemsRequest)
+		},
+		"updating group order items",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *GroupOrderHandler) LockGroupOrder(c *gin.Context) {
+	groupOrderID := c.Param("groupOrderId")
+
+	var hostRequest models.GroupOrderHostRequest
+	if err := c.ShouldBindJSON(&hostRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for locking group order",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.groupOrderService.LockGroupOrder(groupOrderID, hostRequest.UserID)
+		},
+		"locking group order",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *GroupOrderHandler) CheckoutGroupOrder(c *gin.Context) {
+	groupOrderID := c.Param("groupOrderId")
+
+	var hostRequest models.GroupOrderHostRequest
+	if err := c.ShouldBindJSON(&hostRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for group order checkout",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.groupOrderService.CheckoutGroupOrder(groupOrderID, hostRequest.UserID)
+		},
+		"checking out group order",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *GroupOrderHandler) PayShare(c *gin.Context) {
+	groupOrderID := c.Param("groupOrderId")
+
+	var payRequest models.PayGroupOrderShareRequest
+	if err := c.ShouldBindJSON(&payRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for group order payment",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.groupOrderService.PayShare(groupOrderID, &payRequest)
+		},
+		"paying group order share",
+	)
+	result.RespondWithJSON(c)
+}
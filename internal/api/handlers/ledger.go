@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"strconv"
+
+	"dfood/internal/models"
+	"dfood/internal/service"
+	"dfood/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LedgerHandler struct {
+	ledgerService service.LedgerService
+}
+
+func NewLedgerHandler(ledgerService service.LedgerService) *LedgerHandler {
+	return &LedgerHandler{
+		ledgerService: ledgerService,
+	}
+}
+
+func (h *LedgerHandler) GetAccountBalance(c *gin.Context) {
+	accountType := models.LedgerAccountType(c.Param("accountType"))
+	ownerID := c.Query("ownerId")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.ledgerService.GetAccountBalance(accountType, ownerID, limit, offset)
+		},
+		"fetching ledger account balance",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *LedgerHandler) CheckLedger(c *gin.Context) {
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.ledgerService.CheckInvariants()
+		},
+		"checking ledger invariants",
+	)
+	result.RespondWithJSON(c)
+}
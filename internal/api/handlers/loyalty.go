@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"dfood/internal/models"
+	"dfood/internal/service"
+	"dfood/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoyaltyHandler struct {
+	loyaltyService service.LoyaltyService
+}
+
+func NewLoyaltyHandler(loyaltyService service.LoyaltyService) *LoyaltyHandler {
+	return &LoyaltyHandler{
+		loyaltyService: loyaltyService,
+	}
+}
+
+func (h *LoyaltyHandler) GetLoyalty(c *gin.Context) {
+	userID := c.Param("userId")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.loyaltyService.GetLoyalty(userID, limit, offset)
+		},
+		"fetching loyalty points",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *LoyaltyHandler) SetMultiplier(c *gin.Context) {
+	restaurantID := c.Param("id")
+
+	var multiplierRequest models.SetLoyaltyMultiplierRequest
+	if err := c.ShouldBindJSON(&multiplierRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for loyalty multiplier",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.loyaltyService.SetMultiplier(restaurantID, &multiplierRequest)
+		},
+		"setting loyalty multiplier",
+	)
+	result.RespondWithJSON(c)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+
+	"dfood/internal/models"
+	"dfood/internal/service"
+	"dfood/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MembershipHandler struct {
+	membershipService service.MembershipService
+}
+
+func NewMembershipHandler(membershipService service.MembershipService) *MembershipHandler {
+	return &MembershipHandler{
+		membershipService: membershipService,
+	}
+}
+
+func (h *MembershipHandler) GetPlans(c *gin.Context) {
+	activeOnly := c.Query("all") != "true"
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.membershipService.GetPlans(activeOnly)
+		},
+		"fetching membership plans",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *MembershipHandler) CreatePlan(c *gin.Context) {
+	var planRequest models.CreateMembershipPlanRequest
+	if err := c.ShouldBindJSON(&planRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for new membership plan",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.membershipService.CreatePlan(&planRequest)
+		},
+		"creating membership plan",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *MembershipHandler) UpdatePlan(c *gin.Context) {
+	planID := c.Param("id")
+
+	var planRequest models.UpdateMembershipPlanRequest
+	if err := c.ShouldBindJSON(&planRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for membership plan update",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.membershipService.UpdatePlan(planID, &planRequest)
+		},
+		"updating membership plan",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *MembershipHandler) BillDue(c *gin.Context) {
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.membershipService.BillDue()
+		},
+		"billing memberships",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *MembershipHandler) GetUserMembership(c *gin.Context) {
+	userID := c.Param("userId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.membershipService.GetUserMembership(userID)
+		},
+		"fetching membership",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *MembershipHandler) Subscribe(c *gin.Context) {
+	var subscribeRequest models.SubscribeMembershipRequest
+	if err := c.ShouldBindJSON(&subscribeRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for membership",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+	subscribeRequest.UserID = c.Param("userId")
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.membershipService.Subscribe(orderViewer(c), &subscribeRequest)
+		},
+		"starting membership",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *MembershipHandler) CancelMembership(c *gin.Context) {
+	userID := c.Param("userId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.membershipService.CancelMembership(orderViewer(c), userID)
+		},
+		"cancelling membership",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *MembershipHandler) ResumeMembership(c *gin.Context) {
+	userID := c.Param("userId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.membershipService.ResumeMembership(orderViewer(c), userID)
+		},
+		"resuming membership",
+	)
+	result.RespondWithJSON(c)
+}
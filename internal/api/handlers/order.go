@@ -1,7 +1,13 @@
 package handlers
 
 import (
+	"net/http"
+	"strconv"
+
+	"dfood/internal/api/middleware"
+	"dfood/internal/models"
 	"dfood/internal/service"
+	"dfood/pkg/errors"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,13 +24,152 @@ func NewOrderHandler(orderService service.OrderService) *OrderHandler {
 
 // Order Management
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
-	// TODO: Implement create new order
-	c.JSON(200, gin.H{"message": "Create order - TODO"})
+	var newOrder models.Order
+	if err := c.ShouldBindJSON(&newOrder); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for new order",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.orderService.CreateOrder(&newOrder)
+		},
+		"creating new order",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *OrderHandler) QuoteOrder(c *gin.Context) {
+	var quoteRequest models.QuoteOrderRequest
+	if err := c.ShouldBindJSON(&quoteRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for order quote",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.orderService.QuoteOrder(&quoteRequest)
+		},
+		"quoting order",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *OrderHandler) Reorder(c *gin.Context) {
+	orderID := c.Param("orderId")
+
+	var reorderRequest models.ReorderRequest
+	if err := c.ShouldBindJSON(&reorderRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for reorder",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.orderService.Reorder(orderID, &reorderRequest)
+		},
+		"rebuilding order from history",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *OrderHandler) SearchOrders(c *gin.Context) {
+	var searchParams models.OrderSearchParams
+	if err := c.ShouldBindQuery(&searchParams); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid search parameters", err)
+			},
+			"binding order search parameters",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.orderService.SearchOrders(orderViewer(c), &searchParams)
+		},
+		"searching orders",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *OrderHandler) ExportOrders(c *gin.Context) {
+	var searchParams models.OrderSearchParams
+	if err := c.ShouldBindQuery(&searchParams); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid search parameters", err)
+			},
+			"binding order export parameters",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="orders.csv"`)
+	if err := h.orderService.ExportOrdersCSV(orderViewer(c), &searchParams, c.Writer); err != nil {
+		// Nothing has been streamed yet when validation fails, so a JSON error can still be sent
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			result := errors.HandleError(
+				func() (interface{}, error) {
+					return nil, err
+				},
+				"exporting orders",
+			)
+			result.RespondWithJSON(c)
+			return
+		}
+		_ = c.Error(err)
+	}
+}
+
+// orderViewer reads the caller identity set by the role middleware
+func orderViewer(c *gin.Context) models.OrderViewer {
+	role, _ := c.Get(middleware.ContextUserRole)
+	userRole, _ := role.(models.UserRole)
+	return models.OrderViewer{
+		Email: c.GetString(middleware.ContextUserEmail),
+		Role:  userRole,
+	}
 }
 
 func (h *OrderHandler) GetUserOrders(c *gin.Context) {
-	// TODO: Implement get user's order history
-	c.JSON(200, gin.H{"message": "Get user orders - TODO"})
+	userID := c.Param("userId")
+	timeframe := models.OrderTimeframe(c.Query("timeframe"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.orderService.GetUserOrders(userID, timeframe, limit, offset)
+		},
+		"fetching user orders",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *OrderHandler) GetOrderByID(c *gin.Context) {
@@ -33,16 +178,203 @@ func (h *OrderHandler) GetOrderByID(c *gin.Context) {
 }
 
 func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
-	// TODO: Implement update order status
-	c.JSON(200, gin.H{"message": "Update order status - TODO"})
+	orderID := c.Param("orderId")
+
+	var statusRequest models.UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&statusRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for order status update",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			if err := h.orderService.UpdateOrderStatus(orderViewer(c), orderID, &statusRequest); err != nil {
+				return nil, err
+			}
+			return h.orderService.GetOrderByID(orderID)
+		},
+		"updating order status",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *OrderHandler) AdjustTip(c *gin.Context) {
+	orderID := c.Param("orderId")
+
+	var tipRequest models.AdjustTipRequest
+	if err := c.ShouldBindJSON(&tipRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for tip adjustment",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.orderService.AdjustTip(orderViewer(c), orderID, &tipRequest)
+		},
+		"adjusting order tip",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *OrderHandler) RemoveOrderItems(c *gin.Context) {
+	orderID := c.Param("orderId")
+
+	var removeRequest models.RemoveOrderItemsRequest
+	if err := c.ShouldBindJSON(&removeRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for order item removal",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.orderService.RemoveOrderItems(orderViewer(c), orderID, &removeRequest)
+		},
+		"removing order items",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *OrderHandler) CancelOrder(c *gin.Context) {
-	// TODO: Implement cancel order
-	c.JSON(200, gin.H{"message": "Cancel order - TODO"})
+	orderID := c.Param("orderId")
+
+	var cancelRequest models.CancelOrderRequest
+	if err := c.ShouldBindJSON(&cancelRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for order cancellation",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.orderService.CancelOrder(orderViewer(c), orderID, &cancelRequest)
+		},
+		"cancelling order",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *OrderHandler) PreviewCancellation(c *gin.Context) {
+	orderID := c.Param("orderId")
+	actor := models.CancellationActor(c.Query("cancelledBy"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.orderService.PreviewCancellation(orderViewer(c), orderID, actor)
+		},
+		"previewing order cancellation",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *OrderHandler) TrackOrder(c *gin.Context) {
 	// TODO: Implement get order tracking info
 	c.JSON(200, gin.H{"message": "Track order - TODO"})
 }
+
+// Order Templates
+func (h *OrderHandler) CreateOrderTemplate(c *gin.Context) {
+	var templateRequest models.CreateOrderTemplateRequest
+	if err := c.ShouldBindJSON(&templateRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for order template",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.orderService.CreateOrderTemplate(&templateRequest)
+		},
+		"creating order template",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *OrderHandler) GetUserOrderTemplates(c *gin.Context) {
+	userID := c.Param("userId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.orderService.GetUserOrderTemplates(userID)
+		},
+		"fetching order templates",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *OrderHandler) DeleteOrderTemplate(c *gin.Context) {
+	templateID := c.Param("templateId")
+
+	var deleteRequest models.DeleteOrderTemplateRequest
+	if err := c.ShouldBindJSON(&deleteRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for order template deletion",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return nil, h.orderService.DeleteOrderTemplate(templateID, &deleteRequest)
+		},
+		"deleting order template",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *OrderHandler) PlaceOrderTemplate(c *gin.Context) {
+	templateID := c.Param("templateId")
+
+	var placeRequest models.PlaceOrderTemplateRequest
+	if err := c.ShouldBindJSON(&placeRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for placing order template",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.orderService.PlaceOrderTemplate(templateID, &placeRequest)
+		},
+		"placing order from template",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"net/http"
+
+	"dfood/internal/models"
 	"dfood/internal/service"
+	"dfood/pkg/errors"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,37 +22,155 @@ func NewPaymentHandler(paymentService service.PaymentService) *PaymentHandler {
 
 // Payment Methods
 func (h *PaymentHandler) GetPaymentMethods(c *gin.Context) {
-	// TODO: Implement get available payment methods
-	c.JSON(200, gin.H{"message": "Get payment methods - TODO"})
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.paymentService.GetPaymentMethods()
+		},
+		"fetching payment methods",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *PaymentHandler) GetUserCards(c *gin.Context) {
-	// TODO: Implement get user's saved cards
-	c.JSON(200, gin.H{"message": "Get user cards - TODO"})
+	userID := c.Param("userId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.paymentService.GetUserCards(userID)
+		},
+		"fetching user cards",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *PaymentHandler) SaveCard(c *gin.Context) {
-	// TODO: Implement save new payment card
-	c.JSON(200, gin.H{"message": "Save card - TODO"})
+	var cardRequest models.SaveCardRequest
+	if err := c.ShouldBindJSON(&cardRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for new card",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.paymentService.SaveCard(&cardRequest)
+		},
+		"saving card",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *PaymentHandler) DeleteCard(c *gin.Context) {
-	// TODO: Implement delete saved card
-	c.JSON(200, gin.H{"message": "Delete card - TODO"})
+	cardID := c.Param("cardId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return nil, h.paymentService.DeleteCard(cardID)
+		},
+		"deleting card",
+	)
+	result.RespondWithJSON(c)
 }
 
 // Payment Processing
 func (h *PaymentHandler) ProcessPayment(c *gin.Context) {
-	// TODO: Implement process payment for order
-	c.JSON(200, gin.H{"message": "Process payment - TODO"})
+	var transaction models.PaymentTransaction
+	if err := c.ShouldBindJSON(&transaction); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for payment",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.paymentService.ProcessPayment(&transaction)
+		},
+		"processing payment",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *PaymentHandler) GetTransactionDetails(c *gin.Context) {
-	// TODO: Implement get payment transaction details
-	c.JSON(200, gin.H{"message": "Get transaction details - TODO"})
+	transactionID := c.Param("transactionId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.paymentService.GetTransactionDetails(transactionID)
+		},
+		"fetching payment transaction",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *PaymentHandler) GetOrderTransactions(c *gin.Context) {
+	orderID := c.Param("orderId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.paymentService.GetOrderTransactions(orderID)
+		},
+		"fetching order transactions",
+	)
+	result.RespondWithJSON(c)
+}
+
+// Webhooks
+func (h *PaymentHandler) HandleWebhook(c *gin.Context) {
+	provider := c.Param("provider")
+
+	// The signature covers the exact bytes sent, so the body is read raw rather than bound
+	payload, err := c.GetRawData()
+	if err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid webhook payload", err)
+			},
+			"reading payment webhook",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.paymentService.HandleWebhook(provider, payload, c.GetHeader(service.WebhookSignatureHeader))
+		},
+		"handling payment webhook",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *PaymentHandler) ProcessRefund(c *gin.Context) {
-	// TODO: Implement process refund
-	c.JSON(200, gin.H{"message": "Process refund - TODO"})
+	var refundRequest models.ProcessRefundRequest
+	if err := c.ShouldBindJSON(&refundRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for refund",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.paymentService.ProcessRefund(orderViewer(c), refundRequest.TransactionID, refundRequest.Amount, refundRequest.ToWallet)
+		},
+		"processing refund",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
 }
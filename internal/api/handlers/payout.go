@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"dfood/internal/models"
+	"dfood/internal/service"
+	"dfood/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PayoutHandler struct {
+	payoutService service.PayoutService
+}
+
+func NewPayoutHandler(payoutService service.PayoutService) *PayoutHandler {
+	return &PayoutHandler{
+		payoutService: payoutService,
+	}
+}
+
+func (h *PayoutHandler) GetRestaurantPayouts(c *gin.Context) {
+	restaurantID := c.Param("id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.payoutService.GetRestaurantPayouts(orderViewer(c), restaurantID, limit, offset)
+		},
+		"fetching restaurant payouts",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *PayoutHandler) GetPayout(c *gin.Context) {
+	restaurantID := c.Param("id")
+	payoutID := c.Param("payoutId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.payoutService.GetPayout(orderViewer(c), restaurantID, payoutID)
+		},
+		"fetching payout",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *PayoutHandler) DownloadStatement(c *gin.Context) {
+	restaurantID := c.Param("id")
+	payoutID := c.Param("payoutId")
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+payoutID+`.csv"`)
+	if err := h.payoutService.WriteStatementCSV(orderViewer(c), restaurantID, payoutID, c.Writer); err != nil {
+		// Nothing has been written yet when the payout cannot be found or seen, so a JSON error can still be sent
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			result := errors.HandleError(
+				func() (interface{}, error) {
+					return nil, err
+				},
+				"downloading payout statement",
+			)
+			result.RespondWithJSON(c)
+			return
+		}
+		_ = c.Error(err)
+	}
+}
+
+func (h *PayoutHandler) SetCommissionRule(c *gin.Context) {
+	restaurantID := c.Param("id")
+
+	var ruleRequest models.SetCommissionRuleRequest
+	if err := c.ShouldBindJSON(&ruleRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for commission rule",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.payoutService.SetCommissionRule(restaurantID, &ruleRequest)
+		},
+		"setting commission rule",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *PayoutHandler) RunSettlement(c *gin.Context) {
+	var settleRequest models.SettlePayoutsRequest
+	if err := c.ShouldBindJSON(&settleRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for settlement run",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.payoutService.RunSettlement(&settleRequest)
+		},
+		"running payout settlement",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *PayoutHandler) MarkPaid(c *gin.Context) {
+	payoutID := c.Param("payoutId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.payoutService.MarkPaid(payoutID)
+		},
+		"marking payout paid",
+	)
+	result.RespondWithJSON(c)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"dfood/internal/models"
+	"dfood/internal/service"
+	"dfood/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromotionHandler struct {
+	promotionService service.PromotionService
+}
+
+func NewPromotionHandler(promotionService service.PromotionService) *PromotionHandler {
+	return &PromotionHandler{
+		promotionService: promotionService,
+	}
+}
+
+func (h *PromotionHandler) CreatePromotion(c *gin.Context) {
+	var promotionRequest models.CreatePromotionRequest
+	if err := c.ShouldBindJSON(&promotionRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for new promotion",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.promotionService.CreatePromotion(&promotionRequest)
+		},
+		"creating promotion",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *PromotionHandler) GetPromotions(c *gin.Context) {
+	activeOnly := c.Query("active") == "true"
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.promotionService.GetPromotions(activeOnly, limit, offset)
+		},
+		"fetching promotions",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *PromotionHandler) GetPromotion(c *gin.Context) {
+	promotionID := c.Param("id")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.promotionService.GetPromotion(promotionID)
+		},
+		"fetching promotion",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *PromotionHandler) UpdatePromotion(c *gin.Context) {
+	promotionID := c.Param("id")
+
+	var promotionRequest models.UpdatePromotionRequest
+	if err := c.ShouldBindJSON(&promotionRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for promotion update",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.promotionService.UpdatePromotion(promotionID, &promotionRequest)
+		},
+		"updating promotion",
+	)
+	result.RespondWithJSON(c)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"dfood/internal/models"
+	"dfood/internal/service"
+	"dfood/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReferralHandler struct {
+	referralService service.ReferralService
+}
+
+func NewReferralHandler(referralService service.ReferralService) *ReferralHandler {
+	return &ReferralHandler{
+		referralService: referralService,
+	}
+}
+
+func (h *ReferralHandler) GetUserReferrals(c *gin.Context) {
+	userID := c.Param("userId")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.referralService.GetUserReferrals(userID)
+		},
+		"fetching referrals",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *ReferralHandler) GetReport(c *gin.Context) {
+	var reportParams models.ReferralReportParams
+	if err := c.ShouldBindQuery(&reportParams); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid report parameters", err)
+			},
+			"binding referral report parameters",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.referralService.GetReport(&reportParams)
+		},
+		"building referral report",
+	)
+	result.RespondWithJSON(c)
+}
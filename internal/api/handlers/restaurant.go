@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"net/http"
+	"strconv"
+
+	"dfood/internal/models"
 	"dfood/internal/service"
+	"dfood/pkg/errors"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,36 +23,208 @@ func NewRestaurantHandler(restaurantService service.RestaurantService) *Restaura
 
 // Restaurant Data
 func (h *RestaurantHandler) GetAllRestaurants(c *gin.Context) {
-	// TODO: Implement get all restaurants (paginated)
-	c.JSON(200, gin.H{"message": "Get all restaurants - TODO"})
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.GetAllRestaurants(limit, offset)
+		},
+		"fetching restaurants",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *RestaurantHandler) GetRestaurantByID(c *gin.Context) {
-	// TODO: Implement get restaurant by ID
-	c.JSON(200, gin.H{"message": "Get restaurant by ID - TODO"})
+	restaurantID := c.Param("id")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.GetRestaurantByID(restaurantID)
+		},
+		"fetching restaurant",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *RestaurantHandler) GetPopularRestaurants(c *gin.Context) {
-	// TODO: Implement get popular restaurants (rating >= 4.0)
-	c.JSON(200, gin.H{"message": "Get popular restaurants - TODO"})
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.GetPopularRestaurants(limit)
+		},
+		"fetching popular restaurants",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *RestaurantHandler) GetNearbyRestaurants(c *gin.Context) {
-	// TODO: Implement get nearby restaurants (lat/lng query params)
-	c.JSON(200, gin.H{"message": "Get nearby restaurants - TODO"})
+	latitude, latErr := strconv.ParseFloat(c.Query("latitude"), 64)
+	longitude, lngErr := strconv.ParseFloat(c.Query("longitude"), 64)
+	radius, _ := strconv.ParseFloat(c.DefaultQuery("radius", "10"), 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			if latErr != nil || lngErr != nil {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Latitude and longitude are required", nil)
+			}
+			return h.restaurantService.GetNearbyRestaurants(latitude, longitude, radius, limit)
+		},
+		"fetching nearby restaurants",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *RestaurantHandler) SearchRestaurants(c *gin.Context) {
-	// TODO: Implement search restaurants by name/category/description
-	c.JSON(200, gin.H{"message": "Search restaurants - TODO"})
+	query := c.Query("query")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.SearchRestaurants(query, limit, offset)
+		},
+		"searching restaurants",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *RestaurantHandler) GetRestaurantsByCategory(c *gin.Context) {
-	// TODO: Implement get restaurants by category
-	c.JSON(200, gin.H{"message": "Get restaurants by category - TODO"})
+	category := c.Param("category")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.GetRestaurantsByCategory(category, limit, offset)
+		},
+		"fetching restaurants by category",
+	)
+	result.RespondWithJSON(c)
 }
 
 func (h *RestaurantHandler) GetRestaurantMenu(c *gin.Context) {
-	// TODO: Implement get restaurant menu/categories with foods
-	c.JSON(200, gin.H{"message": "Get restaurant menu - TODO"})
+	restaurantID := c.Param("id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.GetRestaurantMenu(restaurantID, limit, offset)
+		},
+		"fetching restaurant menu",
+	)
+	result.RespondWithJSON(c)
+}
+
+// Opening Hours
+func (h *RestaurantHandler) GetOpeningHours(c *gin.Context) {
+	restaurantID := c.Param("id")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.GetOpeningSchedule(restaurantID)
+		},
+		"fetching opening hours",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *RestaurantHandler) SetOpeningHours(c *gin.Context) {
+	restaurantID := c.Param("id")
+
+	var hoursRequest models.SetOpeningHoursRequest
+	if err := c.ShouldBindJSON(&hoursRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for opening hours",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.SetOpeningHours(orderViewer(c), restaurantID, &hoursRequest)
+		},
+		"setting opening hours",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *RestaurantHandler) SetHolidayHours(c *gin.Context) {
+	restaurantID := c.Param("id")
+
+	var holidayRequest models.SetHolidayHoursRequest
+	if err := c.ShouldBindJSON(&holidayRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for holiday hours",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.SetHolidayHours(orderViewer(c), restaurantID, &holidayRequest)
+		},
+		"setting holiday hours",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *RestaurantHandler) DeleteHolidayHours(c *gin.Context) {
+	restaurantID := c.Param("id")
+	date := c.Param("date")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.DeleteHolidayHours(orderViewer(c), restaurantID, date)
+		},
+		"deleting holiday hours",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *RestaurantHandler) PauseRestaurant(c *gin.Context) {
+	restaurantID := c.Param("id")
+
+	var pauseRequest models.PauseRestaurantRequest
+	if err := c.ShouldBindJSON(&pauseRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for restaurant pause",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.PauseRestaurant(orderViewer(c), restaurantID, &pauseRequest)
+		},
+		"pausing restaurant",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *RestaurantHandler) ResumeRestaurant(c *gin.Context) {
+	restaurantID := c.Param("id")
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.restaurantService.ResumeRestaurant(orderViewer(c), restaurantID)
+		},
+		"resuming restaurant",
+	)
+	result.RespondWithJSON(c)
 }
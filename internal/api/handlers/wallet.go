@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"dfood/internal/models"
+	"dfood/internal/service"
+	"dfood/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WalletHandler struct {
+	paymentService service.PaymentService
+	ledgerService  service.LedgerService
+}
+
+func NewWalletHandler(paymentService service.PaymentService, ledgerService service.LedgerService) *WalletHandler {
+	return &WalletHandler{
+		paymentService: paymentService,
+		ledgerService:  ledgerService,
+	}
+}
+
+func (h *WalletHandler) GetWallet(c *gin.Context) {
+	userID := c.Param("userId")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	result := errors.HandleError(
+		func() (interface{}, error) {
+			return h.ledgerService.GetWallet(userID, limit, offset)
+		},
+		"fetching wallet",
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *WalletHandler) TopUpWallet(c *gin.Context) {
+	var topUpRequest models.TopUpWalletRequest
+	if err := c.ShouldBindJSON(&topUpRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for wallet top-up",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+	topUpRequest.UserID = c.Param("userId")
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.paymentService.TopUpWallet(orderViewer(c), &topUpRequest)
+		},
+		"topping up wallet",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
+
+func (h *WalletHandler) GrantStoreCredit(c *gin.Context) {
+	var creditRequest models.GrantStoreCreditRequest
+	if err := c.ShouldBindJSON(&creditRequest); err != nil {
+		result := errors.HandleError(
+			func() (interface{}, error) {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload", err)
+			},
+			"binding JSON for store credit",
+		)
+		result.RespondWithJSON(c)
+		return
+	}
+	creditRequest.UserID = c.Param("userId")
+
+	result := errors.HandleErrorWithStatusCode(
+		func() (interface{}, error) {
+			return h.ledgerService.GrantStoreCredit(&creditRequest)
+		},
+		"granting store credit",
+		http.StatusCreated,
+	)
+	result.RespondWithJSON(c)
+}
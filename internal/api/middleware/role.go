@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"dfood/internal/models"
+	"dfood/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys set by RequireRoles for the authenticated caller
+const (
+	ContextUserEmail = "userEmail"
+	ContextUserRole  = "userRole"
+)
+
+// RequireRoles authenticates the bearer token and only lets callers with one of the given roles through.
+// Tokens issued before roles existed carry no role claim and are treated as customers.
+func RequireRoles(roles ...models.UserRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "No authorization token provided"})
+			return
+		}
+		claims, err := utils.ValidateToken(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization token"})
+			return
+		}
+
+		email, _ := (*claims)["sub"].(string)
+		role := models.RoleCustomer
+		if claimed, ok := (*claims)["role"].(string); ok && claimed != "" {
+			role = models.UserRole(claimed)
+		}
+
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Set(ContextUserEmail, email)
+				c.Set(ContextUserRole, role)
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"dfood/internal/api/handlers"
 	"dfood/internal/api/middleware"
+	"dfood/internal/models"
 	"dfood/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -16,7 +17,15 @@ type Dependencies struct {
 	RestaurantService   service.RestaurantService
 	FoodService         service.FoodService
 	OrderService        service.OrderService
+	GroupOrderService   service.GroupOrderService
 	PaymentService      service.PaymentService
+	LedgerService       service.LedgerService
+	PayoutService       service.PayoutService
+	PromotionService    service.PromotionService
+	LoyaltyService      service.LoyaltyService
+	ReferralService     service.ReferralService
+	GiftCardService     service.GiftCardService
+	MembershipService   service.MembershipService
 	AddressService      service.AddressService
 	FavoritesService    service.FavoritesService
 	ChatService         service.ChatService
@@ -39,7 +48,16 @@ func SetupRoutes(deps *Dependencies) *gin.Engine {
 	restaurantHandler := handlers.NewRestaurantHandler(deps.RestaurantService)
 	foodHandler := handlers.NewFoodHandler(deps.FoodService)
 	orderHandler := handlers.NewOrderHandler(deps.OrderService)
+	groupOrderHandler := handlers.NewGroupOrderHandler(deps.GroupOrderService)
 	paymentHandler := handlers.NewPaymentHandler(deps.PaymentService)
+	ledgerHandler := handlers.NewLedgerHandler(deps.LedgerService)
+	walletHandler := handlers.NewWalletHandler(deps.PaymentService, deps.LedgerService)
+	payoutHandler := handlers.NewPayoutHandler(deps.PayoutService)
+	promotionHandler := handlers.NewPromotionHandler(deps.PromotionService)
+	loyaltyHandler := handlers.NewLoyaltyHandler(deps.LoyaltyService)
+	referralHandler := handlers.NewReferralHandler(deps.ReferralService)
+	giftCardHandler := handlers.NewGiftCardHandler(deps.GiftCardService, deps.PaymentService)
+	membershipHandler := handlers.NewMembershipHandler(deps.MembershipService)
 	addressHandler := handlers.NewAddressHandler(deps.AddressService)
 	favoritesHandler := handlers.NewFavoritesHandler(deps.FavoritesService)
 	chatHandler := handlers.NewChatHandler(deps.ChatService)
@@ -103,6 +121,26 @@ func SetupRoutes(deps *Dependencies) *gin.Engine {
 			users.GET("/:userId/favorites/foods/stream", favoritesHandler.GetFavoriteFoodsStream)
 			users.GET("/:userId/favorites/restaurants/stream", favoritesHandler.GetFavoriteRestaurantsStream)
 
+			// User Wallet
+			users.GET("/:userId/wallet", walletHandler.GetWallet)
+			users.POST("/:userId/wallet/top-up", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), walletHandler.TopUpWallet)
+			users.POST("/:userId/wallet/credit", middleware.RequireRoles(models.RoleSupport, models.RoleAdmin), walletHandler.GrantStoreCredit)
+
+			// Loyalty Points
+			users.GET("/:userId/loyalty", loyaltyHandler.GetLoyalty)
+
+			// Referrals
+			users.GET("/:userId/referrals", referralHandler.GetUserReferrals)
+
+			// Gift Cards
+			users.GET("/:userId/gift-cards", giftCardHandler.GetUserGiftCards)
+
+			// Membership
+			users.GET("/:userId/membership", membershipHandler.GetUserMembership)
+			users.POST("/:userId/membership", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), membershipHandler.Subscribe)
+			users.POST("/:userId/membership/cancel", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), membershipHandler.CancelMembership)
+			users.POST("/:userId/membership/resume", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), membershipHandler.ResumeMembership)
+
 			// User Chats
 			users.GET("/:userId/chats", chatHandler.GetUserChats)
 			users.GET("/:userId/chats/stream", chatHandler.GetChatsStream)
@@ -125,6 +163,21 @@ func SetupRoutes(deps *Dependencies) *gin.Engine {
 			restaurants.GET("/search", restaurantHandler.SearchRestaurants)
 			restaurants.GET("/category/:category", restaurantHandler.GetRestaurantsByCategory)
 			restaurants.GET("/:id/menu", restaurantHandler.GetRestaurantMenu)
+
+			// Opening Hours (owners manage their own restaurant's, admins any)
+			restaurants.GET("/:id/hours", restaurantHandler.GetOpeningHours)
+			restaurants.PUT("/:id/hours", middleware.RequireRoles(models.RoleCustomer, models.RoleAdmin), restaurantHandler.SetOpeningHours)
+			restaurants.PUT("/:id/holidays", middleware.RequireRoles(models.RoleCustomer, models.RoleAdmin), restaurantHandler.SetHolidayHours)
+			restaurants.DELETE("/:id/holidays/:date", middleware.RequireRoles(models.RoleCustomer, models.RoleAdmin), restaurantHandler.DeleteHolidayHours)
+			restaurants.POST("/:id/pause", middleware.RequireRoles(models.RoleCustomer, models.RoleAdmin), restaurantHandler.PauseRestaurant)
+			restaurants.DELETE("/:id/pause", middleware.RequireRoles(models.RoleCustomer, models.RoleAdmin), restaurantHandler.ResumeRestaurant)
+
+			// Restaurant Payouts (owners see their own restaurant's, support and admin any)
+			restaurants.GET("/:id/payouts", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), payoutHandler.GetRestaurantPayouts)
+			restaurants.GET("/:id/payouts/:payoutId", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), payoutHandler.GetPayout)
+			restaurants.GET("/:id/payouts/:payoutId/statement", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), payoutHandler.DownloadStatement)
+			restaurants.PUT("/:id/commission", middleware.RequireRoles(models.RoleAdmin), payoutHandler.SetCommissionRule)
+			restaurants.PUT("/:id/loyalty-multiplier", middleware.RequireRoles(models.RoleAdmin), loyaltyHandler.SetMultiplier)
 		}
 
 		// 4. Food/Menu Endpoints
@@ -145,14 +198,39 @@ func SetupRoutes(deps *Dependencies) *gin.Engine {
 		{
 			// Order Management
 			orders.POST("", orderHandler.CreateOrder)
+			orders.GET("", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), orderHandler.SearchOrders)
+			orders.GET("/export", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), orderHandler.ExportOrders)
+			orders.POST("/quote", orderHandler.QuoteOrder)
 			orders.GET("/user/:userId", orderHandler.GetUserOrders)
 			orders.GET("/:orderId", orderHandler.GetOrderByID)
-			orders.PUT("/:orderId/status", orderHandler.UpdateOrderStatus)
-			orders.DELETE("/:orderId", orderHandler.CancelOrder)
+			orders.PUT("/:orderId/status", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), orderHandler.UpdateOrderStatus)
+			orders.PUT("/:orderId/tip", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), orderHandler.AdjustTip)
+			orders.POST("/:orderId/items/remove", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), orderHandler.RemoveOrderItems)
+			orders.DELETE("/:orderId", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), orderHandler.CancelOrder)
+			orders.GET("/:orderId/cancellation", middleware.RequireRoles(models.RoleCustomer, models.RoleSupport, models.RoleAdmin), orderHandler.PreviewCancellation)
 			orders.GET("/:orderId/track", orderHandler.TrackOrder)
+			orders.POST("/:orderId/reorder", orderHandler.Reorder)
+
+			// Order Templates
+			orders.POST("/templates", orderHandler.CreateOrderTemplate)
+			orders.GET("/templates/user/:userId", orderHandler.GetUserOrderTemplates)
+			orders.DELETE("/templates/:templateId", orderHandler.DeleteOrderTemplate)
+			orders.POST("/templates/:templateId/place", orderHandler.PlaceOrderTemplate)
 		}
 
-		// 6. Payment Endpoints
+		// 6. Group Order Endpoints
+		groupOrders := v1.Group("/group-orders")
+		{
+			groupOrders.POST("", groupOrderHandler.CreateGroupOrder)
+			groupOrders.POST("/join", groupOrderHandler.JoinGroupOrder)
+			groupOrders.GET("/:groupOrderId", groupOrderHandler.GetGroupOrder)
+			groupOrders.PUT("/:groupOrderId/items", groupOrderHandler.UpdateItems)
+			groupOrders.POST("/:groupOrderId/lock", groupOrderHandler.LockGroupOrder)
+			groupOrders.POST("/:groupOrderId/checkout", groupOrderHandler.CheckoutGroupOrder)
+			groupOrders.POST("/:groupOrderId/pay", groupOrderHandler.PayShare)
+		}
+
+		// 7. Payment Endpoints
 		payments := v1.Group("/payments")
 		{
 			// Payment Methods
@@ -164,10 +242,61 @@ func SetupRoutes(deps *Dependencies) *gin.Engine {
 			// Payment Processing
 			payments.POST("/process", paymentHandler.ProcessPayment)
 			payments.GET("/transaction/:transactionId", paymentHandler.GetTransactionDetails)
-			payments.POST("/refund", paymentHandler.ProcessRefund)
+			payments.GET("/orders/:orderId/transactions", paymentHandler.GetOrderTransactions)
+			payments.POST("/refund", middleware.RequireRoles(models.RoleSupport, models.RoleAdmin), paymentHandler.ProcessRefund)
+
+			// Payment Provider Webhooks
+			payments.POST("/webhooks/:provider", paymentHandler.HandleWebhook)
+		}
+
+		// Ledger Endpoints (finance staff only)
+		ledger := v1.Group("/ledger", middleware.RequireRoles(models.RoleSupport, models.RoleAdmin))
+		{
+			ledger.GET("/accounts/:accountType", ledgerHandler.GetAccountBalance)
+			ledger.GET("/check", ledgerHandler.CheckLedger)
+		}
+
+		// Payout Settlement Endpoints (admin only)
+		payouts := v1.Group("/payouts", middleware.RequireRoles(models.RoleAdmin))
+		{
+			payouts.POST("/settle", payoutHandler.RunSettlement)
+			payouts.POST("/:payoutId/paid", payoutHandler.MarkPaid)
+		}
+
+		// Promo Code Endpoints (admin only)
+		promotions := v1.Group("/promotions", middleware.RequireRoles(models.RoleAdmin))
+		{
+			promotions.POST("", promotionHandler.CreatePromotion)
+			promotions.GET("", promotionHandler.GetPromotions)
+			promotions.GET("/:id", promotionHandler.GetPromotion)
+			promotions.PUT("/:id", promotionHandler.UpdatePromotion)
+		}
+
+		// Referral Program Endpoints (admin only)
+		referrals := v1.Group("/referrals", middleware.RequireRoles(models.RoleAdmin))
+		{
+			referrals.GET("/report", referralHandler.GetReport)
+		}
+
+		// Gift Card Endpoints
+		giftCards := v1.Group("/gift-cards")
+		{
+			giftCards.POST("/purchase", giftCardHandler.PurchaseGiftCard)
+			giftCards.POST("/balance", giftCardHandler.GetGiftCard)
+			giftCards.POST("/redeem", giftCardHandler.RedeemGiftCard)
+			giftCards.POST("/issue", middleware.RequireRoles(models.RoleAdmin), giftCardHandler.IssueGiftCards)
+		}
+
+		// Membership Plan Endpoints (plans are public; managing them and billing runs are admin only)
+		memberships := v1.Group("/memberships")
+		{
+			memberships.GET("/plans", membershipHandler.GetPlans)
+			memberships.POST("/plans", middleware.RequireRoles(models.RoleAdmin), membershipHandler.CreatePlan)
+			memberships.PUT("/plans/:id", middleware.RequireRoles(models.RoleAdmin), membershipHandler.UpdatePlan)
+			memberships.POST("/bill", middleware.RequireRoles(models.RoleAdmin), membershipHandler.BillDue)
 		}
 
-		// 7. Chat/Messaging Endpoints
+		// 8. Chat/Messaging Endpoints
 		chats := v1.Group("/chats")
 		{
 			// Chat Management
@@ -187,7 +316,7 @@ func SetupRoutes(deps *Dependencies) *gin.Engine {
 			messages.DELETE("/:messageId", chatHandler.DeleteMessage)
 		}
 
-		// 8. Notification Endpoints
+		// 9. Notification Endpoints
 		notifications := v1.Group("/notifications")
 		{
 			// Notification Management
@@ -202,7 +331,7 @@ func SetupRoutes(deps *Dependencies) *gin.Engine {
 			pushNotifications.POST("/send", notificationHandler.SendPushNotification)
 		}
 
-		// 9. File Upload Endpoints
+		// 10. File Upload Endpoints
 		upload := v1.Group("/upload")
 		{
 			// Image Management
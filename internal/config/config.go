@@ -8,11 +8,22 @@ import (
 )
 
 type Config struct {
-	AppName  string         `yaml:"app_name"`
-	Env      string         `yaml:"env"`
-	Port     int            `yaml:"port"`
-	DB       DatabaseConfig `yaml:"db"`
-	LogLevel string         `yaml:"log_level"`
+	AppName      string             `yaml:"app_name"`
+	Env          string             `yaml:"env"`
+	Port         int                `yaml:"port"`
+	DB           DatabaseConfig     `yaml:"db"`
+	LogLevel     string             `yaml:"log_level"`
+	Tax          TaxConfig          `yaml:"tax"`
+	Cancellation CancellationConfig `yaml:"cancellation"`
+	Tip          TipConfig          `yaml:"tip"`
+	Loyalty      LoyaltyConfig      `yaml:"loyalty"`
+	Referral     ReferralConfig     `yaml:"referral"`
+	GiftCard     GiftCardConfig     `yaml:"gift_card"`
+	ServiceFee   ServiceFeeConfig   `yaml:"service_fee"`
+	Membership   MembershipConfig   `yaml:"membership"`
+	Payment      PaymentConfig      `yaml:"payment"`
+	Ledger       LedgerConfig       `yaml:"ledger"`
+	Encryption   EncryptionConfig   `yaml:"encryption"`
 }
 
 type DatabaseConfig struct {
@@ -20,6 +31,97 @@ type DatabaseConfig struct {
 	Datasource string `yaml:"datasource"`
 }
 
+// TaxConfig holds the fallback tax applied when no tax rule matches an order's jurisdiction
+type TaxConfig struct {
+	DefaultName             string `yaml:"default_name"`
+	DefaultRateBasisPoints  int64  `yaml:"default_rate_basis_points"` // 1% = 100
+	DefaultPricesIncludeTax bool   `yaml:"default_prices_include_tax"`
+}
+
+// CancellationConfig holds the fees kept when an order is cancelled once the kitchen has started on it,
+// as a share of the order subtotal in basis points (1% = 100). Confirmed orders can still be cancelled for free.
+type CancellationConfig struct {
+	PreparingFeeBasisPoints int64 `yaml:"preparing_fee_basis_points"`
+	OnTheWayFeeBasisPoints  int64 `yaml:"on_the_way_fee_basis_points"` // Support cancellations only
+}
+
+// TipConfig holds how long after delivery a customer can still change their tip
+type TipConfig struct {
+	AdjustmentWindowMinutes int `yaml:"adjustment_window_minutes"` // Default 60
+}
+
+// LoyaltyConfig holds how customers earn, spend and lose loyalty points, and the tiers they can reach
+type LoyaltyConfig struct {
+	EarnPointsPerUnit   int64                 `yaml:"earn_points_per_unit"`   // Points per whole currency unit spent on items, default 10
+	RedeemPointsPerUnit int64                 `yaml:"redeem_points_per_unit"` // Points that take one currency unit off an order, default 100
+	MinRedeemPoints     int64                 `yaml:"min_redeem_points"`      // Smallest redemption, default 500
+	ExpiryPolicy        string                `yaml:"expiry_policy"`          // per_accrual (default): each lot expires expiry_days after it is earned; inactivity: all points expire after expiry_days without earning or spending; none
+	ExpiryDays          int                   `yaml:"expiry_days"`            // Default 365
+	TierWindowDays      int                   `yaml:"tier_window_days"`       // Points earned over this many days decide the tier, default 365
+	Tiers               map[string]TierConfig `yaml:"tiers"`                  // By tier name: bronze, silver, gold
+}
+
+// TierConfig holds what it takes to reach a loyalty tier and what the tier is worth
+type TierConfig struct {
+	MinPoints                 int64 `yaml:"min_points"`
+	EarnMultiplierBasisPoints int64 `yaml:"earn_multiplier_basis_points"` // 1x = 10000
+	FreeDelivery              bool  `yaml:"free_delivery"`
+}
+
+// ReferralConfig holds the wallet credit, in minor units of the default currency, paid to both users when
+// a referred user's first order is delivered, and how many referrals a single user can be rewarded for
+type ReferralConfig struct {
+	ReferrerCredit        *int64 `yaml:"referrer_credit"`          // Default 1000 when unset; 0 pays nothing
+	RefereeCredit         *int64 `yaml:"referee_credit"`           // Default 1000 when unset; 0 pays nothing
+	MaxRewardsPerReferrer int    `yaml:"max_rewards_per_referrer"` // Zero means unlimited
+}
+
+// GiftCardConfig holds the amounts gift cards can be bought for, in minor units of the default currency, and how long they last
+type GiftCardConfig struct {
+	MinAmount             int64 `yaml:"min_amount"`              // Default 500
+	MaxAmount             int64 `yaml:"max_amount"`              // Default 50000
+	ExpiryDays            int   `yaml:"expiry_days"`             // Purchased cards, default 1825
+	PromotionalExpiryDays int   `yaml:"promotional_expiry_days"` // Issued cards without an explicit expiry, default 90
+	MaxIssueCount         int   `yaml:"max_issue_count"`         // Largest promotional batch, default 1000
+}
+
+// ServiceFeeConfig holds the service fee charged on every order, as a share of the subtotal in basis points
+// (1% = 100) kept between a minimum and maximum in minor units of the default currency
+type ServiceFeeConfig struct {
+	BasisPoints int64 `yaml:"basis_points"` // Zero charges no service fee
+	MinFee      int64 `yaml:"min_fee"`
+	MaxFee      int64 `yaml:"max_fee"` // Zero means no cap
+}
+
+// MembershipConfig holds how failed membership renewals are retried
+type MembershipConfig struct {
+	RetryDays []int `yaml:"retry_days"` // Days after each failed charge to try again before the membership lapses, default 1, 3, 5
+}
+
+// PaymentConfig selects the payment gateway; "fake" is an in-process gateway driven by test card numbers
+type PaymentConfig struct {
+	Gateway                 string            `yaml:"gateway"`
+	WebhookSecrets          map[string]string `yaml:"webhook_secrets"`           // HMAC secret per provider
+	WebhookToleranceSeconds int               `yaml:"webhook_tolerance_seconds"` // Maximum age of a signed webhook, default 300
+	FakeWebhookURL          string            `yaml:"fake_webhook_url"`          // Where the fake gateway delivers its webhooks; empty disables them
+	CaptureOn               string            `yaml:"capture_on"`                // Order status at which authorized payments are captured: confirmed (default) or delivered
+	WalletMaxTopUp          int64             `yaml:"wallet_max_top_up"`         // Largest single wallet top-up in minor units, default 50000
+}
+
+// LedgerConfig holds the commission we keep on a restaurant's share of each captured order
+type LedgerConfig struct {
+	CommissionBasisPoints int64 `yaml:"commission_basis_points"` // 1% = 100
+}
+
+// EncryptionConfig holds the master keys that wrap the per-value data keys of encrypted columns.
+// Keys are base64-encoded 32-byte values. New values are encrypted under ActiveKeyID; older keys
+// stay listed until the rotate-keys command has re-wrapped everything under the active key.
+type EncryptionConfig struct {
+	ActiveKeyID   string            `yaml:"active_key_id"`
+	MasterKeys    map[string]string `yaml:"master_keys"`
+	BlindIndexKey string            `yaml:"blind_index_key"` // HMAC key for equality lookups on encrypted columns
+}
+
 func New() (*Config, error) {
 	env := getEnvOrDefault("APP_ENV", "dev")
 	configFile := fmt.Sprintf("config/config.%s.yaml", env)
@@ -28,8 +130,9 @@ func New() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file %s: %w", configFile, err)
 	}
 
+	// ${VAR} references let secrets such as encryption keys come from the environment
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 	return &cfg, nil
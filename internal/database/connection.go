@@ -2,8 +2,11 @@ package database
 
 import (
 	"dfood/internal/config"
+	"dfood/internal/encryption"
 	"dfood/internal/models"
 	"fmt"
+	"slices"
+	"strings"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -11,7 +14,13 @@ import (
 
 var DB *gorm.DB
 
-func InitDatabase(cfg *config.Config) error {
+// CardTokenizer exchanges the raw number and security code of a card saved before tokenization
+// for a gateway token, filling in the card's Token, Brand, Last4 and Fingerprint
+type CardTokenizer func(card *models.Card, number, cvv string) error
+
+// InitDatabase connects and migrates the database. tokenizeCard is only needed while saved cards
+// still hold plaintext numbers; tools that never run first on such a database may pass nil.
+func InitDatabase(cfg *config.Config, tokenizeCard CardTokenizer) error {
 	var err error
 	DB, err = gorm.Open(sqlite.Open(cfg.DB.Datasource), &gorm.Config{})
 	if err != nil {
@@ -27,6 +36,26 @@ func InitDatabase(cfg *config.Config) error {
 	sqlDB.SetMaxOpenConns(10)
 	sqlDB.SetMaxIdleConns(5)
 
+	// Sensitive columns are encrypted at rest with keys from config
+	keyring, err := encryption.NewKeyring(cfg.Encryption)
+	if err != nil {
+		return fmt.Errorf("could not configure encryption: %w", err)
+	}
+	encryption.SetKeyring(keyring)
+	if err = encryption.RegisterCallbacks(DB); err != nil {
+		return fmt.Errorf("could not register encryption callbacks: %w", err)
+	}
+
+	// Convert monetary columns stored as REAL major units before the schema switches them to integers
+	if err = migrateMoneyColumns(DB); err != nil {
+		return fmt.Errorf("could not migrate money columns: %w", err)
+	}
+
+	// Tokenize stored card numbers and drop them and the security codes before the tokenized card schema is applied
+	if err = migrateCardSecrets(DB, tokenizeCard); err != nil {
+		return fmt.Errorf("could not migrate cards: %w", err)
+	}
+
 	// Auto migrate the schema
 	if err = DB.AutoMigrate(
 		&models.User{},
@@ -34,11 +63,36 @@ func InitDatabase(cfg *config.Config) error {
 		&models.Permission{},
 		&models.Restaurant{},
 		&models.RestaurantFoodCategory{},
+		&models.OpeningHours{},
+		&models.HolidayHours{},
 		&models.Food{},
+		&models.ModifierGroup{},
+		&models.ModifierOption{},
 		&models.Order{},
+		&models.OrderQuote{},
+		&models.OrderTemplate{},
+		&models.GroupOrder{},
+		&models.GroupOrderParticipant{},
+		&models.TaxRule{},
+		&models.DeliveryZone{},
+		&models.DeliveryFeeBand{},
 		&models.PaymentMethod{},
 		&models.Card{},
 		&models.PaymentTransaction{},
+		&models.PaymentWebhookEvent{},
+		&models.LedgerJournal{},
+		&models.LedgerEntry{},
+		&models.CommissionRule{},
+		&models.RestaurantPayout{},
+		&models.PayoutLine{},
+		&models.Promotion{},
+		&models.PromotionRedemption{},
+		&models.LoyaltyEntry{},
+		&models.LoyaltyMultiplier{},
+		&models.Referral{},
+		&models.GiftCard{},
+		&models.MembershipPlan{},
+		&models.Membership{},
 		&models.Chat{},
 		&models.Message{},
 		&models.Notification{},
@@ -52,6 +106,178 @@ func InitDatabase(cfg *config.Config) error {
 	return nil
 }
 
+// encryptedModels lists the models with columns tagged serializer:encrypted
+var encryptedModels = []interface{}{
+	&models.User{},
+	&models.Address{},
+	&models.Order{},
+	&models.OrderTemplate{},
+	&models.GroupOrder{},
+}
+
+// ReencryptAll brings every encrypted column under the active master key and refreshes blind
+// indexes, returning the number of rows changed per table
+func ReencryptAll(db *gorm.DB) (map[string]int, error) {
+	changed := make(map[string]int)
+	for _, model := range encryptedModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return changed, err
+		}
+		count, err := encryption.Reencrypt(db, model)
+		changed[stmt.Schema.Table] = count
+		if err != nil {
+			return changed, fmt.Errorf("could not re-encrypt %s: %w", stmt.Schema.Table, err)
+		}
+	}
+	return changed, nil
+}
+
+// legacyMoneyColumns lists the columns that held float64 major units before amounts became models.Money
+var legacyMoneyColumns = []struct {
+	model   interface{}
+	columns []string
+}{
+	{&models.Food{}, []string{"price"}},
+	{&models.ModifierOption{}, []string{"price_delta"}},
+	{&models.Restaurant{}, []string{"delivery_fee"}},
+	{&models.Order{}, []string{"subtotal", "delivery_fee", "tax", "total"}},
+	{&models.PaymentTransaction{}, []string{"amount"}},
+}
+
+// migrateMoneyColumns rewrites REAL major-unit amounts as integer minor units and redeclares the column as
+// integer, so running it on an already migrated database is a no-op. Amounts stored before the switch carried
+// no currency and were all in the default currency, so its exponent gives the scale.
+func migrateMoneyColumns(db *gorm.DB) error {
+	scale := "1" + strings.Repeat("0", models.DefaultCurrency.Exponent())
+	for _, legacy := range legacyMoneyColumns {
+		if !db.Migrator().HasTable(legacy.model) {
+			continue
+		}
+
+		columnTypes, err := db.Migrator().ColumnTypes(legacy.model)
+		if err != nil {
+			return err
+		}
+
+		for _, columnType := range columnTypes {
+			column := columnType.Name()
+			if !slices.Contains(legacy.columns, column) || !strings.EqualFold(columnType.DatabaseTypeName(), "real") {
+				continue
+			}
+
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(legacy.model).Where(column+" IS NOT NULL").
+					Update(column, gorm.Expr("CAST(ROUND("+column+" * "+scale+") AS INTEGER)")).Error; err != nil {
+					return err
+				}
+				return tx.Migrator().AlterColumn(legacy.model, column)
+			})
+			if err != nil {
+				return fmt.Errorf("could not convert column %s: %w", column, err)
+			}
+		}
+	}
+	return nil
+}
+
+// legacyCard is a cards row from before tokenization, with the plaintext number and security code
+type legacyCard struct {
+	ID             string
+	UserID         string
+	PAN            string
+	CVV            string
+	ExpiryMonth    int
+	ExpiryYear     int
+	CardholderName string
+}
+
+// tokenColumns are the card columns added by tokenization. They are added with an empty default
+// so existing rows can be filled in; AutoMigrate brings them in line with the model afterwards.
+var tokenColumns = []string{"token", "brand", "last4", "fingerprint"}
+
+// migrateCardSecrets exchanges every card still holding a plaintext pan for a gateway token, then drops
+// the pan and cvv columns. A card saved twice by the same user keeps one row, and payments made with
+// the duplicate are moved onto it. Everything happens in one transaction, so a gateway failure leaves
+// the cards untouched for the next start.
+func migrateCardSecrets(db *gorm.DB, tokenizeCard CardTokenizer) error {
+	if !db.Migrator().HasTable(&models.Card{}) {
+		return nil
+	}
+	hasPAN := db.Migrator().HasColumn(&models.Card{}, "pan")
+	hasCVV := db.Migrator().HasColumn(&models.Card{}, "cvv")
+	if !hasPAN && !hasCVV {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if hasPAN {
+			var cards []legacyCard
+			if err := tx.Table("cards").Select("id, user_id, pan, cvv, expiry_month, expiry_year, cardholder_name").
+				Order("created_at").Scan(&cards).Error; err != nil {
+				return err
+			}
+			if len(cards) > 0 && tokenizeCard == nil {
+				return fmt.Errorf("%d saved cards still hold card numbers; start the API server to tokenize them", len(cards))
+			}
+
+			for _, column := range tokenColumns {
+				if tx.Migrator().HasColumn(&models.Card{}, column) {
+					continue
+				}
+				if err := tx.Exec("ALTER TABLE `cards` ADD COLUMN `" + column + "` text NOT NULL DEFAULT ''").Error; err != nil {
+					return err
+				}
+			}
+
+			kept := make(map[string]string, len(cards)) // user ID and fingerprint to card ID
+			for _, legacy := range cards {
+				card := &models.Card{
+					ID:             legacy.ID,
+					UserID:         legacy.UserID,
+					ExpiryMonth:    legacy.ExpiryMonth,
+					ExpiryYear:     legacy.ExpiryYear,
+					CardholderName: legacy.CardholderName,
+				}
+				if err := tokenizeCard(card, models.NormalizeCardNumber(legacy.PAN), legacy.CVV); err != nil {
+					return fmt.Errorf("could not tokenize card %s: %w", legacy.ID, err)
+				}
+
+				key := card.UserID + ":" + card.Fingerprint
+				if keptID, exists := kept[key]; exists {
+					if err := tx.Model(&models.PaymentTransaction{}).Where("card_id = ?", card.ID).
+						Update("card_id", keptID).Error; err != nil {
+						return err
+					}
+					if err := tx.Table("cards").Where("id = ?", card.ID).Delete(nil).Error; err != nil {
+						return err
+					}
+					continue
+				}
+				kept[key] = card.ID
+
+				if err := tx.Table("cards").Where("id = ?", card.ID).Updates(map[string]interface{}{
+					"token":       card.Token,
+					"brand":       card.Brand,
+					"last4":       card.Last4,
+					"fingerprint": card.Fingerprint,
+					"expiry_year": card.ExpiryYear,
+				}).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Migrator().DropColumn(&models.Card{}, "pan"); err != nil {
+				return err
+			}
+		}
+		if hasCVV {
+			return tx.Migrator().DropColumn(&models.Card{}, "cvv")
+		}
+		return nil
+	})
+}
+
 func CloseDB() error {
 	if DB != nil {
 		sqlDB, err := DB.DB()
@@ -0,0 +1,138 @@
+package encryption
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// RegisterCallbacks keeps blind indexes in step with their source columns and encrypts values
+// passed to Update/Updates as maps, which GORM writes without running field serializers
+func RegisterCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("encryption:before_create", beforeCreate); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("encryption:before_update", beforeUpdate)
+}
+
+func beforeCreate(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	setBlindIndexes(db, db.Statement.ReflectValue)
+}
+
+func beforeUpdate(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+
+	updates, isMap := db.Statement.Dest.(map[string]interface{})
+	if !isMap {
+		// Struct updates go through the serializer; only the blind indexes need filling in
+		setBlindIndexes(db, reflect.Indirect(reflect.ValueOf(db.Statement.Dest)))
+		return
+	}
+
+	// The caller's map is left as given, so reusing it for another update cannot encrypt a value twice
+	s := db.Statement.Schema
+	sealed := make(map[string]interface{}, len(updates))
+	plaintexts := make(map[string]string)
+	for key, value := range updates {
+		field := s.LookUpField(key)
+		if !isEncryptedField(field) {
+			sealed[key] = value
+			continue
+		}
+		if text, ok := stringValue(value); ok {
+			plaintexts[field.DBName] = text
+		}
+		encrypted, err := encryptValue(field.DBName, value)
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		sealed[key] = encrypted
+	}
+	updates = sealed
+	db.Statement.Dest = sealed
+
+	if len(plaintexts) == 0 {
+		return
+	}
+	keyring, err := currentKeyring()
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	for _, field := range s.Fields {
+		source := blindIndexSource(s, field)
+		if source == nil {
+			continue
+		}
+		if text, changed := plaintexts[source.DBName]; changed {
+			updates[field.DBName] = keyring.BlindIndex(source.DBName, text)
+		}
+	}
+}
+
+// setBlindIndexes computes every blind index of the struct (or slice of structs) from its plaintext source
+func setBlindIndexes(db *gorm.DB, value reflect.Value) {
+	s := db.Statement.Schema
+	var indexes []*schema.Field
+	for _, field := range s.Fields {
+		if blindIndexSource(s, field) != nil {
+			indexes = append(indexes, field)
+		}
+	}
+	if len(indexes) == 0 {
+		return
+	}
+
+	keyring, err := currentKeyring()
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+
+	apply := func(row reflect.Value) {
+		if row.Kind() != reflect.Struct || row.Type() != s.ModelType || !row.CanAddr() {
+			return
+		}
+		for _, index := range indexes {
+			source := blindIndexSource(s, index)
+			// ValueOf on a serializer field returns the serializer wrapper, so read the field directly
+			text, _ := stringValue(source.ReflectValueOf(db.Statement.Context, row).Interface())
+			if err := index.Set(db.Statement.Context, row, keyring.BlindIndex(source.DBName, text)); err != nil {
+				db.AddError(err)
+				return
+			}
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			apply(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		apply(value)
+	}
+}
+
+func stringValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case *string:
+		if v == nil {
+			return "", true
+		}
+		return *v, true
+	case nil:
+		return "", true
+	default:
+		return "", false
+	}
+}
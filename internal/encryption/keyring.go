@@ -0,0 +1,213 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dfood/internal/config"
+)
+
+// envelopePrefix marks an encrypted value: enc:v1:<master key id>:<wrapped data key>:<ciphertext>
+const envelopePrefix = "enc:v1:"
+
+const keySize = 32
+
+// Keyring encrypts column values with a fresh AES-256-GCM data key per value and wraps each
+// data key with a master key, so rotating a master key only re-wraps data keys
+type Keyring struct {
+	activeID string
+	masters  map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// NewKeyring builds a keyring from the configured master and blind index keys
+func NewKeyring(cfg config.EncryptionConfig) (*Keyring, error) {
+	if cfg.ActiveKeyID == "" {
+		return nil, errors.New("encryption: active_key_id is required")
+	}
+
+	keyring := &Keyring{activeID: cfg.ActiveKeyID, masters: make(map[string]cipher.AEAD)}
+	for id, encoded := range cfg.MasterKeys {
+		if strings.Contains(id, ":") {
+			return nil, fmt.Errorf("encryption: master key id %q must not contain ':'", id)
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption: master key %s: %w", id, err)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		keyring.masters[id] = aead
+	}
+	if _, exists := keyring.masters[cfg.ActiveKeyID]; !exists {
+		return nil, fmt.Errorf("encryption: active master key %s is not configured", cfg.ActiveKeyID)
+	}
+
+	indexKey, err := decodeKey(cfg.BlindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: blind index key: %w", err)
+	}
+	keyring.indexKey = indexKey
+
+	return keyring, nil
+}
+
+// IsEncrypted reports whether a stored value is an encryption envelope
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// Encrypt seals plaintext for the given column under a new data key wrapped by the active master key.
+// The column name is authenticated, so a value copied into another column fails to decrypt.
+func (k *Keyring) Encrypt(column, plaintext string) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("encryption: generating data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(dataAEAD, []byte(plaintext), []byte(column))
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.masters[k.activeID], dataKey, []byte(k.activeID))
+	if err != nil {
+		return "", err
+	}
+
+	return envelopePrefix + k.activeID + ":" + encode(wrapped) + ":" + encode(ciphertext), nil
+}
+
+// Decrypt opens an envelope produced by Encrypt for the same column
+func (k *Keyring) Decrypt(column, value string) (string, error) {
+	keyID, dataKey, ciphertext, err := k.open(value)
+	if err != nil {
+		return "", err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := unseal(dataAEAD, ciphertext, []byte(column))
+	if err != nil {
+		return "", fmt.Errorf("encryption: decrypting %s under key %s: %w", column, keyID, err)
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap re-wraps an envelope's data key under the active master key without touching the ciphertext.
+// It reports false when the value is already wrapped by the active key.
+func (k *Keyring) Rewrap(value string) (string, bool, error) {
+	keyID, dataKey, ciphertext, err := k.open(value)
+	if err != nil {
+		return "", false, err
+	}
+	if keyID == k.activeID {
+		return value, false, nil
+	}
+
+	wrapped, err := seal(k.masters[k.activeID], dataKey, []byte(k.activeID))
+	if err != nil {
+		return "", false, err
+	}
+	return envelopePrefix + k.activeID + ":" + encode(wrapped) + ":" + encode(ciphertext), true, nil
+}
+
+// BlindIndex returns a keyed hash of the value that supports equality lookups on an encrypted column.
+// Surrounding whitespace is ignored; empty values have no index.
+func (k *Keyring) BlindIndex(column, value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(column + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// open splits an envelope and unwraps its data key
+func (k *Keyring) open(value string) (string, []byte, []byte, error) {
+	if !IsEncrypted(value) {
+		return "", nil, nil, errors.New("encryption: value is not an encryption envelope")
+	}
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, errors.New("encryption: malformed envelope")
+	}
+
+	keyID := parts[0]
+	master, exists := k.masters[keyID]
+	if !exists {
+		return "", nil, nil, fmt.Errorf("encryption: master key %s is not configured", keyID)
+	}
+	wrapped, err := decode(parts[1])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("encryption: malformed data key: %w", err)
+	}
+	ciphertext, err := decode(parts[2])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("encryption: malformed ciphertext: %w", err)
+	}
+	dataKey, err := unseal(master, wrapped, []byte(keyID))
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("encryption: unwrapping data key with %s: %w", keyID, err)
+	}
+	return keyID, dataKey, ciphertext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts with a random nonce that is prepended to the result
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("encryption: generating nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func unseal(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("key must be base64: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(data string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(data)
+}
@@ -0,0 +1,128 @@
+package encryption
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const rotateBatchSize = 500
+
+// Reencrypt brings every encrypted column of the model's table under the active master key:
+// envelopes wrapped by an older key are re-wrapped, plaintext written before the column was
+// encrypted is encrypted, and blind indexes are recomputed. It returns the number of rows changed.
+func Reencrypt(db *gorm.DB, model interface{}) (int, error) {
+	keyring, err := currentKeyring()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return 0, err
+	}
+	s := stmt.Schema
+	if s.PrioritizedPrimaryField == nil {
+		return 0, fmt.Errorf("encryption: %s has no primary key", s.Table)
+	}
+	primaryKey := s.PrioritizedPrimaryField.DBName
+
+	var encrypted, indexes []*schema.Field
+	columns := []string{primaryKey}
+	for _, field := range s.Fields {
+		switch {
+		case isEncryptedField(field):
+			encrypted = append(encrypted, field)
+		case blindIndexSource(s, field) != nil:
+			indexes = append(indexes, field)
+		default:
+			continue
+		}
+		columns = append(columns, field.DBName)
+	}
+	if len(encrypted) == 0 {
+		return 0, nil
+	}
+
+	changed := 0
+	var lastKey interface{}
+	for {
+		query := db.Table(s.Table).Select(columns).Order(primaryKey).Limit(rotateBatchSize)
+		if lastKey != nil {
+			query = query.Where(primaryKey+" > ?", lastKey)
+		}
+		var rows []map[string]interface{}
+		if err := query.Find(&rows).Error; err != nil {
+			return changed, err
+		}
+		if len(rows) == 0 {
+			return changed, nil
+		}
+
+		for _, row := range rows {
+			updates := make(map[string]interface{})
+			plaintexts := make(map[string]string)
+
+			for _, field := range encrypted {
+				stored, ok := rawString(row[field.DBName])
+				if !ok || stored == "" {
+					plaintexts[field.DBName] = stored
+					continue
+				}
+
+				if !IsEncrypted(stored) {
+					sealed, err := keyring.Encrypt(field.DBName, stored)
+					if err != nil {
+						return changed, err
+					}
+					updates[field.DBName] = sealed
+					plaintexts[field.DBName] = stored
+					continue
+				}
+
+				plaintext, err := keyring.Decrypt(field.DBName, stored)
+				if err != nil {
+					return changed, fmt.Errorf("%s %v: %w", s.Table, row[primaryKey], err)
+				}
+				plaintexts[field.DBName] = plaintext
+				rewrapped, rotated, err := keyring.Rewrap(stored)
+				if err != nil {
+					return changed, err
+				}
+				if rotated {
+					updates[field.DBName] = rewrapped
+				}
+			}
+
+			for _, index := range indexes {
+				source := blindIndexSource(s, index)
+				current, _ := rawString(row[index.DBName])
+				if expected := keyring.BlindIndex(source.DBName, plaintexts[source.DBName]); expected != current {
+					updates[index.DBName] = expected
+				}
+			}
+
+			if len(updates) > 0 {
+				// Writing through Table bypasses the model callbacks, so values are stored exactly as given
+				if err := db.Table(s.Table).Where(primaryKey+" = ?", row[primaryKey]).UpdateColumns(updates).Error; err != nil {
+					return changed, err
+				}
+				changed++
+			}
+		}
+
+		lastKey = rows[len(rows)-1][primaryKey]
+	}
+}
+
+func rawString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,139 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName is used in model tags: `gorm:"serializer:encrypted"`
+const SerializerName = "encrypted"
+
+var (
+	keyringMu     sync.RWMutex
+	activeKeyring *Keyring
+)
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// SetKeyring installs the keyring used by the serializer, callbacks and BlindIndex
+func SetKeyring(keyring *Keyring) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	activeKeyring = keyring
+}
+
+func currentKeyring() (*Keyring, error) {
+	keyringMu.RLock()
+	defer keyringMu.RUnlock()
+	if activeKeyring == nil {
+		return nil, errors.New("encryption: keyring is not configured")
+	}
+	return activeKeyring, nil
+}
+
+// BlindIndex returns the lookup hash for a value of an encrypted column, for use in
+// queries such as Where("phone_number_index = ?", encryption.BlindIndex("phone_number", phone))
+func BlindIndex(column, value string) string {
+	keyring, err := currentKeyring()
+	if err != nil {
+		return ""
+	}
+	return keyring.BlindIndex(column, value)
+}
+
+// Serializer transparently encrypts string and *string fields at rest.
+// Values written before a column was encrypted are read back as plaintext until rotate-keys encrypts them.
+type Serializer struct{}
+
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+
+	if dbValue != nil {
+		var stored string
+		switch v := dbValue.(type) {
+		case string:
+			stored = v
+		case []byte:
+			stored = string(v)
+		default:
+			return fmt.Errorf("encryption: cannot scan %T into %s", dbValue, field.Name)
+		}
+
+		plaintext := stored
+		if IsEncrypted(stored) {
+			keyring, err := currentKeyring()
+			if err != nil {
+				return err
+			}
+			if plaintext, err = keyring.Decrypt(field.DBName, stored); err != nil {
+				return err
+			}
+		}
+
+		if field.FieldType.Kind() == reflect.Ptr {
+			pointer := reflect.New(field.FieldType.Elem())
+			pointer.Elem().SetString(plaintext)
+			fieldValue.Elem().Set(pointer)
+		} else {
+			fieldValue.Elem().SetString(plaintext)
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	return encryptValue(field.DBName, fieldValue)
+}
+
+// encryptValue encrypts a string or *string for a column; nil stays NULL and empty strings stay empty.
+// Values that already look like envelopes are encrypted too: they come from the app, and storing them
+// as given would leave a row that cannot be decrypted. Only Reencrypt writes envelopes as they are.
+func encryptValue(column string, value interface{}) (interface{}, error) {
+	var plaintext string
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		plaintext = v
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		plaintext = *v
+	default:
+		return nil, fmt.Errorf("encryption: %s must be a string, got %T", column, value)
+	}
+
+	if plaintext == "" {
+		return plaintext, nil
+	}
+	keyring, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	return keyring.Encrypt(column, plaintext)
+}
+
+// isEncryptedField reports whether a model field uses the encrypted serializer
+func isEncryptedField(field *schema.Field) bool {
+	return field != nil && field.TagSettings["SERIALIZER"] == SerializerName
+}
+
+// blindIndexSource returns the field a blind index column is computed from, declared with a
+// `blind_index:"<column>"` struct tag on the index field
+func blindIndexSource(s *schema.Schema, field *schema.Field) *schema.Field {
+	source := field.Tag.Get("blind_index")
+	if source == "" {
+		return nil
+	}
+	return s.LookUpField(source)
+}
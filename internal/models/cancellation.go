@@ -0,0 +1,93 @@
+package models
+
+// CancellationActor identifies who cancelled an order
+type CancellationActor string
+
+const (
+	CancelledByCustomer   CancellationActor = "customer"
+	CancelledByRestaurant CancellationActor = "restaurant"
+	CancelledBySupport    CancellationActor = "support"
+)
+
+// CancellationReason is a mandatory reason code recorded on every cancellation
+type CancellationReason string
+
+const (
+	CancellationReasonChangedMind      CancellationReason = "changed_mind"
+	CancellationReasonOrderedByMistake CancellationReason = "ordered_by_mistake"
+	CancellationReasonWrongAddress     CancellationReason = "wrong_address"
+	CancellationReasonTakingTooLong    CancellationReason = "taking_too_long"
+	CancellationReasonItemUnavailable  CancellationReason = "item_unavailable"
+	CancellationReasonRestaurantClosed CancellationReason = "restaurant_closed"
+	CancellationReasonRestaurantBusy   CancellationReason = "restaurant_busy"
+	CancellationReasonNoCourier        CancellationReason = "no_courier"
+	CancellationReasonPaymentIssue     CancellationReason = "payment_issue"
+	CancellationReasonSuspectedFraud   CancellationReason = "suspected_fraud"
+	CancellationReasonOther            CancellationReason = "other"
+)
+
+// cancellationReasonsByActor lists the reason codes each actor may give
+var cancellationReasonsByActor = map[CancellationActor][]CancellationReason{
+	CancelledByCustomer: {
+		CancellationReasonChangedMind,
+		CancellationReasonOrderedByMistake,
+		CancellationReasonWrongAddress,
+		CancellationReasonTakingTooLong,
+		CancellationReasonOther,
+	},
+	CancelledByRestaurant: {
+		CancellationReasonItemUnavailable,
+		CancellationReasonRestaurantClosed,
+		CancellationReasonRestaurantBusy,
+		CancellationReasonNoCourier,
+		CancellationReasonOther,
+	},
+	CancelledBySupport: {
+		CancellationReasonChangedMind,
+		CancellationReasonOrderedByMistake,
+		CancellationReasonWrongAddress,
+		CancellationReasonTakingTooLong,
+		CancellationReasonItemUnavailable,
+		CancellationReasonRestaurantClosed,
+		CancellationReasonRestaurantBusy,
+		CancellationReasonNoCourier,
+		CancellationReasonPaymentIssue,
+		CancellationReasonSuspectedFraud,
+		CancellationReasonOther,
+	},
+}
+
+// IsValid reports whether the actor is a known cancellation actor
+func (a CancellationActor) IsValid() bool {
+	_, ok := cancellationReasonsByActor[a]
+	return ok
+}
+
+// IsValidFor reports whether the reason code may be given by the actor
+func (r CancellationReason) IsValidFor(actor CancellationActor) bool {
+	for _, reason := range cancellationReasonsByActor[actor] {
+		if reason == r {
+			return true
+		}
+	}
+	return false
+}
+
+// RefundStatus represents the state of the refund created when an order is cancelled
+type RefundStatus string
+
+const (
+	RefundStatusNone     RefundStatus = "none"     // Nothing was paid or nothing is refundable
+	RefundStatusRefunded RefundStatus = "refunded" // The refundable portion has been refunded
+	RefundStatusFailed   RefundStatus = "failed"   // The refund could not be completed and needs attention
+)
+
+// CancellationDecision is the outcome of applying the cancellation policy to an order
+type CancellationDecision struct {
+	Allowed        bool        `json:"allowed"`
+	Message        string      `json:"message,omitempty"` // Why the cancellation is not allowed
+	Status         OrderStatus `json:"status"`
+	FeeBasisPoints int64       `json:"fee_basis_points"` // Share of the subtotal kept as a fee, 1% = 100
+	Fee            Money       `json:"fee"`
+	RefundAmount   Money       `json:"refund_amount"`
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const earthRadiusKm = 6371.0
+
+// GeoPoint represents a latitude/longitude pair
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// DistanceKm returns the great-circle (haversine) distance to another point in kilometers
+func (p GeoPoint) DistanceKm(other GeoPoint) float64 {
+	lat1 := p.Latitude * math.Pi / 180
+	lat2 := other.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (other.Longitude - p.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// GeoPolygon is a custom type for storing a polygon's vertices in GORM
+type GeoPolygon []GeoPoint
+
+func (gp GeoPolygon) Value() (driver.Value, error) {
+	return json.Marshal(gp)
+}
+
+func (gp *GeoPolygon) Scan(value interface{}) error {
+	if value == nil {
+		*gp = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, gp)
+}
+
+// Contains reports whether the point lies inside the polygon using ray casting.
+// The polygon is closed implicitly, so the first vertex need not be repeated.
+func (gp GeoPolygon) Contains(point GeoPoint) bool {
+	inside := false
+	n := len(gp)
+	if n < 3 {
+		return false
+	}
+
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := gp[i], gp[j]
+		if (a.Latitude > point.Latitude) != (b.Latitude > point.Latitude) &&
+			point.Longitude < (b.Longitude-a.Longitude)*(point.Latitude-a.Latitude)/(b.Latitude-a.Latitude)+a.Longitude {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// DeliveryZone represents an area a restaurant delivers to
+type DeliveryZone struct {
+	ID           string     `json:"id" gorm:"primaryKey;column:id"`
+	RestaurantID string     `json:"restaurant_id" gorm:"column:restaurant_id;not null;index"`
+	Name         string     `json:"name" gorm:"column:name;not null"`
+	Polygon      GeoPolygon `json:"polygon" gorm:"column:polygon;not null"`
+	IsActive     bool       `json:"is_active" gorm:"column:is_active;default:true"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// DeliveryFeeBand represents the delivery fee charged up to a given distance.
+// Bands without a restaurant are platform defaults used when a restaurant has none of its own.
+type DeliveryFeeBand struct {
+	ID            string    `json:"id" gorm:"primaryKey;column:id"`
+	RestaurantID  *string   `json:"restaurant_id,omitempty" gorm:"column:restaurant_id;index"`
+	MaxDistanceKm float64   `json:"max_distance_km" gorm:"column:max_distance_km;not null"`
+	Fee           Money     `json:"fee" gorm:"column:fee;not null"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// BeforeSave refuses fees in a currency other than DefaultCurrency
+func (b *DeliveryFeeBand) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(DefaultCurrency, b.Fee)
+}
+
+// DeliveryQuote represents the delivery pricing for a basket and destination
+type DeliveryQuote struct {
+	DistanceKm    *float64 `json:"distance_km,omitempty"`
+	ZoneID        *string  `json:"zone_id,omitempty"`
+	Fee           Money    `json:"fee"`
+	SmallOrderFee Money    `json:"small_order_fee"`
+}
@@ -2,28 +2,76 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Food represents the food entity
 type Food struct {
-	ID              string      `json:"id" gorm:"primaryKey;column:id"`
-	Name            string      `json:"name" gorm:"column:name;not null;index"`
-	Description     string      `json:"description" gorm:"column:description;not null"`
-	Price           float64     `json:"price" gorm:"column:price;not null"`
-	Rating          float64     `json:"rating" gorm:"column:rating;default:0.0"`
-	ImageURL        string      `json:"image_url" gorm:"column:image_url;not null"`
-	Category        string      `json:"category" gorm:"column:category;not null;index"`
-	RestaurantID    string      `json:"restaurant_id" gorm:"column:restaurant_id;not null;index"`
-	RestaurantName  string      `json:"restaurant_name" gorm:"column:restaurant_name;not null"`
-	Ingredients     StringArray `json:"ingredients" gorm:"column:ingredients"`
-	IsAvailable     bool        `json:"is_available" gorm:"column:is_available;default:true"`
-	PreparationTime string      `json:"preparation_time" gorm:"column:preparation_time"`
-	Calories        int         `json:"calories" gorm:"column:calories;default:0"`
-	Quantity        int         `json:"quantity" gorm:"column:quantity;default:1"`
-	IsVegetarian    bool        `json:"is_vegetarian" gorm:"column:is_vegetarian;default:false"`
-	IsVegan         bool        `json:"is_vegan" gorm:"column:is_vegan;default:false"`
-	IsGlutenFree    bool        `json:"is_gluten_free" gorm:"column:is_gluten_free;default:false"`
-	CreatedAt       time.Time   `json:"created_at" gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt       time.Time   `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
-	Restaurant      Restaurant  `json:"restaurant,omitempty" gorm:"foreignKey:RestaurantID"`
+	ID              string          `json:"id" gorm:"primaryKey;column:id"`
+	Name            string          `json:"name" gorm:"column:name;not null;index"`
+	Description     string          `json:"description" gorm:"column:description;not null"`
+	Price           Money           `json:"price" gorm:"column:price;not null"`
+	Rating          float64         `json:"rating" gorm:"column:rating;default:0.0"`
+	ImageURL        string          `json:"image_url" gorm:"column:image_url;not null"`
+	Category        string          `json:"category" gorm:"column:category;not null;index"`
+	TaxCategory     string          `json:"tax_category" gorm:"column:tax_category"` // Empty for standard-rated, or alcohol, grocery, ...
+	RestaurantID    string          `json:"restaurant_id" gorm:"column:restaurant_id;not null;index"`
+	RestaurantName  string          `json:"restaurant_name" gorm:"column:restaurant_name;not null"`
+	Ingredients     StringArray     `json:"ingredients" gorm:"column:ingredients"`
+	IsAvailable     bool            `json:"is_available" gorm:"column:is_available;default:true"`
+	PreparationTime string          `json:"preparation_time" gorm:"column:preparation_time"`
+	Calories        int             `json:"calories" gorm:"column:calories;default:0"`
+	Quantity        int             `json:"quantity" gorm:"column:quantity;default:1"`
+	IsVegetarian    bool            `json:"is_vegetarian" gorm:"column:is_vegetarian;default:false"`
+	IsVegan         bool            `json:"is_vegan" gorm:"column:is_vegan;default:false"`
+	IsGlutenFree    bool            `json:"is_gluten_free" gorm:"column:is_gluten_free;default:false"`
+	CreatedAt       time.Time       `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt       time.Time       `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+	Restaurant      Restaurant      `json:"restaurant,omitempty" gorm:"foreignKey:RestaurantID"`
+	ModifierGroups  []ModifierGroup `json:"modifier_groups,omitempty" gorm:"foreignKey:FoodID"`
+}
+
+// BeforeSave refuses prices in a currency other than DefaultCurrency, the only one catalog prices are stored in
+func (f *Food) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(DefaultCurrency, f.Price)
+}
+
+// ModifierGroup represents a set of choices offered on a food item (e.g. size, extras, sides)
+type ModifierGroup struct {
+	ID            string           `json:"id" gorm:"primaryKey;column:id"`
+	FoodID        string           `json:"food_id" gorm:"column:food_id;not null;index"`
+	Name          string           `json:"name" gorm:"column:name;not null"`
+	MinSelections int              `json:"min_selections" gorm:"column:min_selections;default:0"`
+	MaxSelections int              `json:"max_selections" gorm:"column:max_selections;not null"` // 0 means unlimited; no column default, so a 0 is stored as given
+	IsRequired    bool             `json:"is_required" gorm:"column:is_required;default:false"`
+	SortOrder     int              `json:"sort_order" gorm:"column:sort_order;default:0"`
+	CreatedAt     time.Time        `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt     time.Time        `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+	Options       []ModifierOption `json:"options,omitempty" gorm:"foreignKey:GroupID"`
+}
+
+// ModifierOption represents a selectable option within a modifier group
+type ModifierOption struct {
+	ID          string    `json:"id" gorm:"primaryKey;column:id"`
+	GroupID     string    `json:"group_id" gorm:"column:group_id;not null;index"`
+	Name        string    `json:"name" gorm:"column:name;not null"`
+	PriceDelta  Money     `json:"price_delta" gorm:"column:price_delta;default:0"`
+	IsAvailable bool      `json:"is_available" gorm:"column:is_available;default:true"`
+	SortOrder   int       `json:"sort_order" gorm:"column:sort_order;default:0"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// BeforeSave refuses price deltas in a currency other than DefaultCurrency
+func (o *ModifierOption) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(DefaultCurrency, o.PriceDelta)
+}
+
+// EffectiveMinSelections returns the minimum number of options that must be chosen
+func (g *ModifierGroup) EffectiveMinSelections() int {
+	if g.IsRequired && g.MinSelections < 1 {
+		return 1
+	}
+	return g.MinSelections
 }
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GiftCardSource records how a gift card came to exist
+type GiftCardSource string
+
+const (
+	GiftCardSourcePurchase    GiftCardSource = "purchase"    // Bought by a customer with a saved card
+	GiftCardSourcePromotional GiftCardSource = "promotional" // Issued by an admin at our expense
+)
+
+// GiftCardStatus tracks a gift card through its life
+type GiftCardStatus string
+
+const (
+	GiftCardStatusActive   GiftCardStatus = "active"   // Can be spent at checkout or redeemed to a wallet
+	GiftCardStatusRedeemed GiftCardStatus = "redeemed" // Its balance was moved to a customer's wallet
+	GiftCardStatusExpired  GiftCardStatus = "expired"  // Past its expiry; any unspent balance is forfeited
+)
+
+// GiftCardPaymentMethodID identifies payments made with a gift card at checkout
+const GiftCardPaymentMethodID = "gift_card"
+
+// GiftCard is a prepaid code that can be spent at checkout or redeemed to a wallet. Only a hash of the
+// code is stored; the code itself is shown once, when the card is created. The balance lives in the
+// ledger's gift card account, so partial use needs no bookkeeping on the card.
+type GiftCard struct {
+	ID             string         `json:"id" gorm:"primaryKey;column:id"`
+	CodeHash       string         `json:"-" gorm:"column:code_hash;not null;uniqueIndex"`
+	Last4          string         `json:"last4" gorm:"column:last4;not null"` // End of the code, to tell cards apart
+	Source         GiftCardSource `json:"source" gorm:"column:source;not null"`
+	Status         GiftCardStatus `json:"status" gorm:"column:status;not null;default:'active';index"`
+	InitialAmount  Money          `json:"initial_amount" gorm:"column:initial_amount;not null"`
+	Currency       Currency       `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	PurchaserID    *string        `json:"purchaser_id,omitempty" gorm:"column:purchaser_id;index"`
+	PaymentID      *string        `json:"payment_id,omitempty" gorm:"column:payment_id"` // The card payment that bought it
+	BatchID        *string        `json:"batch_id,omitempty" gorm:"column:batch_id;index"`
+	IssuedBy       *string        `json:"issued_by,omitempty" gorm:"column:issued_by"`
+	RecipientEmail *string        `json:"recipient_email,omitempty" gorm:"column:recipient_email"`
+	Message        *string        `json:"message,omitempty" gorm:"column:message"`
+	RedeemedBy     *string        `json:"redeemed_by,omitempty" gorm:"column:redeemed_by;index"`
+	RedeemedAt     *time.Time     `json:"redeemed_at,omitempty" gorm:"column:redeemed_at"`
+	ExpiresAt      time.Time      `json:"expires_at" gorm:"column:expires_at;not null;index"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+	Code           string         `json:"code,omitempty" gorm:"-"` // Only returned when the card is created
+	Balance        Money          `json:"balance" gorm:"-"`        // From the ledger
+}
+
+// AfterFind stamps the gift card currency onto its amounts, which store only minor units
+func (g *GiftCard) AfterFind(tx *gorm.DB) error {
+	if g.Currency == "" {
+		g.Currency = DefaultCurrency
+	}
+	g.InitialAmount.Currency = g.Currency
+	g.Balance = Zero(g.Currency)
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the gift card's
+func (g *GiftCard) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(g.Currency, g.InitialAmount)
+}
+
+// IsExpired reports whether the gift card can no longer be used
+func (g *GiftCard) IsExpired(now time.Time) bool {
+	return g.Status == GiftCardStatusExpired || !now.Before(g.ExpiresAt)
+}
+
+// GiftCardBatch is a set of promotional gift cards issued together, with their codes
+type GiftCardBatch struct {
+	BatchID   string     `json:"batch_id"`
+	Count     int        `json:"count"`
+	Amount    Money      `json:"amount"` // Per card
+	Total     Money      `json:"total"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	GiftCards []GiftCard `json:"gift_cards"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GroupOrderStatus represents the lifecycle of a shared group cart
+type GroupOrderStatus string
+
+const (
+	GroupOrderStatusOpen       GroupOrderStatus = "open"        // Participants can join and change their items
+	GroupOrderStatusLocked     GroupOrderStatus = "locked"      // The host has frozen the cart for checkout
+	GroupOrderStatusCheckedOut GroupOrderStatus = "checked_out" // The order has been placed
+)
+
+// GroupPaymentMode decides who pays for a group order
+type GroupPaymentMode string
+
+const (
+	GroupPaymentModeHost  GroupPaymentMode = "host"  // The host pays the whole order
+	GroupPaymentModeSplit GroupPaymentMode = "split" // Each participant pays their own portion
+)
+
+// GroupPaymentStatus represents the payment state of one participant's portion
+type GroupPaymentStatus string
+
+const (
+	GroupPaymentStatusUnpaid      GroupPaymentStatus = "unpaid"
+	GroupPaymentStatusPaid        GroupPaymentStatus = "paid"
+	GroupPaymentStatusFailed      GroupPaymentStatus = "failed"
+	GroupPaymentStatusCoveredHost GroupPaymentStatus = "covered_by_host"
+	GroupPaymentStatusNotRequired GroupPaymentStatus = "not_required" // Joined but ordered nothing
+)
+
+// GroupOrder represents a shared cart for one restaurant that several users fill before the host checks out
+type GroupOrder struct {
+	ID                string                  `json:"id" gorm:"primaryKey;column:id"`
+	HostUserID        string                  `json:"host_user_id" gorm:"column:host_user_id;not null;index"`
+	RestaurantID      string                  `json:"restaurant_id" gorm:"column:restaurant_id;not null"`
+	RestaurantName    string                  `json:"restaurant_name" gorm:"column:restaurant_name;not null"`
+	InviteCode        string                  `json:"invite_code" gorm:"column:invite_code;not null;uniqueIndex"`
+	Status            GroupOrderStatus        `json:"status" gorm:"column:status;not null;default:'open'"`
+	PaymentMode       GroupPaymentMode        `json:"payment_mode" gorm:"column:payment_mode;not null;default:'host'"`
+	DeliveryAddress   string                  `json:"delivery_address" gorm:"column:delivery_address;serializer:encrypted"`
+	DeliveryAddressID *string                 `json:"delivery_address_id,omitempty" gorm:"column:delivery_address_id"`
+	PaymentMethod     string                  `json:"payment_method" gorm:"column:payment_method"`
+	OrderID           *string                 `json:"order_id,omitempty" gorm:"column:order_id"` // Set at checkout
+	LockedAt          *time.Time              `json:"locked_at,omitempty" gorm:"column:locked_at"`
+	CreatedAt         time.Time               `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt         time.Time               `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+	Participants      []GroupOrderParticipant `json:"participants,omitempty" gorm:"foreignKey:GroupOrderID"`
+}
+
+// GroupOrderParticipant represents one user's items and portion of a group order
+type GroupOrderParticipant struct {
+	ID                   uint               `json:"id" gorm:"primaryKey;autoIncrement"`
+	GroupOrderID         string             `json:"group_order_id" gorm:"column:group_order_id;not null;uniqueIndex:idx_group_order_participant"`
+	UserID               string             `json:"user_id" gorm:"column:user_id;not null;uniqueIndex:idx_group_order_participant;index"`
+	DisplayName          string             `json:"display_name" gorm:"column:display_name"`
+	IsHost               bool               `json:"is_host" gorm:"column:is_host;default:false"`
+	Items                OrderItemsArray    `json:"items" gorm:"column:items"`
+	OrderID              *string            `json:"order_id,omitempty" gorm:"column:order_id;index"`
+	Currency             Currency           `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	Subtotal             Money              `json:"subtotal" gorm:"column:subtotal;not null;default:0"`
+	Share                Money              `json:"share" gorm:"column:share;not null;default:0"` // Subtotal plus a proportional part of fees and tax
+	PaymentMethodID      string             `json:"payment_method_id" gorm:"column:payment_method_id"`
+	PaymentStatus        GroupPaymentStatus `json:"payment_status" gorm:"column:payment_status;not null;default:'unpaid'"`
+	PaymentTransactionID *string            `json:"payment_transaction_id,omitempty" gorm:"column:payment_transaction_id"`
+	PaymentFailureReason *string            `json:"payment_failure_reason,omitempty" gorm:"column:payment_failure_reason"`
+	CreatedAt            time.Time          `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt            time.Time          `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// AfterFind stamps the participant currency onto its monetary columns, which store only minor units
+func (p *GroupOrderParticipant) AfterFind(tx *gorm.DB) error {
+	if p.Currency == "" {
+		p.Currency = DefaultCurrency
+	}
+	p.Subtotal.Currency = p.Currency
+	p.Share.Currency = p.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the participant's
+func (p *GroupOrderParticipant) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(p.Currency, p.Subtotal, p.Share)
+}
+
+// GroupOrderPortion is a participant's part of a group order, shown alongside the order
+type GroupOrderPortion struct {
+	GroupOrderID  string             `json:"group_order_id"`
+	IsHost        bool               `json:"is_host"`
+	Items         OrderItemsArray    `json:"items"`
+	Subtotal      Money              `json:"subtotal"`
+	Share         Money              `json:"share"`
+	PaymentStatus GroupPaymentStatus `json:"payment_status"`
+}
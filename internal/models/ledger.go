@@ -0,0 +1,164 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LedgerAccountType identifies a kind of ledger account. Customer, restaurant and courier
+// accounts are kept per owner; the others are single platform-wide accounts.
+type LedgerAccountType string
+
+const (
+	LedgerAccountGateway      LedgerAccountType = "gateway"       // Funds held at the payment gateway
+	LedgerAccountCustomer     LedgerAccountType = "customer"      // What we owe a customer: their wallet balance
+	LedgerAccountRestaurant   LedgerAccountType = "restaurant"    // What we owe a restaurant
+	LedgerAccountCourier      LedgerAccountType = "courier"       // What we owe a courier
+	LedgerAccountPlatformFees LedgerAccountType = "platform_fees" // Commission and delivery fees we have earned
+	LedgerAccountTaxPayable   LedgerAccountType = "tax_payable"   // Tax collected on behalf of tax authorities
+	LedgerAccountRefunds      LedgerAccountType = "refunds"       // Money returned to customers
+	LedgerAccountTipsPayable  LedgerAccountType = "tips_payable"  // Tips collected and not yet settled to a courier
+	LedgerAccountGiftCards    LedgerAccountType = "gift_cards"    // What we owe the holder of a gift card: its balance
+)
+
+// IsValid reports whether the account type is known
+func (t LedgerAccountType) IsValid() bool {
+	switch t {
+	case LedgerAccountGateway, LedgerAccountCustomer, LedgerAccountRestaurant, LedgerAccountCourier,
+		LedgerAccountPlatformFees, LedgerAccountTaxPayable, LedgerAccountRefunds, LedgerAccountTipsPayable, LedgerAccountGiftCards:
+		return true
+	}
+	return false
+}
+
+// HasOwner reports whether accounts of this type are kept per customer, restaurant, courier or gift card
+func (t LedgerAccountType) HasOwner() bool {
+	return t == LedgerAccountCustomer || t == LedgerAccountRestaurant || t == LedgerAccountCourier || t == LedgerAccountGiftCards
+}
+
+// LedgerJournalType describes the money movement a journal records
+type LedgerJournalType string
+
+const (
+	LedgerJournalCapture        LedgerJournalType = "capture"          // Card funds collected for an order
+	LedgerJournalCommission     LedgerJournalType = "commission"       // Our cut of a restaurant's takings
+	LedgerJournalRefund         LedgerJournalType = "refund"           // Funds returned to a customer's card
+	LedgerJournalRefundReversal LedgerJournalType = "refund_reversal"  // A refund the gateway could not deliver
+	LedgerJournalWalletTopUp    LedgerJournalType = "wallet_top_up"    // Card funds added to a customer's wallet
+	LedgerJournalStoreCredit    LedgerJournalType = "store_credit"     // Goodwill credit granted to a customer's wallet
+	LedgerJournalRefundShare    LedgerJournalType = "refund_share"     // A restaurant's part of a refund, charged at settlement
+	LedgerJournalPayout         LedgerJournalType = "payout"           // Money transferred to a restaurant
+	LedgerJournalCourierTip     LedgerJournalType = "courier_tip"      // A collected tip settled to the order's courier
+	LedgerJournalReferral       LedgerJournalType = "referral_credit"  // Wallet credit for a referral that converted
+	LedgerJournalGiftCardSale   LedgerJournalType = "gift_card_sale"   // Card funds paid for a gift card
+	LedgerJournalGiftCardIssue  LedgerJournalType = "gift_card_issue"  // A promotional gift card issued at our expense
+	LedgerJournalGiftCardRedeem LedgerJournalType = "gift_card_redeem" // A gift card's balance moved to a customer's wallet
+	LedgerJournalGiftCardExpiry LedgerJournalType = "gift_card_expiry" // The unspent balance of an expired gift card
+	LedgerJournalMembershipFee  LedgerJournalType = "membership_fee"   // Card funds paid for a membership period
+)
+
+// ErrLedgerAppendOnly is returned when something tries to change or remove posted ledger rows
+var ErrLedgerAppendOnly = errors.New("ledger is append-only; post a reversing journal instead")
+
+// LedgerJournal groups the entries of one money movement. Its entries always sum to zero,
+// and each (type, reference) pair is posted at most once.
+type LedgerJournal struct {
+	ID          string            `json:"id" gorm:"primaryKey;column:id"`
+	Type        LedgerJournalType `json:"type" gorm:"column:type;not null;uniqueIndex:idx_ledger_journals_type_reference"`
+	Reference   string            `json:"reference" gorm:"column:reference;not null;uniqueIndex:idx_ledger_journals_type_reference"` // The payment transaction or payout behind the movement, or the journal itself for store credit
+	OrderID     *string           `json:"order_id,omitempty" gorm:"column:order_id;index"`
+	Description string            `json:"description" gorm:"column:description"`
+	Currency    Currency          `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	CreatedAt   time.Time         `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	Entries     []LedgerEntry     `json:"entries,omitempty" gorm:"foreignKey:JournalID"`
+}
+
+// LedgerEntry is one side of a journal. Debits are positive and credits negative, so an account
+// we owe money to carries a negative balance and the gateway account a positive one.
+type LedgerEntry struct {
+	ID          string            `json:"id" gorm:"primaryKey;column:id"`
+	JournalID   string            `json:"journal_id" gorm:"column:journal_id;not null;index"`
+	AccountType LedgerAccountType `json:"account_type" gorm:"column:account_type;not null;index:idx_ledger_entries_account"`
+	OwnerID     string            `json:"owner_id,omitempty" gorm:"column:owner_id;not null;default:'';index:idx_ledger_entries_account"`
+	Amount      Money             `json:"amount" gorm:"column:amount;not null"`
+	Currency    Currency          `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	CreatedAt   time.Time         `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+// AfterFind stamps the entry currency onto the amount, which stores only minor units
+func (e *LedgerEntry) AfterFind(tx *gorm.DB) error {
+	if e.Currency == "" {
+		e.Currency = DefaultCurrency
+	}
+	e.Amount.Currency = e.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the entry's
+func (e *LedgerEntry) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(e.Currency, e.Amount)
+}
+
+// BeforeUpdate keeps posted journals immutable
+func (j *LedgerJournal) BeforeUpdate(tx *gorm.DB) error {
+	return ErrLedgerAppendOnly
+}
+
+// BeforeDelete keeps posted journals immutable
+func (j *LedgerJournal) BeforeDelete(tx *gorm.DB) error {
+	return ErrLedgerAppendOnly
+}
+
+// BeforeUpdate keeps posted entries immutable
+func (e *LedgerEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrLedgerAppendOnly
+}
+
+// BeforeDelete keeps posted entries immutable
+func (e *LedgerEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrLedgerAppendOnly
+}
+
+// LedgerAccountBalance is an account's balance per currency along with a page of its entries
+type LedgerAccountBalance struct {
+	AccountType LedgerAccountType `json:"account_type"`
+	OwnerID     string            `json:"owner_id,omitempty"`
+	Balances    []Money           `json:"balances"`
+	Entries     []LedgerEntry     `json:"entries"`
+}
+
+// LedgerImbalance is a journal, or the ledger as a whole, whose entries do not sum to zero
+type LedgerImbalance struct {
+	JournalID string `json:"journal_id,omitempty"` // Empty for the ledger-wide total
+	Amount    Money  `json:"amount"`
+}
+
+// LedgerCheck reports the result of verifying the ledger's invariants
+type LedgerCheck struct {
+	Balanced             bool              `json:"balanced"`
+	Journals             int64             `json:"journals"`
+	Imbalances           []LedgerImbalance `json:"imbalances"`
+	UnpostedTransactions []string          `json:"unposted_transactions"` // Completed payment transactions with no journal
+}
+
+// LedgerActivity is one entry of an account together with the journal it belongs to
+type LedgerActivity struct {
+	EntryID     string            `json:"entry_id"`
+	JournalID   string            `json:"journal_id"`
+	Type        LedgerJournalType `json:"type"`
+	Reference   string            `json:"reference"`
+	OrderID     *string           `json:"order_id,omitempty"`
+	Description string            `json:"description"`
+	Amount      Money             `json:"amount"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// Wallet is a customer's spendable balance per currency with a page of its history. Amounts are
+// from the customer's side: credits to the wallet are positive and payments from it negative.
+type Wallet struct {
+	UserID   string           `json:"user_id"`
+	Balances []Money          `json:"balances"`
+	History  []LedgerActivity `json:"history"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoyaltyEntryType describes a change to a customer's points balance
+type LoyaltyEntryType string
+
+const (
+	LoyaltyEntryEarn    LoyaltyEntryType = "earn"    // Points for a delivered order
+	LoyaltyEntryRedeem  LoyaltyEntryType = "redeem"  // Points spent as a discount at checkout
+	LoyaltyEntryRestore LoyaltyEntryType = "restore" // Spent points given back when the order is cancelled
+	LoyaltyEntryExpire  LoyaltyEntryType = "expire"  // Points lost under the expiry policy
+)
+
+// LoyaltyTier is a customer's standing in the points program, decided by points earned recently
+type LoyaltyTier string
+
+const (
+	LoyaltyTierBronze LoyaltyTier = "bronze"
+	LoyaltyTierSilver LoyaltyTier = "silver"
+	LoyaltyTierGold   LoyaltyTier = "gold"
+)
+
+// LoyaltyEntry is one row of a customer's append-only points ledger. Entries that add points are lots
+// spent oldest first: Remaining tracks what is left of a lot, and the lot expires at ExpiresAt.
+type LoyaltyEntry struct {
+	ID          string           `json:"id" gorm:"primaryKey;column:id"`
+	UserID      string           `json:"user_id" gorm:"column:user_id;not null;index"`
+	Type        LoyaltyEntryType `json:"type" gorm:"column:type;not null;uniqueIndex:idx_loyalty_entries_order"`
+	OrderID     *string          `json:"order_id,omitempty" gorm:"column:order_id;uniqueIndex:idx_loyalty_entries_order"` // Each order earns, redeems and restores at most once
+	Points      int64            `json:"points" gorm:"column:points;not null"`                                            // Positive when added, negative when spent or expired
+	Remaining   int64            `json:"-" gorm:"column:remaining;not null;default:0"`
+	Description string           `json:"description" gorm:"column:description"`
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty" gorm:"column:expires_at;index"`
+	CreatedAt   time.Time        `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+// BeforeUpdate keeps posted entries fixed apart from what remains of a lot
+func (e *LoyaltyEntry) BeforeUpdate(tx *gorm.DB) error {
+	for _, column := range []string{"user_id", "type", "order_id", "points", "description", "expires_at", "created_at"} {
+		if tx.Statement.Changed(column) {
+			return ErrLedgerAppendOnly
+		}
+	}
+	return nil
+}
+
+// BeforeDelete keeps posted entries immutable
+func (e *LoyaltyEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrLedgerAppendOnly
+}
+
+// LoyaltyMultiplier is a bonus earn rate on orders from one restaurant, optionally for a limited time
+type LoyaltyMultiplier struct {
+	RestaurantID string     `json:"restaurant_id" gorm:"primaryKey;column:restaurant_id"`
+	BasisPoints  int64      `json:"basis_points" gorm:"column:basis_points;not null"` // 2x = 20000
+	StartsAt     *time.Time `json:"starts_at,omitempty" gorm:"column:starts_at"`
+	EndsAt       *time.Time `json:"ends_at,omitempty" gorm:"column:ends_at"`
+	UpdatedBy    string     `json:"updated_by" gorm:"column:updated_by"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// ActiveAt reports whether the multiplier applies at the given time
+func (m *LoyaltyMultiplier) ActiveAt(at time.Time) bool {
+	return (m.StartsAt == nil || !at.Before(*m.StartsAt)) && (m.EndsAt == nil || at.Before(*m.EndsAt))
+}
+
+// LoyaltyPerks are the benefits of a tier
+type LoyaltyPerks struct {
+	EarnMultiplierBasisPoints int64 `json:"earn_multiplier_basis_points"` // 1x = 10000
+	FreeDelivery              bool  `json:"free_delivery"`
+}
+
+// LoyaltyAccount is a customer's points balance and tier with a page of their points history
+type LoyaltyAccount struct {
+	UserID           string         `json:"user_id"`
+	Balance          int64          `json:"balance"`
+	BalanceValue     Money          `json:"balance_value"` // What the balance takes off an order
+	Tier             LoyaltyTier    `json:"tier"`
+	TierPoints       int64          `json:"tier_points"` // Points earned within the tier window
+	NextTier         *LoyaltyTier   `json:"next_tier,omitempty"`
+	PointsToNextTier int64          `json:"points_to_next_tier,omitempty"`
+	Perks            LoyaltyPerks   `json:"perks"`
+	NextExpiryAt     *time.Time     `json:"next_expiry_at,omitempty"`
+	NextExpiryPoints int64          `json:"next_expiry_points,omitempty"`
+	MinRedeemPoints  int64          `json:"min_redeem_points"`
+	History          []LoyaltyEntry `json:"history"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MembershipStatus tracks a membership through its trial, billing and cancellation
+type MembershipStatus string
+
+const (
+	MembershipStatusTrialing  MembershipStatus = "trialing"  // In a free trial; the first charge is due when it ends
+	MembershipStatusActive    MembershipStatus = "active"    // Paid up for the current period
+	MembershipStatusPastDue   MembershipStatus = "past_due"  // A renewal charge failed and is being retried; perks continue meanwhile
+	MembershipStatusCancelled MembershipStatus = "cancelled" // Ended at the close of a period the customer cancelled
+	MembershipStatusLapsed    MembershipStatus = "lapsed"    // Ended after every renewal retry failed
+)
+
+// IsCurrent reports whether a membership in this status grants its plan's perks
+func (s MembershipStatus) IsCurrent() bool {
+	return s == MembershipStatusTrialing || s == MembershipStatusActive || s == MembershipStatusPastDue
+}
+
+// MembershipPlan is a subscription customers can buy, with the perks it gives at checkout
+type MembershipPlan struct {
+	ID                            string    `json:"id" gorm:"primaryKey;column:id"`
+	Name                          string    `json:"name" gorm:"column:name;not null"`
+	Description                   string    `json:"description" gorm:"column:description"`
+	Price                         Money     `json:"price" gorm:"column:price;not null"` // Charged every billing period
+	Currency                      Currency  `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	IntervalMonths                int       `json:"interval_months" gorm:"column:interval_months;not null;default:1"`
+	TrialDays                     int       `json:"trial_days" gorm:"column:trial_days;not null;default:0"` // Offered to customers who have never been members
+	FreeDelivery                  bool      `json:"free_delivery" gorm:"column:free_delivery;not null;default:false"`
+	FreeDeliveryMinSubtotal       Money     `json:"free_delivery_min_subtotal" gorm:"column:free_delivery_min_subtotal;not null;default:0"`               // Orders below this still pay for delivery
+	ServiceFeeDiscountBasisPoints int64     `json:"service_fee_discount_basis_points" gorm:"column:service_fee_discount_basis_points;not null;default:0"` // Share of the service fee members do not pay, 10000 waives it
+	IsActive                      bool      `json:"is_active" gorm:"column:is_active;not null;default:true"`                                              // Retired plans take no new members but keep renewing existing ones
+	CreatedBy                     string    `json:"created_by" gorm:"column:created_by;not null"`
+	CreatedAt                     time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt                     time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// AfterFind stamps the plan currency onto its amounts, which store only minor units
+func (p *MembershipPlan) AfterFind(tx *gorm.DB) error {
+	if p.Currency == "" {
+		p.Currency = DefaultCurrency
+	}
+	p.Price.Currency = p.Currency
+	p.FreeDeliveryMinSubtotal.Currency = p.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the plan's
+func (p *MembershipPlan) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(p.Currency, p.Price, p.FreeDeliveryMinSubtotal)
+}
+
+// Membership is a customer's subscription to a plan. It renews at the end of each period until cancelled;
+// a renewal that cannot be charged is retried on the configured schedule before the membership lapses.
+type Membership struct {
+	ID                 string           `json:"id" gorm:"primaryKey;column:id"`
+	UserID             string           `json:"user_id" gorm:"column:user_id;not null;index"`
+	PlanID             string           `json:"plan_id" gorm:"column:plan_id;not null;index"`
+	Status             MembershipStatus `json:"status" gorm:"column:status;not null;index"`
+	CardID             *string          `json:"card_id,omitempty" gorm:"column:card_id"` // Defaults to the user's default card at each renewal
+	CurrentPeriodStart time.Time        `json:"current_period_start" gorm:"column:current_period_start;not null"`
+	CurrentPeriodEnd   time.Time        `json:"current_period_end" gorm:"column:current_period_end;not null;index"` // The next renewal
+	TrialEndsAt        *time.Time       `json:"trial_ends_at,omitempty" gorm:"column:trial_ends_at"`
+	CancelAtPeriodEnd  bool             `json:"cancel_at_period_end" gorm:"column:cancel_at_period_end;not null;default:false"`
+	CancelledAt        *time.Time       `json:"cancelled_at,omitempty" gorm:"column:cancelled_at"` // When the customer asked to cancel
+	EndedAt            *time.Time       `json:"ended_at,omitempty" gorm:"column:ended_at"`
+	FailedAttempts     int              `json:"failed_attempts" gorm:"column:failed_attempts;not null;default:0"` // Failed charges for the renewal being retried
+	NextRetryAt        *time.Time       `json:"next_retry_at,omitempty" gorm:"column:next_retry_at;index"`
+	LastFailureReason  *string          `json:"last_failure_reason,omitempty" gorm:"column:last_failure_reason"`
+	LastPaymentID      *string          `json:"last_payment_id,omitempty" gorm:"column:last_payment_id"`
+	CreatedAt          time.Time        `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt          time.Time        `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+	Plan               MembershipPlan   `json:"plan" gorm:"foreignKey:PlanID"`
+}
+
+// MembershipBillingRun sums up one pass over the memberships due for renewal
+type MembershipBillingRun struct {
+	Renewed   int `json:"renewed"`
+	Failed    int `json:"failed"` // Charges that failed and will be retried
+	Lapsed    int `json:"lapsed"`
+	Cancelled int `json:"cancelled"` // Memberships that ended as their customers asked
+}
@@ -0,0 +1,365 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Currency represents an ISO 4217 currency code
+type Currency string
+
+const (
+	CurrencyUSD Currency = "USD"
+	CurrencyEUR Currency = "EUR"
+	CurrencyGBP Currency = "GBP"
+	CurrencyNGN Currency = "NGN"
+	CurrencyJPY Currency = "JPY"
+	CurrencyKWD Currency = "KWD"
+)
+
+// DefaultCurrency is the currency used for stored amounts that carry no currency of their own
+const DefaultCurrency = CurrencyUSD
+
+// currencyExponents maps currencies to the number of minor-unit digits
+var currencyExponents = map[Currency]int{
+	CurrencyUSD: 2,
+	CurrencyEUR: 2,
+	CurrencyGBP: 2,
+	CurrencyNGN: 2,
+	CurrencyJPY: 0,
+	CurrencyKWD: 3,
+}
+
+// Exponent returns the number of minor-unit digits for the currency
+func (c Currency) Exponent() int {
+	if exp, ok := currencyExponents[c]; ok {
+		return exp
+	}
+	return 2
+}
+
+// IsValid reports whether the currency is supported
+func (c Currency) IsValid() bool {
+	_, ok := currencyExponents[c]
+	return ok
+}
+
+// Money represents a monetary amount in integer minor units (e.g. cents)
+type Money struct {
+	Amount   int64    `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+// NewMoney creates a Money value from minor units
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses a decimal string in major units (e.g. "12.50") without going through float64
+func ParseMoney(value string, currency Currency) (Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Money{}, errors.New("empty money value")
+	}
+
+	negative := false
+	if value[0] == '-' || value[0] == '+' {
+		negative = value[0] == '-'
+		value = value[1:]
+	}
+
+	whole, frac, _ := strings.Cut(value, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	exp := currency.Exponent()
+
+	// Round any extra fractional digits half-to-even
+	var roundUp bool
+	if len(frac) > exp {
+		extra := frac[exp:]
+		frac = frac[:exp]
+		if strings.Trim(extra, "0123456789") != "" {
+			return Money{}, fmt.Errorf("invalid money value %q", value)
+		}
+		switch {
+		case extra[0] > '5':
+			roundUp = true
+		case extra[0] == '5' && strings.Trim(extra[1:], "0") != "":
+			roundUp = true
+		case extra[0] == '5':
+			last := byte('0')
+			if len(frac) > 0 {
+				last = frac[len(frac)-1]
+			} else if len(whole) > 0 {
+				last = whole[len(whole)-1]
+			}
+			roundUp = (last-'0')%2 == 1
+		}
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	amount, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid money value %q: %w", value, err)
+	}
+	if roundUp {
+		amount++
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// Zero returns a zero amount in the given currency
+func Zero(currency Currency) Money {
+	return Money{Currency: currency}
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// IsPositive reports whether the amount is above zero
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// Add returns m + other
+func (m Money) Add(other Money) Money {
+	currency := m.mustMatch(other)
+	return Money{Amount: m.Amount + other.Amount, Currency: currency}
+}
+
+// Sub returns m - other
+func (m Money) Sub(other Money) Money {
+	currency := m.mustMatch(other)
+	return Money{Amount: m.Amount - other.Amount, Currency: currency}
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Mul returns m multiplied by an integer quantity
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// MulRat returns m * numerator / denominator rounded half-to-even (banker's rounding)
+func (m Money) MulRat(numerator, denominator int64) Money {
+	return Money{Amount: divRoundHalfEven(m.Amount*numerator, denominator), Currency: m.Currency}
+}
+
+// Percent returns the given percentage of m, expressed in basis points (1% = 100)
+func (m Money) Percent(basisPoints int64) Money {
+	return m.MulRat(basisPoints, 10000)
+}
+
+// Cmp compares m and other, returning -1, 0 or 1
+func (m Money) Cmp(other Money) int {
+	m.mustMatch(other)
+	switch {
+	case m.Amount < other.Amount:
+		return -1
+	case m.Amount > other.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Min returns the smaller of m and other
+func (m Money) Min(other Money) Money {
+	if m.Cmp(other) <= 0 {
+		return m
+	}
+	return other
+}
+
+// Max returns the larger of m and other
+func (m Money) Max(other Money) Money {
+	if m.Cmp(other) >= 0 {
+		return m
+	}
+	return other
+}
+
+// String formats the amount in major units, e.g. "12.50 USD"
+func (m Money) String() string {
+	return m.Decimal() + " " + string(m.currencyOrDefault())
+}
+
+// Decimal formats the amount in major units without the currency, e.g. "12.50"
+func (m Money) Decimal() string {
+	exp := m.currencyOrDefault().Exponent()
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// mustMatch returns the shared currency of m and other, treating an empty currency as a wildcard.
+// Mixing currencies is a programming error, so it panics rather than silently converting.
+func (m Money) mustMatch(other Money) Currency {
+	switch {
+	case m.Currency == "":
+		return other.Currency
+	case other.Currency == "" || other.Currency == m.Currency:
+		return m.Currency
+	default:
+		panic(fmt.Sprintf("money: currency mismatch %s vs %s", m.Currency, other.Currency))
+	}
+}
+
+func (m Money) currencyOrDefault() Currency {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// divRoundHalfEven divides a by b rounding to the nearest integer, ties to even
+func divRoundHalfEven(a, b int64) int64 {
+	if b < 0 {
+		a, b = -a, -b
+	}
+	quotient := a / b
+	remainder := a % b
+	if remainder < 0 {
+		remainder = -remainder
+	}
+	if twice := remainder * 2; twice > b || (twice == b && quotient%2 != 0) {
+		if a < 0 {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+	return quotient
+}
+
+// checkStoredCurrency makes sure amounts written to a row are in the row's currency. Only minor units are
+// stored, and AfterFind stamps the row's currency back onto them, so any other currency would be relabelled.
+func checkStoredCurrency(currency Currency, amounts ...Money) error {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	for _, amount := range amounts {
+		if amount.Currency != "" && amount.Currency != currency {
+			return fmt.Errorf("money: cannot store %s in a %s row", amount, currency)
+		}
+	}
+	return nil
+}
+
+// Value stores the amount as integer minor units. The currency is kept in the row's own currency column,
+// or is DefaultCurrency for catalog prices; checkStoredCurrency guards both on save.
+func (m Money) Value() (driver.Value, error) {
+	return m.Amount, nil
+}
+
+// Scan reads integer minor units; the currency defaults to DefaultCurrency
+func (m *Money) Scan(value interface{}) error {
+	if m.Currency == "" {
+		m.Currency = DefaultCurrency
+	}
+	switch v := value.(type) {
+	case nil:
+		m.Amount = 0
+	case int64:
+		m.Amount = v
+	case []byte:
+		amount, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return fmt.Errorf("money: cannot scan %q: %w", v, err)
+		}
+		m.Amount = amount
+	case string:
+		amount, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("money: cannot scan %q: %w", v, err)
+		}
+		m.Amount = amount
+	default:
+		return fmt.Errorf("money: cannot scan type %T", value)
+	}
+	return nil
+}
+
+// GormDataType tells GORM to store Money in an integer column
+func (Money) GormDataType() string {
+	return "integer"
+}
+
+// MarshalJSON encodes Money as {"amount": 1250, "currency": "USD", "display": "12.50"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   int64    `json:"amount"`
+		Currency Currency `json:"currency"`
+		Display  string   `json:"display"`
+	}{
+		Amount:   m.Amount,
+		Currency: m.currencyOrDefault(),
+		Display:  m.Decimal(),
+	})
+}
+
+// UnmarshalJSON accepts either the object form or a bare decimal number in major units
+// (e.g. 3.99), which keeps order items stored before the switch to minor units readable
+func (m *Money) UnmarshalJSON(data []byte) error {
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "null" {
+		return nil
+	}
+	if strings.HasPrefix(trimmed, "{") {
+		var raw struct {
+			Amount   int64    `json:"amount"`
+			Currency Currency `json:"currency"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		if raw.Currency == "" {
+			raw.Currency = DefaultCurrency
+		}
+		if !raw.Currency.IsValid() {
+			return fmt.Errorf("money: unsupported currency %q", raw.Currency)
+		}
+		*m = Money{Amount: raw.Amount, Currency: raw.Currency}
+		return nil
+	}
+
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	parsed, err := ParseMoney(strings.Trim(trimmed, `"`), currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
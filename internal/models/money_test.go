@@ -0,0 +1,267 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		currency Currency
+		want     int64
+		wantErr  bool
+	}{
+		{name: "whole units", value: "12", currency: CurrencyUSD, want: 1200},
+		{name: "two decimals", value: "12.50", currency: CurrencyUSD, want: 1250},
+		{name: "one decimal", value: "12.5", currency: CurrencyUSD, want: 1250},
+		{name: "leading dot", value: ".99", currency: CurrencyUSD, want: 99},
+		{name: "negative", value: "-3.99", currency: CurrencyUSD, want: -399},
+		{name: "explicit plus", value: "+1.01", currency: CurrencyUSD, want: 101},
+		{name: "surrounding spaces", value: " 7.25 ", currency: CurrencyUSD, want: 725},
+		{name: "extra digits round down", value: "1.234", currency: CurrencyUSD, want: 123},
+		{name: "extra digits round up", value: "1.236", currency: CurrencyUSD, want: 124},
+		{name: "tie rounds to even down", value: "1.245", currency: CurrencyUSD, want: 124},
+		{name: "tie rounds to even up", value: "1.235", currency: CurrencyUSD, want: 124},
+		{name: "above tie rounds up", value: "1.2451", currency: CurrencyUSD, want: 125},
+		{name: "negative tie rounds to even", value: "-1.235", currency: CurrencyUSD, want: -124},
+		{name: "zero exponent", value: "500", currency: CurrencyJPY, want: 500},
+		{name: "zero exponent tie to even", value: "500.5", currency: CurrencyJPY, want: 500},
+		{name: "zero exponent odd tie", value: "501.5", currency: CurrencyJPY, want: 502},
+		{name: "three decimals", value: "1.5", currency: CurrencyKWD, want: 1500},
+		{name: "empty", value: "", currency: CurrencyUSD, wantErr: true},
+		{name: "not a number", value: "abc", currency: CurrencyUSD, wantErr: true},
+		{name: "junk in extra digits", value: "1.23x", currency: CurrencyUSD, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMoney(tt.value, tt.currency)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMoney(%q) = %v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney(%q) error = %v", tt.value, err)
+			}
+			if got.Amount != tt.want || got.Currency != tt.currency {
+				t.Errorf("ParseMoney(%q) = %d %s, want %d %s", tt.value, got.Amount, got.Currency, tt.want, tt.currency)
+			}
+		})
+	}
+}
+
+func TestMoneyMulRat(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      int64
+		numerator   int64
+		denominator int64
+		want        int64
+	}{
+		{name: "exact", amount: 1000, numerator: 1, denominator: 4, want: 250},
+		{name: "rounds down", amount: 1000, numerator: 1, denominator: 3, want: 333},
+		{name: "rounds up", amount: 2000, numerator: 1, denominator: 3, want: 667},
+		{name: "tie to even down", amount: 5, numerator: 1, denominator: 2, want: 2},
+		{name: "tie to even up", amount: 7, numerator: 1, denominator: 2, want: 4},
+		{name: "negative tie to even", amount: -5, numerator: 1, denominator: 2, want: -2},
+		{name: "negative rounds away", amount: -7, numerator: 1, denominator: 2, want: -4},
+		{name: "negative denominator", amount: 7, numerator: 1, denominator: -2, want: -4},
+		{name: "zero amount", amount: 0, numerator: 3, denominator: 7, want: 0},
+		{name: "scales up", amount: 333, numerator: 3, denominator: 1, want: 999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewMoney(tt.amount, CurrencyUSD).MulRat(tt.numerator, tt.denominator)
+			if got.Amount != tt.want {
+				t.Errorf("MulRat(%d, %d) of %d = %d, want %d", tt.numerator, tt.denominator, tt.amount, got.Amount, tt.want)
+			}
+			if got.Currency != CurrencyUSD {
+				t.Errorf("MulRat changed currency to %q", got.Currency)
+			}
+		})
+	}
+}
+
+func TestMoneyPercent(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      int64
+		basisPoints int64
+		want        int64
+	}{
+		{name: "whole percent", amount: 10000, basisPoints: 800, want: 800},
+		{name: "fractional percent", amount: 1999, basisPoints: 825, want: 165},
+		{name: "tie to even down", amount: 250, basisPoints: 1000, want: 25},
+		{name: "tie to even", amount: 50, basisPoints: 500, want: 2},
+		{name: "tie to even up", amount: 150, basisPoints: 500, want: 8},
+		{name: "full amount", amount: 1234, basisPoints: 10000, want: 1234},
+		{name: "zero rate", amount: 1234, basisPoints: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewMoney(tt.amount, CurrencyUSD).Percent(tt.basisPoints); got.Amount != tt.want {
+				t.Errorf("Percent(%d) of %d = %d, want %d", tt.basisPoints, tt.amount, got.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{money: NewMoney(1250, CurrencyUSD), want: "12.50"},
+		{money: NewMoney(5, CurrencyUSD), want: "0.05"},
+		{money: NewMoney(-399, CurrencyUSD), want: "-3.99"},
+		{money: NewMoney(0, CurrencyUSD), want: "0.00"},
+		{money: NewMoney(500, CurrencyJPY), want: "500"},
+		{money: NewMoney(1500, CurrencyKWD), want: "1.500"},
+		{money: NewMoney(42, ""), want: "0.42"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.Decimal(); got != tt.want {
+			t.Errorf("Decimal() of %d %q = %q, want %q", tt.money.Amount, tt.money.Currency, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyCurrencyMismatchPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Add of USD and EUR did not panic")
+		}
+	}()
+	NewMoney(100, CurrencyUSD).Add(NewMoney(100, CurrencyEUR))
+}
+
+func TestMoneyEmptyCurrencyMatchesAny(t *testing.T) {
+	got := Zero("").Add(NewMoney(100, CurrencyEUR))
+	if got.Amount != 100 || got.Currency != CurrencyEUR {
+		t.Errorf("Zero(\"\").Add(100 EUR) = %d %s, want 100 EUR", got.Amount, got.Currency)
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		start   Money
+		want    Money
+		wantErr bool
+	}{
+		{name: "object", data: `{"amount":1250,"currency":"EUR"}`, want: NewMoney(1250, CurrencyEUR)},
+		{name: "object ignores display", data: `{"amount":1250,"currency":"USD","display":"99.99"}`, want: NewMoney(1250, CurrencyUSD)},
+		{name: "object without currency", data: `{"amount":300}`, want: NewMoney(300, DefaultCurrency)},
+		{name: "object with unsupported currency", data: `{"amount":300,"currency":"XYZ"}`, wantErr: true},
+		{name: "legacy number", data: `3.99`, want: NewMoney(399, DefaultCurrency)},
+		{name: "legacy number rounds half to even", data: `3.995`, want: NewMoney(400, DefaultCurrency)},
+		{name: "legacy number keeps currency", data: `500`, start: Zero(CurrencyJPY), want: NewMoney(500, CurrencyJPY)},
+		{name: "legacy quoted number", data: `"12.50"`, want: NewMoney(1250, DefaultCurrency)},
+		{name: "null leaves value", data: `null`, start: NewMoney(7, CurrencyGBP), want: NewMoney(7, CurrencyGBP)},
+		{name: "garbage", data: `"twelve"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.start
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) = %v, want error", tt.data, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", tt.data, err)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal(%s) = %d %s, want %d %s", tt.data, got.Amount, got.Currency, tt.want.Amount, tt.want.Currency)
+			}
+		})
+	}
+}
+
+func TestMoneyJSONRoundTrip(t *testing.T) {
+	original := NewMoney(-1234, CurrencyKWD)
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal error = %v", err)
+	}
+	if want := `{"amount":-1234,"currency":"KWD","display":"-1.234"}`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+
+	var decoded Money
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if decoded != original {
+		t.Errorf("round trip = %v, want %v", decoded, original)
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    int64
+		wantErr bool
+	}{
+		{name: "integer", value: int64(1250), want: 1250},
+		{name: "bytes", value: []byte("-75"), want: -75},
+		{name: "string", value: "300", want: 300},
+		{name: "null", value: nil, want: 0},
+		{name: "legacy float", value: 12.5, wantErr: true},
+		{name: "not a number", value: "12.50", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := got.Scan(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Scan(%v) = %v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan(%v) error = %v", tt.value, err)
+			}
+			if got.Amount != tt.want || got.Currency != DefaultCurrency {
+				t.Errorf("Scan(%v) = %d %s, want %d %s", tt.value, got.Amount, got.Currency, tt.want, DefaultCurrency)
+			}
+		})
+	}
+}
+
+func TestBeforeSaveRefusesOtherCurrencies(t *testing.T) {
+	if err := (&Food{Price: NewMoney(500, CurrencyEUR)}).BeforeSave(nil); err == nil {
+		t.Error("saving a EUR catalog price succeeded, want error")
+	}
+	if err := (&Food{Price: NewMoney(500, DefaultCurrency)}).BeforeSave(nil); err != nil {
+		t.Errorf("saving a %s catalog price: %v", DefaultCurrency, err)
+	}
+
+	order := &Order{Currency: CurrencyEUR, Subtotal: NewMoney(1000, CurrencyEUR), Total: NewMoney(1000, CurrencyEUR)}
+	if err := order.BeforeSave(nil); err != nil {
+		t.Errorf("saving a EUR order: %v", err)
+	}
+	order.Tip = NewMoney(100, CurrencyUSD)
+	if err := order.BeforeSave(nil); err == nil {
+		t.Error("saving a USD tip on a EUR order succeeded, want error")
+	}
+
+	// Update models are built empty, so amounts without a currency always pass
+	if err := (&Order{}).BeforeSave(nil); err != nil {
+		t.Errorf("saving an empty order: %v", err)
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // OrderStatus represents order status enum
 type OrderStatus string
 
 const (
+	OrderStatusScheduled OrderStatus = "scheduled"
 	OrderStatusPending   OrderStatus = "pending"
 	OrderStatusConfirmed OrderStatus = "confirmed"
 	OrderStatusPreparing OrderStatus = "preparing"
@@ -19,14 +22,55 @@ const (
 	OrderStatusCancelled OrderStatus = "cancelled"
 )
 
+// orderProgress ranks the statuses an order moves through on its way to the customer
+var orderProgress = map[OrderStatus]int{
+	OrderStatusScheduled: 0,
+	OrderStatusPending:   1,
+	OrderStatusConfirmed: 2,
+	OrderStatusPreparing: 3,
+	OrderStatusOnTheWay:  4,
+	OrderStatusDelivered: 5,
+}
+
+// HasReached reports whether an order in this status is at or past the target status.
+// Cancelled orders never reach anything.
+func (s OrderStatus) HasReached(target OrderStatus) bool {
+	current, ok := orderProgress[s]
+	if !ok {
+		return false
+	}
+	wanted, ok := orderProgress[target]
+	return ok && current >= wanted
+}
+
+// OrderTimeframe filters a user's orders by whether they are still to come
+type OrderTimeframe string
+
+const (
+	OrderTimeframeAll      OrderTimeframe = ""
+	OrderTimeframeUpcoming OrderTimeframe = "upcoming"
+	OrderTimeframePast     OrderTimeframe = "past"
+)
+
+// OrderItemModifier represents a modifier option selected for an order item
+type OrderItemModifier struct {
+	GroupID    string `json:"group_id"`
+	GroupName  string `json:"group_name"`
+	OptionID   string `json:"option_id"`
+	OptionName string `json:"option_name"`
+	PriceDelta Money  `json:"price_delta"`
+}
+
 // OrderItem represents individual items in an order
 type OrderItem struct {
-	FoodID              string  `json:"food_id"`
-	FoodName            string  `json:"food_name"`
-	Price               float64 `json:"price"`
-	Quantity            int     `json:"quantity"`
-	Total               float64 `json:"total"`
-	SpecialInstructions *string `json:"special_instructions,omitempty"`
+	FoodID              string              `json:"food_id"`
+	FoodName            string              `json:"food_name"`
+	Price               Money               `json:"price"`      // Base price of the food
+	UnitPrice           Money               `json:"unit_price"` // Base price plus selected modifiers
+	Quantity            int                 `json:"quantity"`
+	Total               Money               `json:"total"`
+	Modifiers           []OrderItemModifier `json:"modifiers,omitempty"`
+	SpecialInstructions *string             `json:"special_instructions,omitempty"`
 }
 
 // OrderItemsArray is a custom type for handling order items array
@@ -52,33 +96,85 @@ func (oia *OrderItemsArray) Scan(value interface{}) error {
 
 // Order represents the order entity
 type Order struct {
-	ID                  string          `json:"id" gorm:"primaryKey;column:id"`
-	UserID              string          `json:"user_id" gorm:"column:user_id;not null;index"`
-	RestaurantID        string          `json:"restaurant_id" gorm:"column:restaurant_id;not null;index"`
-	RestaurantName      string          `json:"restaurant_name" gorm:"column:restaurant_name;not null"`
-	Items               OrderItemsArray `json:"items" gorm:"column:items;not null"`
-	Subtotal            float64         `json:"subtotal" gorm:"column:subtotal;not null"`
-	DeliveryFee         float64         `json:"delivery_fee" gorm:"column:delivery_fee;not null"`
-	Tax                 float64         `json:"tax" gorm:"column:tax;not null"`
-	Total               float64         `json:"total" gorm:"column:total;not null"`
-	DeliveryAddress     string          `json:"delivery_address" gorm:"column:delivery_address;not null"`
-	PaymentMethod       string          `json:"payment_method" gorm:"column:payment_method;not null"`
-	Status              OrderStatus     `json:"status" gorm:"column:status;not null;default:'pending'"`
-	DeliveryPersonName  *string         `json:"delivery_person_name,omitempty" gorm:"column:delivery_person_name"`
-	DeliveryPersonPhone *string         `json:"delivery_person_phone,omitempty" gorm:"column:delivery_person_phone"`
-	TrackingURL         *string         `json:"tracking_url,omitempty" gorm:"column:tracking_url"`
-	Notes               *string         `json:"notes,omitempty" gorm:"column:notes"`
-	CreatedAt           time.Time       `json:"created_at" gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt           time.Time       `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
-	DeliveredAt         *time.Time      `json:"delivered_at,omitempty" gorm:"column:delivered_at"`
-	User                User            `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Restaurant          Restaurant      `json:"restaurant,omitempty" gorm:"foreignKey:RestaurantID"`
+	ID                  string              `json:"id" gorm:"primaryKey;column:id"`
+	UserID              string              `json:"user_id" gorm:"column:user_id;not null;index"`
+	RestaurantID        string              `json:"restaurant_id" gorm:"column:restaurant_id;not null;index"`
+	RestaurantName      string              `json:"restaurant_name" gorm:"column:restaurant_name;not null"`
+	Items               OrderItemsArray     `json:"items" gorm:"column:items;not null"`
+	Currency            Currency            `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	Subtotal            Money               `json:"subtotal" gorm:"column:subtotal;not null"`
+	DeliveryFee         Money               `json:"delivery_fee" gorm:"column:delivery_fee;not null"`
+	SmallOrderFee       Money               `json:"small_order_fee" gorm:"column:small_order_fee;default:0"`
+	ServiceFee          Money               `json:"service_fee" gorm:"column:service_fee;not null;default:0"`
+	MembershipID        *string             `json:"membership_id,omitempty" gorm:"column:membership_id;index"` // The membership whose perks priced the order
+	DeliveryDistanceKm  *float64            `json:"delivery_distance_km,omitempty" gorm:"column:delivery_distance_km"`
+	Tax                 Money               `json:"tax" gorm:"column:tax;not null"` // Inclusive and exclusive tax
+	TaxLines            TaxLinesArray       `json:"tax_lines" gorm:"column:tax_lines"`
+	PromoCodes          StringArray         `json:"promo_codes,omitempty" gorm:"column:promo_codes"`
+	Discount            Money               `json:"discount" gorm:"column:discount;not null;default:0"` // Sum of the discount lines, already taken off the total
+	DiscountLines       DiscountLinesArray  `json:"discount_lines,omitempty" gorm:"column:discount_lines"`
+	RedeemPoints        int64               `json:"redeem_points,omitempty" gorm:"column:redeem_points;not null;default:0"` // Loyalty points spent on this order
+	Tip                 Money               `json:"tip" gorm:"column:tip;not null;default:0"`                               // Paid in full to the courier
+	TipBasisPoints      *int64              `json:"tip_basis_points,omitempty" gorm:"column:tip_basis_points"`              // Set when the tip was chosen as a share of the subtotal (1% = 100)
+	Total               Money               `json:"total" gorm:"column:total;not null"`
+	DeliveryAddress     string              `json:"delivery_address" gorm:"column:delivery_address;not null;serializer:encrypted"`
+	DeliveryAddressID   *string             `json:"delivery_address_id,omitempty" gorm:"column:delivery_address_id;index"`
+	PaymentMethod       string              `json:"payment_method" gorm:"column:payment_method;not null"`
+	Status              OrderStatus         `json:"status" gorm:"column:status;not null;default:'pending'"`
+	DeliveryPersonName  *string             `json:"delivery_person_name,omitempty" gorm:"column:delivery_person_name"`
+	DeliveryPersonPhone *string             `json:"delivery_person_phone,omitempty" gorm:"column:delivery_person_phone"`
+	CourierID           *string             `json:"courier_id,omitempty" gorm:"column:courier_id;index"` // Whose ledger account the tip is settled to
+	TrackingURL         *string             `json:"tracking_url,omitempty" gorm:"column:tracking_url"`
+	Notes               *string             `json:"notes,omitempty" gorm:"column:notes"`
+	QuoteID             *string             `json:"quote_id,omitempty" gorm:"column:quote_id"`
+	EstimatedDeliveryAt *time.Time          `json:"estimated_delivery_at,omitempty" gorm:"column:estimated_delivery_at"`
+	ScheduledFor        *time.Time          `json:"scheduled_for,omitempty" gorm:"column:scheduled_for;index"` // Requested delivery time for pre-orders
+	ReleaseAt           *time.Time          `json:"release_at,omitempty" gorm:"column:release_at;index"`       // When a pre-order enters the kitchen queue
+	CancelledBy         *CancellationActor  `json:"cancelled_by,omitempty" gorm:"column:cancelled_by"`
+	CancellationReason  *CancellationReason `json:"cancellation_reason,omitempty" gorm:"column:cancellation_reason"`
+	CancellationNote    *string             `json:"cancellation_note,omitempty" gorm:"column:cancellation_note"`
+	CancelledAt         *time.Time          `json:"cancelled_at,omitempty" gorm:"column:cancelled_at"`
+	CancellationFee     Money               `json:"cancellation_fee" gorm:"column:cancellation_fee;not null;default:0"`
+	RefundAmount        Money               `json:"refund_amount" gorm:"column:refund_amount;not null;default:0"`
+	RefundStatus        *RefundStatus       `json:"refund_status,omitempty" gorm:"column:refund_status"`
+	DisputedAt          *time.Time          `json:"disputed_at,omitempty" gorm:"column:disputed_at"` // When a payment for the order was disputed
+	PaymentClaimedAt    *time.Time          `json:"-" gorm:"column:payment_claimed_at"`              // Set while a payment is being taken so two cannot run at once
+	GroupOrderID        *string             `json:"group_order_id,omitempty" gorm:"column:group_order_id;index"`
+	Portion             *GroupOrderPortion  `json:"portion,omitempty" gorm:"-"` // The requesting user's part of a group order
+	CreatedAt           time.Time           `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt           time.Time           `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+	DeliveredAt         *time.Time          `json:"delivered_at,omitempty" gorm:"column:delivered_at"`
+	User                User                `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Restaurant          Restaurant          `json:"restaurant,omitempty" gorm:"foreignKey:RestaurantID"`
+}
+
+// AfterFind stamps the order currency onto its monetary columns, which store only minor units
+func (o *Order) AfterFind(tx *gorm.DB) error {
+	if o.Currency == "" {
+		o.Currency = DefaultCurrency
+	}
+	o.Subtotal.Currency = o.Currency
+	o.DeliveryFee.Currency = o.Currency
+	o.SmallOrderFee.Currency = o.Currency
+	o.ServiceFee.Currency = o.Currency
+	o.Tax.Currency = o.Currency
+	o.Discount.Currency = o.Currency
+	o.Tip.Currency = o.Currency
+	o.Total.Currency = o.Currency
+	o.CancellationFee.Currency = o.Currency
+	o.RefundAmount.Currency = o.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the order's
+func (o *Order) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(o.Currency, o.Subtotal, o.DeliveryFee, o.SmallOrderFee, o.ServiceFee, o.Tax, o.Discount, o.Tip, o.Total, o.CancellationFee, o.RefundAmount)
 }
 
 // Cart represents cart entity (typically handled in memory/session)
 type Cart struct {
-	UserID     string  `json:"user_id"`
-	Items      []Food  `json:"items"`
-	TotalPrice float64 `json:"total_price"`
-	ItemCount  int     `json:"item_count"`
+	UserID     string `json:"user_id"`
+	Items      []Food `json:"items"`
+	TotalPrice Money  `json:"total_price"`
+	ItemCount  int    `json:"item_count"`
 }
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// OrderSort is a supported ordering for order search results
+type OrderSort string
+
+const (
+	OrderSortNewest    OrderSort = "newest"
+	OrderSortOldest    OrderSort = "oldest"
+	OrderSortTotalDesc OrderSort = "total_desc"
+	OrderSortTotalAsc  OrderSort = "total_asc"
+)
+
+// IsValid reports whether the sort option is supported
+func (s OrderSort) IsValid() bool {
+	switch s {
+	case OrderSortNewest, OrderSortOldest, OrderSortTotalDesc, OrderSortTotalAsc:
+		return true
+	}
+	return false
+}
+
+// OrderCursor marks the last order of a page so the next page starts right after it
+type OrderCursor struct {
+	CreatedAt time.Time `json:"c,omitempty"`
+	Total     int64     `json:"t,omitempty"`
+	ID        string    `json:"id"`
+}
+
+// OrderSearchFilter holds validated order search criteria for the repository
+type OrderSearchFilter struct {
+	UserID        string
+	Statuses      []OrderStatus
+	RestaurantID  string
+	From          *time.Time
+	To            *time.Time
+	MinTotal      *Money
+	MaxTotal      *Money
+	PaymentMethod string
+	ItemQuery     string
+	Sort          OrderSort
+	After         *OrderCursor
+	Limit         int
+}
+
+// OrderViewer identifies the authenticated caller of an order or payment endpoint; customers only ever see their own
+type OrderViewer struct {
+	Email string
+	Role  UserRole
+}
+
+// IsStaff reports whether the caller is a support agent or an admin
+func (v OrderViewer) IsStaff() bool {
+	return v.Role == RoleSupport || v.Role == RoleAdmin
+}
+
+// OrderSearchResult represents one page of order search results
+type OrderSearchResult struct {
+	Orders     []Order `json:"orders"`
+	NextCursor *string `json:"next_cursor,omitempty"` // Absent on the last page
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// OrderTemplate represents a named basket a user can place again in one call
+type OrderTemplate struct {
+	ID                string          `json:"id" gorm:"primaryKey;column:id"`
+	UserID            string          `json:"user_id" gorm:"column:user_id;not null;index"`
+	Name              string          `json:"name" gorm:"column:name;not null"`
+	RestaurantID      string          `json:"restaurant_id" gorm:"column:restaurant_id;not null"`
+	RestaurantName    string          `json:"restaurant_name" gorm:"column:restaurant_name;not null"`
+	Items             OrderItemsArray `json:"items" gorm:"column:items;not null"` // Prices reflect the menu when the template was saved
+	DeliveryAddress   string          `json:"delivery_address" gorm:"column:delivery_address;serializer:encrypted"`
+	DeliveryAddressID *string         `json:"delivery_address_id,omitempty" gorm:"column:delivery_address_id"`
+	PaymentMethod     string          `json:"payment_method" gorm:"column:payment_method"`
+	SourceOrderID     *string         `json:"source_order_id,omitempty" gorm:"column:source_order_id"`
+	LastPlacedAt      *time.Time      `json:"last_placed_at,omitempty" gorm:"column:last_placed_at"`
+	CreatedAt         time.Time       `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt         time.Time       `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// ReorderItemIssue describes a past order item that can no longer be ordered as it was
+type ReorderItemIssue struct {
+	FoodID   string `json:"food_id"`
+	FoodName string `json:"food_name"`
+	Reason   string `json:"reason"`
+}
+
+// ReorderPriceChange describes a past order item whose unit price has changed since it was ordered
+type ReorderPriceChange struct {
+	FoodID            string `json:"food_id"`
+	FoodName          string `json:"food_name"`
+	PreviousUnitPrice Money  `json:"previous_unit_price"`
+	CurrentUnitPrice  Money  `json:"current_unit_price"`
+}
+
+// ReorderResult represents a basket rebuilt from a past order, priced at today's menu
+type ReorderResult struct {
+	SourceOrderID string               `json:"source_order_id"`
+	Items         []OrderItem          `json:"items"`
+	Unavailable   []ReorderItemIssue   `json:"unavailable"`
+	Repriced      []ReorderPriceChange `json:"repriced"`
+	Quote         *OrderQuote          `json:"quote,omitempty"` // Absent when nothing from the order can be reordered
+}
@@ -1,7 +1,11 @@
 package models
 
 import (
+	"strconv"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // PaymentMethod represents payment method entity
@@ -12,13 +16,27 @@ type PaymentMethod struct {
 	IconURL string `json:"icon_url" gorm:"column:icon_url;not null"`
 }
 
-// Card represents payment card entity
+// CardBrand identifies a card network
+type CardBrand string
+
+const (
+	CardBrandVisa       CardBrand = "visa"
+	CardBrandMastercard CardBrand = "mastercard"
+	CardBrandAmex       CardBrand = "amex"
+	CardBrandDiscover   CardBrand = "discover"
+	CardBrandUnknown    CardBrand = "unknown"
+)
+
+// Card represents a saved payment card. Only the gateway token and non-sensitive display
+// details are stored; the card number and security code never reach the database.
 type Card struct {
 	ID              string        `json:"id" gorm:"primaryKey;column:id"`
-	UserID          string        `json:"user_id" gorm:"column:user_id;not null;index"`
+	UserID          string        `json:"user_id" gorm:"column:user_id;not null;index;uniqueIndex:idx_cards_user_fingerprint"`
 	PaymentMethodID string        `json:"payment_method_id" gorm:"column:payment_method_id;not null"`
-	PAN             string        `json:"pan" gorm:"column:pan;not null"` // Should be encrypted
-	CVV             string        `json:"cvv" gorm:"column:cvv;not null"` // Should be encrypted
+	Token           string        `json:"-" gorm:"column:token;not null"` // Gateway token used to charge the card
+	Brand           CardBrand     `json:"brand" gorm:"column:brand;not null"`
+	Last4           string        `json:"last4" gorm:"column:last4;not null"`
+	Fingerprint     string        `json:"-" gorm:"column:fingerprint;not null;uniqueIndex:idx_cards_user_fingerprint"` // Same card number, same fingerprint
 	ExpiryMonth     int           `json:"expiry_month" gorm:"column:expiry_month;not null"`
 	ExpiryYear      int           `json:"expiry_year" gorm:"column:expiry_year;not null"`
 	CardholderName  string        `json:"cardholder_name" gorm:"column:cardholder_name;not null"`
@@ -29,20 +47,160 @@ type Card struct {
 	PaymentMethod   PaymentMethod `json:"payment_method,omitempty" gorm:"foreignKey:PaymentMethodID"`
 }
 
+// IsExpired reports whether the card has expired; cards are valid through the end of their expiry month
+func (c *Card) IsExpired(now time.Time) bool {
+	return CardExpired(c.ExpiryMonth, c.ExpiryYear, now)
+}
+
+// CardExpired reports whether an expiry month and four-digit year lie in the past
+func CardExpired(month, year int, now time.Time) bool {
+	return year < now.Year() || (year == now.Year() && month < int(now.Month()))
+}
+
+// NormalizeCardNumber strips the spaces and dashes people type into card numbers
+func NormalizeCardNumber(number string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(number)
+}
+
+// IsValidCardNumber checks the length and Luhn checksum of a normalized card number
+func IsValidCardNumber(number string) bool {
+	if len(number) < 12 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// DetectCardBrand identifies the card network from the number's issuer prefix
+func DetectCardBrand(number string) CardBrand {
+	prefix := func(n int) int {
+		if len(number) < n {
+			return -1
+		}
+		value, err := strconv.Atoi(number[:n])
+		if err != nil {
+			return -1
+		}
+		return value
+	}
+
+	switch {
+	case prefix(1) == 4:
+		return CardBrandVisa
+	case prefix(2) == 34 || prefix(2) == 37:
+		return CardBrandAmex
+	case prefix(2) >= 51 && prefix(2) <= 55, prefix(4) >= 2221 && prefix(4) <= 2720:
+		return CardBrandMastercard
+	case prefix(4) == 6011, prefix(2) == 65, prefix(3) >= 644 && prefix(3) <= 649:
+		return CardBrandDiscover
+	default:
+		return CardBrandUnknown
+	}
+}
+
+// Payment transaction statuses
+const (
+	PaymentStatusPending        = "pending"
+	PaymentStatusRequiresAction = "requires_action" // The card issuer asked for 3-D Secure authentication
+	PaymentStatusAuthorized     = "authorized"      // Funds are held on the card but not yet collected
+	PaymentStatusCaptured       = "captured"        // An authorization whose held funds have been collected
+	PaymentStatusVoided         = "voided"          // An authorization released without collecting anything
+	PaymentStatusCompleted      = "completed"
+	PaymentStatusFailed         = "failed"
+	PaymentStatusRefunded       = "refunded" // A charge whose full amount has been refunded
+	PaymentStatusDisputed       = "disputed" // The cardholder has challenged the charge with their bank
+)
+
+// Payment transaction types
+const (
+	PaymentTypeCharge        = "charge" // Authorized and captured in one step
+	PaymentTypeAuthorization = "authorization"
+	PaymentTypeCapture       = "capture"            // Collects part or all of an authorization
+	PaymentTypeVoid          = "void"               // Releases an authorization
+	PaymentTypeWallet        = "wallet"             // Paid from the user's wallet balance, collected at once
+	PaymentTypeTopUp         = "top_up"             // Card funds added to the user's wallet
+	PaymentTypeGiftCard      = "gift_card"          // Paid from a gift card's balance, collected at once
+	PaymentTypeGiftCardSale  = "gift_card_purchase" // Card funds paid for a gift card
+	PaymentTypeMembership    = "membership"         // Card funds paid for a membership period
+	PaymentTypeRefund        = "refund"
+)
+
+// WalletPaymentMethodID identifies payments made from, and refunds made to, a user's wallet balance
+const WalletPaymentMethodID = "wallet"
+
+// WalletPaymentMethod is the wallet as offered alongside the stored payment methods
+func WalletPaymentMethod() PaymentMethod {
+	return PaymentMethod{ID: WalletPaymentMethodID, Name: "Wallet", Type: "wallet", IconURL: ""}
+}
+
 // PaymentTransaction represents payment transaction entity
 type PaymentTransaction struct {
 	ID              string     `json:"id" gorm:"primaryKey;column:id"`
-	OrderID         string     `json:"order_id" gorm:"column:order_id;not null;index"`
+	OrderID         string     `json:"order_id" gorm:"column:order_id;not null;index"` // Empty for wallet top-ups
 	UserID          string     `json:"user_id" gorm:"column:user_id;not null;index"`
 	PaymentMethodID string     `json:"payment_method_id" gorm:"column:payment_method_id;not null"`
-	Amount          float64    `json:"amount" gorm:"column:amount;not null"`
-	Currency        string     `json:"currency" gorm:"column:currency;default:'USD';not null"`
-	Status          string     `json:"status" gorm:"column:status;not null"`                  // pending, completed, failed, refunded
+	CardID          *string    `json:"card_id,omitempty" gorm:"column:card_id"` // Defaults to the user's default card
+	Type            string     `json:"type" gorm:"column:type;not null;default:'charge'"`
+	Amount          Money      `json:"amount" gorm:"column:amount;not null"`
+	RefundedAmount  Money      `json:"refunded_amount" gorm:"column:refunded_amount;not null;default:0"`
+	Currency        Currency   `json:"currency" gorm:"column:currency;default:'USD';not null"`
+	Status          string     `json:"status" gorm:"column:status;not null"`                  // pending, requires_action, authorized, captured, voided, completed, failed, refunded
+	ParentID        *string    `json:"parent_id,omitempty" gorm:"column:parent_id;index"`     // The authorization a capture or void belongs to, or the charge a refund belongs to
 	TransactionID   *string    `json:"transaction_id,omitempty" gorm:"column:transaction_id"` // External payment gateway transaction ID
 	FailureReason   *string    `json:"failure_reason,omitempty" gorm:"column:failure_reason"`
 	ProcessedAt     *time.Time `json:"processed_at,omitempty" gorm:"column:processed_at"`
 	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at;autoCreateTime"`
 	UpdatedAt       time.Time  `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+	GiftCardID      *string    `json:"gift_card_id,omitempty" gorm:"column:gift_card_id;index"`
+	UseWallet       bool       `json:"use_wallet,omitempty" gorm:"-"`     // Pay what the wallet covers from it and the rest by card
+	GiftCardCode    string     `json:"gift_card_code,omitempty" gorm:"-"` // Pay what the gift card covers from it and the rest by card
 	Order           Order      `json:"order,omitempty" gorm:"foreignKey:OrderID"`
 	User            User       `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
+
+// AfterFind stamps the transaction currency onto the amount, which stores only minor units
+func (t *PaymentTransaction) AfterFind(tx *gorm.DB) error {
+	if t.Currency == "" {
+		t.Currency = DefaultCurrency
+	}
+	t.Amount.Currency = t.Currency
+	t.RefundedAmount.Currency = t.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the transaction's
+func (t *PaymentTransaction) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(t.Currency, t.Amount, t.RefundedAmount)
+}
+
+// CollectsFunds reports whether the transaction pays for an order: a one-step charge, a capture, or a wallet or gift card payment
+func (t *PaymentTransaction) CollectsFunds() bool {
+	return t.Type == PaymentTypeCharge || t.Type == PaymentTypeCapture || t.Type == PaymentTypeWallet || t.Type == PaymentTypeGiftCard
+}
+
+// IsCollected reports whether a charge or capture went through, whatever happened to it afterwards
+func (t *PaymentTransaction) IsCollected() bool {
+	if !t.CollectsFunds() {
+		return false
+	}
+	switch t.Status {
+	case PaymentStatusCompleted, PaymentStatusRefunded, PaymentStatusDisputed:
+		return true
+	}
+	return false
+}
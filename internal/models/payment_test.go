@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsValidCardNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		number string
+		want   bool
+	}{
+		{name: "visa", number: "4242424242424242", want: true},
+		{name: "mastercard", number: "5555555555554444", want: true},
+		{name: "amex", number: "378282246310005", want: true},
+		{name: "discover", number: "6011111111111117", want: true},
+		{name: "nineteen digits", number: "6011000990139424009", want: true},
+		{name: "shortest allowed", number: "000000000000", want: true},
+		{name: "checksum off by one", number: "4242424242424241", want: false},
+		{name: "transposed digits", number: "4242424242424224", want: false},
+		{name: "too short", number: "42424242424", want: false},
+		{name: "too long", number: "42424242424242424242", want: false},
+		{name: "empty", number: "", want: false},
+		{name: "not normalized", number: "4242 4242 4242 4242", want: false},
+		{name: "letters", number: "4242424242424a42", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsValidCardNumber(tt.number); got != tt.want {
+				t.Errorf("IsValidCardNumber(%q) = %v, want %v", tt.number, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCardNumber(t *testing.T) {
+	if got := NormalizeCardNumber("4242 4242-4242 4242"); got != "4242424242424242" {
+		t.Errorf("NormalizeCardNumber = %q, want 4242424242424242", got)
+	}
+}
+
+func TestDetectCardBrand(t *testing.T) {
+	tests := []struct {
+		number string
+		want   CardBrand
+	}{
+		{number: "4242424242424242", want: CardBrandVisa},
+		{number: "5555555555554444", want: CardBrandMastercard},
+		{number: "2223003122003222", want: CardBrandMastercard},
+		{number: "2720990000000000", want: CardBrandMastercard},
+		{number: "2721000000000000", want: CardBrandUnknown},
+		{number: "378282246310005", want: CardBrandAmex},
+		{number: "340000000000009", want: CardBrandAmex},
+		{number: "6011111111111117", want: CardBrandDiscover},
+		{number: "6445000000000000", want: CardBrandDiscover},
+		{number: "6500000000000002", want: CardBrandDiscover},
+		{number: "3530111333300000", want: CardBrandUnknown},
+		{number: "", want: CardBrandUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := DetectCardBrand(tt.number); got != tt.want {
+			t.Errorf("DetectCardBrand(%q) = %q, want %q", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestCardExpired(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		month int
+		year  int
+		want  bool
+	}{
+		{name: "valid through the end of this month", month: 10, year: 2026, want: false},
+		{name: "last month", month: 9, year: 2026, want: true},
+		{name: "later this year", month: 12, year: 2026, want: false},
+		{name: "last year", month: 12, year: 2025, want: true},
+		{name: "next year", month: 1, year: 2027, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CardExpired(tt.month, tt.year, now); got != tt.want {
+				t.Errorf("CardExpired(%d, %d) = %v, want %v", tt.month, tt.year, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// WebhookEventType is the kind of asynchronous payment event a provider reports
+type WebhookEventType string
+
+const (
+	WebhookChargeSucceeded WebhookEventType = "charge.succeeded"
+	WebhookChargeFailed    WebhookEventType = "charge.failed"
+	WebhookRefundSucceeded WebhookEventType = "refund.succeeded"
+	WebhookRefundFailed    WebhookEventType = "refund.failed"
+	WebhookDisputeOpened   WebhookEventType = "dispute.opened"
+)
+
+// WebhookEventStatus records what became of a received webhook event
+type WebhookEventStatus string
+
+const (
+	WebhookEventReceived  WebhookEventStatus = "received"
+	WebhookEventProcessed WebhookEventStatus = "processed"
+	WebhookEventIgnored   WebhookEventStatus = "ignored" // Unknown type, or nothing left to change
+	WebhookEventFailed    WebhookEventStatus = "failed"  // Processed again when the provider retries
+)
+
+// PaymentWebhookEvent stores every webhook delivery once per provider event ID, so retried
+// and replayed deliveries are recognised instead of being applied twice
+type PaymentWebhookEvent struct {
+	ID          string             `json:"id" gorm:"primaryKey;column:id"`
+	Provider    string             `json:"provider" gorm:"column:provider;not null;uniqueIndex:idx_webhook_provider_event"`
+	EventID     string             `json:"event_id" gorm:"column:event_id;not null;uniqueIndex:idx_webhook_provider_event"`
+	Type        WebhookEventType   `json:"type" gorm:"column:type;not null"`
+	Payload     string             `json:"payload" gorm:"column:payload;not null"`
+	Status      WebhookEventStatus `json:"status" gorm:"column:status;not null;default:'received'"`
+	Error       *string            `json:"error,omitempty" gorm:"column:error"`
+	ProcessedAt *time.Time         `json:"processed_at,omitempty" gorm:"column:processed_at"`
+	CreatedAt   time.Time          `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time          `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// WebhookPayload is the normalized webhook body; provider adapters translate into it
+type WebhookPayload struct {
+	ID      string           `json:"id"`
+	Type    WebhookEventType `json:"type"`
+	Created int64            `json:"created"` // Unix seconds
+	Data    WebhookEventData `json:"data"`
+}
+
+// WebhookEventData identifies the gateway object an event is about
+type WebhookEventData struct {
+	ID             string `json:"id"`                  // Gateway ID of the charge or refund
+	Reference      string `json:"reference,omitempty"` // Our transaction ID, when the gateway knows it
+	ChargeID       string `json:"charge_id,omitempty"` // For refunds and disputes, the charge they belong to
+	Amount         Money  `json:"amount"`
+	FailureMessage string `json:"failure_message,omitempty"`
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CommissionRule is the commission we keep on a restaurant's share of each order: a percentage of the
+// share plus a fixed fee per order. Restaurants without a rule pay the default ledger commission.
+type CommissionRule struct {
+	RestaurantID string    `json:"restaurant_id" gorm:"primaryKey;column:restaurant_id"`
+	BasisPoints  int64     `json:"basis_points" gorm:"column:basis_points;not null"` // 1% = 100
+	FixedFee     Money     `json:"fixed_fee" gorm:"column:fixed_fee;not null;default:0"`
+	Currency     Currency  `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	UpdatedBy    string    `json:"updated_by" gorm:"column:updated_by"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// AfterFind stamps the rule currency onto the fixed fee, which stores only minor units
+func (r *CommissionRule) AfterFind(tx *gorm.DB) error {
+	if r.Currency == "" {
+		r.Currency = DefaultCurrency
+	}
+	r.FixedFee.Currency = r.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the rule's
+func (r *CommissionRule) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(r.Currency, r.FixedFee)
+}
+
+// PayoutStatus tracks a payout from statement to transfer
+type PayoutStatus string
+
+const (
+	PayoutStatusPending PayoutStatus = "pending" // Statement issued, transfer not yet made
+	PayoutStatusPaid    PayoutStatus = "paid"
+)
+
+// PayoutLineKind says what a payout line settles
+type PayoutLineKind string
+
+const (
+	PayoutLineOrder  PayoutLineKind = "order"  // The restaurant's takings from a delivered or cancelled order
+	PayoutLineRefund PayoutLineKind = "refund" // The restaurant's part of a refund to the customer
+)
+
+// RestaurantPayout is one settlement statement: what we owe a restaurant for the orders and refunds
+// settled up to PeriodEnd that had not been settled before
+type RestaurantPayout struct {
+	ID           string       `json:"id" gorm:"primaryKey;column:id"`
+	RestaurantID string       `json:"restaurant_id" gorm:"column:restaurant_id;not null;index"`
+	PeriodEnd    time.Time    `json:"period_end" gorm:"column:period_end;not null"`
+	Currency     Currency     `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	Sales        Money        `json:"sales" gorm:"column:sales;not null"`           // Restaurant share of captured order payments
+	Commission   Money        `json:"commission" gorm:"column:commission;not null"` // Our commission on those sales
+	Refunds      Money        `json:"refunds" gorm:"column:refunds;not null"`       // Restaurant share of refunds to customers
+	Net          Money        `json:"net" gorm:"column:net;not null"`               // Sales less commission and refunds
+	OrderCount   int          `json:"order_count" gorm:"column:order_count;not null"`
+	Status       PayoutStatus `json:"status" gorm:"column:status;not null;default:'pending';index"`
+	PaidAt       *time.Time   `json:"paid_at,omitempty" gorm:"column:paid_at"`
+	CreatedAt    time.Time    `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	Lines        []PayoutLine `json:"lines,omitempty" gorm:"foreignKey:PayoutID"`
+}
+
+// AfterFind stamps the payout currency onto its amounts, which store only minor units
+func (p *RestaurantPayout) AfterFind(tx *gorm.DB) error {
+	if p.Currency == "" {
+		p.Currency = DefaultCurrency
+	}
+	p.Sales.Currency = p.Currency
+	p.Commission.Currency = p.Currency
+	p.Refunds.Currency = p.Currency
+	p.Net.Currency = p.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the payout's
+func (p *RestaurantPayout) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(p.Currency, p.Sales, p.Commission, p.Refunds, p.Net)
+}
+
+// PayoutLine is one order or refund on a payout statement. Each order and refund is settled at most once.
+type PayoutLine struct {
+	ID         string         `json:"id" gorm:"primaryKey;column:id"`
+	PayoutID   string         `json:"payout_id" gorm:"column:payout_id;not null;index"`
+	Kind       PayoutLineKind `json:"kind" gorm:"column:kind;not null;uniqueIndex:idx_payout_lines_kind_reference"`
+	Reference  string         `json:"reference" gorm:"column:reference;not null;uniqueIndex:idx_payout_lines_kind_reference"` // Order ID or refund transaction ID
+	OrderID    string         `json:"order_id" gorm:"column:order_id;not null;index"`
+	Sales      Money          `json:"sales" gorm:"column:sales;not null"`
+	Commission Money          `json:"commission" gorm:"column:commission;not null"`
+	Refunds    Money          `json:"refunds" gorm:"column:refunds;not null"`
+	Net        Money          `json:"net" gorm:"column:net;not null"`
+	Currency   Currency       `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	OccurredAt time.Time      `json:"occurred_at" gorm:"column:occurred_at;not null"` // Delivery, cancellation or refund time
+}
+
+// AfterFind stamps the line currency onto its amounts, which store only minor units
+func (l *PayoutLine) AfterFind(tx *gorm.DB) error {
+	if l.Currency == "" {
+		l.Currency = DefaultCurrency
+	}
+	l.Sales.Currency = l.Currency
+	l.Commission.Currency = l.Currency
+	l.Refunds.Currency = l.Currency
+	l.Net.Currency = l.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the line's
+func (l *PayoutLine) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(l.Currency, l.Sales, l.Commission, l.Refunds, l.Net)
+}
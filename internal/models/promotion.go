@@ -0,0 +1,161 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PromotionType describes what a promotion takes off an order
+type PromotionType string
+
+const (
+	PromotionPercentage   PromotionType = "percentage"    // A share of the eligible items
+	PromotionFixed        PromotionType = "fixed"         // A fixed amount off the eligible items
+	PromotionFreeDelivery PromotionType = "free_delivery" // Waives the delivery fee
+
+	// PromotionLoyaltyPoints marks discount lines paid for with loyalty points; there are no promo codes of this type
+	PromotionLoyaltyPoints PromotionType = "loyalty_points"
+)
+
+// IsValid reports whether the promotion type is known
+func (t PromotionType) IsValid() bool {
+	switch t {
+	case PromotionPercentage, PromotionFixed, PromotionFreeDelivery:
+		return true
+	}
+	return false
+}
+
+// Promotion represents a promo code and the rules for redeeming it
+type Promotion struct {
+	ID             string        `json:"id" gorm:"primaryKey;column:id"`
+	Code           string        `json:"code" gorm:"column:code;not null;uniqueIndex"` // Stored upper case; codes are matched case-insensitively
+	Description    string        `json:"description" gorm:"column:description"`
+	Type           PromotionType `json:"type" gorm:"column:type;not null"`
+	BasisPoints    int64         `json:"basis_points,omitempty" gorm:"column:basis_points"` // Percentage promotions, 1% = 100
+	Amount         Money         `json:"amount" gorm:"column:amount;not null;default:0"`    // Fixed promotions
+	MaxDiscount    Money         `json:"max_discount" gorm:"column:max_discount;not null;default:0"`
+	MinSubtotal    Money         `json:"min_subtotal" gorm:"column:min_subtotal;not null;default:0"`
+	Currency       Currency      `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	RestaurantID   *string       `json:"restaurant_id,omitempty" gorm:"column:restaurant_id;index"` // Only orders from this restaurant
+	Category       *string       `json:"category,omitempty" gorm:"column:category"`                 // Only items in this food category
+	FirstOrderOnly bool          `json:"first_order_only" gorm:"column:first_order_only;default:false"`
+	Stackable      bool          `json:"stackable" gorm:"column:stackable;default:false"` // Can be combined with other stackable codes
+	MaxUses        int           `json:"max_uses" gorm:"column:max_uses;default:0"`       // 0 means unlimited
+	MaxUsesPerUser int           `json:"max_uses_per_user" gorm:"column:max_uses_per_user;default:0"`
+	UsedCount      int           `json:"used_count" gorm:"column:used_count;not null;default:0"`
+	StartsAt       *time.Time    `json:"starts_at,omitempty" gorm:"column:starts_at"`
+	EndsAt         *time.Time    `json:"ends_at,omitempty" gorm:"column:ends_at"`
+	IsActive       bool          `json:"is_active" gorm:"column:is_active;default:true"`
+	CreatedBy      string        `json:"created_by" gorm:"column:created_by"`
+	CreatedAt      time.Time     `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time     `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// AfterFind stamps the promotion currency onto its monetary columns, which store only minor units
+func (p *Promotion) AfterFind(tx *gorm.DB) error {
+	if p.Currency == "" {
+		p.Currency = DefaultCurrency
+	}
+	p.Amount.Currency = p.Currency
+	p.MaxDiscount.Currency = p.Currency
+	p.MinSubtotal.Currency = p.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the promotion's
+func (p *Promotion) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(p.Currency, p.Amount, p.MaxDiscount, p.MinSubtotal)
+}
+
+// PromotionRedemption records one use of a promotion on an order. Rows are removed again when the
+// order is cancelled so the use counts towards no limit.
+type PromotionRedemption struct {
+	ID          string    `json:"id" gorm:"primaryKey;column:id"`
+	PromotionID string    `json:"promotion_id" gorm:"column:promotion_id;not null;uniqueIndex:idx_promotion_redemptions_order"`
+	OrderID     string    `json:"order_id" gorm:"column:order_id;not null;uniqueIndex:idx_promotion_redemptions_order;index"`
+	UserID      string    `json:"user_id" gorm:"column:user_id;not null;index"`
+	Code        string    `json:"code" gorm:"column:code;not null"`
+	Discount    Money     `json:"discount" gorm:"column:discount;not null"`
+	Currency    Currency  `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+// AfterFind stamps the redemption currency onto the discount, which stores only minor units
+func (r *PromotionRedemption) AfterFind(tx *gorm.DB) error {
+	if r.Currency == "" {
+		r.Currency = DefaultCurrency
+	}
+	r.Discount.Currency = r.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the redemption's
+func (r *PromotionRedemption) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(r.Currency, r.Discount)
+}
+
+// DiscountableLine is an order line a promotion may take money off
+type DiscountableLine struct {
+	Category string
+	Amount   Money
+}
+
+// DiscountLine represents one line of an order's discount breakdown
+type DiscountLine struct {
+	PromotionID string        `json:"promotion_id,omitempty"` // Empty for tier perks and loyalty points
+	Code        string        `json:"code,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Type        PromotionType `json:"type"`
+	Amount      Money         `json:"amount"`
+}
+
+// DiscountLinesArray is a custom type for handling the discount breakdown array
+type DiscountLinesArray []DiscountLine
+
+func (dla DiscountLinesArray) Value() (driver.Value, error) {
+	return json.Marshal(dla)
+}
+
+func (dla *DiscountLinesArray) Scan(value interface{}) error {
+	if value == nil {
+		*dla = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, dla)
+}
+
+// OfType sums the discount lines of one promotion type
+func (dla DiscountLinesArray) OfType(promotionType PromotionType, currency Currency) Money {
+	total := Zero(currency)
+	for _, line := range dla {
+		if line.Type == promotionType {
+			total = total.Add(line.Amount)
+		}
+	}
+	return total
+}
+
+// PlatformFunded sums the discount lines the platform pays for rather than the restaurant: waived delivery
+// fees and loyalty points
+func (dla DiscountLinesArray) PlatformFunded(currency Currency) Money {
+	return dla.OfType(PromotionFreeDelivery, currency).Add(dla.OfType(PromotionLoyaltyPoints, currency))
+}
+
+// PromotionDiscount is what a set of promo codes takes off an order
+type PromotionDiscount struct {
+	Lines         DiscountLinesArray
+	ItemDiscounts []Money // Per discountable line, in the order the lines were given
+	Delivery      Money   // Waived delivery fee
+	Total         Money
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OrderQuote represents an authoritative price for a basket that CreateOrder honors until it expires
+type OrderQuote struct {
+	ID                       string             `json:"-" gorm:"primaryKey;column:id"`
+	QuoteID                  string             `json:"quote_id" gorm:"-"` // Signed ID handed to clients
+	UserID                   string             `json:"user_id" gorm:"column:user_id;not null;index"`
+	RestaurantID             string             `json:"restaurant_id" gorm:"column:restaurant_id;not null"`
+	RestaurantName           string             `json:"restaurant_name" gorm:"column:restaurant_name;not null"`
+	DeliveryAddressID        *string            `json:"delivery_address_id,omitempty" gorm:"column:delivery_address_id"`
+	PromoCodes               StringArray        `json:"promo_codes,omitempty" gorm:"column:promo_codes"`
+	Fingerprint              string             `json:"-" gorm:"column:fingerprint;not null"`
+	Currency                 Currency           `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	Items                    OrderItemsArray    `json:"items" gorm:"column:items;not null"`
+	Subtotal                 Money              `json:"subtotal" gorm:"column:subtotal;not null"`
+	DeliveryFee              Money              `json:"delivery_fee" gorm:"column:delivery_fee;not null"`
+	SmallOrderFee            Money              `json:"small_order_fee" gorm:"column:small_order_fee;not null"`
+	ServiceFee               Money              `json:"service_fee" gorm:"column:service_fee;not null;default:0"`
+	MembershipID             *string            `json:"membership_id,omitempty" gorm:"column:membership_id"`
+	Discount                 Money              `json:"discount" gorm:"column:discount;not null"`
+	DiscountLines            DiscountLinesArray `json:"discount_lines,omitempty" gorm:"column:discount_lines"`
+	Tax                      Money              `json:"tax" gorm:"column:tax;not null"`
+	TaxLines                 TaxLinesArray      `json:"tax_lines" gorm:"column:tax_lines"`
+	Tip                      Money              `json:"tip" gorm:"column:tip;not null;default:0"`
+	Total                    Money              `json:"total" gorm:"column:total;not null"`
+	DeliveryDistanceKm       *float64           `json:"delivery_distance_km,omitempty" gorm:"column:delivery_distance_km"`
+	EstimatedDeliveryMinutes int                `json:"estimated_delivery_minutes" gorm:"column:estimated_delivery_minutes"`
+	EstimatedDeliveryAt      time.Time          `json:"estimated_delivery_at" gorm:"column:estimated_delivery_at"`
+	OrderID                  *string            `json:"order_id,omitempty" gorm:"column:order_id"` // Set once the quote is used
+	ExpiresAt                time.Time          `json:"expires_at" gorm:"column:expires_at;not null"`
+	CreatedAt                time.Time          `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+// AfterFind stamps the quote currency onto its monetary columns, which store only minor units
+func (q *OrderQuote) AfterFind(tx *gorm.DB) error {
+	if q.Currency == "" {
+		q.Currency = DefaultCurrency
+	}
+	q.Subtotal.Currency = q.Currency
+	q.DeliveryFee.Currency = q.Currency
+	q.SmallOrderFee.Currency = q.Currency
+	q.ServiceFee.Currency = q.Currency
+	q.Discount.Currency = q.Currency
+	q.Tax.Currency = q.Currency
+	q.Tip.Currency = q.Currency
+	q.Total.Currency = q.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the quote's
+func (q *OrderQuote) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(q.Currency, q.Subtotal, q.DeliveryFee, q.SmallOrderFee, q.ServiceFee, q.Discount, q.Tax, q.Tip, q.Total)
+}
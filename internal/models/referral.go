@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReferralStatus tracks a referral from sign-up to reward
+type ReferralStatus string
+
+const (
+	ReferralStatusPending  ReferralStatus = "pending"  // Waiting for the referred user's first delivered order
+	ReferralStatusRewarded ReferralStatus = "rewarded" // Both users have been credited
+	ReferralStatusRejected ReferralStatus = "rejected" // A fraud check failed; nobody is credited
+	ReferralStatusCapped   ReferralStatus = "capped"   // The referrer had already earned the most rewards allowed
+)
+
+// Referral fraud checks, recorded as the reason a referral was rejected
+const (
+	ReferralFraudSameDevice  = "same_device"  // The new account was registered from a device another account uses
+	ReferralFraudSamePhone   = "same_phone"   // The new account's phone number belongs to another account
+	ReferralFraudSameAddress = "same_address" // The first order went to one of the referrer's addresses
+	ReferralFraudSameCard    = "same_card"    // The new account has a card the referrer also saved
+)
+
+// Referral links a new user to the user whose referral code they registered with
+type Referral struct {
+	ID              string         `json:"id" gorm:"primaryKey;column:id"`
+	ReferrerID      string         `json:"referrer_id" gorm:"column:referrer_id;not null;index"`
+	RefereeID       string         `json:"referee_id" gorm:"column:referee_id;not null;uniqueIndex"` // A user can be referred once
+	Code            string         `json:"code" gorm:"column:code;not null"`
+	Status          ReferralStatus `json:"status" gorm:"column:status;not null;default:'pending';index"`
+	RejectionReason *string        `json:"rejection_reason,omitempty" gorm:"column:rejection_reason"`
+	OrderID         *string        `json:"order_id,omitempty" gorm:"column:order_id"` // The referee's first delivered order
+	ReferrerCredit  Money          `json:"referrer_credit" gorm:"column:referrer_credit;not null;default:0"`
+	RefereeCredit   Money          `json:"referee_credit" gorm:"column:referee_credit;not null;default:0"`
+	Currency        Currency       `json:"currency" gorm:"column:currency;not null;default:'USD'"`
+	RewardedAt      *time.Time     `json:"rewarded_at,omitempty" gorm:"column:rewarded_at"`
+	CreatedAt       time.Time      `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt       time.Time      `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// AfterFind stamps the referral currency onto the credits, which store only minor units
+func (r *Referral) AfterFind(tx *gorm.DB) error {
+	if r.Currency == "" {
+		r.Currency = DefaultCurrency
+	}
+	r.ReferrerCredit.Currency = r.Currency
+	r.RefereeCredit.Currency = r.Currency
+	return nil
+}
+
+// BeforeSave refuses amounts in a currency other than the referral's
+func (r *Referral) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(r.Currency, r.ReferrerCredit, r.RefereeCredit)
+}
+
+// UserReferrals is a user's referral code with the people who registered with it
+type UserReferrals struct {
+	UserID     string     `json:"user_id"`
+	Code       string     `json:"code"`
+	Rewarded   int64      `json:"rewarded"`
+	MaxRewards int        `json:"max_rewards,omitempty"` // Zero means unlimited
+	Referrals  []Referral `json:"referrals"`
+}
+
+// ReferralReportRow counts one referrer's referrals in one status
+type ReferralReportRow struct {
+	ReferrerID      string
+	Status          ReferralStatus
+	RejectionReason string
+	Currency        Currency
+	Referrals       int64
+	CreditsPaid     int64 // Minor units
+}
+
+// ReferrerPerformance sums up the referrals of one referrer over a report period
+type ReferrerPerformance struct {
+	ReferrerID  string `json:"referrer_id"`
+	Referred    int64  `json:"referred"`
+	Pending     int64  `json:"pending"`
+	Rewarded    int64  `json:"rewarded"`
+	Rejected    int64  `json:"rejected"`
+	Capped      int64  `json:"capped"`
+	CreditsPaid Money  `json:"credits_paid"` // To the referrer and the users they referred
+}
+
+// ReferralReport is the referral program's performance over a period, best referrers first
+type ReferralReport struct {
+	From           *time.Time            `json:"from,omitempty"`
+	To             *time.Time            `json:"to,omitempty"`
+	Referred       int64                 `json:"referred"`
+	Rewarded       int64                 `json:"rewarded"`
+	ConversionRate float64               `json:"conversion_rate"` // Share of referred users whose referral was rewarded
+	Rejections     map[string]int64      `json:"rejections"`      // By fraud check
+	CreditsPaid    Money                 `json:"credits_paid"`
+	Referrers      []ReferrerPerformance `json:"referrers"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // RegisterRequest represents user registration request
 type RegisterRequest struct {
 	Email       string `json:"email" binding:"required,email"`
@@ -15,7 +17,6 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-
 // UpdatePasswordModel represents password update request
 type UpdatePasswordRequest struct {
 	Email           string `json:"email"`
@@ -33,16 +34,97 @@ type AuthResponse struct {
 
 // CreateOrderRequest represents create order request
 type CreateOrderRequest struct {
-	RestaurantID    string      `json:"restaurantId" binding:"required"`
-	RestaurantName  string      `json:"restaurantName" binding:"required"`
-	Items           []OrderItem `json:"items" binding:"required,min=1"`
-	Subtotal        float64     `json:"subtotal" binding:"required,min=0"`
-	DeliveryFee     float64     `json:"deliveryFee" binding:"required,min=0"`
-	Tax             float64     `json:"tax" binding:"required,min=0"`
-	Total           float64     `json:"total" binding:"required,min=0"`
-	DeliveryAddress string      `json:"deliveryAddress" binding:"required"`
-	PaymentMethodID string      `json:"paymentMethodId" binding:"required"`
-	Notes           *string     `json:"notes,omitempty"`
+	RestaurantID      string      `json:"restaurantId" binding:"required"`
+	RestaurantName    string      `json:"restaurantName" binding:"required"`
+	Items             []OrderItem `json:"items" binding:"required,min=1"`
+	Subtotal          Money       `json:"subtotal"`
+	DeliveryFee       Money       `json:"deliveryFee"`
+	Total             Money       `json:"total"`
+	DeliveryAddress   string      `json:"deliveryAddress" binding:"required"`
+	DeliveryAddressID *string     `json:"deliveryAddressId,omitempty"`
+	PaymentMethodID   string      `json:"paymentMethodId" binding:"required"`
+	Notes             *string     `json:"notes,omitempty"`
+}
+
+// QuoteOrderRequest represents a request to price a basket without placing an order
+type QuoteOrderRequest struct {
+	UserID            string      `json:"userId" binding:"required"`
+	RestaurantID      string      `json:"restaurantId" binding:"required"`
+	Items             []OrderItem `json:"items" binding:"required,min=1"`
+	DeliveryAddressID *string     `json:"deliveryAddressId,omitempty"`
+	PromoCode         *string     `json:"promoCode,omitempty"`
+	PromoCodes        []string    `json:"promoCodes,omitempty"`     // Several codes when they are stackable
+	RedeemPoints      int64       `json:"redeemPoints,omitempty"`   // Loyalty points to spend as a discount
+	Tip               Money       `json:"tip"`                      // Fixed tip, or
+	TipBasisPoints    *int64      `json:"tipBasisPoints,omitempty"` // a share of the subtotal (1% = 100)
+}
+
+// ReorderRequest represents a request to rebuild a basket from a past order
+type ReorderRequest struct {
+	UserID            string  `json:"userId" binding:"required"`
+	DeliveryAddressID *string `json:"deliveryAddressId,omitempty"` // Defaults to the past order's address
+}
+
+// CreateOrderTemplateRequest represents a request to save a named order template,
+// either copied from a past order or built from the given items
+type CreateOrderTemplateRequest struct {
+	UserID            string      `json:"userId" binding:"required"`
+	Name              string      `json:"name" binding:"required"`
+	SourceOrderID     *string     `json:"sourceOrderId,omitempty"`
+	RestaurantID      string      `json:"restaurantId"`
+	Items             []OrderItem `json:"items"`
+	DeliveryAddress   string      `json:"deliveryAddress"`
+	DeliveryAddressID *string     `json:"deliveryAddressId,omitempty"`
+	PaymentMethod     string      `json:"paymentMethod"`
+}
+
+// DeleteOrderTemplateRequest identifies the user deleting one of their saved templates
+type DeleteOrderTemplateRequest struct {
+	UserID string `json:"userId" binding:"required"`
+}
+
+// PlaceOrderTemplateRequest represents a request to place an order from a saved template
+type PlaceOrderTemplateRequest struct {
+	UserID            string     `json:"userId" binding:"required"`
+	DeliveryAddressID *string    `json:"deliveryAddressId,omitempty"` // Overrides the template's address
+	PaymentMethod     *string    `json:"paymentMethod,omitempty"`     // Overrides the template's payment method
+	ScheduledFor      *time.Time `json:"scheduledFor,omitempty"`
+}
+
+// CreateGroupOrderRequest represents a request to start a group order
+type CreateGroupOrderRequest struct {
+	HostUserID        string           `json:"hostUserId" binding:"required"`
+	RestaurantID      string           `json:"restaurantId" binding:"required"`
+	PaymentMode       GroupPaymentMode `json:"paymentMode"` // Default: host
+	DeliveryAddress   string           `json:"deliveryAddress"`
+	DeliveryAddressID *string          `json:"deliveryAddressId,omitempty"`
+	PaymentMethod     string           `json:"paymentMethod" binding:"required"`
+	DisplayName       string           `json:"displayName"`
+}
+
+// JoinGroupOrderRequest represents a request to join a group order by invite code
+type JoinGroupOrderRequest struct {
+	UserID          string `json:"userId" binding:"required"`
+	InviteCode      string `json:"inviteCode" binding:"required"`
+	DisplayName     string `json:"displayName"`
+	PaymentMethodID string `json:"paymentMethodId"` // Used when the payment is split
+}
+
+// UpdateGroupOrderItemsRequest represents a participant replacing their items in a group cart
+type UpdateGroupOrderItemsRequest struct {
+	UserID string      `json:"userId" binding:"required"`
+	Items  []OrderItem `json:"items"`
+}
+
+// GroupOrderHostRequest represents a host-only action on a group order
+type GroupOrderHostRequest struct {
+	UserID string `json:"userId" binding:"required"`
+}
+
+// PayGroupOrderShareRequest represents a participant paying their portion of a split group order
+type PayGroupOrderShareRequest struct {
+	UserID          string `json:"userId" binding:"required"`
+	PaymentMethodID string `json:"paymentMethodId"`
 }
 
 // UpdateOrderStatusRequest represents update order status request
@@ -50,9 +132,72 @@ type UpdateOrderStatusRequest struct {
 	Status              OrderStatus `json:"status" binding:"required"`
 	DeliveryPersonName  *string     `json:"deliveryPersonName,omitempty"`
 	DeliveryPersonPhone *string     `json:"deliveryPersonPhone,omitempty"`
+	CourierID           *string     `json:"courierId,omitempty"` // Assigns the courier who receives the tip
 	TrackingURL         *string     `json:"trackingUrl,omitempty"`
 }
 
+// AdjustTipRequest represents a customer changing their tip, as a fixed amount or a share of the subtotal
+type AdjustTipRequest struct {
+	Tip            Money  `json:"tip"`
+	TipBasisPoints *int64 `json:"tipBasisPoints,omitempty"` // 1% = 100
+}
+
+// RemoveOrderItemsRequest represents a restaurant taking items it cannot fulfil off an order
+type RemoveOrderItemsRequest struct {
+	Items []OrderItemRemoval `json:"items" binding:"required,min=1"`
+}
+
+// OrderItemRemoval identifies an order line by its position in the items array.
+// Quantity is how many to take off the line; zero removes the whole line.
+type OrderItemRemoval struct {
+	Line     int `json:"line"`
+	Quantity int `json:"quantity"`
+}
+
+// CancelOrderRequest represents a cancellation by a customer, the restaurant or support.
+// The acting user comes from the bearer token, which must be allowed to cancel as CancelledBy.
+type CancelOrderRequest struct {
+	CancelledBy CancellationActor  `json:"cancelledBy" binding:"required"`
+	Reason      CancellationReason `json:"reason" binding:"required"`
+	Note        *string            `json:"note,omitempty"`
+	WaiveFee    bool               `json:"waiveFee"` // Support only
+	ToWallet    bool               `json:"toWallet"` // Refund to the customer's wallet instead of their card
+}
+
+// SaveCardRequest carries raw card details, which are exchanged for a gateway token and then discarded
+type SaveCardRequest struct {
+	UserID          string `json:"userId" binding:"required"`
+	PaymentMethodID string `json:"paymentMethodId" binding:"required"`
+	Number          string `json:"number" binding:"required"`
+	CVV             string `json:"cvv" binding:"required"`
+	ExpiryMonth     int    `json:"expiryMonth" binding:"required"`
+	ExpiryYear      int    `json:"expiryYear" binding:"required"` // Two-digit years are read as 20xx
+	CardholderName  string `json:"cardholderName" binding:"required"`
+	IsDefault       bool   `json:"isDefault"`
+}
+
+// ProcessRefundRequest represents a refund of all or part of a completed charge
+type ProcessRefundRequest struct {
+	TransactionID string `json:"transactionId" binding:"required"`
+	Amount        Money  `json:"amount"`   // Defaults to everything not yet refunded
+	ToWallet      bool   `json:"toWallet"` // Credit the customer's wallet at once instead of their card; wallet payments always are
+}
+
+// TopUpWalletRequest adds funds to a user's wallet from a saved card
+type TopUpWalletRequest struct {
+	UserID string  `json:"-"` // Taken from the path
+	Amount Money   `json:"amount"`
+	CardID *string `json:"cardId,omitempty"` // Defaults to the user's default card
+}
+
+// GrantStoreCreditRequest credits a user's wallet as a goodwill gesture
+type GrantStoreCreditRequest struct {
+	UserID  string `json:"-"` // Taken from the path
+	Amount  Money  `json:"amount"`
+	Reason  string `json:"reason" binding:"required"`
+	AgentID string `json:"agentId" binding:"required"`
+}
+
 // CreateAddressRequest represents create address request
 type CreateAddressRequest struct {
 	Street    string   `json:"street" binding:"required"`
@@ -87,6 +232,22 @@ type RestaurantSearchParams struct {
 	Limit     int     `form:"limit" binding:"min=1,max=100"`
 }
 
+// OrderSearchParams represents order search parameters
+type OrderSearchParams struct {
+	Status        []OrderStatus `form:"status"` // Repeatable
+	RestaurantID  string        `form:"restaurantId"`
+	UserID        string        `form:"userId"`                                       // Ignored for customers
+	From          *time.Time    `form:"from" time_format:"2006-01-02T15:04:05Z07:00"` // RFC 3339
+	To            *time.Time    `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`   // RFC 3339
+	MinTotal      string        `form:"minTotal"`                                     // Decimal, e.g. "12.50"
+	MaxTotal      string        `form:"maxTotal"`
+	PaymentMethod string        `form:"paymentMethod"`
+	Query         string        `form:"query"` // Matches item names
+	Sort          OrderSort     `form:"sort"`  // newest, oldest, total_desc, total_asc
+	Cursor        string        `form:"cursor"`
+	Limit         int           `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
 // FoodSearchParams represents food search parameters
 type FoodSearchParams struct {
 	Query        string  `form:"query"`
@@ -100,3 +261,147 @@ type FoodSearchParams struct {
 	Page         int     `form:"page" binding:"min=1"`
 	Limit        int     `form:"limit" binding:"min=1,max=100"`
 }
+
+// SetCommissionRuleRequest sets the commission a restaurant pays on its share of each order
+type SetCommissionRuleRequest struct {
+	BasisPoints int64  `json:"basisPoints"` // 1% = 100
+	FixedFee    Money  `json:"fixedFee"`    // Per order
+	AgentID     string `json:"agentId" binding:"required"`
+}
+
+// SetLoyaltyMultiplierRequest sets the bonus points customers earn on a restaurant's orders
+type SetLoyaltyMultiplierRequest struct {
+	BasisPoints int64      `json:"basisPoints"` // 2x = 20000; 10000 removes the bonus
+	StartsAt    *time.Time `json:"startsAt,omitempty"`
+	EndsAt      *time.Time `json:"endsAt,omitempty"`
+	AgentID     string     `json:"agentId" binding:"required"`
+}
+
+// SettlePayoutsRequest runs a settlement of everything delivered, cancelled or refunded before PeriodEnd
+type SettlePayoutsRequest struct {
+	PeriodEnd    *time.Time `json:"periodEnd,omitempty"`    // Defaults to now
+	RestaurantID string     `json:"restaurantId,omitempty"` // Defaults to every restaurant with unsettled orders
+}
+
+// CreatePromotionRequest represents an admin setting up a promo code
+type CreatePromotionRequest struct {
+	Code           string        `json:"code" binding:"required"`
+	Description    string        `json:"description"`
+	Type           PromotionType `json:"type" binding:"required"`
+	BasisPoints    int64         `json:"basisPoints"` // Percentage promotions, 1% = 100
+	Amount         Money         `json:"amount"`      // Fixed promotions
+	MaxDiscount    Money         `json:"maxDiscount"` // Caps a percentage discount; zero means no cap
+	MinSubtotal    Money         `json:"minSubtotal"`
+	RestaurantID   *string       `json:"restaurantId,omitempty"`
+	Category       *string       `json:"category,omitempty"`
+	FirstOrderOnly bool          `json:"firstOrderOnly"`
+	Stackable      bool          `json:"stackable"`
+	MaxUses        int           `json:"maxUses"`
+	MaxUsesPerUser int           `json:"maxUsesPerUser"`
+	StartsAt       *time.Time    `json:"startsAt,omitempty"`
+	EndsAt         *time.Time    `json:"endsAt,omitempty"`
+	AgentID        string        `json:"agentId" binding:"required"`
+}
+
+// UpdatePromotionRequest changes the limits, validity or status of a promo code; omitted fields are left alone
+type UpdatePromotionRequest struct {
+	Description    *string    `json:"description,omitempty"`
+	MaxUses        *int       `json:"maxUses,omitempty"`
+	MaxUsesPerUser *int       `json:"maxUsesPerUser,omitempty"`
+	StartsAt       *time.Time `json:"startsAt,omitempty"`
+	EndsAt         *time.Time `json:"endsAt,omitempty"`
+	IsActive       *bool      `json:"isActive,omitempty"`
+}
+
+// ReferralReportParams selects the referrals, by registration time, that a referral report covers
+type ReferralReportParams struct {
+	From *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"` // RFC 3339
+	To   *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`   // RFC 3339
+}
+
+// PurchaseGiftCardRequest buys a gift card with a saved card
+type PurchaseGiftCardRequest struct {
+	UserID         string  `json:"userId" binding:"required"`
+	Amount         Money   `json:"amount"`
+	CardID         *string `json:"cardId,omitempty"` // Defaults to the user's default card
+	RecipientEmail *string `json:"recipientEmail,omitempty"`
+	Message        *string `json:"message,omitempty"`
+}
+
+// GiftCardCodeRequest looks a gift card up by its code; codes go in the body so they stay out of access logs
+type GiftCardCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RedeemGiftCardRequest moves a gift card's balance to the user's wallet
+type RedeemGiftCardRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	Code   string `json:"code" binding:"required"`
+}
+
+// IssueGiftCardsRequest issues a batch of promotional gift cards of the same amount
+type IssueGiftCardsRequest struct {
+	Count     int        `json:"count" binding:"required,min=1"`
+	Amount    Money      `json:"amount"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // Defaults to gift_card.promotional_expiry_days from now
+	Message   *string    `json:"message,omitempty"`
+	AgentID   string     `json:"agentId" binding:"required"`
+}
+
+// CreateMembershipPlanRequest represents an admin setting up a membership plan
+type CreateMembershipPlanRequest struct {
+	Name                          string `json:"name" binding:"required"`
+	Description                   string `json:"description"`
+	Price                         Money  `json:"price"`
+	IntervalMonths                int    `json:"intervalMonths"` // Defaults to 1
+	TrialDays                     int    `json:"trialDays"`
+	FreeDelivery                  bool   `json:"freeDelivery"`
+	FreeDeliveryMinSubtotal       Money  `json:"freeDeliveryMinSubtotal"`
+	ServiceFeeDiscountBasisPoints int64  `json:"serviceFeeDiscountBasisPoints"` // 10000 waives the service fee
+	AgentID                       string `json:"agentId" binding:"required"`
+}
+
+// UpdateMembershipPlanRequest changes how a plan is presented or retires it; omitted fields are left alone.
+// Prices and perks are fixed once a plan has members, so changing them means a new plan.
+type UpdateMembershipPlanRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	TrialDays   *int    `json:"trialDays,omitempty"`
+	IsActive    *bool   `json:"isActive,omitempty"`
+}
+
+// SubscribeMembershipRequest starts a membership, with a trial when the plan offers one and the user has never been a member
+type SubscribeMembershipRequest struct {
+	UserID string  `json:"-"` // Taken from the path
+	PlanID string  `json:"planId" binding:"required"`
+	CardID *string `json:"cardId,omitempty"` // Defaults to the user's default card at each charge
+}
+
+// OpeningInterval is one opening interval in an hours request; closing at or before the opening time runs past midnight
+type OpeningInterval struct {
+	DayOfWeek int    `json:"dayOfWeek"`                   // 0 = Sunday ... 6 = Saturday; ignored for holidays
+	OpensAt   string `json:"opensAt" binding:"required"`  // HH:MM
+	ClosesAt  string `json:"closesAt" binding:"required"` // HH:MM
+}
+
+// SetOpeningHoursRequest replaces a restaurant's weekly hours. A day may have several intervals;
+// a week without any leaves the restaurant open around the clock.
+type SetOpeningHoursRequest struct {
+	TimeZone *string           `json:"timeZone,omitempty"` // IANA name such as "Europe/Berlin"; omitted keeps the current zone
+	Hours    []OpeningInterval `json:"hours" binding:"dive"`
+}
+
+// SetHolidayHoursRequest replaces the hours of one date, either closing the restaurant for the day or
+// opening it only during the given intervals
+type SetHolidayHoursRequest struct {
+	Date   string            `json:"date" binding:"required"` // YYYY-MM-DD in the restaurant's time zone
+	Closed bool              `json:"closed"`
+	Hours  []OpeningInterval `json:"hours" binding:"dive"`
+	Note   string            `json:"note"`
+}
+
+// PauseRestaurantRequest stops a restaurant taking orders for a while, e.g. when the kitchen is swamped
+type PauseRestaurantRequest struct {
+	Minutes int    `json:"minutes" binding:"required,min=1,max=1440"`
+	Reason  string `json:"reason"` // Shown to customers, e.g. "Busy, back in 30 min"
+}
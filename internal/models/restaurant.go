@@ -2,27 +2,88 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Restaurant represents the restaurant entity - SQLite compatible
 type Restaurant struct {
-	ID             string                   `json:"id" gorm:"primaryKey;column:id"`
-	Name           string                   `json:"name" gorm:"column:name;not null;index"`
-	Description    string                   `json:"description" gorm:"column:description;not null"`
-	Location       string                   `json:"location" gorm:"column:location;not null"`
-	Distance       float64                  `json:"distance" gorm:"column:distance;default:0.0"`
-	Rating         float64                  `json:"rating" gorm:"column:rating;default:0.0;index"`
-	DeliveryTime   string                   `json:"delivery_time" gorm:"column:delivery_time;not null"`
-	DeliveryFee    float64                  `json:"delivery_fee" gorm:"column:delivery_fee;not null"`
-	ImageURL       string                   `json:"image_url" gorm:"column:image_url;not null"`
-	Categories     StringArray              `json:"categories" gorm:"column:categories"`
-	IsOpen         bool                     `json:"is_open" gorm:"column:is_open;default:true;index"`
-	Latitude       float64                  `json:"latitude" gorm:"column:latitude;not null"`
-	Longitude      float64                  `json:"longitude" gorm:"column:longitude;not null"`
-	CreatedAt      time.Time                `json:"created_at" gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt      time.Time                `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
-	Foods          []Food                   `json:"foods,omitempty" gorm:"foreignKey:RestaurantID"`
-	FoodCategories []RestaurantFoodCategory `json:"food_categories,omitempty" gorm:"foreignKey:RestaurantID"`
+	ID                    string                   `json:"id" gorm:"primaryKey;column:id"`
+	Name                  string                   `json:"name" gorm:"column:name;not null;index"`
+	Description           string                   `json:"description" gorm:"column:description;not null"`
+	Location              string                   `json:"location" gorm:"column:location;not null"`
+	State                 string                   `json:"state" gorm:"column:state"`
+	ZipCode               string                   `json:"zip_code" gorm:"column:zip_code"`
+	Distance              float64                  `json:"distance" gorm:"column:distance;default:0.0"`
+	Rating                float64                  `json:"rating" gorm:"column:rating;default:0.0;index"`
+	DeliveryTime          string                   `json:"delivery_time" gorm:"column:delivery_time;not null"`
+	DeliveryFee           Money                    `json:"delivery_fee" gorm:"column:delivery_fee;not null"` // Flat fee when no fee bands apply
+	MinimumOrder          Money                    `json:"minimum_order" gorm:"column:minimum_order;default:0"`
+	SmallOrderThreshold   Money                    `json:"small_order_threshold" gorm:"column:small_order_threshold;default:0"`
+	SmallOrderSurcharge   Money                    `json:"small_order_surcharge" gorm:"column:small_order_surcharge;default:0"`
+	MaxDeliveryDistanceKm float64                  `json:"max_delivery_distance_km" gorm:"column:max_delivery_distance_km;default:0"` // 0 means no limit
+	ImageURL              string                   `json:"image_url" gorm:"column:image_url;not null"`
+	Categories            StringArray              `json:"categories" gorm:"column:categories"`
+	IsOpen                bool                     `json:"is_open" gorm:"-"`                                  // Computed from the opening hours, holidays and pause on every read
+	OpensAt               *time.Time               `json:"opens_at" gorm:"-"`                                 // Next time the restaurant opens, null if not within two weeks
+	ClosesAt              *time.Time               `json:"closes_at" gorm:"-"`                                // Next time the restaurant closes, null if not within two weeks
+	TimeZone              string                   `json:"time_zone" gorm:"column:time_zone"`                 // IANA name the opening hours are in; empty uses the server's zone
+	PausedUntil           *time.Time               `json:"paused_until,omitempty" gorm:"column:paused_until"` // Orders are refused until then, whatever the hours say
+	PauseReason           *string                  `json:"pause_reason,omitempty" gorm:"column:pause_reason"`
+	ScheduleLeadMinutes   int                      `json:"schedule_lead_minutes" gorm:"column:schedule_lead_minutes;default:45"` // Minimum notice for scheduled orders
+	MaxScheduleDays       int                      `json:"max_schedule_days" gorm:"column:max_schedule_days;default:7"`          // How far ahead orders can be scheduled
+	OwnerID               *string                  `json:"owner_id,omitempty" gorm:"column:owner_id;index"`                      // User who manages the restaurant and can see its payouts
+	Latitude              float64                  `json:"latitude" gorm:"column:latitude;not null"`
+	Longitude             float64                  `json:"longitude" gorm:"column:longitude;not null"`
+	CreatedAt             time.Time                `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt             time.Time                `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+	Foods                 []Food                   `json:"foods,omitempty" gorm:"foreignKey:RestaurantID"`
+	FoodCategories        []RestaurantFoodCategory `json:"food_categories,omitempty" gorm:"foreignKey:RestaurantID"`
+	OpeningHours          []OpeningHours           `json:"opening_hours,omitempty" gorm:"foreignKey:RestaurantID"`
+}
+
+// BeforeSave refuses fees in a currency other than DefaultCurrency, the only one restaurant fees are stored in
+func (r *Restaurant) BeforeSave(tx *gorm.DB) error {
+	return checkStoredCurrency(DefaultCurrency, r.DeliveryFee, r.MinimumOrder, r.SmallOrderThreshold, r.SmallOrderSurcharge)
+}
+
+// OpeningHours represents one opening interval of a restaurant on a day of the week.
+// A day may have several intervals; an interval closing before it opens runs past midnight.
+type OpeningHours struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	RestaurantID string    `json:"restaurant_id" gorm:"column:restaurant_id;not null;index"`
+	DayOfWeek    int       `json:"day_of_week" gorm:"column:day_of_week;not null"` // 0 = Sunday ... 6 = Saturday
+	OpensAt      string    `json:"opens_at" gorm:"column:opens_at;not null"`       // HH:MM
+	ClosesAt     string    `json:"closes_at" gorm:"column:closes_at;not null"`     // HH:MM
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// HolidayHours overrides a restaurant's weekly hours on one date. A date with overrides ignores its weekly
+// intervals and is open only during its override intervals, or not at all when it is marked closed.
+type HolidayHours struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	RestaurantID string    `json:"restaurant_id" gorm:"column:restaurant_id;not null;index:idx_holiday_hours_restaurant_date"`
+	Date         string    `json:"date" gorm:"column:date;not null;index:idx_holiday_hours_restaurant_date"` // YYYY-MM-DD in the restaurant's time zone
+	IsClosed     bool      `json:"is_closed" gorm:"column:is_closed;not null;default:false"`
+	OpensAt      string    `json:"opens_at,omitempty" gorm:"column:opens_at"`   // HH:MM, empty when closed
+	ClosesAt     string    `json:"closes_at,omitempty" gorm:"column:closes_at"` // HH:MM, empty when closed
+	Note         string    `json:"note,omitempty" gorm:"column:note"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// OpeningSchedule is a restaurant's current status with its weekly hours and upcoming holiday overrides
+type OpeningSchedule struct {
+	RestaurantID string         `json:"restaurant_id"`
+	TimeZone     string         `json:"time_zone"`
+	IsOpen       bool           `json:"is_open"`
+	OpensAt      *time.Time     `json:"opens_at"`
+	ClosesAt     *time.Time     `json:"closes_at"`
+	PausedUntil  *time.Time     `json:"paused_until,omitempty"`
+	PauseReason  *string        `json:"pause_reason,omitempty"`
+	Weekly       []OpeningHours `json:"weekly"`
+	Holidays     []HolidayHours `json:"holidays"`
 }
 
 // RestaurantFoodCategory represents food categories within a restaurant
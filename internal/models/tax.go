@@ -0,0 +1,102 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Tax categories used to pick category-specific rates; an empty category means standard-rated
+const (
+	TaxCategoryStandard = ""
+	TaxCategoryAlcohol  = "alcohol"
+	TaxCategoryGrocery  = "grocery"
+	TaxCategoryDelivery = "delivery"
+)
+
+// TaxRule represents a tax rate for a jurisdiction and optional tax category.
+// Rules with the same Name are alternatives (the most specific match wins),
+// while rules with different names stack (e.g. state tax + city tax).
+type TaxRule struct {
+	ID              string     `json:"id" gorm:"primaryKey;column:id"`
+	Name            string     `json:"name" gorm:"column:name;not null"`
+	Country         string     `json:"country" gorm:"column:country;default:'US'"`
+	State           string     `json:"state" gorm:"column:state;index"`                            // Empty matches any state
+	ZipCode         string     `json:"zip_code" gorm:"column:zip_code"`                            // Empty matches any zip code
+	Category        string     `json:"category" gorm:"column:category"`                            // Empty matches standard-rated items
+	RateBasisPoints int64      `json:"rate_basis_points" gorm:"column:rate_basis_points;not null"` // 1% = 100
+	IsInclusive     bool       `json:"is_inclusive" gorm:"column:is_inclusive;default:false"`
+	IsActive        bool       `json:"is_active" gorm:"column:is_active;default:true"`
+	ValidFrom       *time.Time `json:"valid_from,omitempty" gorm:"column:valid_from"`
+	ValidUntil      *time.Time `json:"valid_until,omitempty" gorm:"column:valid_until"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// Specificity ranks how narrowly a rule targets a jurisdiction and category
+func (r *TaxRule) Specificity() int {
+	score := 0
+	if r.ZipCode != "" {
+		score += 4
+	}
+	if r.State != "" {
+		score += 2
+	}
+	if r.Category != "" {
+		score++
+	}
+	return score
+}
+
+// TaxJurisdiction identifies where an order is taxed
+type TaxJurisdiction struct {
+	Country string `json:"country"`
+	State   string `json:"state"`
+	ZipCode string `json:"zip_code"`
+}
+
+// TaxableLine is an amount to be taxed under a given tax category
+type TaxableLine struct {
+	Category string `json:"category"`
+	Amount   Money  `json:"amount"`
+}
+
+// TaxLine represents one line of an order's tax breakdown
+type TaxLine struct {
+	Name            string `json:"name"`
+	RateBasisPoints int64  `json:"rate_basis_points"`
+	Category        string `json:"category,omitempty"`
+	TaxableAmount   Money  `json:"taxable_amount"`
+	Amount          Money  `json:"amount"`
+	IsInclusive     bool   `json:"is_inclusive"`
+}
+
+// TaxLinesArray is a custom type for handling the tax breakdown array
+type TaxLinesArray []TaxLine
+
+func (tla TaxLinesArray) Value() (driver.Value, error) {
+	return json.Marshal(tla)
+}
+
+func (tla *TaxLinesArray) Scan(value interface{}) error {
+	if value == nil {
+		*tla = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, tla)
+}
+
+// TaxResult represents the tax calculated for an order
+type TaxResult struct {
+	Lines     TaxLinesArray `json:"lines"`
+	Inclusive Money         `json:"inclusive"` // Already contained in item prices
+	Exclusive Money         `json:"exclusive"` // Added on top of item prices
+	Total     Money         `json:"total"`
+}
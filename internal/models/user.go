@@ -4,36 +4,51 @@ import (
 	"time"
 )
 
-// User represents the main user entity - SQLite compatible
+// UserRole controls which back-office endpoints a user can reach
+type UserRole string
+
+const (
+	RoleCustomer UserRole = "customer"
+	RoleSupport  UserRole = "support"
+	RoleAdmin    UserRole = "admin"
+)
+
+// User represents the main user entity - SQLite compatible.
+// Fields tagged serializer:encrypted are encrypted at rest by the encryption package.
 type User struct {
-	ID              string    `json:"id" gorm:"primaryKey;column:id"`
-	FirstName       string    `json:"first_name" gorm:"column:first_name;not null"`
-	LastName        string    `json:"last_name" gorm:"column:last_name;not null"`
-	Email           string    `json:"email" gorm:"column:email;uniqueIndex;not null"`
-	PhoneNumber     string    `json:"phone_number" gorm:"column:phone_number;not null;index"`
-	Password        string    `json:"password,omitempty" gorm:"column:password;not null"`
-	ProfileImageURL *string   `json:"profile_image_url,omitempty" gorm:"column:profile_image_url"`
-	Bio             *string   `json:"bio,omitempty" gorm:"column:bio"`
-	FirstTimeLogin  bool      `json:"first_time_login" gorm:"column:first_time_login;default:1"`
-	EmailVerified   bool      `json:"email_verified" gorm:"column:email_verified;default:0"`
-	FCMToken        *string   `json:"fcm_token,omitempty" gorm:"column:fcm_token"`
-	CreatedAt       time.Time `json:"created_at" gorm:"column:created_at"`
-	UpdatedAt       time.Time `json:"updated_at" gorm:"column:updated_at"`
-	AccessToken     string    `json:"access_token,omitempty" gorm:"-"`
-	RefreshToken    string    `json:"refresh_token,omitempty" gorm:"-"`
+	ID               string    `json:"id" gorm:"primaryKey;column:id"`
+	FirstName        string    `json:"first_name" gorm:"column:first_name;not null"`
+	LastName         string    `json:"last_name" gorm:"column:last_name;not null"`
+	Email            string    `json:"email" gorm:"column:email;uniqueIndex;not null"`
+	PhoneNumber      string    `json:"phone_number" gorm:"column:phone_number;not null;serializer:encrypted"`
+	PhoneNumberIndex string    `json:"-" gorm:"column:phone_number_index;index" blind_index:"phone_number"` // Blind index for lookups by phone number
+	Password         string    `json:"password,omitempty" gorm:"column:password;not null"`
+	ProfileImageURL  *string   `json:"profile_image_url,omitempty" gorm:"column:profile_image_url"`
+	Bio              *string   `json:"bio,omitempty" gorm:"column:bio"`
+	FirstTimeLogin   bool      `json:"first_time_login" gorm:"column:first_time_login;default:1"`
+	EmailVerified    bool      `json:"email_verified" gorm:"column:email_verified;default:0"`
+	Role             UserRole  `json:"role" gorm:"column:role;not null;default:'customer'"`
+	FCMToken         *string   `json:"fcm_token,omitempty" gorm:"column:fcm_token;serializer:encrypted"`
+	ReferralCode     *string   `json:"referral_code,omitempty" gorm:"column:referral_code;uniqueIndex"` // Shared to invite others
+	ReferredByCode   string    `json:"referred_by_code,omitempty" gorm:"-"`                             // Referral code given at registration
+	DeviceID         *string   `json:"device_id,omitempty" gorm:"column:device_id;index"`               // Device the account was registered from
+	CreatedAt        time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt        time.Time `json:"updated_at" gorm:"column:updated_at"`
+	AccessToken      string    `json:"access_token,omitempty" gorm:"-"`
+	RefreshToken     string    `json:"refresh_token,omitempty" gorm:"-"`
 }
 
 // Address represents user address entity - SQLite compatible
 type Address struct {
 	ID        string    `json:"id" gorm:"primaryKey;column:id"`
 	UserID    string    `json:"user_id" gorm:"column:user_id;not null;index"`
-	Street    string    `json:"street" gorm:"column:street;not null"`
+	Street    string    `json:"street" gorm:"column:street;not null;serializer:encrypted"`
 	City      string    `json:"city" gorm:"column:city;not null"`
 	State     string    `json:"state" gorm:"column:state;not null"`
 	ZipCode   string    `json:"zip_code" gorm:"column:zip_code;not null"`
 	Type      string    `json:"type" gorm:"column:type;default:'home'"`
-	Address   string    `json:"address" gorm:"column:address;not null"`
-	Apartment string    `json:"apartment" gorm:"column:apartment;not null"`
+	Address   string    `json:"address" gorm:"column:address;not null;serializer:encrypted"`
+	Apartment string    `json:"apartment" gorm:"column:apartment;not null;serializer:encrypted"`
 	Title     *string   `json:"title,omitempty" gorm:"column:title"`
 	Latitude  *float64  `json:"latitude,omitempty" gorm:"column:latitude"`
 	Longitude *float64  `json:"longitude,omitempty" gorm:"column:longitude"`
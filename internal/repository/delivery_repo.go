@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"net/http"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type deliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewDeliveryRepository() DeliveryRepository {
+	return &deliveryRepository{
+		db: database.DB,
+	}
+}
+
+func (r *deliveryRepository) GetZones(restaurantID string) ([]models.DeliveryZone, error) {
+	var zones []models.DeliveryZone
+	err := r.db.Where("restaurant_id = ? AND is_active = ?", restaurantID, true).Find(&zones).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch delivery zones", err)
+	}
+	return zones, nil
+}
+
+func (r *deliveryRepository) GetFeeBands(restaurantID string) ([]models.DeliveryFeeBand, error) {
+	var bands []models.DeliveryFeeBand
+	err := r.db.Where("restaurant_id = ?", restaurantID).Order("max_distance_km ASC").Find(&bands).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch delivery fee bands", err)
+	}
+	if len(bands) > 0 {
+		return bands, nil
+	}
+
+	// Fall back to the platform default bands
+	err = r.db.Where("restaurant_id IS NULL").Order("max_distance_km ASC").Find(&bands).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch delivery fee bands", err)
+	}
+	return bands, nil
+}
@@ -21,6 +21,17 @@ func NewFoodRepository() FoodRepository {
 	}
 }
 
+// withModifiers preloads modifier groups and their options in display order
+func (r *foodRepository) withModifiers() *gorm.DB {
+	return r.db.
+		Preload("ModifierGroups", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC")
+		}).
+		Preload("ModifierGroups.Options", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC")
+		})
+}
+
 func (r *foodRepository) GetAll(limit, offset int) ([]models.Food, error) {
 	var foods []models.Food
 	err := r.db.Where("is_available = ?", true).Limit(limit).Offset(offset).Find(&foods).Error
@@ -32,7 +43,7 @@ func (r *foodRepository) GetAll(limit, offset int) ([]models.Food, error) {
 
 func (r *foodRepository) GetByID(id string) (*models.Food, error) {
 	var food models.Food
-	err := r.db.Where("id = ?", id).First(&food).Error
+	err := r.withModifiers().Where("id = ?", id).First(&food).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Food not found", err)
@@ -64,7 +75,7 @@ func (r *foodRepository) GetByCategory(category string, limit, offset int) ([]mo
 
 func (r *foodRepository) GetByRestaurant(restaurantID string, limit, offset int) ([]models.Food, error) {
 	var foods []models.Food
-	err := r.db.Where("restaurant_id = ? AND is_available = ?", restaurantID, true).
+	err := r.withModifiers().Where("restaurant_id = ? AND is_available = ?", restaurantID, true).
 		Limit(limit).Offset(offset).Find(&foods).Error
 	if err != nil {
 		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch foods by restaurant", err)
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type giftCardRepository struct {
+	db *gorm.DB
+}
+
+func NewGiftCardRepository() GiftCardRepository {
+	return &giftCardRepository{
+		db: database.DB,
+	}
+}
+
+func (r *giftCardRepository) Create(giftCard *models.GiftCard) error {
+	if err := r.db.Create(giftCard).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to create gift card", err)
+	}
+	return nil
+}
+
+// CreateBatch stores a batch of gift cards all or nothing
+func (r *giftCardRepository) CreateBatch(giftCards []models.GiftCard) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(giftCards, 100).Error
+	})
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to issue gift cards", err)
+	}
+	return nil
+}
+
+func (r *giftCardRepository) GetByID(id string) (*models.GiftCard, error) {
+	var giftCard models.GiftCard
+	err := r.db.Where("id = ?", id).First(&giftCard).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Gift card not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch gift card", err)
+	}
+	return &giftCard, nil
+}
+
+// GetByCodeHash looks a gift card up by the hash of its code. Unknown codes are reported as invalid
+// rather than not found, the same way promo codes are.
+func (r *giftCardRepository) GetByCodeHash(codeHash string) (*models.GiftCard, error) {
+	var giftCard models.GiftCard
+	err := r.db.Where("code_hash = ?", codeHash).First(&giftCard).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusBadRequest, "Invalid gift card code", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch gift card", err)
+	}
+	return &giftCard, nil
+}
+
+// GetByUserID returns the gift cards a user bought or redeemed, newest first
+func (r *giftCardRepository) GetByUserID(userID string) ([]models.GiftCard, error) {
+	var giftCards []models.GiftCard
+	err := r.db.Where("purchaser_id = ? OR redeemed_by = ?", userID, userID).Order("created_at DESC").Find(&giftCards).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch gift cards", err)
+	}
+	return giftCards, nil
+}
+
+// UpdateIfStatus applies the updates only while the gift card is still in the expected status
+func (r *giftCardRepository) UpdateIfStatus(id string, status models.GiftCardStatus, updates map[string]interface{}) error {
+	result := r.db.Model(&models.GiftCard{}).Where("id = ? AND status = ?", id, status).Updates(updates)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update gift card", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "Gift card is no longer "+string(status), nil)
+	}
+	return nil
+}
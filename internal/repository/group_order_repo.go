@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type groupOrderRepository struct {
+	db *gorm.DB
+}
+
+func NewGroupOrderRepository() GroupOrderRepository {
+	return &groupOrderRepository{
+		db: database.DB,
+	}
+}
+
+func (r *groupOrderRepository) Create(group *models.GroupOrder) error {
+	if err := r.db.Create(group).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to create group order", err)
+	}
+	return nil
+}
+
+func (r *groupOrderRepository) GetByID(id string) (*models.GroupOrder, error) {
+	var group models.GroupOrder
+	err := r.withParticipants().Where("id = ?", id).First(&group).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Group order not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch group order", err)
+	}
+	return &group, nil
+}
+
+func (r *groupOrderRepository) GetByInviteCode(code string) (*models.GroupOrder, error) {
+	var group models.GroupOrder
+	err := r.withParticipants().Where("invite_code = ?", code).First(&group).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Invalid invite code", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch group order", err)
+	}
+	return &group, nil
+}
+
+// UpdateStatus moves a group order from one status to another, failing if it is no longer in the expected status
+func (r *groupOrderRepository) UpdateStatus(id string, from, to models.GroupOrderStatus, updates map[string]interface{}) error {
+	values := map[string]interface{}{"status": to}
+	for column, value := range updates {
+		values[column] = value
+	}
+
+	result := r.db.Model(&models.GroupOrder{}).Where("id = ? AND status = ?", id, from).Updates(values)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update group order", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "Group order is no longer "+string(from), nil)
+	}
+	return nil
+}
+
+func (r *groupOrderRepository) AddParticipant(participant *models.GroupOrderParticipant) error {
+	if err := r.db.Create(participant).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to join group order", err)
+	}
+	return nil
+}
+
+func (r *groupOrderRepository) UpdateParticipant(participant *models.GroupOrderParticipant) error {
+	if err := r.db.Save(participant).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update group order participant", err)
+	}
+	return nil
+}
+
+// GetParticipantsByOrderIDs returns the user's participation in any of the given placed orders
+func (r *groupOrderRepository) GetParticipantsByOrderIDs(userID string, orderIDs []string) ([]models.GroupOrderParticipant, error) {
+	var participants []models.GroupOrderParticipant
+	if len(orderIDs) == 0 {
+		return participants, nil
+	}
+	err := r.db.Where("user_id = ? AND order_id IN ?", userID, orderIDs).Find(&participants).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch group order participants", err)
+	}
+	return participants, nil
+}
+
+func (r *groupOrderRepository) withParticipants() *gorm.DB {
+	return r.db.Preload("Participants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	})
+}
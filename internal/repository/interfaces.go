@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"dfood/internal/models"
 )
 
@@ -13,6 +15,9 @@ type UserRepository interface {
 	Update(id string, updates map[string]interface{}) error
 	UpdateField(id, field string, value interface{}) error
 	UpdateFCMToken(id, token string) error
+	GetByReferralCode(code string) (*models.User, error)
+	CountOthersByDeviceID(deviceID, excludeUserID string) (int64, error)
+	CountOthersByPhoneNumber(phoneNumberIndex, excludeUserID string) (int64, error)
 }
 
 type RestaurantRepository interface {
@@ -22,6 +27,12 @@ type RestaurantRepository interface {
 	GetNearby(latitude, longitude, radius float64, limit int) ([]models.Restaurant, error)
 	Search(query string, limit, offset int) ([]models.Restaurant, error)
 	GetByCategory(category string, limit, offset int) ([]models.Restaurant, error)
+	Update(id string, updates map[string]interface{}) error
+	GetOpeningHours(restaurantIDs ...string) ([]models.OpeningHours, error)
+	ReplaceOpeningHours(restaurantID string, timeZone string, hours []models.OpeningHours) error
+	GetHolidayHours(from, to string, restaurantIDs ...string) ([]models.HolidayHours, error)
+	ReplaceHolidayHours(restaurantID, date string, hours []models.HolidayHours) error
+	DeleteHolidayHours(restaurantID, date string) error
 }
 
 type FoodRepository interface {
@@ -37,18 +48,66 @@ type OrderRepository interface {
 	Create(order *models.Order) error
 	GetByID(id string) (*models.Order, error)
 	GetByUserID(userID string, limit, offset int) ([]models.Order, error)
+	GetByUserIDAndStatuses(userID string, statuses []models.OrderStatus, limit, offset int) ([]models.Order, error)
+	CountPlacedByUserID(userID string) (int64, error)
+	Search(filter models.OrderSearchFilter) ([]models.Order, error)
 	UpdateStatus(id string, status models.OrderStatus) error
+	Update(id string, updates map[string]interface{}) error
+	UpdateIfStatus(id string, status models.OrderStatus, updates map[string]interface{}) error
+	ReleaseScheduled(now time.Time) (int64, error)
+	ClaimPayment(id string, now, staleBefore time.Time) error
+	ReleasePayment(id string) error
 	Delete(id string) error
 }
 
 type PaymentRepository interface {
 	GetPaymentMethods() ([]models.PaymentMethod, error)
 	GetUserCards(userID string) ([]models.Card, error)
+	GetCardByID(id string) (*models.Card, error)
+	GetCardByFingerprint(userID, fingerprint string) (*models.Card, error)
 	CreateCard(card *models.Card) error
+	SetDefaultCard(userID, cardID string) error
 	DeleteCard(id string) error
 	CreateTransaction(transaction *models.PaymentTransaction) error
 	GetTransactionByID(id string) (*models.PaymentTransaction, error)
+	GetTransactionByGatewayID(gatewayID string) (*models.PaymentTransaction, error)
+	GetTransactionsByOrderID(orderID string) ([]models.PaymentTransaction, error)
 	UpdateTransaction(id string, updates map[string]interface{}) error
+	UpdateTransactionIfStatus(id, status string, updates map[string]interface{}) error
+	ClaimRefund(id string, amount models.Money) error
+	ReleaseRefund(id string, amount models.Money) error
+}
+
+type PaymentWebhookRepository interface {
+	CreateIfAbsent(event *models.PaymentWebhookEvent) (bool, error)
+	GetByEventID(provider, eventID string) (*models.PaymentWebhookEvent, error)
+	Update(id string, updates map[string]interface{}) error
+}
+
+type LedgerRepository interface {
+	Post(journal *models.LedgerJournal) (bool, error)
+	PostIfCovered(journal *models.LedgerJournal, accountType models.LedgerAccountType, ownerID string, amount models.Money) error
+	GetJournalsByOrderID(orderID string) ([]models.LedgerJournal, error)
+	GetBalances(accountType models.LedgerAccountType, ownerID string) ([]models.Money, error)
+	GetEntries(accountType models.LedgerAccountType, ownerID string, limit, offset int) ([]models.LedgerEntry, error)
+	GetActivity(accountType models.LedgerAccountType, ownerID string, limit, offset int) ([]models.LedgerActivity, error)
+	CountJournals() (int64, error)
+	GetUnbalancedJournals() ([]models.LedgerImbalance, error)
+	GetTotals() ([]models.Money, error)
+	GetUnpostedTransactionIDs() ([]string, error)
+}
+
+type PayoutRepository interface {
+	GetCommissionRule(restaurantID string) (*models.CommissionRule, error)
+	SaveCommissionRule(rule *models.CommissionRule) error
+	GetUnsettledRestaurantIDs(before time.Time) ([]string, error)
+	GetUnsettledOrders(restaurantID string, before time.Time) ([]models.Order, error)
+	GetUnsettledRefunds(restaurantID string, before time.Time) ([]models.PaymentTransaction, error)
+	GetOrdersWithUnsettledTips(restaurantID string, before time.Time) ([]models.Order, error)
+	Create(payout *models.RestaurantPayout) error
+	GetByID(id string) (*models.RestaurantPayout, error)
+	GetByRestaurantID(restaurantID string, limit, offset int) ([]models.RestaurantPayout, error)
+	UpdateIfStatus(id string, status models.PayoutStatus, updates map[string]interface{}) error
 }
 
 type AddressRepository interface {
@@ -92,3 +151,94 @@ type NotificationRepository interface {
 	MarkAsRead(id string) error
 	Delete(id string) error
 }
+
+type TaxRuleRepository interface {
+	GetApplicable(jurisdiction models.TaxJurisdiction, at time.Time) ([]models.TaxRule, error)
+	Create(rule *models.TaxRule) error
+}
+
+type DeliveryRepository interface {
+	GetZones(restaurantID string) ([]models.DeliveryZone, error)
+	GetFeeBands(restaurantID string) ([]models.DeliveryFeeBand, error)
+}
+
+type OrderTemplateRepository interface {
+	Create(template *models.OrderTemplate) error
+	GetByID(id string) (*models.OrderTemplate, error)
+	GetByUserID(userID string) ([]models.OrderTemplate, error)
+	MarkPlaced(id string, at time.Time) error
+	Delete(id string) error
+}
+
+type GroupOrderRepository interface {
+	Create(group *models.GroupOrder) error
+	GetByID(id string) (*models.GroupOrder, error)
+	GetByInviteCode(code string) (*models.GroupOrder, error)
+	UpdateStatus(id string, from, to models.GroupOrderStatus, updates map[string]interface{}) error
+	AddParticipant(participant *models.GroupOrderParticipant) error
+	UpdateParticipant(participant *models.GroupOrderParticipant) error
+	GetParticipantsByOrderIDs(userID string, orderIDs []string) ([]models.GroupOrderParticipant, error)
+}
+
+type PromotionRepository interface {
+	Create(promotion *models.Promotion) error
+	GetByID(id string) (*models.Promotion, error)
+	GetByCode(code string) (*models.Promotion, error)
+	List(activeOnly bool, limit, offset int) ([]models.Promotion, error)
+	Update(id string, updates map[string]interface{}) error
+	CountUserRedemptions(promotionID, userID string) (int64, error)
+	Redeem(redemptions []models.PromotionRedemption) error
+	ReleaseByOrderID(orderID string) error
+}
+
+type LoyaltyRepository interface {
+	GetMultiplier(restaurantID string) (*models.LoyaltyMultiplier, error)
+	SaveMultiplier(multiplier *models.LoyaltyMultiplier) error
+	GetBalance(userID string) (int64, error)
+	GetEntries(userID string, limit, offset int) ([]models.LoyaltyEntry, error)
+	GetOrderEntry(entryType models.LoyaltyEntryType, orderID string) (*models.LoyaltyEntry, error)
+	GetNextExpiringLot(userID string) (*models.LoyaltyEntry, error)
+	GetLastActivityAt(userID string) (*time.Time, error)
+	SumEarnedSince(userID string, since time.Time) (int64, error)
+	AddPoints(entry *models.LoyaltyEntry) error
+	SpendPoints(entry *models.LoyaltyEntry) error
+	ExpireLots(entry *models.LoyaltyEntry, dueBy *time.Time) error
+}
+
+type ReferralRepository interface {
+	Create(referral *models.Referral) error
+	GetByRefereeID(refereeID string) (*models.Referral, error)
+	GetByReferrerID(referrerID string) ([]models.Referral, error)
+	CountByReferrerID(referrerID string, status models.ReferralStatus) (int64, error)
+	UpdateIfStatus(id string, status models.ReferralStatus, updates map[string]interface{}) error
+	GetReportRows(from, to *time.Time) ([]models.ReferralReportRow, error)
+}
+
+type GiftCardRepository interface {
+	Create(giftCard *models.GiftCard) error
+	CreateBatch(giftCards []models.GiftCard) error
+	GetByID(id string) (*models.GiftCard, error)
+	GetByCodeHash(codeHash string) (*models.GiftCard, error)
+	GetByUserID(userID string) ([]models.GiftCard, error)
+	UpdateIfStatus(id string, status models.GiftCardStatus, updates map[string]interface{}) error
+}
+
+type MembershipRepository interface {
+	CreatePlan(plan *models.MembershipPlan) error
+	GetPlanByID(id string) (*models.MembershipPlan, error)
+	ListPlans(activeOnly bool) ([]models.MembershipPlan, error)
+	UpdatePlan(id string, updates map[string]interface{}) error
+	Create(membership *models.Membership) error
+	GetByID(id string) (*models.Membership, error)
+	GetCurrentByUserID(userID string) (*models.Membership, error)
+	CountByUserID(userID string) (int64, error)
+	GetDue(now time.Time) ([]models.Membership, error)
+	UpdateIfStatus(id string, status models.MembershipStatus, updates map[string]interface{}) error
+}
+
+type OrderQuoteRepository interface {
+	Create(quote *models.OrderQuote) error
+	GetByID(id string) (*models.OrderQuote, error)
+	MarkUsed(id, orderID string) error
+	Release(id, orderID string) error
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"net/http"
+	"time"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ledgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository() LedgerRepository {
+	return &ledgerRepository{
+		db: database.DB,
+	}
+}
+
+// ledgerSum is one row of an aggregate over ledger entries
+type ledgerSum struct {
+	JournalID string
+	Currency  models.Currency
+	Total     int64
+}
+
+// Post stores a journal and its entries in one database transaction, reporting whether it was new.
+// A journal already posted for the same type and reference is left untouched.
+func (r *ledgerRepository) Post(journal *models.LedgerJournal) (bool, error) {
+	created := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Omit("Entries").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "type"}, {Name: "reference"}},
+			DoNothing: true,
+		}).Create(journal)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		created = true
+
+		for i := range journal.Entries {
+			journal.Entries[i].JournalID = journal.ID
+		}
+		return tx.Create(&journal.Entries).Error
+	})
+	if err != nil {
+		return false, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to post ledger journal", err)
+	}
+	return created, nil
+}
+
+// PostIfCovered posts a journal only if the account's balance, read inside the same database transaction,
+// still covers amount. It is how wallet payments spend a balance without two payments spending it twice.
+func (r *ledgerRepository) PostIfCovered(journal *models.LedgerJournal, accountType models.LedgerAccountType, ownerID string, amount models.Money) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var balance int64
+		if err := tx.Model(&models.LedgerEntry{}).
+			Select("COALESCE(SUM(amount), 0)").
+			Where("account_type = ? AND owner_id = ? AND currency = ?", accountType, ownerID, amount.Currency).
+			Scan(&balance).Error; err != nil {
+			return err
+		}
+		// Accounts we owe money to carry a negative balance
+		if -balance < amount.Amount {
+			return pkgErrors.NewHTTPError(http.StatusPaymentRequired, "Wallet balance of "+models.NewMoney(-balance, amount.Currency).String()+" does not cover "+amount.String(), nil)
+		}
+
+		if err := tx.Omit("Entries").Create(journal).Error; err != nil {
+			return err
+		}
+		for i := range journal.Entries {
+			journal.Entries[i].JournalID = journal.ID
+		}
+		return tx.Create(&journal.Entries).Error
+	})
+	if _, ok := err.(*pkgErrors.HTTPError); ok {
+		return err
+	}
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to post ledger journal", err)
+	}
+	return nil
+}
+
+func (r *ledgerRepository) GetJournalsByOrderID(orderID string) ([]models.LedgerJournal, error) {
+	var journals []models.LedgerJournal
+	err := r.db.Preload("Entries").Where("order_id = ?", orderID).Order("created_at ASC").Find(&journals).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch ledger journals", err)
+	}
+	return journals, nil
+}
+
+// GetBalances sums an account's entries per currency
+func (r *ledgerRepository) GetBalances(accountType models.LedgerAccountType, ownerID string) ([]models.Money, error) {
+	var sums []ledgerSum
+	err := r.db.Model(&models.LedgerEntry{}).
+		Select("currency, SUM(amount) AS total").
+		Where("account_type = ? AND owner_id = ?", accountType, ownerID).
+		Group("currency").
+		Order("currency").
+		Scan(&sums).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch ledger balance", err)
+	}
+
+	balances := make([]models.Money, 0, len(sums))
+	for _, sum := range sums {
+		balances = append(balances, models.NewMoney(sum.Total, sum.Currency))
+	}
+	return balances, nil
+}
+
+// GetEntries returns an account's entries, newest first
+func (r *ledgerRepository) GetEntries(accountType models.LedgerAccountType, ownerID string, limit, offset int) ([]models.LedgerEntry, error) {
+	var entries []models.LedgerEntry
+	err := r.db.Where("account_type = ? AND owner_id = ?", accountType, ownerID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch ledger entries", err)
+	}
+	return entries, nil
+}
+
+// GetActivity returns an account's entries with their journal details, newest first
+func (r *ledgerRepository) GetActivity(accountType models.LedgerAccountType, ownerID string, limit, offset int) ([]models.LedgerActivity, error) {
+	var rows []struct {
+		EntryID     string
+		JournalID   string
+		Type        models.LedgerJournalType
+		Reference   string
+		OrderID     *string
+		Description string
+		Amount      int64
+		Currency    models.Currency
+		CreatedAt   time.Time
+	}
+	err := r.db.Table("ledger_entries").
+		Select("ledger_entries.id AS entry_id, ledger_entries.journal_id, ledger_journals.type, ledger_journals.reference, "+
+			"ledger_journals.order_id, ledger_journals.description, ledger_entries.amount, ledger_entries.currency, ledger_entries.created_at").
+		Joins("JOIN ledger_journals ON ledger_journals.id = ledger_entries.journal_id").
+		Where("ledger_entries.account_type = ? AND ledger_entries.owner_id = ?", accountType, ownerID).
+		Order("ledger_entries.created_at DESC, ledger_entries.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch ledger activity", err)
+	}
+
+	activity := make([]models.LedgerActivity, 0, len(rows))
+	for _, row := range rows {
+		activity = append(activity, models.LedgerActivity{
+			EntryID:     row.EntryID,
+			JournalID:   row.JournalID,
+			Type:        row.Type,
+			Reference:   row.Reference,
+			OrderID:     row.OrderID,
+			Description: row.Description,
+			Amount:      models.NewMoney(row.Amount, row.Currency),
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+	return activity, nil
+}
+
+func (r *ledgerRepository) CountJournals() (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.LedgerJournal{}).Count(&count).Error; err != nil {
+		return 0, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to count ledger journals", err)
+	}
+	return count, nil
+}
+
+// GetUnbalancedJournals finds journals whose entries do not sum to zero in some currency
+func (r *ledgerRepository) GetUnbalancedJournals() ([]models.LedgerImbalance, error) {
+	var sums []ledgerSum
+	err := r.db.Model(&models.LedgerEntry{}).
+		Select("journal_id, currency, SUM(amount) AS total").
+		Group("journal_id, currency").
+		Having("SUM(amount) <> 0").
+		Scan(&sums).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to check ledger journals", err)
+	}
+
+	imbalances := make([]models.LedgerImbalance, 0, len(sums))
+	for _, sum := range sums {
+		imbalances = append(imbalances, models.LedgerImbalance{JournalID: sum.JournalID, Amount: models.NewMoney(sum.Total, sum.Currency)})
+	}
+	return imbalances, nil
+}
+
+// GetTotals sums every entry in the ledger per currency
+func (r *ledgerRepository) GetTotals() ([]models.Money, error) {
+	var sums []ledgerSum
+	err := r.db.Model(&models.LedgerEntry{}).
+		Select("currency, SUM(amount) AS total").
+		Group("currency").
+		Order("currency").
+		Scan(&sums).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to total the ledger", err)
+	}
+
+	totals := make([]models.Money, 0, len(sums))
+	for _, sum := range sums {
+		totals = append(totals, models.NewMoney(sum.Total, sum.Currency))
+	}
+	return totals, nil
+}
+
+// GetUnpostedTransactionIDs finds captures, wallet and gift card payments, top-ups, gift card sales, membership
+// fees and refunds that moved money but never reached the ledger
+func (r *ledgerRepository) GetUnpostedTransactionIDs() ([]string, error) {
+	var ids []string
+	err := r.db.Model(&models.PaymentTransaction{}).
+		Where("(type IN ? AND status IN ?) OR (type = ? AND status IN ?) OR (type IN ? AND status = ?)",
+			[]string{models.PaymentTypeCapture, models.PaymentTypeWallet, models.PaymentTypeGiftCard}, []string{models.PaymentStatusCompleted, models.PaymentStatusRefunded, models.PaymentStatusDisputed},
+			models.PaymentTypeRefund, []string{models.PaymentStatusCompleted, models.PaymentStatusFailed},
+			[]string{models.PaymentTypeTopUp, models.PaymentTypeGiftCardSale, models.PaymentTypeMembership}, models.PaymentStatusCompleted).
+		Where("NOT EXISTS (SELECT 1 FROM ledger_journals WHERE ledger_journals.reference = payment_transactions.id AND ledger_journals.type IN ?)",
+			[]models.LedgerJournalType{models.LedgerJournalCapture, models.LedgerJournalRefund, models.LedgerJournalWalletTopUp, models.LedgerJournalGiftCardSale, models.LedgerJournalMembershipFee}).
+		Order("created_at ASC").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to check unposted payments", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type loyaltyRepository struct {
+	db *gorm.DB
+}
+
+func NewLoyaltyRepository() LoyaltyRepository {
+	return &loyaltyRepository{
+		db: database.DB,
+	}
+}
+
+func (r *loyaltyRepository) GetMultiplier(restaurantID string) (*models.LoyaltyMultiplier, error) {
+	var multiplier models.LoyaltyMultiplier
+	err := r.db.Where("restaurant_id = ?", restaurantID).First(&multiplier).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch loyalty multiplier", err)
+	}
+	return &multiplier, nil
+}
+
+// SaveMultiplier creates or replaces the restaurant's bonus points multiplier
+func (r *loyaltyRepository) SaveMultiplier(multiplier *models.LoyaltyMultiplier) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "restaurant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"basis_points", "starts_at", "ends_at", "updated_by", "updated_at"}),
+	}).Create(multiplier).Error
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to save loyalty multiplier", err)
+	}
+	return nil
+}
+
+func (r *loyaltyRepository) GetBalance(userID string) (int64, error) {
+	var balance int64
+	err := r.db.Model(&models.LoyaltyEntry{}).Select("COALESCE(SUM(points), 0)").Where("user_id = ?", userID).Scan(&balance).Error
+	if err != nil {
+		return 0, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch points balance", err)
+	}
+	return balance, nil
+}
+
+func (r *loyaltyRepository) GetEntries(userID string, limit, offset int) ([]models.LoyaltyEntry, error) {
+	var entries []models.LoyaltyEntry
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch points history", err)
+	}
+	return entries, nil
+}
+
+func (r *loyaltyRepository) GetOrderEntry(entryType models.LoyaltyEntryType, orderID string) (*models.LoyaltyEntry, error) {
+	var entry models.LoyaltyEntry
+	err := r.db.Where("type = ? AND order_id = ?", entryType, orderID).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch points entry", err)
+	}
+	return &entry, nil
+}
+
+// GetNextExpiringLot returns the user's open lot that expires first, or nil when none of them expire
+func (r *loyaltyRepository) GetNextExpiringLot(userID string) (*models.LoyaltyEntry, error) {
+	var entry models.LoyaltyEntry
+	err := r.db.Where("user_id = ? AND remaining > 0 AND expires_at IS NOT NULL", userID).Order("expires_at ASC").First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch expiring points", err)
+	}
+	return &entry, nil
+}
+
+// GetLastActivityAt returns when the user last earned or spent points
+func (r *loyaltyRepository) GetLastActivityAt(userID string) (*time.Time, error) {
+	var entry models.LoyaltyEntry
+	err := r.db.Where("user_id = ? AND type IN ?", userID, []models.LoyaltyEntryType{models.LoyaltyEntryEarn, models.LoyaltyEntryRedeem}).
+		Order("created_at DESC").First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch points activity", err)
+	}
+	return &entry.CreatedAt, nil
+}
+
+func (r *loyaltyRepository) SumEarnedSince(userID string, since time.Time) (int64, error) {
+	var earned int64
+	err := r.db.Model(&models.LoyaltyEntry{}).Select("COALESCE(SUM(points), 0)").
+		Where("user_id = ? AND type = ? AND created_at >= ?", userID, models.LoyaltyEntryEarn, since).
+		Scan(&earned).Error
+	if err != nil {
+		return 0, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to sum earned points", err)
+	}
+	return earned, nil
+}
+
+// AddPoints stores a lot of points. An order earns or gets its points back at most once, so a repeated
+// call for the same order is ignored.
+func (r *loyaltyRepository) AddPoints(entry *models.LoyaltyEntry) error {
+	if entry.OrderID != nil {
+		existing, err := r.GetOrderEntry(entry.Type, *entry.OrderID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			*entry = *existing
+			return nil
+		}
+	}
+	entry.Remaining = entry.Points
+	if err := r.db.Create(entry).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to add points", err)
+	}
+	return nil
+}
+
+// SpendPoints takes -entry.Points from the user's open lots, oldest expiry first, and stores the entry in the
+// same database transaction. A 409 is returned if the lots no longer cover it.
+func (r *loyaltyRepository) SpendPoints(entry *models.LoyaltyEntry) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var lots []models.LoyaltyEntry
+		if err := tx.Where("user_id = ? AND remaining > 0 AND (expires_at IS NULL OR expires_at > ?)", entry.UserID, time.Now()).
+			Order("expires_at IS NULL, expires_at ASC, created_at ASC").
+			Find(&lots).Error; err != nil {
+			return err
+		}
+		if err := consumeLots(tx, lots, -entry.Points); err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+	if _, ok := err.(*pkgErrors.HTTPError); ok {
+		return err
+	}
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to spend points", err)
+	}
+	return nil
+}
+
+// consumeLots draws points from the lots in order, failing if they hold fewer than asked for
+func consumeLots(tx *gorm.DB, lots []models.LoyaltyEntry, points int64) error {
+	available := int64(0)
+	for _, lot := range lots {
+		available += lot.Remaining
+	}
+	if available < points {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "Points balance of "+strconv.FormatInt(available, 10)+" does not cover "+strconv.FormatInt(points, 10)+" points", nil)
+	}
+
+	for _, lot := range lots {
+		if points == 0 {
+			break
+		}
+		used := min(lot.Remaining, points)
+		if err := tx.Model(&models.LoyaltyEntry{}).Where("id = ?", lot.ID).Update("remaining", lot.Remaining-used).Error; err != nil {
+			return err
+		}
+		points -= used
+	}
+	return nil
+}
+
+// ExpireLots zeroes the user's open lots that expired by dueBy, or all of them when dueBy is nil, and stores
+// the expire entry for what they held. Nothing is stored when no points expire.
+func (r *loyaltyRepository) ExpireLots(entry *models.LoyaltyEntry, dueBy *time.Time) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("user_id = ? AND remaining > 0", entry.UserID)
+		if dueBy != nil {
+			query = query.Where("expires_at IS NOT NULL AND expires_at <= ?", *dueBy)
+		}
+		var lots []models.LoyaltyEntry
+		if err := query.Find(&lots).Error; err != nil {
+			return err
+		}
+		expired := int64(0)
+		for _, lot := range lots {
+			expired += lot.Remaining
+		}
+		if expired == 0 {
+			return nil
+		}
+		if err := consumeLots(tx, lots, expired); err != nil {
+			return err
+		}
+		entry.Points = -expired
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to expire points", err)
+	}
+	return nil
+}
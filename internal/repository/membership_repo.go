@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type membershipRepository struct {
+	db *gorm.DB
+}
+
+func NewMembershipRepository() MembershipRepository {
+	return &membershipRepository{
+		db: database.DB,
+	}
+}
+
+func (r *membershipRepository) CreatePlan(plan *models.MembershipPlan) error {
+	if err := r.db.Create(plan).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to create membership plan", err)
+	}
+	return nil
+}
+
+func (r *membershipRepository) GetPlanByID(id string) (*models.MembershipPlan, error) {
+	var plan models.MembershipPlan
+	err := r.db.Where("id = ?", id).First(&plan).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Membership plan not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch membership plan", err)
+	}
+	return &plan, nil
+}
+
+// ListPlans returns the plans cheapest first
+func (r *membershipRepository) ListPlans(activeOnly bool) ([]models.MembershipPlan, error) {
+	var plans []models.MembershipPlan
+	query := r.db.Order("price ASC, name ASC")
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	if err := query.Find(&plans).Error; err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch membership plans", err)
+	}
+	return plans, nil
+}
+
+func (r *membershipRepository) UpdatePlan(id string, updates map[string]interface{}) error {
+	result := r.db.Model(&models.MembershipPlan{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update membership plan", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusNotFound, "Membership plan not found", nil)
+	}
+	return nil
+}
+
+func (r *membershipRepository) Create(membership *models.Membership) error {
+	if err := r.db.Create(membership).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to create membership", err)
+	}
+	return nil
+}
+
+func (r *membershipRepository) GetByID(id string) (*models.Membership, error) {
+	var membership models.Membership
+	err := r.db.Preload("Plan").Where("id = ?", id).First(&membership).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Membership not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch membership", err)
+	}
+	return &membership, nil
+}
+
+// GetCurrentByUserID returns the user's trialing, active or past due membership, or nil if they have none
+func (r *membershipRepository) GetCurrentByUserID(userID string) (*models.Membership, error) {
+	var membership models.Membership
+	err := r.db.Preload("Plan").
+		Where("user_id = ? AND status IN ?", userID, []models.MembershipStatus{models.MembershipStatusTrialing, models.MembershipStatusActive, models.MembershipStatusPastDue}).
+		Order("created_at DESC").
+		First(&membership).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch membership", err)
+	}
+	return &membership, nil
+}
+
+// CountByUserID counts every membership the user has ever had, ended or not
+func (r *membershipRepository) CountByUserID(userID string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.Membership{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to count memberships", err)
+	}
+	return count, nil
+}
+
+// GetDue finds memberships whose period has ended and past due memberships whose next retry has come
+func (r *membershipRepository) GetDue(now time.Time) ([]models.Membership, error) {
+	var memberships []models.Membership
+	err := r.db.Preload("Plan").
+		Where("(status IN ? AND current_period_end <= ?) OR (status = ? AND next_retry_at <= ?)",
+			[]models.MembershipStatus{models.MembershipStatusTrialing, models.MembershipStatusActive}, now,
+			models.MembershipStatusPastDue, now).
+		Order("current_period_end ASC").
+		Find(&memberships).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch memberships due for renewal", err)
+	}
+	return memberships, nil
+}
+
+// UpdateIfStatus applies the updates only while the membership is still in the expected status
+func (r *membershipRepository) UpdateIfStatus(id string, status models.MembershipStatus, updates map[string]interface{}) error {
+	result := r.db.Model(&models.Membership{}).Where("id = ? AND status = ?", id, status).Updates(updates)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update membership", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "Membership is no longer "+string(status), nil)
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"dfood/internal/database"
 	"dfood/internal/models"
@@ -42,7 +44,7 @@ func (r *orderRepository) GetByID(id string) (*models.Order, error) {
 
 func (r *orderRepository) GetByUserID(userID string, limit, offset int) ([]models.Order, error) {
 	var orders []models.Order
-	err := r.db.Where("user_id = ?", userID).
+	err := r.db.Where("user_id = ? OR id IN (?)", userID, r.participantOrderIDs(userID)).
 		Order("created_at DESC").
 		Limit(limit).Offset(offset).Find(&orders).Error
 	if err != nil {
@@ -51,6 +53,97 @@ func (r *orderRepository) GetByUserID(userID string, limit, offset int) ([]model
 	return orders, nil
 }
 
+// CountPlacedByUserID counts the orders a user has placed themselves and not cancelled
+func (r *orderRepository) CountPlacedByUserID(userID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Order{}).Where("user_id = ? AND status <> ?", userID, models.OrderStatusCancelled).Count(&count).Error
+	if err != nil {
+		return 0, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to count user orders", err)
+	}
+	return count, nil
+}
+
+func (r *orderRepository) GetByUserIDAndStatuses(userID string, statuses []models.OrderStatus, limit, offset int) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.db.Where("(user_id = ? OR id IN (?)) AND status IN ?", userID, r.participantOrderIDs(userID), statuses).
+		Order("COALESCE(scheduled_for, created_at) DESC").
+		Limit(limit).Offset(offset).Find(&orders).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch user orders", err)
+	}
+	return orders, nil
+}
+
+// Search returns one page of orders matching the filter, continuing after filter.After when set
+func (r *orderRepository) Search(filter models.OrderSearchFilter) ([]models.Order, error) {
+	query := r.db.Model(&models.Order{})
+	if filter.UserID != "" {
+		query = query.Where("(user_id = ? OR id IN (?))", filter.UserID, r.participantOrderIDs(filter.UserID))
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.RestaurantID != "" {
+		query = query.Where("restaurant_id = ?", filter.RestaurantID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.MinTotal != nil {
+		query = query.Where("total >= ?", filter.MinTotal.Amount)
+	}
+	if filter.MaxTotal != nil {
+		query = query.Where("total <= ?", filter.MaxTotal.Amount)
+	}
+	if filter.PaymentMethod != "" {
+		query = query.Where("payment_method = ?", filter.PaymentMethod)
+	}
+	if filter.ItemQuery != "" {
+		// Items are stored as a JSON array, so match on the food names inside it
+		query = query.Where("EXISTS (SELECT 1 FROM json_each(CAST(orders.items AS TEXT)) WHERE LOWER(json_extract(json_each.value, '$.food_name')) LIKE ?)",
+			"%"+strings.ToLower(filter.ItemQuery)+"%")
+	}
+
+	// Keyset pagination: the id breaks ties so no order is skipped or repeated between pages
+	after := filter.After
+	switch filter.Sort {
+	case models.OrderSortOldest:
+		if after != nil {
+			query = query.Where("(created_at > ? OR (created_at = ? AND id > ?))", after.CreatedAt, after.CreatedAt, after.ID)
+		}
+		query = query.Order("created_at ASC").Order("id ASC")
+	case models.OrderSortTotalDesc:
+		if after != nil {
+			query = query.Where("(total < ? OR (total = ? AND id < ?))", after.Total, after.Total, after.ID)
+		}
+		query = query.Order("total DESC").Order("id DESC")
+	case models.OrderSortTotalAsc:
+		if after != nil {
+			query = query.Where("(total > ? OR (total = ? AND id > ?))", after.Total, after.Total, after.ID)
+		}
+		query = query.Order("total ASC").Order("id ASC")
+	default:
+		if after != nil {
+			query = query.Where("(created_at < ? OR (created_at = ? AND id < ?))", after.CreatedAt, after.CreatedAt, after.ID)
+		}
+		query = query.Order("created_at DESC").Order("id DESC")
+	}
+
+	var orders []models.Order
+	if err := query.Limit(filter.Limit).Find(&orders).Error; err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to search orders", err)
+	}
+	return orders, nil
+}
+
+// participantOrderIDs selects the group orders the user took part in without hosting them
+func (r *orderRepository) participantOrderIDs(userID string) *gorm.DB {
+	return r.db.Model(&models.GroupOrderParticipant{}).Select("order_id").Where("user_id = ? AND order_id IS NOT NULL", userID)
+}
+
 func (r *orderRepository) UpdateStatus(id string, status models.OrderStatus) error {
 	err := r.db.Model(&models.Order{}).Where("id = ?", id).Update("status", status).Error
 	if err != nil {
@@ -59,6 +152,61 @@ func (r *orderRepository) UpdateStatus(id string, status models.OrderStatus) err
 	return nil
 }
 
+func (r *orderRepository) Update(id string, updates map[string]interface{}) error {
+	err := r.db.Model(&models.Order{}).Where("id = ?", id).Updates(updates).Error
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update order", err)
+	}
+	return nil
+}
+
+// UpdateIfStatus applies the updates only while the order is still in the given status
+func (r *orderRepository) UpdateIfStatus(id string, status models.OrderStatus, updates map[string]interface{}) error {
+	result := r.db.Model(&models.Order{}).Where("id = ? AND status = ?", id, status).Updates(updates)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update order", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "Order status has changed, please try again", nil)
+	}
+	return nil
+}
+
+// ReleaseScheduled moves scheduled orders whose release time has passed into the kitchen queue
+func (r *orderRepository) ReleaseScheduled(now time.Time) (int64, error) {
+	result := r.db.Model(&models.Order{}).
+		Where("status = ? AND release_at <= ?", models.OrderStatusScheduled, now).
+		Update("status", models.OrderStatusPending)
+	if result.Error != nil {
+		return 0, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to release scheduled orders", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// ClaimPayment marks the order as having a payment in progress. It fails while another payment holds the
+// claim, unless that claim was taken before staleBefore and so was abandoned.
+func (r *orderRepository) ClaimPayment(id string, now, staleBefore time.Time) error {
+	result := r.db.Model(&models.Order{}).
+		Where("id = ? AND (payment_claimed_at IS NULL OR payment_claimed_at < ?)", id, staleBefore).
+		Update("payment_claimed_at", now)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update order", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "A payment for this order is already in progress, please try again", nil)
+	}
+	return nil
+}
+
+// ReleasePayment ends the claim taken by ClaimPayment
+func (r *orderRepository) ReleasePayment(id string) error {
+	err := r.db.Model(&models.Order{}).Where("id = ?", id).Update("payment_claimed_at", nil).Error
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update order", err)
+	}
+	return nil
+}
+
 func (r *orderRepository) Delete(id string) error {
 	err := r.db.Where("id = ?", id).Delete(&models.Order{}).Error
 	if err != nil {
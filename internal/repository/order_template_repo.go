@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type orderTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderTemplateRepository() OrderTemplateRepository {
+	return &orderTemplateRepository{
+		db: database.DB,
+	}
+}
+
+func (r *orderTemplateRepository) Create(template *models.OrderTemplate) error {
+	if err := r.db.Create(template).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to create order template", err)
+	}
+	return nil
+}
+
+func (r *orderTemplateRepository) GetByID(id string) (*models.OrderTemplate, error) {
+	var template models.OrderTemplate
+	err := r.db.Where("id = ?", id).First(&template).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Order template not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch order template", err)
+	}
+	return &template, nil
+}
+
+func (r *orderTemplateRepository) GetByUserID(userID string) ([]models.OrderTemplate, error) {
+	var templates []models.OrderTemplate
+	err := r.db.Where("user_id = ?", userID).Order("name ASC").Find(&templates).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch order templates", err)
+	}
+	return templates, nil
+}
+
+func (r *orderTemplateRepository) MarkPlaced(id string, at time.Time) error {
+	err := r.db.Model(&models.OrderTemplate{}).Where("id = ?", id).Update("last_placed_at", at).Error
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update order template", err)
+	}
+	return nil
+}
+
+func (r *orderTemplateRepository) Delete(id string) error {
+	result := r.db.Where("id = ?", id).Delete(&models.OrderTemplate{})
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to delete order template", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusNotFound, "Order template not found", nil)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type paymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository() PaymentRepository {
+	return &paymentRepository{
+		db: database.DB,
+	}
+}
+
+func (r *paymentRepository) GetPaymentMethods() ([]models.PaymentMethod, error) {
+	var methods []models.PaymentMethod
+	if err := r.db.Order("name ASC").Find(&methods).Error; err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch payment methods", err)
+	}
+	return methods, nil
+}
+
+func (r *paymentRepository) GetUserCards(userID string) ([]models.Card, error) {
+	var cards []models.Card
+	err := r.db.Where("user_id = ?", userID).Order("is_default DESC, created_at DESC").Find(&cards).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch user cards", err)
+	}
+	return cards, nil
+}
+
+func (r *paymentRepository) GetCardByID(id string) (*models.Card, error) {
+	var card models.Card
+	err := r.db.Where("id = ?", id).First(&card).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Card not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch card", err)
+	}
+	return &card, nil
+}
+
+// GetCardByFingerprint returns the user's card with the fingerprint, or nil when there is none
+func (r *paymentRepository) GetCardByFingerprint(userID, fingerprint string) (*models.Card, error) {
+	var card models.Card
+	err := r.db.Where("user_id = ? AND fingerprint = ?", userID, fingerprint).First(&card).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch card", err)
+	}
+	return &card, nil
+}
+
+func (r *paymentRepository) CreateCard(card *models.Card) error {
+	if err := r.db.Omit("User", "PaymentMethod").Create(card).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to save card", err)
+	}
+	return nil
+}
+
+// SetDefaultCard makes the card the user's only default card
+func (r *paymentRepository) SetDefaultCard(userID, cardID string) error {
+	err := r.db.Model(&models.Card{}).Where("user_id = ?", userID).
+		Update("is_default", gorm.Expr("id = ?", cardID)).Error
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update default card", err)
+	}
+	return nil
+}
+
+func (r *paymentRepository) DeleteCard(id string) error {
+	result := r.db.Where("id = ?", id).Delete(&models.Card{})
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to delete card", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusNotFound, "Card not found", nil)
+	}
+	return nil
+}
+
+func (r *paymentRepository) CreateTransaction(transaction *models.PaymentTransaction) error {
+	if err := r.db.Omit("Order", "User").Create(transaction).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to create payment transaction", err)
+	}
+	return nil
+}
+
+func (r *paymentRepository) GetTransactionByID(id string) (*models.PaymentTransaction, error) {
+	var transaction models.PaymentTransaction
+	err := r.db.Where("id = ?", id).First(&transaction).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Payment transaction not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch payment transaction", err)
+	}
+	return &transaction, nil
+}
+
+// GetTransactionByGatewayID finds a transaction by the payment gateway's reference for it
+func (r *paymentRepository) GetTransactionByGatewayID(gatewayID string) (*models.PaymentTransaction, error) {
+	var transaction models.PaymentTransaction
+	err := r.db.Where("transaction_id = ?", gatewayID).First(&transaction).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Payment transaction not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch payment transaction", err)
+	}
+	return &transaction, nil
+}
+
+func (r *paymentRepository) GetTransactionsByOrderID(orderID string) ([]models.PaymentTransaction, error) {
+	var transactions []models.PaymentTransaction
+	err := r.db.Where("order_id = ?", orderID).Order("created_at ASC").Find(&transactions).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch order transactions", err)
+	}
+	return transactions, nil
+}
+
+func (r *paymentRepository) UpdateTransaction(id string, updates map[string]interface{}) error {
+	err := r.db.Model(&models.PaymentTransaction{}).Where("id = ?", id).Updates(updates).Error
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update payment transaction", err)
+	}
+	return nil
+}
+
+// UpdateTransactionIfStatus applies the updates only while the transaction still has the given status
+func (r *paymentRepository) UpdateTransactionIfStatus(id, status string, updates map[string]interface{}) error {
+	result := r.db.Model(&models.PaymentTransaction{}).Where("id = ? AND status = ?", id, status).Updates(updates)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update payment transaction", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "Payment transaction status has changed", nil)
+	}
+	return nil
+}
+
+// ClaimRefund adds amount to a completed transaction's refunded amount, only while the total refunded
+// stays within what was charged, and marks the transaction refunded once nothing is left.
+// Concurrent refunds each claim their share first, so together they can never exceed the charge.
+func (r *paymentRepository) ClaimRefund(id string, amount models.Money) error {
+	result := r.db.Model(&models.PaymentTransaction{}).
+		Where("id = ? AND status = ? AND refunded_amount + ? <= amount", id, models.PaymentStatusCompleted, amount.Amount).
+		Updates(map[string]interface{}{
+			"status":          gorm.Expr("CASE WHEN refunded_amount + ? >= amount THEN ? ELSE status END", amount.Amount, models.PaymentStatusRefunded),
+			"refunded_amount": gorm.Expr("refunded_amount + ?", amount.Amount),
+		})
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update payment transaction", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "Refund exceeds the amount left to refund", nil)
+	}
+	return nil
+}
+
+// ReleaseRefund gives back amount claimed by ClaimRefund for a refund that did not go through,
+// reopening a transaction that the claim marked refunded
+func (r *paymentRepository) ReleaseRefund(id string, amount models.Money) error {
+	err := r.db.Model(&models.PaymentTransaction{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":          gorm.Expr("CASE WHEN status = ? AND refunded_amount - ? < amount THEN ? ELSE status END", models.PaymentStatusRefunded, amount.Amount, models.PaymentStatusCompleted),
+			"refunded_amount": gorm.Expr("MAX(refunded_amount - ?, 0)", amount.Amount),
+		}).Error
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update payment transaction", err)
+	}
+	return nil
+}
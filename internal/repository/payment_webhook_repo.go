@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type paymentWebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentWebhookRepository() PaymentWebhookRepository {
+	return &paymentWebhookRepository{
+		db: database.DB,
+	}
+}
+
+// CreateIfAbsent stores the event unless the provider's event ID is already stored, reporting whether it was new
+func (r *paymentWebhookRepository) CreateIfAbsent(event *models.PaymentWebhookEvent) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}, {Name: "event_id"}},
+		DoNothing: true,
+	}).Create(event)
+	if result.Error != nil {
+		return false, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to store webhook event", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *paymentWebhookRepository) GetByEventID(provider, eventID string) (*models.PaymentWebhookEvent, error) {
+	var event models.PaymentWebhookEvent
+	err := r.db.Where("provider = ? AND event_id = ?", provider, eventID).First(&event).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Webhook event not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch webhook event", err)
+	}
+	return &event, nil
+}
+
+func (r *paymentWebhookRepository) Update(id string, updates map[string]interface{}) error {
+	err := r.db.Model(&models.PaymentWebhookEvent{}).Where("id = ?", id).Updates(updates).Error
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update webhook event", err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type payoutRepository struct {
+	db *gorm.DB
+}
+
+func NewPayoutRepository() PayoutRepository {
+	return &payoutRepository{
+		db: database.DB,
+	}
+}
+
+// settledOrderStatuses are the order statuses whose takings can be paid out
+var settledOrderStatuses = []models.OrderStatus{models.OrderStatusDelivered, models.OrderStatusCancelled}
+
+// GetCommissionRule returns the restaurant's commission rule, or nil when it pays the default
+func (r *payoutRepository) GetCommissionRule(restaurantID string) (*models.CommissionRule, error) {
+	var rule models.CommissionRule
+	err := r.db.Where("restaurant_id = ?", restaurantID).First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch commission rule", err)
+	}
+	return &rule, nil
+}
+
+// SaveCommissionRule creates or replaces the restaurant's commission rule
+func (r *payoutRepository) SaveCommissionRule(rule *models.CommissionRule) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "restaurant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"basis_points", "fixed_fee", "currency", "updated_by", "updated_at"}),
+	}).Create(rule).Error
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to save commission rule", err)
+	}
+	return nil
+}
+
+// unsettledOrders selects delivered and cancelled orders finished before the cutoff that took
+// a payment and have not been on a payout yet
+func (r *payoutRepository) unsettledOrders(before time.Time) *gorm.DB {
+	return r.db.Model(&models.Order{}).
+		Where("status IN ? AND COALESCE(delivered_at, cancelled_at, updated_at) < ?", settledOrderStatuses, before).
+		Where("NOT EXISTS (SELECT 1 FROM payout_lines WHERE payout_lines.kind = ? AND payout_lines.reference = orders.id)", models.PayoutLineOrder).
+		Where("EXISTS (SELECT 1 FROM ledger_journals WHERE ledger_journals.order_id = orders.id AND ledger_journals.type = ?)", models.LedgerJournalCapture)
+}
+
+// unsettledRefunds selects completed refunds made before the cutoff on finished orders
+// that have not been on a payout yet
+func (r *payoutRepository) unsettledRefunds(before time.Time) *gorm.DB {
+	return r.db.Model(&models.PaymentTransaction{}).
+		Joins("JOIN orders ON orders.id = payment_transactions.order_id").
+		Where("payment_transactions.type = ? AND payment_transactions.status = ? AND payment_transactions.created_at < ?",
+			models.PaymentTypeRefund, models.PaymentStatusCompleted, before).
+		Where("orders.status IN ?", settledOrderStatuses).
+		Where("NOT EXISTS (SELECT 1 FROM payout_lines WHERE payout_lines.kind = ? AND payout_lines.reference = payment_transactions.id)", models.PayoutLineRefund)
+}
+
+// GetUnsettledRestaurantIDs lists the restaurants with orders or refunds waiting to be settled
+func (r *payoutRepository) GetUnsettledRestaurantIDs(before time.Time) ([]string, error) {
+	var fromOrders, fromRefunds []string
+	if err := r.unsettledOrders(before).Distinct().Pluck("restaurant_id", &fromOrders).Error; err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to find restaurants to settle", err)
+	}
+	if err := r.unsettledRefunds(before).Distinct().Pluck("orders.restaurant_id", &fromRefunds).Error; err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to find restaurants to settle", err)
+	}
+
+	seen := make(map[string]bool, len(fromOrders)+len(fromRefunds))
+	ids := make([]string, 0, len(fromOrders)+len(fromRefunds))
+	for _, id := range append(fromOrders, fromRefunds...) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *payoutRepository) GetUnsettledOrders(restaurantID string, before time.Time) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.unsettledOrders(before).Where("restaurant_id = ?", restaurantID).Order("created_at ASC").Find(&orders).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch orders to settle", err)
+	}
+	return orders, nil
+}
+
+func (r *payoutRepository) GetUnsettledRefunds(restaurantID string, before time.Time) ([]models.PaymentTransaction, error) {
+	var refunds []models.PaymentTransaction
+	err := r.unsettledRefunds(before).
+		Where("orders.restaurant_id = ?", restaurantID).
+		Order("payment_transactions.created_at ASC").
+		Find(&refunds).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch refunds to settle", err)
+	}
+	return refunds, nil
+}
+
+// GetOrdersWithUnsettledTips returns delivered orders with a courier, finished before the cutoff, that have
+// a capture whose tip has not been settled to the courier yet. An empty restaurant ID matches every restaurant.
+func (r *payoutRepository) GetOrdersWithUnsettledTips(restaurantID string, before time.Time) ([]models.Order, error) {
+	query := r.db.Model(&models.Order{}).
+		Where("status = ? AND delivered_at < ? AND courier_id IS NOT NULL AND courier_id <> ''", models.OrderStatusDelivered, before).
+		Where(`EXISTS (SELECT 1 FROM ledger_journals captures JOIN ledger_entries ON ledger_entries.journal_id = captures.id
+			WHERE captures.order_id = orders.id AND captures.type = ? AND ledger_entries.account_type = ?
+			AND NOT EXISTS (SELECT 1 FROM ledger_journals tips WHERE tips.type = ? AND tips.reference = captures.reference))`,
+			models.LedgerJournalCapture, models.LedgerAccountTipsPayable, models.LedgerJournalCourierTip)
+	if restaurantID != "" {
+		query = query.Where("restaurant_id = ?", restaurantID)
+	}
+
+	var orders []models.Order
+	if err := query.Order("delivered_at ASC").Find(&orders).Error; err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch tips to settle", err)
+	}
+	return orders, nil
+}
+
+// Create stores a payout together with its lines, which gorm writes in one database transaction. The unique
+// index on lines makes a concurrent run that tries to settle the same order or refund fail instead of paying twice.
+func (r *payoutRepository) Create(payout *models.RestaurantPayout) error {
+	if err := r.db.Create(payout).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to create payout", err)
+	}
+	return nil
+}
+
+func (r *payoutRepository) GetByID(id string) (*models.RestaurantPayout, error) {
+	var payout models.RestaurantPayout
+	err := r.db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("occurred_at ASC, id ASC")
+	}).Where("id = ?", id).First(&payout).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Payout not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch payout", err)
+	}
+	return &payout, nil
+}
+
+// GetByRestaurantID returns a restaurant's payouts without their lines, newest first
+func (r *payoutRepository) GetByRestaurantID(restaurantID string, limit, offset int) ([]models.RestaurantPayout, error) {
+	var payouts []models.RestaurantPayout
+	err := r.db.Where("restaurant_id = ?", restaurantID).
+		Order("period_end DESC, created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&payouts).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch payouts", err)
+	}
+	return payouts, nil
+}
+
+// UpdateIfStatus applies the updates only while the payout is still in the given status
+func (r *payoutRepository) UpdateIfStatus(id string, status models.PayoutStatus, updates map[string]interface{}) error {
+	result := r.db.Model(&models.RestaurantPayout{}).Where("id = ? AND status = ?", id, status).Updates(updates)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update payout", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "Payout status has changed, please try again", nil)
+	}
+	return nil
+}
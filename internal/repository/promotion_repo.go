@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type promotionRepository struct {
+	db *gorm.DB
+}
+
+func NewPromotionRepository() PromotionRepository {
+	return &promotionRepository{
+		db: database.DB,
+	}
+}
+
+func (r *promotionRepository) Create(promotion *models.Promotion) error {
+	var count int64
+	if err := r.db.Model(&models.Promotion{}).Where("code = ?", promotion.Code).Count(&count).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to check promo code", err)
+	}
+	if count > 0 {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "Promo code already exists", nil)
+	}
+	if err := r.db.Create(promotion).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to create promotion", err)
+	}
+	return nil
+}
+
+func (r *promotionRepository) GetByID(id string) (*models.Promotion, error) {
+	var promotion models.Promotion
+	err := r.db.Where("id = ?", id).First(&promotion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Promotion not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch promotion", err)
+	}
+	return &promotion, nil
+}
+
+// GetByCode looks a promotion up by its code, ignoring case. Unknown codes are reported as invalid
+// rather than not found so a customer cannot tell a mistyped code from a retired one.
+func (r *promotionRepository) GetByCode(code string) (*models.Promotion, error) {
+	var promotion models.Promotion
+	err := r.db.Where("code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&promotion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusBadRequest, "Invalid promo code", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch promotion", err)
+	}
+	return &promotion, nil
+}
+
+func (r *promotionRepository) List(activeOnly bool, limit, offset int) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	query := r.db.Order("created_at DESC")
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	if err := query.Limit(limit).Offset(offset).Find(&promotions).Error; err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch promotions", err)
+	}
+	return promotions, nil
+}
+
+func (r *promotionRepository) Update(id string, updates map[string]interface{}) error {
+	result := r.db.Model(&models.Promotion{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update promotion", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusNotFound, "Promotion not found", nil)
+	}
+	return nil
+}
+
+func (r *promotionRepository) CountUserRedemptions(promotionID, userID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.PromotionRedemption{}).Where("promotion_id = ? AND user_id = ?", promotionID, userID).Count(&count).Error
+	if err != nil {
+		return 0, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to count promo code uses", err)
+	}
+	return count, nil
+}
+
+// Redeem records the uses of an order's promotions in one database transaction. Each promotion's counter is
+// only raised while it is under its global limit and the user under theirs, so two orders racing for the
+// last use cannot both get it; if any promotion is used up, none of the order's uses are recorded.
+func (r *promotionRepository) Redeem(redemptions []models.PromotionRedemption) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range redemptions {
+			redemption := &redemptions[i]
+			var promotion models.Promotion
+			if err := tx.Where("id = ?", redemption.PromotionID).First(&promotion).Error; err != nil {
+				return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch promotion", err)
+			}
+
+			if promotion.MaxUsesPerUser > 0 {
+				var used int64
+				if err := tx.Model(&models.PromotionRedemption{}).
+					Where("promotion_id = ? AND user_id = ?", promotion.ID, redemption.UserID).
+					Count(&used).Error; err != nil {
+					return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to count promo code uses", err)
+				}
+				if used >= int64(promotion.MaxUsesPerUser) {
+					return pkgErrors.NewHTTPError(http.StatusConflict, "You have already used promo code "+promotion.Code, nil)
+				}
+			}
+
+			result := tx.Model(&models.Promotion{}).
+				Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", promotion.ID).
+				Update("used_count", gorm.Expr("used_count + 1"))
+			if result.Error != nil {
+				return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to record promo code use", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return pkgErrors.NewHTTPError(http.StatusConflict, "Promo code "+promotion.Code+" has reached its usage limit", nil)
+			}
+
+			if err := tx.Create(redemption).Error; err != nil {
+				return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to record promo code use", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ReleaseByOrderID gives back the promotion uses of an order that did not go ahead
+func (r *promotionRepository) ReleaseByOrderID(orderID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var redemptions []models.PromotionRedemption
+		if err := tx.Where("order_id = ?", orderID).Find(&redemptions).Error; err != nil {
+			return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch promo code uses", err)
+		}
+		for _, redemption := range redemptions {
+			if err := tx.Model(&models.Promotion{}).
+				Where("id = ? AND used_count > 0", redemption.PromotionID).
+				Update("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+				return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to release promo code use", err)
+			}
+		}
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.PromotionRedemption{}).Error; err != nil {
+			return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to release promo code use", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type orderQuoteRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderQuoteRepository() OrderQuoteRepository {
+	return &orderQuoteRepository{
+		db: database.DB,
+	}
+}
+
+func (r *orderQuoteRepository) Create(quote *models.OrderQuote) error {
+	if err := r.db.Create(quote).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to create order quote", err)
+	}
+	return nil
+}
+
+func (r *orderQuoteRepository) GetByID(id string) (*models.OrderQuote, error) {
+	var quote models.OrderQuote
+	err := r.db.Where("id = ?", id).First(&quote).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "Order quote not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch order quote", err)
+	}
+	return &quote, nil
+}
+
+func (r *orderQuoteRepository) MarkUsed(id, orderID string) error {
+	result := r.db.Model(&models.OrderQuote{}).Where("id = ? AND order_id IS NULL", id).Update("order_id", orderID)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to mark order quote as used", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "Order quote has already been used", nil)
+	}
+	return nil
+}
+
+// Release clears a quote's use by an order that was never stored
+func (r *orderQuoteRepository) Release(id, orderID string) error {
+	err := r.db.Model(&models.OrderQuote{}).Where("id = ? AND order_id = ?", id, orderID).Update("order_id", nil).Error
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to release order quote", err)
+	}
+	return nil
+}
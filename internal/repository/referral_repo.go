@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type referralRepository struct {
+	db *gorm.DB
+}
+
+func NewReferralRepository() ReferralRepository {
+	return &referralRepository{
+		db: database.DB,
+	}
+}
+
+func (r *referralRepository) Create(referral *models.Referral) error {
+	if err := r.db.Create(referral).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to record referral", err)
+	}
+	return nil
+}
+
+// GetByRefereeID returns the referral the user registered with, or nil if they were not referred
+func (r *referralRepository) GetByRefereeID(refereeID string) (*models.Referral, error) {
+	var referral models.Referral
+	err := r.db.Where("referee_id = ?", refereeID).First(&referral).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch referral", err)
+	}
+	return &referral, nil
+}
+
+func (r *referralRepository) GetByReferrerID(referrerID string) ([]models.Referral, error) {
+	var referrals []models.Referral
+	err := r.db.Where("referrer_id = ?", referrerID).Order("created_at DESC").Find(&referrals).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch referrals", err)
+	}
+	return referrals, nil
+}
+
+func (r *referralRepository) CountByReferrerID(referrerID string, status models.ReferralStatus) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Referral{}).Where("referrer_id = ? AND status = ?", referrerID, status).Count(&count).Error
+	if err != nil {
+		return 0, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to count referrals", err)
+	}
+	return count, nil
+}
+
+// UpdateIfStatus applies the updates only while the referral is still in the expected status
+func (r *referralRepository) UpdateIfStatus(id string, status models.ReferralStatus, updates map[string]interface{}) error {
+	result := r.db.Model(&models.Referral{}).Where("id = ? AND status = ?", id, status).Updates(updates)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update referral", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusConflict, "Referral is no longer "+string(status), nil)
+	}
+	return nil
+}
+
+// GetReportRows counts the referrals registered in the period by referrer, status and rejection reason
+func (r *referralRepository) GetReportRows(from, to *time.Time) ([]models.ReferralReportRow, error) {
+	query := r.db.Model(&models.Referral{}).
+		Select("referrer_id, status, COALESCE(rejection_reason, '') AS rejection_reason, currency, COUNT(*) AS referrals, " +
+			"COALESCE(SUM(referrer_credit + referee_credit), 0) AS credits_paid")
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at < ?", *to)
+	}
+
+	var rows []models.ReferralReportRow
+	err := query.Group("referrer_id, status, rejection_reason, currency").Order("referrer_id").Scan(&rows).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to build referral report", err)
+	}
+	return rows, nil
+}
@@ -95,3 +95,87 @@ func (r *restaurantRepository) GetByCategory(category string, limit, offset int)
 	}
 	return restaurants, nil
 }
+
+func (r *restaurantRepository) Update(id string, updates map[string]interface{}) error {
+	result := r.db.Model(&models.Restaurant{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to update restaurant", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusNotFound, "Restaurant not found", nil)
+	}
+	return nil
+}
+
+func (r *restaurantRepository) GetOpeningHours(restaurantIDs ...string) ([]models.OpeningHours, error) {
+	var hours []models.OpeningHours
+	err := r.db.Where("restaurant_id IN ?", restaurantIDs).Order("day_of_week, opens_at").Find(&hours).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch opening hours", err)
+	}
+	return hours, nil
+}
+
+// ReplaceOpeningHours swaps the restaurant's whole weekly schedule and time zone in one transaction
+func (r *restaurantRepository) ReplaceOpeningHours(restaurantID string, timeZone string, hours []models.OpeningHours) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Restaurant{}).Where("id = ?", restaurantID).Update("time_zone", timeZone)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("restaurant_id = ?", restaurantID).Delete(&models.OpeningHours{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		return tx.Create(&hours).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pkgErrors.NewHTTPError(http.StatusNotFound, "Restaurant not found", err)
+	}
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to save opening hours", err)
+	}
+	return nil
+}
+
+// GetHolidayHours returns the overrides dated between from and to inclusive, both YYYY-MM-DD
+func (r *restaurantRepository) GetHolidayHours(from, to string, restaurantIDs ...string) ([]models.HolidayHours, error) {
+	var hours []models.HolidayHours
+	err := r.db.Where("restaurant_id IN ? AND date >= ? AND date <= ?", restaurantIDs, from, to).
+		Order("date, opens_at").
+		Find(&hours).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch holiday hours", err)
+	}
+	return hours, nil
+}
+
+// ReplaceHolidayHours swaps every override of the restaurant on the date in one transaction
+func (r *restaurantRepository) ReplaceHolidayHours(restaurantID, date string, hours []models.HolidayHours) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("restaurant_id = ? AND date = ?", restaurantID, date).Delete(&models.HolidayHours{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&hours).Error
+	})
+	if err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to save holiday hours", err)
+	}
+	return nil
+}
+
+func (r *restaurantRepository) DeleteHolidayHours(restaurantID, date string) error {
+	result := r.db.Where("restaurant_id = ? AND date = ?", restaurantID, date).Delete(&models.HolidayHours{})
+	if result.Error != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to delete holiday hours", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return pkgErrors.NewHTTPError(http.StatusNotFound, "No holiday hours on that date", nil)
+	}
+	return nil
+}
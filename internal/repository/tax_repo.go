@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"net/http"
+	"time"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	pkgErrors "dfood/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+type taxRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewTaxRuleRepository() TaxRuleRepository {
+	return &taxRuleRepository{
+		db: database.DB,
+	}
+}
+
+func (r *taxRuleRepository) GetApplicable(jurisdiction models.TaxJurisdiction, at time.Time) ([]models.TaxRule, error) {
+	var rules []models.TaxRule
+	query := r.db.Where("is_active = ?", true).
+		Where("(state = '' OR state IS NULL OR state = ?)", jurisdiction.State).
+		Where("(zip_code = '' OR zip_code IS NULL OR zip_code = ?)", jurisdiction.ZipCode).
+		Where("(valid_from IS NULL OR valid_from <= ?)", at).
+		Where("(valid_until IS NULL OR valid_until > ?)", at)
+	if jurisdiction.Country != "" {
+		query = query.Where("(country = '' OR country IS NULL OR country = ?)", jurisdiction.Country)
+	}
+	err := query.Order("name ASC").Find(&rules).Error
+	if err != nil {
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch tax rules", err)
+	}
+	return rules, nil
+}
+
+func (r *taxRuleRepository) Create(rule *models.TaxRule) error {
+	if err := r.db.Create(rule).Error; err != nil {
+		return pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to create tax rule", err)
+	}
+	return nil
+}
@@ -92,3 +92,34 @@ func (r *userRepository) UpdateFCMToken(id, token string) error {
 	}
 	return nil
 }
+
+func (r *userRepository) GetByReferralCode(code string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("referral_code = ?", code).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, pkgErrors.NewHTTPError(http.StatusNotFound, "User not found", err)
+		}
+		return nil, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to fetch user by referral code", err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) CountOthersByDeviceID(deviceID, excludeUserID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("device_id = ? AND id <> ?", deviceID, excludeUserID).Count(&count).Error
+	if err != nil {
+		return 0, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to count users by device", err)
+	}
+	return count, nil
+}
+
+// CountOthersByPhoneNumber matches on the phone number's blind index, as the number itself is encrypted
+func (r *userRepository) CountOthersByPhoneNumber(phoneNumberIndex, excludeUserID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("phone_number_index = ? AND id <> ?", phoneNumberIndex, excludeUserID).Count(&count).Error
+	if err != nil {
+		return 0, pkgErrors.NewHTTPError(http.StatusInternalServerError, "Failed to count users by phone number", err)
+	}
+	return count, nil
+}
@@ -2,12 +2,14 @@ package service
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"dfood/internal/models"
 	"dfood/internal/repository"
 	"dfood/internal/utils"
 	"dfood/pkg/errors"
+	"dfood/pkg/logger"
 )
 
 type AuthService interface {
@@ -19,12 +21,14 @@ type AuthService interface {
 }
 
 type authService struct {
-	userRepo repository.UserRepository
+	userRepo        repository.UserRepository
+	referralService ReferralService
 }
 
-func NewAuthService(userRepo repository.UserRepository) AuthService {
+func NewAuthService(userRepo repository.UserRepository, referralService ReferralService) AuthService {
 	return &authService{
-		userRepo: userRepo,
+		userRepo:        userRepo,
+		referralService: referralService,
 	}
 }
 
@@ -69,6 +73,14 @@ func (s *authService) Register(user *models.User) error {
 		return errors.NewHTTPError(http.StatusConflict, "User already exists", nil)
 	}
 
+	// Resolve the referral code first so a mistyped code is reported before the account exists
+	var referrer *models.User
+	if strings.TrimSpace(user.ReferredByCode) != "" {
+		if referrer, err = s.referralService.ResolveReferrer(user.ReferredByCode); err != nil {
+			return err
+		}
+	}
+
 	// Generate ID if not provided
 	if user.ID == "" {
 		user.ID = utils.GenerateID()
@@ -84,13 +96,29 @@ func (s *authService) Register(user *models.User) error {
 	// Set default values
 	user.FirstTimeLogin = true
 	user.EmailVerified = false
+	user.Role = models.RoleCustomer // Elevated roles are only granted by an admin
+	referralCode := utils.GenerateInviteCode()
+	user.ReferralCode = &referralCode
+	if user.DeviceID != nil && strings.TrimSpace(*user.DeviceID) == "" {
+		user.DeviceID = nil
+	}
 
 	// Set timestamps
 	now := time.Now()
 	user.CreatedAt = now
 	user.UpdatedAt = now
 
-	return s.userRepo.Create(user)
+	if err := s.userRepo.Create(user); err != nil {
+		return err
+	}
+
+	// The account stands even if the referral cannot be recorded
+	if referrer != nil {
+		if _, err := s.referralService.RecordReferral(user, referrer); err != nil {
+			logger.Error("Failed to record referral", "user_id", user.ID, "referrer_id", referrer.ID, "error", err)
+		}
+	}
+	return nil
 }
 
 func (s *authService) Login(email, password string) (*models.User, error) {
@@ -106,11 +134,11 @@ func (s *authService) Login(email, password string) (*models.User, error) {
 
 	user.Password = ""
 	// Generate JWT token
-	accessToken, err := utils.GenerateJwtToken(user.Email, false)
+	accessToken, err := utils.GenerateJwtToken(user.Email, string(user.Role), false)
 	if err != nil {
 		return nil, errors.NewHTTPError(http.StatusInternalServerError, "Failed to generate access token", err)
 	}
-	refreshToken, err := utils.GenerateJwtToken(user.Email, true)
+	refreshToken, err := utils.GenerateJwtToken(user.Email, string(user.Role), true)
 	if err != nil {
 		return nil, errors.NewHTTPError(http.StatusInternalServerError, "Failed to generate refresh token", err)
 	}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"net/http"
+
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/pkg/errors"
+)
+
+// callerUser loads the user behind the caller's bearer token
+func callerUser(userRepo repository.UserRepository, viewer models.OrderViewer) (*models.User, error) {
+	user, err := userRepo.GetByEmail(viewer.Email)
+	if err != nil {
+		return nil, errors.NewHTTPError(http.StatusForbidden, "User not found for token", err)
+	}
+	return user, nil
+}
+
+// authorizeUser checks that the caller is the user a request is made for and returns that user. The user ID
+// from the path is only compared against the token, never trusted on its own.
+func authorizeUser(userRepo repository.UserRepository, viewer models.OrderViewer, userID string) (*models.User, error) {
+	user, err := callerUser(userRepo, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if userID != "" && userID != user.ID {
+		return nil, errors.NewHTTPError(http.StatusForbidden, "Users can only act on their own account", nil)
+	}
+	return user, nil
+}
+
+// authorizeRestaurantOwner checks that the caller owns the restaurant. Support and admins may act for any restaurant.
+func authorizeRestaurantOwner(userRepo repository.UserRepository, restaurantRepo repository.RestaurantRepository, viewer models.OrderViewer, restaurantID string) error {
+	if viewer.IsStaff() {
+		return nil
+	}
+	user, err := callerUser(userRepo, viewer)
+	if err != nil {
+		return err
+	}
+	restaurant, err := restaurantRepo.GetByID(restaurantID)
+	if err != nil {
+		return err
+	}
+	if restaurant.OwnerID == nil || *restaurant.OwnerID != user.ID {
+		return errors.NewHTTPError(http.StatusForbidden, "Only the restaurant's owner can manage its orders", nil)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/pkg/errors"
+)
+
+type DeliveryService interface {
+	QuoteDelivery(restaurant *models.Restaurant, address *models.Address, subtotal models.Money) (*models.DeliveryQuote, error)
+}
+
+type deliveryService struct {
+	deliveryRepo repository.DeliveryRepository
+}
+
+func NewDeliveryService(deliveryRepo repository.DeliveryRepository) DeliveryService {
+	return &deliveryService{
+		deliveryRepo: deliveryRepo,
+	}
+}
+
+func (s *deliveryService) QuoteDelivery(restaurant *models.Restaurant, address *models.Address, subtotal models.Money) (*models.DeliveryQuote, error) {
+	if restaurant == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Restaurant is required", nil)
+	}
+
+	currency := subtotal.Currency
+	restaurant.DeliveryFee.Currency = currency
+	restaurant.MinimumOrder.Currency = currency
+	restaurant.SmallOrderThreshold.Currency = currency
+	restaurant.SmallOrderSurcharge.Currency = currency
+
+	// Enforce the restaurant's minimum order value
+	if restaurant.MinimumOrder.IsPositive() && subtotal.Cmp(restaurant.MinimumOrder) < 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Minimum order for %s is %s", restaurant.Name, restaurant.MinimumOrder), nil)
+	}
+
+	quote := &models.DeliveryQuote{
+		Fee:           restaurant.DeliveryFee,
+		SmallOrderFee: models.Zero(currency),
+	}
+	if restaurant.SmallOrderThreshold.IsPositive() && subtotal.Cmp(restaurant.SmallOrderThreshold) < 0 {
+		quote.SmallOrderFee = restaurant.SmallOrderSurcharge
+	}
+
+	zones, err := s.deliveryRepo.GetZones(restaurant.ID)
+	if err != nil {
+		return nil, err
+	}
+	bands, err := s.deliveryRepo.GetFeeBands(restaurant.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without coordinates we can only charge the flat fee, and only if nothing needs a location
+	if address == nil || address.Latitude == nil || address.Longitude == nil {
+		if len(zones) > 0 || len(bands) > 0 || restaurant.MaxDeliveryDistanceKm > 0 {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "A saved delivery address with coordinates is required for this restaurant", nil)
+		}
+		return quote, nil
+	}
+
+	destination := models.GeoPoint{Latitude: *address.Latitude, Longitude: *address.Longitude}
+	origin := models.GeoPoint{Latitude: restaurant.Latitude, Longitude: restaurant.Longitude}
+	distance := origin.DistanceKm(destination)
+	quote.DistanceKm = &distance
+
+	// The address must fall inside one of the restaurant's zones when it has any
+	if len(zones) > 0 {
+		for i := range zones {
+			if zones[i].Polygon.Contains(destination) {
+				quote.ZoneID = &zones[i].ID
+				break
+			}
+		}
+		if quote.ZoneID == nil {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Delivery address is outside "+restaurant.Name+"'s delivery area", nil)
+		}
+	}
+
+	if restaurant.MaxDeliveryDistanceKm > 0 && distance > restaurant.MaxDeliveryDistanceKm {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Delivery address is %.1f km away; %s delivers up to %.1f km", distance, restaurant.Name, restaurant.MaxDeliveryDistanceKm), nil)
+	}
+
+	// Bands are sorted by distance; the first band that covers the distance sets the fee
+	if len(bands) > 0 {
+		matched := false
+		for _, band := range bands {
+			if distance <= band.MaxDistanceKm {
+				quote.Fee = band.Fee
+				quote.Fee.Currency = currency
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Delivery address is %.1f km away, beyond %s's delivery range", distance, restaurant.Name), nil)
+		}
+	}
+
+	return quote, nil
+}
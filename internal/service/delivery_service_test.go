@@ -0,0 +1,114 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"dfood/internal/models"
+	"dfood/pkg/errors"
+)
+
+// deliveryAreas serves the same zones and fee bands for every restaurant
+type deliveryAreas struct {
+	zones []models.DeliveryZone
+	bands []models.DeliveryFeeBand
+}
+
+func (d deliveryAreas) GetZones(string) ([]models.DeliveryZone, error) { return d.zones, nil }
+
+func (d deliveryAreas) GetFeeBands(string) ([]models.DeliveryFeeBand, error) { return d.bands, nil }
+
+// northOf returns an address the given number of hundredths of a degree north of the equator at the meridian,
+// about 1.11 km per step
+func northOf(steps float64) *models.Address {
+	latitude, longitude := steps/100, 0.0
+	return &models.Address{Latitude: &latitude, Longitude: &longitude}
+}
+
+func rejectedAs(t *testing.T, err error, status int) {
+	t.Helper()
+	if got, ok := errors.GetStatusCode(err); !ok || got != status {
+		t.Errorf("error = %v, want status %d", err, status)
+	}
+}
+
+func TestQuoteDeliveryPicksTheFirstCoveringBand(t *testing.T) {
+	service := NewDeliveryService(deliveryAreas{bands: []models.DeliveryFeeBand{
+		{ID: "near", MaxDistanceKm: 2, Fee: usd(299)},
+		{ID: "far", MaxDistanceKm: 5, Fee: usd(499)},
+	}})
+	restaurant := &models.Restaurant{ID: "r1", Name: "Corner Deli", DeliveryFee: usd(999)}
+
+	near, err := service.QuoteDelivery(restaurant, northOf(1), usd(2000))
+	if err != nil || near.Fee.Amount != 299 {
+		t.Fatalf("quote at 1.1 km = %+v, %v; want the 2.99 band", near, err)
+	}
+	if near.DistanceKm == nil || *near.DistanceKm < 1.1 || *near.DistanceKm > 1.12 {
+		t.Errorf("distance = %v, want about 1.11 km", near.DistanceKm)
+	}
+
+	far, err := service.QuoteDelivery(restaurant, northOf(4), usd(2000))
+	if err != nil || far.Fee.Amount != 499 {
+		t.Errorf("quote at 4.4 km = %+v, %v; want the 4.99 band", far, err)
+	}
+
+	_, err = service.QuoteDelivery(restaurant, northOf(5), usd(2000))
+	rejectedAs(t, err, http.StatusBadRequest)
+}
+
+func TestQuoteDeliveryRequiresTheAddressInsideAZone(t *testing.T) {
+	square := models.GeoPolygon{
+		{Latitude: -0.02, Longitude: -0.02},
+		{Latitude: -0.02, Longitude: 0.02},
+		{Latitude: 0.02, Longitude: 0.02},
+		{Latitude: 0.02, Longitude: -0.02},
+	}
+	service := NewDeliveryService(deliveryAreas{
+		zones: []models.DeliveryZone{{ID: "downtown", Polygon: square}},
+		bands: []models.DeliveryFeeBand{{ID: "all", MaxDistanceKm: 50, Fee: usd(350)}},
+	})
+	restaurant := &models.Restaurant{ID: "r1", Name: "Corner Deli"}
+
+	inside, err := service.QuoteDelivery(restaurant, northOf(1), usd(2000))
+	if err != nil || inside.ZoneID == nil || *inside.ZoneID != "downtown" {
+		t.Fatalf("quote inside the zone = %+v, %v; want zone downtown", inside, err)
+	}
+
+	// The band would cover 3.3 km, but the zone does not
+	_, err = service.QuoteDelivery(restaurant, northOf(3), usd(2000))
+	rejectedAs(t, err, http.StatusBadRequest)
+
+	// Zones need a location to check against
+	_, err = service.QuoteDelivery(restaurant, &models.Address{}, usd(2000))
+	rejectedAs(t, err, http.StatusBadRequest)
+}
+
+func TestQuoteDeliveryFlatFeeAndBasketRules(t *testing.T) {
+	service := NewDeliveryService(deliveryAreas{})
+	restaurant := &models.Restaurant{
+		ID:                  "r1",
+		Name:                "Corner Deli",
+		DeliveryFee:         usd(199),
+		MinimumOrder:        usd(800),
+		SmallOrderThreshold: usd(1500),
+		SmallOrderSurcharge: usd(250),
+	}
+
+	// Without zones, bands or a distance limit an address without coordinates gets the flat fee
+	small, err := service.QuoteDelivery(restaurant, &models.Address{}, usd(1000))
+	if err != nil || small.Fee.Amount != 199 || small.SmallOrderFee.Amount != 250 {
+		t.Fatalf("quote for 10.00 = %+v, %v; want 1.99 plus a 2.50 small-order fee", small, err)
+	}
+
+	large, err := service.QuoteDelivery(restaurant, &models.Address{}, usd(1500))
+	if err != nil || !large.SmallOrderFee.IsZero() {
+		t.Errorf("quote at the threshold = %+v, %v; want no small-order fee", large, err)
+	}
+
+	_, err = service.QuoteDelivery(restaurant, nil, usd(799))
+	rejectedAs(t, err, http.StatusBadRequest)
+
+	restaurant.MaxDeliveryDistanceKm = 3
+	_, err = service.QuoteDelivery(restaurant, northOf(3), usd(1000))
+	rejectedAs(t, err, http.StatusBadRequest)
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/utils"
+	"dfood/pkg/logger"
+)
+
+// Test card numbers understood by the fake gateway. Any other card number is approved.
+const (
+	FakeCardSuccess           = "4242424242424242"
+	FakeCardDeclined          = "4000000000000002"
+	FakeCardInsufficientFunds = "4000000000009995"
+	FakeCard3DSRequired       = "4000000000003220"
+	FakeCardDisputed          = "4000000000000259" // Charges succeed, then a dispute.opened webhook follows
+	FakeCardRefundFails       = "4000000000005126" // Refunds are accepted, then a refund.failed webhook follows
+)
+
+// fakeTokenPrefix starts every fake token; the outcome follows so tokens keep working across restarts
+const fakeTokenPrefix = "tok_fake_"
+
+// fakeCardOutcomes maps test card numbers to the outcome encoded in their tokens
+var fakeCardOutcomes = map[string]string{
+	FakeCardDeclined:          "declined",
+	FakeCardInsufficientFunds: "nofunds",
+	FakeCard3DSRequired:       "3ds",
+	FakeCardDisputed:          "dispute",
+	FakeCardRefundFails:       "refundfail",
+}
+
+// fakeWebhookDelay holds webhooks back until the synchronous response has been handled, as with a real processor
+const fakeWebhookDelay = 500 * time.Millisecond
+
+type fakeAuthorizationState string
+
+const (
+	fakeAuthorized fakeAuthorizationState = "authorized"
+	fakeCaptured   fakeAuthorizationState = "captured"
+	fakeVoided     fakeAuthorizationState = "voided"
+)
+
+type fakeAuthorization struct {
+	id        string
+	reference string
+	outcome   string
+	state     fakeAuthorizationState
+	amount    models.Money
+	captured  models.Money
+	refunded  models.Money
+}
+
+// fakeGateway is a deterministic in-process gateway for development and local testing.
+// Outcomes depend only on the card number a token was issued for, and repeated authorizations
+// with the same reference return the original result, mirroring real gateways' idempotency keys.
+// When a webhook URL is configured it also delivers signed webhooks for what it did.
+type fakeGateway struct {
+	mu             sync.Mutex
+	authorizations map[string]*fakeAuthorization
+	byReference    map[string]*GatewayResult
+	webhookURL     string
+	webhookSecret  string
+	client         *http.Client
+}
+
+func NewFakeGateway(webhookURL, webhookSecret string) PaymentGateway {
+	return &fakeGateway{
+		authorizations: make(map[string]*fakeAuthorization),
+		byReference:    make(map[string]*GatewayResult),
+		webhookURL:     webhookURL,
+		webhookSecret:  webhookSecret,
+		client:         &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (g *fakeGateway) Tokenize(card GatewayCard) (*GatewayCardToken, error) {
+	outcome, exists := fakeCardOutcomes[card.Number]
+	if !exists {
+		outcome = "ok"
+	}
+	sum := sha256.Sum256([]byte("fake-gateway:" + card.Number))
+
+	return &GatewayCardToken{
+		Token:       fakeTokenPrefix + outcome + "_" + utils.GenerateID(),
+		Brand:       models.DetectCardBrand(card.Number),
+		Last4:       card.Number[len(card.Number)-4:],
+		Fingerprint: hex.EncodeToString(sum[:16]),
+	}, nil
+}
+
+func (g *fakeGateway) Authorize(request GatewayAuthorization) (*GatewayResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if previous, exists := g.byReference[request.Reference]; exists {
+		result := *previous
+		return &result, nil
+	}
+
+	outcome, _, _ := strings.Cut(strings.TrimPrefix(request.CardToken, fakeTokenPrefix), "_")
+	result := &GatewayResult{ID: g.nextID("auth"), Amount: request.Amount}
+	switch {
+	case !strings.HasPrefix(request.CardToken, fakeTokenPrefix):
+		result.Status = GatewayStatusDeclined
+		result.DeclineCode = DeclineCodeInvalidRequest
+		result.Message = "Unknown card token"
+	case !request.Amount.IsPositive():
+		result.Status = GatewayStatusDeclined
+		result.DeclineCode = DeclineCodeInvalidRequest
+		result.Message = "Amount must be positive"
+	case outcome == "declined":
+		result.Status = GatewayStatusDeclined
+		result.DeclineCode = DeclineCodeGeneric
+		result.Message = "Your card was declined"
+	case outcome == "nofunds":
+		result.Status = GatewayStatusDeclined
+		result.DeclineCode = DeclineCodeInsufficientFunds
+		result.Message = "Your card has insufficient funds"
+	case outcome == "3ds":
+		result.Status = GatewayStatusRequiresAction
+		result.Message = "Your card requires 3-D Secure authentication"
+	default:
+		result.Status = GatewayStatusSucceeded
+		g.authorizations[result.ID] = &fakeAuthorization{
+			id:        result.ID,
+			reference: request.Reference,
+			outcome:   outcome,
+			state:     fakeAuthorized,
+			amount:    request.Amount,
+			captured:  models.Zero(request.Amount.Currency),
+			refunded:  models.Zero(request.Amount.Currency),
+		}
+	}
+
+	if request.Reference != "" {
+		g.byReference[request.Reference] = result
+	}
+	if result.Status == GatewayStatusDeclined {
+		g.emit(models.WebhookChargeFailed, models.WebhookEventData{
+			ID:             result.ID,
+			Reference:      request.Reference,
+			Amount:         request.Amount,
+			FailureMessage: result.Message,
+		})
+	}
+	copied := *result
+	return &copied, nil
+}
+
+func (g *fakeGateway) Capture(authorizationID string, amount models.Money) (*GatewayResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, exists := g.authorizations[authorizationID]
+	if !exists {
+		// State lives in memory, so holds placed before a restart are treated as covering the capture
+		auth = &fakeAuthorization{id: authorizationID, state: fakeAuthorized, amount: amount, captured: models.Zero(amount.Currency), refunded: models.Zero(amount.Currency)}
+		g.authorizations[authorizationID] = auth
+	}
+
+	result := &GatewayResult{ID: g.nextID("cap"), Amount: amount}
+	switch {
+	case auth.state != fakeAuthorized:
+		result.Status = GatewayStatusDeclined
+		result.DeclineCode = DeclineCodeInvalidRequest
+		result.Message = fmt.Sprintf("Authorization is %s", auth.state)
+	case !amount.IsPositive() || amount.Cmp(auth.amount) > 0:
+		result.Status = GatewayStatusDeclined
+		result.DeclineCode = DeclineCodeInvalidRequest
+		result.Message = fmt.Sprintf("Capture amount must be between 0 and %s", auth.amount)
+	default:
+		result.Status = GatewayStatusSucceeded
+		auth.state = fakeCaptured
+		auth.captured = amount
+
+		g.emit(models.WebhookChargeSucceeded, models.WebhookEventData{
+			ID:        auth.id,
+			Reference: auth.reference,
+			Amount:    amount,
+		})
+		if auth.outcome == "dispute" {
+			g.emit(models.WebhookDisputeOpened, models.WebhookEventData{
+				ID:        g.nextID("dp"),
+				Reference: auth.reference,
+				ChargeID:  auth.id,
+				Amount:    amount,
+			})
+		}
+	}
+	return result, nil
+}
+
+func (g *fakeGateway) Void(authorizationID string) (*GatewayResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, exists := g.authorizations[authorizationID]
+	if !exists {
+		// Holds placed before a restart are simply released
+		return &GatewayResult{ID: g.nextID("void"), Status: GatewayStatusSucceeded}, nil
+	}
+
+	result := &GatewayResult{ID: g.nextID("void"), Amount: auth.amount}
+	if auth.state != fakeAuthorized {
+		result.Status = GatewayStatusDeclined
+		result.DeclineCode = DeclineCodeInvalidRequest
+		result.Message = fmt.Sprintf("Authorization is %s", auth.state)
+		return result, nil
+	}
+	result.Status = GatewayStatusSucceeded
+	auth.state = fakeVoided
+	return result, nil
+}
+
+func (g *fakeGateway) Refund(authorizationID string, amount models.Money) (*GatewayResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, exists := g.authorizations[authorizationID]
+	if !exists {
+		// State lives in memory, so charges made before a restart are treated as fully refundable
+		auth = &fakeAuthorization{id: authorizationID, state: fakeCaptured, amount: amount, captured: amount, refunded: models.Zero(amount.Currency)}
+		g.authorizations[authorizationID] = auth
+	}
+
+	result := &GatewayResult{ID: g.nextID("ref"), Amount: amount}
+	remaining := auth.captured.Sub(auth.refunded)
+	switch {
+	case auth.state != fakeCaptured:
+		result.Status = GatewayStatusDeclined
+		result.DeclineCode = DeclineCodeInvalidRequest
+		result.Message = fmt.Sprintf("Authorization is %s", auth.state)
+	case !amount.IsPositive() || amount.Cmp(remaining) > 0:
+		result.Status = GatewayStatusDeclined
+		result.DeclineCode = DeclineCodeInvalidRequest
+		result.Message = fmt.Sprintf("Refund amount must be between 0 and %s", remaining)
+	default:
+		result.Status = GatewayStatusSucceeded
+		auth.refunded = auth.refunded.Add(amount)
+
+		data := models.WebhookEventData{ID: result.ID, ChargeID: auth.id, Amount: amount}
+		if auth.outcome == "refundfail" {
+			auth.refunded = auth.refunded.Sub(amount)
+			data.FailureMessage = "The refund could not be returned to the card"
+			g.emit(models.WebhookRefundFailed, data)
+		} else {
+			g.emit(models.WebhookRefundSucceeded, data)
+		}
+	}
+	return result, nil
+}
+
+// emit delivers a signed webhook in the background after fakeWebhookDelay
+func (g *fakeGateway) emit(eventType models.WebhookEventType, data models.WebhookEventData) {
+	if g.webhookURL == "" {
+		return
+	}
+
+	payload, err := json.Marshal(models.WebhookPayload{
+		ID:      g.nextID("evt"),
+		Type:    eventType,
+		Created: time.Now().Unix(),
+		Data:    data,
+	})
+	if err != nil {
+		logger.Error("Failed to encode fake webhook", "type", eventType, "error", err)
+		return
+	}
+
+	go func() {
+		time.Sleep(fakeWebhookDelay)
+		request, err := http.NewRequest(http.MethodPost, g.webhookURL, bytes.NewReader(payload))
+		if err != nil {
+			logger.Error("Failed to build fake webhook", "type", eventType, "error", err)
+			return
+		}
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(WebhookSignatureHeader, SignWebhook(g.webhookSecret, time.Now(), payload))
+
+		response, err := g.client.Do(request)
+		if err != nil {
+			logger.Error("Failed to deliver fake webhook", "type", eventType, "error", err)
+			return
+		}
+		response.Body.Close()
+		if response.StatusCode >= http.StatusMultipleChoices {
+			logger.Error("Fake webhook was rejected", "type", eventType, "status", response.StatusCode)
+		}
+	}()
+}
+
+// nextID returns a gateway reference that stays unique across restarts
+func (g *fakeGateway) nextID(prefix string) string {
+	return "fake_" + prefix + "_" + utils.GenerateID()
+}
@@ -65,7 +65,14 @@ func (s *favoritesService) GetFavoriteRestaurants(userID string) ([]models.Resta
 		return nil, err
 	}
 
-	return s.favoritesRepo.GetFavoriteRestaurants(userID)
+	restaurants, err := s.favoritesRepo.GetFavoriteRestaurants(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := setOpenStatusAll(s.restaurantRepo, restaurants); err != nil {
+		return nil, err
+	}
+	return restaurants, nil
 }
 
 func (s *favoritesService) AddFavoriteFood(userID, foodID string) error {
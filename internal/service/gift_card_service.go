@@ -0,0 +1,280 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+	"dfood/pkg/logger"
+)
+
+const (
+	defaultGiftCardMinAmount             = 500
+	defaultGiftCardMaxAmount             = 50000
+	defaultGiftCardExpiryDays            = 1825
+	defaultPromotionalGiftCardExpiryDays = 90
+	defaultGiftCardMaxIssueCount         = 1000
+)
+
+type GiftCardService interface {
+	GetGiftCard(code string) (*models.GiftCard, error)
+	GetUserGiftCards(userID string) ([]models.GiftCard, error)
+	RedeemGiftCard(request *models.RedeemGiftCardRequest) (*models.GiftCard, error)
+	IssueGiftCards(request *models.IssueGiftCardsRequest) (*models.GiftCardBatch, error)
+}
+
+type giftCardService struct {
+	giftCardRepo  repository.GiftCardRepository
+	userRepo      repository.UserRepository
+	ledgerService LedgerService
+	config        config.GiftCardConfig
+}
+
+func NewGiftCardService(giftCardRepo repository.GiftCardRepository, userRepo repository.UserRepository, ledgerService LedgerService, cfg config.GiftCardConfig) GiftCardService {
+	return &giftCardService{
+		giftCardRepo:  giftCardRepo,
+		userRepo:      userRepo,
+		ledgerService: ledgerService,
+		config:        cfg,
+	}
+}
+
+// GetGiftCard looks a gift card up by its code so the holder can check its balance
+func (s *giftCardService) GetGiftCard(code string) (*models.GiftCard, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Gift card code is required", nil)
+	}
+	giftCard, err := s.giftCardRepo.GetByCodeHash(giftCardCodeHash(code))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refresh(giftCard); err != nil {
+		return nil, err
+	}
+	return giftCard, nil
+}
+
+// GetUserGiftCards lists the gift cards a user bought or redeemed, with what is left on each
+func (s *giftCardService) GetUserGiftCards(userID string) ([]models.GiftCard, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+	giftCards, err := s.giftCardRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range giftCards {
+		if err := s.refresh(&giftCards[i]); err != nil {
+			return nil, err
+		}
+	}
+	return giftCards, nil
+}
+
+// RedeemGiftCard moves what is left on a gift card to the user's wallet, after which the card can no longer be used
+func (s *giftCardService) RedeemGiftCard(request *models.RedeemGiftCardRequest) (*models.GiftCard, error) {
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	if _, err := s.userRepo.GetByID(request.UserID); err != nil {
+		return nil, err
+	}
+	giftCard, err := s.GetGiftCard(request.Code)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGiftCardUsable(giftCard); err != nil {
+		return nil, err
+	}
+	amount := giftCard.Balance
+
+	// Claim the card before moving the money so two redemptions cannot both succeed
+	now := time.Now()
+	if err := s.giftCardRepo.UpdateIfStatus(giftCard.ID, models.GiftCardStatusActive, map[string]interface{}{
+		"status":      models.GiftCardStatusRedeemed,
+		"redeemed_by": request.UserID,
+		"redeemed_at": now,
+	}); err != nil {
+		return nil, err
+	}
+	if err := s.ledgerService.RedeemGiftCard(giftCard, request.UserID, amount); err != nil {
+		if releaseErr := s.giftCardRepo.UpdateIfStatus(giftCard.ID, models.GiftCardStatusRedeemed, map[string]interface{}{
+			"status":      models.GiftCardStatusActive,
+			"redeemed_by": nil,
+			"redeemed_at": nil,
+		}); releaseErr != nil {
+			logger.Error("Failed to release gift card after redemption failed", "gift_card_id", giftCard.ID, "error", releaseErr)
+		}
+		if statusCode, _ := errors.GetStatusCode(err); statusCode == http.StatusPaymentRequired {
+			return nil, errors.NewHTTPError(http.StatusConflict, "Gift card balance changed, please try again", err)
+		}
+		return nil, err
+	}
+
+	giftCard.Status = models.GiftCardStatusRedeemed
+	giftCard.RedeemedBy = &request.UserID
+	giftCard.RedeemedAt = &now
+	giftCard.Balance = models.Zero(giftCard.Currency)
+	return giftCard, nil
+}
+
+// IssueGiftCards creates a batch of promotional gift cards funded at our expense. The codes are returned
+// once, here; only their hashes are kept.
+func (s *giftCardService) IssueGiftCards(request *models.IssueGiftCardsRequest) (*models.GiftCardBatch, error) {
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Gift card batch is required", nil)
+	}
+	maxCount := s.config.MaxIssueCount
+	if maxCount <= 0 {
+		maxCount = defaultGiftCardMaxIssueCount
+	}
+	if request.Count <= 0 || request.Count > maxCount {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Gift card count must be between 1 and "+strconv.Itoa(maxCount), nil)
+	}
+	amount := request.Amount
+	if amount.Currency == "" {
+		amount.Currency = models.DefaultCurrency
+	}
+	if err := checkGiftCardAmount(amount, s.config); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiryDays := s.config.PromotionalExpiryDays
+	if expiryDays <= 0 {
+		expiryDays = defaultPromotionalGiftCardExpiryDays
+	}
+	expiresAt := now.AddDate(0, 0, expiryDays)
+	if request.ExpiresAt != nil {
+		if !request.ExpiresAt.After(now) {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Gift card expiry must be in the future", nil)
+		}
+		expiresAt = *request.ExpiresAt
+	}
+
+	batchID := utils.GenerateGiftCardBatchID()
+	giftCards := make([]models.GiftCard, request.Count)
+	for i := range giftCards {
+		giftCard := newGiftCard(models.GiftCardSourcePromotional, amount, expiresAt)
+		giftCard.BatchID = &batchID
+		giftCard.IssuedBy = &request.AgentID
+		giftCard.Message = request.Message
+		giftCards[i] = *giftCard
+	}
+	if err := s.giftCardRepo.CreateBatch(giftCards); err != nil {
+		return nil, err
+	}
+	for i := range giftCards {
+		if err := s.ledgerService.IssueGiftCard(&giftCards[i]); err != nil {
+			return nil, err
+		}
+	}
+	logger.Info("Promotional gift cards issued", "batch_id", batchID, "count", request.Count, "amount", amount.String(), "agent_id", request.AgentID)
+
+	return &models.GiftCardBatch{
+		BatchID:   batchID,
+		Count:     request.Count,
+		Amount:    amount,
+		Total:     amount.Mul(int64(request.Count)),
+		ExpiresAt: expiresAt,
+		GiftCards: giftCards,
+	}, nil
+}
+
+// refresh fills in the gift card's balance from the ledger. A card found past its expiry is marked
+// expired and its unspent balance taken back; posting the expiry again is harmless.
+func (s *giftCardService) refresh(giftCard *models.GiftCard) error {
+	balance, err := s.ledgerService.GetGiftCardBalance(giftCard)
+	if err != nil {
+		return err
+	}
+	giftCard.Balance = balance
+	if giftCard.Status == models.GiftCardStatusRedeemed || !giftCard.IsExpired(time.Now()) {
+		return nil
+	}
+
+	if giftCard.Status == models.GiftCardStatusActive {
+		err := s.giftCardRepo.UpdateIfStatus(giftCard.ID, models.GiftCardStatusActive, map[string]interface{}{
+			"status": models.GiftCardStatusExpired,
+		})
+		if statusCode, _ := errors.GetStatusCode(err); err != nil && statusCode != http.StatusConflict {
+			return err
+		}
+		giftCard.Status = models.GiftCardStatusExpired
+	}
+	if balance.IsPositive() {
+		if err := s.ledgerService.ExpireGiftCard(giftCard, balance); err != nil {
+			return err
+		}
+		giftCard.Balance = models.Zero(giftCard.Currency)
+	}
+	return nil
+}
+
+// newGiftCard builds an active gift card with a fresh code, returned once in Code
+func newGiftCard(source models.GiftCardSource, amount models.Money, expiresAt time.Time) *models.GiftCard {
+	code := utils.GenerateGiftCardCode()
+	return &models.GiftCard{
+		ID:            utils.GenerateGiftCardID(),
+		CodeHash:      giftCardCodeHash(code),
+		Last4:         code[len(code)-4:],
+		Source:        source,
+		Status:        models.GiftCardStatusActive,
+		InitialAmount: amount,
+		Currency:      amount.Currency,
+		ExpiresAt:     expiresAt,
+		Code:          code,
+		Balance:       amount,
+	}
+}
+
+// checkGiftCardAmount keeps gift card values within the configured range
+func checkGiftCardAmount(amount models.Money, cfg config.GiftCardConfig) error {
+	minAmount := models.NewMoney(cfg.MinAmount, models.DefaultCurrency)
+	if !minAmount.IsPositive() {
+		minAmount = models.NewMoney(defaultGiftCardMinAmount, models.DefaultCurrency)
+	}
+	maxAmount := models.NewMoney(cfg.MaxAmount, models.DefaultCurrency)
+	if !maxAmount.IsPositive() {
+		maxAmount = models.NewMoney(defaultGiftCardMaxAmount, models.DefaultCurrency)
+	}
+	if amount.Currency != models.DefaultCurrency {
+		return errors.NewHTTPError(http.StatusBadRequest, "Gift cards are only available in "+string(models.DefaultCurrency), nil)
+	}
+	if amount.Cmp(minAmount) < 0 || amount.Cmp(maxAmount) > 0 {
+		return errors.NewHTTPError(http.StatusBadRequest, "Gift card amount must be between "+minAmount.String()+" and "+maxAmount.String(), nil)
+	}
+	return nil
+}
+
+// checkGiftCardUsable rejects gift cards that are redeemed, expired or empty
+func checkGiftCardUsable(giftCard *models.GiftCard) error {
+	switch {
+	case giftCard.Status == models.GiftCardStatusRedeemed:
+		return errors.NewHTTPError(http.StatusConflict, "Gift card ending in "+giftCard.Last4+" has already been redeemed", nil)
+	case giftCard.IsExpired(time.Now()):
+		return errors.NewHTTPError(http.StatusConflict, "Gift card ending in "+giftCard.Last4+" expired on "+giftCard.ExpiresAt.Format("2006-01-02"), nil)
+	case !giftCard.Balance.IsPositive():
+		return errors.NewHTTPError(http.StatusConflict, "Gift card ending in "+giftCard.Last4+" has no balance left", nil)
+	}
+	return nil
+}
+
+// giftCardCodeHash is what a gift card is stored and looked up by. Codes are matched regardless of
+// case, spaces and dashes, so "k7qm 2xrd 9ftb hw3n" finds K7QM-2XRD-9FTB-HW3N.
+func giftCardCodeHash(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
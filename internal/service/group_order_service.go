@@ -0,0 +1,455 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+)
+
+type GroupOrderService interface {
+	CreateGroupOrder(request *models.CreateGroupOrderRequest) (*models.GroupOrder, error)
+	GetGroupOrder(groupOrderID string) (*models.GroupOrder, error)
+	JoinGroupOrder(request *models.JoinGroupOrderRequest) (*models.GroupOrder, error)
+	UpdateItems(groupOrderID string, request *models.UpdateGroupOrderItemsRequest) (*models.GroupOrder, error)
+	LockGroupOrder(groupOrderID, userID string) (*models.GroupOrder, error)
+	CheckoutGroupOrder(groupOrderID, userID string) (*models.GroupOrder, error)
+	PayShare(groupOrderID string, request *models.PayGroupOrderShareRequest) (*models.GroupOrderParticipant, error)
+}
+
+type groupOrderService struct {
+	groupOrderRepo repository.GroupOrderRepository
+	userRepo       repository.UserRepository
+	restaurantRepo repository.RestaurantRepository
+	foodRepo       repository.FoodRepository
+	orderService   OrderService
+	paymentService PaymentService
+}
+
+func NewGroupOrderService(groupOrderRepo repository.GroupOrderRepository, userRepo repository.UserRepository, restaurantRepo repository.RestaurantRepository, foodRepo repository.FoodRepository, orderService OrderService, paymentService PaymentService) GroupOrderService {
+	return &groupOrderService{
+		groupOrderRepo: groupOrderRepo,
+		userRepo:       userRepo,
+		restaurantRepo: restaurantRepo,
+		foodRepo:       foodRepo,
+		orderService:   orderService,
+		paymentService: paymentService,
+	}
+}
+
+func (s *groupOrderService) CreateGroupOrder(request *models.CreateGroupOrderRequest) (*models.GroupOrder, error) {
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Group order is required", nil)
+	}
+	if strings.TrimSpace(request.HostUserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Host user ID is required", nil)
+	}
+	if strings.TrimSpace(request.RestaurantID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Restaurant ID is required", nil)
+	}
+	if strings.TrimSpace(request.DeliveryAddress) == "" && request.DeliveryAddressID == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Delivery address is required", nil)
+	}
+	if strings.TrimSpace(request.PaymentMethod) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Payment method is required", nil)
+	}
+
+	mode := request.PaymentMode
+	if mode == "" {
+		mode = models.GroupPaymentModeHost
+	}
+	if mode != models.GroupPaymentModeHost && mode != models.GroupPaymentModeSplit {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid payment mode, expected host or split", nil)
+	}
+
+	host, err := s.userRepo.GetByID(request.HostUserID)
+	if err != nil {
+		return nil, err
+	}
+	restaurant, err := s.restaurantRepo.GetByID(request.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := strings.TrimSpace(request.DisplayName)
+	if displayName == "" {
+		displayName = host.FirstName
+	}
+
+	group := &models.GroupOrder{
+		ID:                utils.GenerateGroupOrderID(),
+		HostUserID:        host.ID,
+		RestaurantID:      restaurant.ID,
+		RestaurantName:    restaurant.Name,
+		InviteCode:        utils.GenerateInviteCode(),
+		Status:            models.GroupOrderStatusOpen,
+		PaymentMode:       mode,
+		DeliveryAddress:   request.DeliveryAddress,
+		DeliveryAddressID: request.DeliveryAddressID,
+		PaymentMethod:     request.PaymentMethod,
+		Participants: []models.GroupOrderParticipant{{
+			UserID:          host.ID,
+			DisplayName:     displayName,
+			IsHost:          true,
+			Items:           models.OrderItemsArray{},
+			PaymentMethodID: request.PaymentMethod,
+			PaymentStatus:   models.GroupPaymentStatusUnpaid,
+		}},
+	}
+	if err := s.groupOrderRepo.Create(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *groupOrderService) GetGroupOrder(groupOrderID string) (*models.GroupOrder, error) {
+	if strings.TrimSpace(groupOrderID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Group order ID is required", nil)
+	}
+
+	return s.groupOrderRepo.GetByID(groupOrderID)
+}
+
+func (s *groupOrderService) JoinGroupOrder(request *models.JoinGroupOrderRequest) (*models.GroupOrder, error) {
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	if strings.TrimSpace(request.InviteCode) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Invite code is required", nil)
+	}
+
+	user, err := s.userRepo.GetByID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.groupOrderRepo.GetByInviteCode(strings.ToUpper(strings.TrimSpace(request.InviteCode)))
+	if err != nil {
+		return nil, err
+	}
+
+	// Joining twice is harmless and just returns the cart
+	if findParticipant(group, user.ID) != nil {
+		return group, nil
+	}
+	if group.Status != models.GroupOrderStatusOpen {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Group order is no longer accepting participants", nil)
+	}
+
+	displayName := strings.TrimSpace(request.DisplayName)
+	if displayName == "" {
+		displayName = user.FirstName
+	}
+	paymentMethodID := request.PaymentMethodID
+	if paymentMethodID == "" {
+		paymentMethodID = group.PaymentMethod
+	}
+
+	participant := &models.GroupOrderParticipant{
+		GroupOrderID:    group.ID,
+		UserID:          user.ID,
+		DisplayName:     displayName,
+		Items:           models.OrderItemsArray{},
+		PaymentMethodID: paymentMethodID,
+		PaymentStatus:   models.GroupPaymentStatusUnpaid,
+	}
+	if err := s.groupOrderRepo.AddParticipant(participant); err != nil {
+		return nil, err
+	}
+	group.Participants = append(group.Participants, *participant)
+
+	return group, nil
+}
+
+// UpdateItems replaces a participant's items in the shared cart
+func (s *groupOrderService) UpdateItems(groupOrderID string, request *models.UpdateGroupOrderItemsRequest) (*models.GroupOrder, error) {
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+
+	group, err := s.GetGroupOrder(groupOrderID)
+	if err != nil {
+		return nil, err
+	}
+	participant := findParticipant(group, request.UserID)
+	if participant == nil {
+		return nil, errors.NewHTTPError(http.StatusForbidden, "User is not part of this group order", nil)
+	}
+	if group.Status != models.GroupOrderStatusOpen {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Group order is locked", nil)
+	}
+
+	items, subtotal, err := s.validateItems(group.RestaurantID, request.Items)
+	if err != nil {
+		return nil, err
+	}
+	participant.Items = items
+	participant.Currency = subtotal.Currency
+	participant.Subtotal = subtotal
+	participant.Share = subtotal
+	if err := s.groupOrderRepo.UpdateParticipant(participant); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (s *groupOrderService) LockGroupOrder(groupOrderID, userID string) (*models.GroupOrder, error) {
+	group, err := s.getAsHost(groupOrderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.groupOrderRepo.UpdateStatus(group.ID, models.GroupOrderStatusOpen, models.GroupOrderStatusLocked, map[string]interface{}{"locked_at": now}); err != nil {
+		return nil, err
+	}
+	group.Status = models.GroupOrderStatusLocked
+	group.LockedAt = &now
+
+	return group, nil
+}
+
+// CheckoutGroupOrder places one order for the whole cart, works out each participant's portion
+// and collects payment from the host or from every participant, depending on the payment mode
+func (s *groupOrderService) CheckoutGroupOrder(groupOrderID, userID string) (*models.GroupOrder, error) {
+	group, err := s.getAsHost(groupOrderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if group.Status != models.GroupOrderStatusLocked {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Group order must be locked before checkout", nil)
+	}
+
+	order := &models.Order{
+		UserID:            group.HostUserID,
+		RestaurantID:      group.RestaurantID,
+		DeliveryAddress:   group.DeliveryAddress,
+		DeliveryAddressID: group.DeliveryAddressID,
+		PaymentMethod:     group.PaymentMethod,
+		GroupOrderID:      &group.ID,
+	}
+	counts := make([]int, len(group.Participants))
+	for i, participant := range group.Participants {
+		order.Items = append(order.Items, participant.Items...)
+		counts[i] = len(participant.Items)
+	}
+	if len(order.Items) == 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Group order has no items", nil)
+	}
+
+	// Claim the cart so a second checkout can't place a duplicate order
+	if err := s.groupOrderRepo.UpdateStatus(group.ID, models.GroupOrderStatusLocked, models.GroupOrderStatusCheckedOut, nil); err != nil {
+		return nil, err
+	}
+	order, err = s.orderService.CreateOrder(order)
+	if err != nil {
+		if revertErr := s.groupOrderRepo.UpdateStatus(group.ID, models.GroupOrderStatusCheckedOut, models.GroupOrderStatusLocked, nil); revertErr != nil {
+			return nil, revertErr
+		}
+		return nil, err
+	}
+	if err := s.groupOrderRepo.UpdateStatus(group.ID, models.GroupOrderStatusCheckedOut, models.GroupOrderStatusCheckedOut, map[string]interface{}{"order_id": order.ID}); err != nil {
+		return nil, err
+	}
+	group.Status = models.GroupOrderStatusCheckedOut
+	group.OrderID = &order.ID
+
+	// Hand the server-priced items back to their owners, in the order they were combined
+	offset := 0
+	for i := range group.Participants {
+		participant := &group.Participants[i]
+		participant.Items = order.Items[offset : offset+counts[i]]
+		offset += counts[i]
+
+		participant.OrderID = &order.ID
+		participant.Currency = order.Currency
+		participant.Subtotal = models.Zero(order.Currency)
+		for _, item := range participant.Items {
+			participant.Subtotal = participant.Subtotal.Add(item.Total)
+		}
+	}
+	allocateShares(group, order)
+
+	for i := range group.Participants {
+		participant := &group.Participants[i]
+		amount := amountDue(group, order, participant)
+		switch {
+		case group.PaymentMode == models.GroupPaymentModeHost && !participant.IsHost:
+			participant.PaymentStatus = models.GroupPaymentStatusCoveredHost
+		case !amount.IsPositive():
+			participant.PaymentStatus = models.GroupPaymentStatusNotRequired
+		default:
+			// A failed charge is recorded on the participant and can be retried with PayShare
+			s.chargeShare(order.ID, participant, amount, participant.PaymentMethodID)
+		}
+		if err := s.groupOrderRepo.UpdateParticipant(participant); err != nil {
+			return nil, err
+		}
+	}
+
+	return group, nil
+}
+
+// PayShare retries the payment of a participant's portion after a failed or missing charge
+func (s *groupOrderService) PayShare(groupOrderID string, request *models.PayGroupOrderShareRequest) (*models.GroupOrderParticipant, error) {
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+
+	group, err := s.GetGroupOrder(groupOrderID)
+	if err != nil {
+		return nil, err
+	}
+	participant := findParticipant(group, request.UserID)
+	if participant == nil {
+		return nil, errors.NewHTTPError(http.StatusForbidden, "User is not part of this group order", nil)
+	}
+	if group.Status != models.GroupOrderStatusCheckedOut || group.OrderID == nil {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Group order has not been checked out yet", nil)
+	}
+	if participant.PaymentStatus != models.GroupPaymentStatusUnpaid && participant.PaymentStatus != models.GroupPaymentStatusFailed {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Nothing to pay for this participant", nil)
+	}
+
+	paymentMethodID := request.PaymentMethodID
+	if paymentMethodID == "" {
+		paymentMethodID = participant.PaymentMethodID
+	}
+	order, err := s.orderService.GetOrderByID(*group.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	s.chargeShare(order.ID, participant, amountDue(group, order, participant), paymentMethodID)
+	if err := s.groupOrderRepo.UpdateParticipant(participant); err != nil {
+		return nil, err
+	}
+
+	return participant, nil
+}
+
+// chargeShare charges a participant through PaymentService and records the outcome on the participant
+func (s *groupOrderService) chargeShare(orderID string, participant *models.GroupOrderParticipant, amount models.Money, paymentMethodID string) {
+	participant.PaymentMethodID = paymentMethodID
+	transaction, err := s.paymentService.ProcessPayment(&models.PaymentTransaction{
+		OrderID:         orderID,
+		UserID:          participant.UserID,
+		PaymentMethodID: paymentMethodID,
+		Amount:          amount,
+		Currency:        participant.Currency,
+	})
+	if err != nil {
+		reason, ok := errors.GetErrorMessage(err)
+		if !ok {
+			reason = err.Error()
+		}
+		participant.PaymentStatus = models.GroupPaymentStatusFailed
+		participant.PaymentFailureReason = &reason
+		return
+	}
+
+	participant.PaymentStatus = models.GroupPaymentStatusPaid
+	participant.PaymentTransactionID = &transaction.ID
+	participant.PaymentFailureReason = nil
+}
+
+// amountDue is what a participant is charged: their own share, or the whole order for a host paying for everyone
+func amountDue(group *models.GroupOrder, order *models.Order, participant *models.GroupOrderParticipant) models.Money {
+	if group.PaymentMode == models.GroupPaymentModeHost && participant.IsHost {
+		return order.Total
+	}
+	return participant.Share
+}
+
+// allocateShares splits the order's fees, tax and any discount across participants
+// in proportion to their subtotals, leaving rounding leftovers to the host
+func allocateShares(group *models.GroupOrder, order *models.Order) {
+	extra := order.Total.Sub(order.Subtotal)
+	allocated := models.Zero(order.Currency)
+	var host *models.GroupOrderParticipant
+	for i := range group.Participants {
+		participant := &group.Participants[i]
+		if participant.IsHost {
+			host = participant
+		}
+		participant.Share = participant.Subtotal
+		if order.Subtotal.IsPositive() {
+			part := extra.MulRat(participant.Subtotal.Amount, order.Subtotal.Amount)
+			participant.Share = participant.Share.Add(part)
+			allocated = allocated.Add(part)
+		}
+	}
+	if host == nil {
+		return
+	}
+	host.Share = host.Share.Add(extra.Sub(allocated))
+}
+
+func (s *groupOrderService) getAsHost(groupOrderID, userID string) (*models.GroupOrder, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	group, err := s.GetGroupOrder(groupOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if group.HostUserID != userID {
+		return nil, errors.NewHTTPError(http.StatusForbidden, "Only the host can do this", nil)
+	}
+	return group, nil
+}
+
+// validateItems checks a participant's items against the restaurant's menu and prices them
+func (s *groupOrderService) validateItems(restaurantID string, items []models.OrderItem) (models.OrderItemsArray, models.Money, error) {
+	subtotal := models.Zero(models.DefaultCurrency)
+	validated := make(models.OrderItemsArray, 0, len(items))
+	for _, item := range items {
+		if strings.TrimSpace(item.FoodID) == "" {
+			return nil, models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "Food ID is required for all items", nil)
+		}
+		if item.Quantity <= 0 {
+			return nil, models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "Quantity must be greater than 0", nil)
+		}
+
+		food, err := s.foodRepo.GetByID(item.FoodID)
+		if err != nil {
+			return nil, models.Money{}, err
+		}
+		if food.RestaurantID != restaurantID {
+			return nil, models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "All items must be from the same restaurant", nil)
+		}
+		if !food.IsAvailable {
+			return nil, models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "Food item is not available: "+food.Name, nil)
+		}
+		modifiers, modifiersTotal, err := resolveModifiers(food, item.Modifiers)
+		if err != nil {
+			return nil, models.Money{}, err
+		}
+
+		validatedItem := models.OrderItem{
+			FoodID:              food.ID,
+			FoodName:            food.Name,
+			Price:               food.Price,
+			UnitPrice:           food.Price.Add(modifiersTotal),
+			Quantity:            item.Quantity,
+			Modifiers:           modifiers,
+			SpecialInstructions: item.SpecialInstructions,
+		}
+		validatedItem.Total = validatedItem.UnitPrice.Mul(int64(item.Quantity))
+		subtotal = subtotal.Add(validatedItem.Total)
+		validated = append(validated, validatedItem)
+	}
+	return validated, subtotal, nil
+}
+
+func findParticipant(group *models.GroupOrder, userID string) *models.GroupOrderParticipant {
+	for i := range group.Participants {
+		if group.Participants[i].UserID == userID {
+			return &group.Participants[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"testing"
+
+	"dfood/internal/models"
+)
+
+func TestAllocateShares(t *testing.T) {
+	type participant struct {
+		subtotal int64
+		isHost   bool
+	}
+	tests := []struct {
+		name         string
+		participants []participant
+		total        int64
+		want         []int64
+	}{
+		{
+			name:         "fees split by subtotal",
+			participants: []participant{{subtotal: 1000, isHost: true}, {subtotal: 3000}},
+			total:        4800,
+			want:         []int64{1200, 3600},
+		},
+		{
+			name:         "rounding leftover goes to the host",
+			participants: []participant{{subtotal: 1000}, {subtotal: 1000, isHost: true}, {subtotal: 1000}},
+			total:        3100,
+			want:         []int64{1033, 1034, 1033},
+		},
+		{
+			name:         "discount reduces every share",
+			participants: []participant{{subtotal: 2000, isHost: true}, {subtotal: 2000}},
+			total:        3000,
+			want:         []int64{1500, 1500},
+		},
+		{
+			name:         "negative leftover taken from the host",
+			participants: []participant{{subtotal: 1000, isHost: true}, {subtotal: 1000}, {subtotal: 1000}},
+			total:        2900,
+			want:         []int64{966, 967, 967},
+		},
+		{
+			name:         "no extras",
+			participants: []participant{{subtotal: 1250, isHost: true}, {subtotal: 750}},
+			total:        2000,
+			want:         []int64{1250, 750},
+		},
+		{
+			name:         "participant with nothing ordered pays nothing",
+			participants: []participant{{subtotal: 2000, isHost: true}, {subtotal: 0}},
+			total:        2500,
+			want:         []int64{2500, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &models.GroupOrder{}
+			subtotal := models.Zero(models.CurrencyUSD)
+			for _, p := range tt.participants {
+				amount := models.NewMoney(p.subtotal, models.CurrencyUSD)
+				group.Participants = append(group.Participants, models.GroupOrderParticipant{Subtotal: amount, IsHost: p.isHost})
+				subtotal = subtotal.Add(amount)
+			}
+			order := &models.Order{
+				Subtotal: subtotal,
+				Total:    models.NewMoney(tt.total, models.CurrencyUSD),
+				Currency: models.CurrencyUSD,
+			}
+
+			allocateShares(group, order)
+
+			sum := int64(0)
+			for i, p := range group.Participants {
+				if p.Share.Amount != tt.want[i] {
+					t.Errorf("participant %d share = %d, want %d", i, p.Share.Amount, tt.want[i])
+				}
+				sum += p.Share.Amount
+			}
+			if sum != tt.total {
+				t.Errorf("shares add up to %d, want the order total %d", sum, tt.total)
+			}
+		})
+	}
+}
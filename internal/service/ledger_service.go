@@ -0,0 +1,590 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+)
+
+const (
+	defaultLedgerPageSize = 50
+	maxLedgerPageSize     = 200
+)
+
+type LedgerService interface {
+	RecordCapture(capture *models.PaymentTransaction) error
+	RecordRefund(refund *models.PaymentTransaction) error
+	RecordRefundReversal(refund *models.PaymentTransaction) error
+	RecordTopUp(topUp *models.PaymentTransaction) error
+	SpendWallet(payment *models.PaymentTransaction) error
+	RecordRefundShare(refund *models.PaymentTransaction, restaurantID string, share, commission models.Money) error
+	RecordPayout(payout *models.RestaurantPayout) error
+	SettleTips(order *models.Order) error
+	GrantStoreCredit(request *models.GrantStoreCreditRequest) (*models.LedgerJournal, error)
+	CreditReferral(referral *models.Referral) error
+	RecordGiftCardSale(payment *models.PaymentTransaction, giftCard *models.GiftCard) error
+	IssueGiftCard(giftCard *models.GiftCard) error
+	SpendGiftCard(payment *models.PaymentTransaction) error
+	RedeemGiftCard(giftCard *models.GiftCard, userID string, amount models.Money) error
+	ExpireGiftCard(giftCard *models.GiftCard, amount models.Money) error
+	GetGiftCardBalance(giftCard *models.GiftCard) (models.Money, error)
+	RecordMembershipFee(payment *models.PaymentTransaction) error
+	GetWallet(userID string, limit, offset int) (*models.Wallet, error)
+	GetWalletBalance(userID string, currency models.Currency) (models.Money, error)
+	GetAccountBalance(accountType models.LedgerAccountType, ownerID string, limit, offset int) (*models.LedgerAccountBalance, error)
+	CheckInvariants() (*models.LedgerCheck, error)
+}
+
+type ledgerService struct {
+	ledgerRepo repository.LedgerRepository
+	orderRepo  repository.OrderRepository
+	payoutRepo repository.PayoutRepository
+	config     config.LedgerConfig
+}
+
+func NewLedgerService(ledgerRepo repository.LedgerRepository, orderRepo repository.OrderRepository, payoutRepo repository.PayoutRepository, cfg config.LedgerConfig) LedgerService {
+	return &ledgerService{
+		ledgerRepo: ledgerRepo,
+		orderRepo:  orderRepo,
+		payoutRepo: payoutRepo,
+		config:     cfg,
+	}
+}
+
+// orderRevenue is how one capture of an order divides between the parties owed a share of it
+type orderRevenue struct {
+	restaurant models.Money // Food and drink, net of any tax included in menu prices
+	fees       models.Money // Delivery and small-order fees
+	tax        models.Money // Inclusive and exclusive tax
+	tip        models.Money // Held for the courier until settlement
+}
+
+func (r orderRevenue) total() models.Money {
+	return r.restaurant.Add(r.fees).Add(r.tax).Add(r.tip)
+}
+
+// RecordCapture posts collected card funds against the restaurant, our fees, tax payable and tips payable,
+// followed by our commission on the restaurant's share
+func (s *ledgerService) RecordCapture(capture *models.PaymentTransaction) error {
+	order, err := s.orderRepo.GetByID(capture.OrderID)
+	if err != nil {
+		return err
+	}
+	journal, revenue, err := s.captureJournal(order, capture, models.LedgerAccountGateway, "", "Payment captured for order "+order.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.post(journal); err != nil {
+		return err
+	}
+	return s.postCommission(order, capture, revenue)
+}
+
+// SpendWallet posts a payment from a customer's wallet, split like a capture. The balance is checked
+// in the same database transaction as the posting, and a 402 is returned if it no longer covers the payment.
+func (s *ledgerService) SpendWallet(payment *models.PaymentTransaction) error {
+	order, err := s.orderRepo.GetByID(payment.OrderID)
+	if err != nil {
+		return err
+	}
+	journal, revenue, err := s.captureJournal(order, payment, models.LedgerAccountCustomer, payment.UserID, "Wallet payment for order "+order.ID)
+	if err != nil {
+		return err
+	}
+	if err := journal.checkBalanced(); err != nil {
+		return err
+	}
+	if err := s.ledgerRepo.PostIfCovered(&journal.LedgerJournal, models.LedgerAccountCustomer, payment.UserID, payment.Amount); err != nil {
+		return err
+	}
+	return s.postCommission(order, payment, revenue)
+}
+
+// SpendGiftCard posts a payment from a gift card, split like a capture. As with the wallet, the card's
+// balance is checked in the same database transaction as the posting, and a 402 is returned if it falls short.
+func (s *ledgerService) SpendGiftCard(payment *models.PaymentTransaction) error {
+	if payment.GiftCardID == nil {
+		return errors.NewHTTPError(http.StatusBadRequest, "Gift card payment has no gift card", nil)
+	}
+	order, err := s.orderRepo.GetByID(payment.OrderID)
+	if err != nil {
+		return err
+	}
+	journal, revenue, err := s.captureJournal(order, payment, models.LedgerAccountGiftCards, *payment.GiftCardID, "Gift card payment for order "+order.ID)
+	if err != nil {
+		return err
+	}
+	if err := journal.checkBalanced(); err != nil {
+		return err
+	}
+	if err := s.ledgerRepo.PostIfCovered(&journal.LedgerJournal, models.LedgerAccountGiftCards, *payment.GiftCardID, payment.Amount); err != nil {
+		return err
+	}
+	return s.postCommission(order, payment, revenue)
+}
+
+// postCommission posts our commission on the restaurant's share of a capture or wallet payment: the
+// restaurant's rule, or the default rate without one. A rule's fixed fee is spread over the order's
+// payments in proportion to their size, and the commission never exceeds the restaurant's share.
+func (s *ledgerService) postCommission(order *models.Order, payment *models.PaymentTransaction, revenue orderRevenue) error {
+	rule, err := s.payoutRepo.GetCommissionRule(order.RestaurantID)
+	if err != nil {
+		return err
+	}
+	commission := revenue.restaurant.Percent(s.config.CommissionBasisPoints)
+	if rule != nil {
+		commission = revenue.restaurant.Percent(rule.BasisPoints)
+		// Cancellation fees carry no per-order fee
+		if rule.FixedFee.Currency == order.Currency && order.Status != models.OrderStatusCancelled && order.Total.IsPositive() {
+			commission = commission.Add(rule.FixedFee.MulRat(payment.Amount.Amount, order.Total.Amount))
+		}
+	}
+	commission = commission.Min(revenue.restaurant)
+	if !commission.IsPositive() {
+		return nil
+	}
+	journal := newLedgerJournal(models.LedgerJournalCommission, payment, "Commission on order "+order.ID)
+	journal.addEntry(models.LedgerAccountRestaurant, order.RestaurantID, commission)
+	journal.addEntry(models.LedgerAccountPlatformFees, "", commission.Neg())
+	return s.post(journal)
+}
+
+// RecordRefund posts money returned to a customer, either to their card or to their wallet
+func (s *ledgerService) RecordRefund(refund *models.PaymentTransaction) error {
+	if refund.PaymentMethodID == models.WalletPaymentMethodID {
+		journal := newLedgerJournal(models.LedgerJournalRefund, refund, "Refund to wallet for order "+refund.OrderID)
+		journal.addEntry(models.LedgerAccountRefunds, "", refund.Amount)
+		journal.addEntry(models.LedgerAccountCustomer, refund.UserID, refund.Amount.Neg())
+		return s.post(journal)
+	}
+
+	journal := newLedgerJournal(models.LedgerJournalRefund, refund, "Refund for order "+refund.OrderID)
+	journal.addEntry(models.LedgerAccountRefunds, "", refund.Amount)
+	journal.addEntry(models.LedgerAccountGateway, "", refund.Amount.Neg())
+	return s.post(journal)
+}
+
+// RecordRefundReversal undoes a refund the gateway reported it could not deliver
+func (s *ledgerService) RecordRefundReversal(refund *models.PaymentTransaction) error {
+	journal := newLedgerJournal(models.LedgerJournalRefundReversal, refund, "Failed refund for order "+refund.OrderID)
+	journal.addEntry(models.LedgerAccountGateway, "", refund.Amount)
+	journal.addEntry(models.LedgerAccountRefunds, "", refund.Amount.Neg())
+	return s.post(journal)
+}
+
+// RecordTopUp posts card funds added to a customer's wallet
+func (s *ledgerService) RecordTopUp(topUp *models.PaymentTransaction) error {
+	journal := newLedgerJournal(models.LedgerJournalWalletTopUp, topUp, "Wallet top-up")
+	journal.addEntry(models.LedgerAccountGateway, "", topUp.Amount)
+	journal.addEntry(models.LedgerAccountCustomer, topUp.UserID, topUp.Amount.Neg())
+	return s.post(journal)
+}
+
+// RecordMembershipFee posts card funds paid for a membership period, which are ours in full
+func (s *ledgerService) RecordMembershipFee(payment *models.PaymentTransaction) error {
+	journal := newLedgerJournal(models.LedgerJournalMembershipFee, payment, "Membership fee")
+	journal.addEntry(models.LedgerAccountGateway, "", payment.Amount)
+	journal.addEntry(models.LedgerAccountPlatformFees, "", payment.Amount.Neg())
+	return s.post(journal)
+}
+
+// RecordGiftCardSale posts card funds paid for a gift card, which we then owe to whoever holds its code
+func (s *ledgerService) RecordGiftCardSale(payment *models.PaymentTransaction, giftCard *models.GiftCard) error {
+	journal := newLedgerJournal(models.LedgerJournalGiftCardSale, payment, "Gift card ending in "+giftCard.Last4)
+	journal.addEntry(models.LedgerAccountGateway, "", payment.Amount)
+	journal.addEntry(models.LedgerAccountGiftCards, giftCard.ID, payment.Amount.Neg())
+	return s.post(journal)
+}
+
+// IssueGiftCard funds a promotional gift card at our expense
+func (s *ledgerService) IssueGiftCard(giftCard *models.GiftCard) error {
+	journal := newReferenceJournal(models.LedgerJournalGiftCardIssue, giftCard.ID, nil, "Promotional gift card ending in "+giftCard.Last4, giftCard.Currency)
+	journal.addEntry(models.LedgerAccountPlatformFees, "", giftCard.InitialAmount)
+	journal.addEntry(models.LedgerAccountGiftCards, giftCard.ID, giftCard.InitialAmount.Neg())
+	return s.post(journal)
+}
+
+// RedeemGiftCard moves amount, the gift card's whole balance, to the customer's wallet. A card is redeemed
+// once, and the balance is checked in the same database transaction so a concurrent checkout cannot spend it twice.
+func (s *ledgerService) RedeemGiftCard(giftCard *models.GiftCard, userID string, amount models.Money) error {
+	journal := newReferenceJournal(models.LedgerJournalGiftCardRedeem, giftCard.ID, nil, "Gift card ending in "+giftCard.Last4+" redeemed", amount.Currency)
+	journal.addEntry(models.LedgerAccountGiftCards, giftCard.ID, amount)
+	journal.addEntry(models.LedgerAccountCustomer, userID, amount.Neg())
+	if err := journal.checkBalanced(); err != nil {
+		return err
+	}
+	return s.ledgerRepo.PostIfCovered(&journal.LedgerJournal, models.LedgerAccountGiftCards, giftCard.ID, amount)
+}
+
+// ExpireGiftCard takes back the unspent balance of an expired gift card
+func (s *ledgerService) ExpireGiftCard(giftCard *models.GiftCard, amount models.Money) error {
+	journal := newReferenceJournal(models.LedgerJournalGiftCardExpiry, giftCard.ID, nil, "Gift card ending in "+giftCard.Last4+" expired", amount.Currency)
+	journal.addEntry(models.LedgerAccountGiftCards, giftCard.ID, amount)
+	journal.addEntry(models.LedgerAccountPlatformFees, "", amount.Neg())
+	return s.post(journal)
+}
+
+// RecordRefundShare charges a restaurant its part of a refund when the refund is settled on a payout,
+// handing back the commission we took on that part
+func (s *ledgerService) RecordRefundShare(refund *models.PaymentTransaction, restaurantID string, share, commission models.Money) error {
+	journal := newLedgerJournal(models.LedgerJournalRefundShare, refund, "Restaurant share of refund for order "+refund.OrderID)
+	journal.addEntry(models.LedgerAccountRestaurant, restaurantID, share.Sub(commission))
+	journal.addEntry(models.LedgerAccountPlatformFees, "", commission)
+	journal.addEntry(models.LedgerAccountRefunds, "", share.Neg())
+	return s.post(journal)
+}
+
+// RecordPayout posts money transferred to a restaurant, clearing what we owed it
+func (s *ledgerService) RecordPayout(payout *models.RestaurantPayout) error {
+	journal := newReferenceJournal(models.LedgerJournalPayout, payout.ID, nil, "Payout to restaurant "+payout.RestaurantID, payout.Currency)
+	journal.addEntry(models.LedgerAccountRestaurant, payout.RestaurantID, payout.Net)
+	journal.addEntry(models.LedgerAccountGateway, "", payout.Net.Neg())
+	return s.post(journal)
+}
+
+// SettleTips moves the tips collected on a delivered order from tips payable to its courier. Each capture's
+// tip is settled under the capture's reference, so a tip raised after an earlier settlement is picked up
+// by the next one and settling the same order twice posts nothing new.
+func (s *ledgerService) SettleTips(order *models.Order) error {
+	if order.CourierID == nil || *order.CourierID == "" {
+		return errors.NewHTTPError(http.StatusConflict, "Order "+order.ID+" has no courier to settle its tip to", nil)
+	}
+	journals, err := s.ledgerRepo.GetJournalsByOrderID(order.ID)
+	if err != nil {
+		return err
+	}
+	for _, capture := range journals {
+		if capture.Type != models.LedgerJournalCapture {
+			continue
+		}
+		tip := models.Zero(capture.Currency)
+		for _, entry := range capture.Entries {
+			if entry.AccountType == models.LedgerAccountTipsPayable {
+				tip = tip.Sub(entry.Amount)
+			}
+		}
+		if !tip.IsPositive() {
+			continue
+		}
+		journal := newReferenceJournal(models.LedgerJournalCourierTip, capture.Reference, &order.ID, "Tip for order "+order.ID, capture.Currency)
+		journal.addEntry(models.LedgerAccountTipsPayable, "", tip)
+		journal.addEntry(models.LedgerAccountCourier, *order.CourierID, tip.Neg())
+		if err := s.post(journal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GrantStoreCredit credits a customer's wallet at our expense, for example to make up for a late order
+func (s *ledgerService) GrantStoreCredit(request *models.GrantStoreCreditRequest) (*models.LedgerJournal, error) {
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	if strings.TrimSpace(request.Reason) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "A reason for the credit is required", nil)
+	}
+	if !request.Amount.IsPositive() {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Store credit amount must be positive", nil)
+	}
+
+	journal := newReferenceJournal(models.LedgerJournalStoreCredit, "", nil, "Store credit from "+request.AgentID+": "+strings.TrimSpace(request.Reason), request.Amount.Currency)
+	journal.Reference = journal.ID
+	journal.addEntry(models.LedgerAccountPlatformFees, "", request.Amount)
+	journal.addEntry(models.LedgerAccountCustomer, request.UserID, request.Amount.Neg())
+	if err := s.post(journal); err != nil {
+		return nil, err
+	}
+	return &journal.LedgerJournal, nil
+}
+
+// CreditReferral credits both sides of a converted referral to their wallets at our expense. Each credit
+// is posted under the referral and the user it pays, so crediting the same referral twice posts nothing new.
+func (s *ledgerService) CreditReferral(referral *models.Referral) error {
+	credits := []struct {
+		userID      string
+		amount      models.Money
+		description string
+	}{
+		{referral.ReferrerID, referral.ReferrerCredit, "Referral credit for inviting " + referral.RefereeID},
+		{referral.RefereeID, referral.RefereeCredit, "Referral credit for joining with code " + referral.Code},
+	}
+	for _, credit := range credits {
+		if !credit.amount.IsPositive() {
+			continue
+		}
+		journal := newReferenceJournal(models.LedgerJournalReferral, referral.ID+":"+credit.userID, referral.OrderID, credit.description, credit.amount.Currency)
+		journal.addEntry(models.LedgerAccountPlatformFees, "", credit.amount)
+		journal.addEntry(models.LedgerAccountCustomer, credit.userID, credit.amount.Neg())
+		if err := s.post(journal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetWallet returns a customer's wallet, which is their ledger account seen from their side
+func (s *ledgerService) GetWallet(userID string, limit, offset int) (*models.Wallet, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	if limit <= 0 {
+		limit = defaultLedgerPageSize
+	}
+	limit = min(limit, maxLedgerPageSize)
+	offset = max(offset, 0)
+
+	balances, err := s.ledgerRepo.GetBalances(models.LedgerAccountCustomer, userID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.ledgerRepo.GetActivity(models.LedgerAccountCustomer, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range balances {
+		balances[i] = balances[i].Neg()
+	}
+	for i := range history {
+		history[i].Amount = history[i].Amount.Neg()
+	}
+	return &models.Wallet{
+		UserID:   userID,
+		Balances: balances,
+		History:  history,
+	}, nil
+}
+
+// GetWalletBalance returns what a customer can spend from their wallet in one currency
+func (s *ledgerService) GetWalletBalance(userID string, currency models.Currency) (models.Money, error) {
+	balances, err := s.ledgerRepo.GetBalances(models.LedgerAccountCustomer, userID)
+	if err != nil {
+		return models.Zero(currency), err
+	}
+	for _, balance := range balances {
+		if balance.Currency == currency {
+			return balance.Neg(), nil
+		}
+	}
+	return models.Zero(currency), nil
+}
+
+// GetGiftCardBalance returns what is left to spend on a gift card
+func (s *ledgerService) GetGiftCardBalance(giftCard *models.GiftCard) (models.Money, error) {
+	balances, err := s.ledgerRepo.GetBalances(models.LedgerAccountGiftCards, giftCard.ID)
+	if err != nil {
+		return models.Zero(giftCard.Currency), err
+	}
+	for _, balance := range balances {
+		if balance.Currency == giftCard.Currency {
+			return balance.Neg(), nil
+		}
+	}
+	return models.Zero(giftCard.Currency), nil
+}
+
+func (s *ledgerService) GetAccountBalance(accountType models.LedgerAccountType, ownerID string, limit, offset int) (*models.LedgerAccountBalance, error) {
+	if !accountType.IsValid() {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid ledger account type", nil)
+	}
+	ownerID = strings.TrimSpace(ownerID)
+	if accountType.HasOwner() && ownerID == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Owner ID is required for "+string(accountType)+" accounts", nil)
+	}
+	if !accountType.HasOwner() {
+		ownerID = ""
+	}
+	if limit <= 0 {
+		limit = defaultLedgerPageSize
+	}
+	limit = min(limit, maxLedgerPageSize)
+	offset = max(offset, 0)
+
+	balances, err := s.ledgerRepo.GetBalances(accountType, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.ledgerRepo.GetEntries(accountType, ownerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LedgerAccountBalance{
+		AccountType: accountType,
+		OwnerID:     ownerID,
+		Balances:    balances,
+		Entries:     entries,
+	}, nil
+}
+
+// CheckInvariants verifies that every journal and the ledger as a whole sum to zero, and that every
+// capture, wallet or gift card payment, top-up, gift card sale and refund that moved money has been posted
+func (s *ledgerService) CheckInvariants() (*models.LedgerCheck, error) {
+	journals, err := s.ledgerRepo.CountJournals()
+	if err != nil {
+		return nil, err
+	}
+	imbalances, err := s.ledgerRepo.GetUnbalancedJournals()
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.ledgerRepo.GetTotals()
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range totals {
+		if !total.IsZero() {
+			imbalances = append(imbalances, models.LedgerImbalance{Amount: total})
+		}
+	}
+	unposted, err := s.ledgerRepo.GetUnpostedTransactionIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LedgerCheck{
+		Balanced:             len(imbalances) == 0,
+		Journals:             journals,
+		Imbalances:           imbalances,
+		UnpostedTransactions: unposted,
+	}, nil
+}
+
+// post refuses to store a journal whose entries do not balance
+func (s *ledgerService) post(journal *ledgerJournalBuilder) error {
+	if err := journal.checkBalanced(); err != nil {
+		return err
+	}
+	_, err := s.ledgerRepo.Post(&journal.LedgerJournal)
+	return err
+}
+
+// ledgerJournalBuilder collects the entries of a journal before it is posted
+type ledgerJournalBuilder struct {
+	models.LedgerJournal
+}
+
+func newLedgerJournal(journalType models.LedgerJournalType, transaction *models.PaymentTransaction, description string) *ledgerJournalBuilder {
+	// Wallet top-ups are not tied to an order
+	var orderID *string
+	if transaction.OrderID != "" {
+		orderID = &transaction.OrderID
+	}
+	return newReferenceJournal(journalType, transaction.ID, orderID, description, transaction.Currency)
+}
+
+func newReferenceJournal(journalType models.LedgerJournalType, reference string, orderID *string, description string, currency models.Currency) *ledgerJournalBuilder {
+	return &ledgerJournalBuilder{models.LedgerJournal{
+		ID:          utils.GenerateLedgerJournalID(),
+		Type:        journalType,
+		Reference:   reference,
+		OrderID:     orderID,
+		Description: description,
+		Currency:    currency,
+	}}
+}
+
+// captureJournal builds the journal for money collected towards an order from the given funding account,
+// returning the revenue split so the commission can be posted after it
+func (s *ledgerService) captureJournal(order *models.Order, payment *models.PaymentTransaction, funding models.LedgerAccountType, fundingOwner, description string) (*ledgerJournalBuilder, orderRevenue, error) {
+	collected, err := s.collectedRevenue(order)
+	if err != nil {
+		return nil, orderRevenue{}, err
+	}
+	revenue := splitOrderRevenue(order, payment.Amount, collected)
+	journal := newLedgerJournal(models.LedgerJournalCapture, payment, description)
+	journal.addEntry(funding, fundingOwner, payment.Amount)
+	journal.addEntry(models.LedgerAccountRestaurant, order.RestaurantID, revenue.restaurant.Neg())
+	journal.addEntry(models.LedgerAccountPlatformFees, "", revenue.fees.Neg())
+	journal.addEntry(models.LedgerAccountTaxPayable, "", revenue.tax.Neg())
+	journal.addEntry(models.LedgerAccountTipsPayable, "", revenue.tip.Neg())
+	return journal, revenue, nil
+}
+
+// collectedRevenue sums what earlier captures and wallet payments of an order have posted to each party
+func (s *ledgerService) collectedRevenue(order *models.Order) (orderRevenue, error) {
+	zero := models.Zero(order.Currency)
+	collected := orderRevenue{restaurant: zero, fees: zero, tax: zero, tip: zero}
+	journals, err := s.ledgerRepo.GetJournalsByOrderID(order.ID)
+	if err != nil {
+		return collected, err
+	}
+	for _, journal := range journals {
+		if journal.Type != models.LedgerJournalCapture || journal.Currency != order.Currency {
+			continue
+		}
+		for _, entry := range journal.Entries {
+			switch entry.AccountType {
+			case models.LedgerAccountRestaurant:
+				collected.restaurant = collected.restaurant.Sub(entry.Amount)
+			case models.LedgerAccountPlatformFees:
+				collected.fees = collected.fees.Sub(entry.Amount)
+			case models.LedgerAccountTaxPayable:
+				collected.tax = collected.tax.Sub(entry.Amount)
+			case models.LedgerAccountTipsPayable:
+				collected.tip = collected.tip.Sub(entry.Amount)
+			}
+		}
+	}
+	return collected, nil
+}
+
+// checkBalanced reports an error if the journal's entries do not sum to zero
+func (j *ledgerJournalBuilder) checkBalanced() error {
+	total := models.Zero(j.Currency)
+	for _, entry := range j.Entries {
+		total = total.Add(entry.Amount)
+	}
+	if !total.IsZero() {
+		return errors.NewHTTPError(http.StatusInternalServerError, "Ledger journal "+string(j.Type)+" for "+j.Reference+" is off by "+total.String(), nil)
+	}
+	return nil
+}
+
+// addEntry appends an entry, skipping zero amounts that would only clutter account histories
+func (j *ledgerJournalBuilder) addEntry(accountType models.LedgerAccountType, ownerID string, amount models.Money) {
+	if amount.IsZero() {
+		return
+	}
+	j.Entries = append(j.Entries, models.LedgerEntry{
+		ID:          utils.GenerateLedgerEntryID(),
+		AccountType: accountType,
+		OwnerID:     ownerID,
+		Amount:      amount,
+		Currency:    j.Currency,
+	})
+}
+
+// splitOrderRevenue divides a captured amount over what earlier captures have left uncollected of each part
+// of the order, in proportion to those remainders and leaving rounding to the restaurant. A tip raised after
+// the rest was collected therefore goes to tips payable alone. Anything captured on a cancelled order is a
+// cancellation fee owed to the restaurant.
+func splitOrderRevenue(order *models.Order, amount models.Money, collected orderRevenue) orderRevenue {
+	zero := models.Zero(amount.Currency)
+	if order.Status == models.OrderStatusCancelled || !order.Total.IsPositive() {
+		return orderRevenue{restaurant: amount, fees: zero, tax: zero, tip: zero}
+	}
+
+	// The delivery, small order and service fees are ours. Waived delivery fees and loyalty points are the
+	// platform's own give-aways and come out of our fees, which can leave them negative; promo code discounts
+	// on items come out of the restaurant's share
+	orderFees := order.DeliveryFee.Add(order.SmallOrderFee).Add(order.ServiceFee).Sub(order.DiscountLines.PlatformFunded(order.Currency))
+	remaining := orderRevenue{
+		restaurant: order.Total.Sub(orderFees).Sub(order.Tax).Sub(order.Tip).Sub(collected.restaurant).Max(zero),
+		fees:       orderFees.Sub(collected.fees),
+		tax:        order.Tax.Sub(collected.tax).Max(zero),
+		tip:        order.Tip.Sub(collected.tip).Max(zero),
+	}
+	split := remaining
+	if outstanding := remaining.total(); amount.Cmp(outstanding) < 0 {
+		split.fees = remaining.fees.MulRat(amount.Amount, outstanding.Amount)
+		split.tax = remaining.tax.MulRat(amount.Amount, outstanding.Amount)
+		split.tip = remaining.tip.MulRat(amount.Amount, outstanding.Amount)
+	}
+	split.restaurant = amount.Sub(split.fees).Sub(split.tax).Sub(split.tip)
+	return split
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"testing"
+
+	"dfood/internal/models"
+)
+
+func usd(amount int64) models.Money {
+	return models.NewMoney(amount, models.CurrencyUSD)
+}
+
+func TestLedgerJournalCheckBalanced(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []int64
+		wantErr bool
+	}{
+		{name: "two legs", entries: []int64{1000, -1000}},
+		{name: "split across accounts", entries: []int64{2500, -2000, -300, -150, -50}},
+		{name: "no entries", entries: nil},
+		{name: "off by one cent", entries: []int64{1000, -999}, wantErr: true},
+		{name: "one-sided", entries: []int64{1000}, wantErr: true},
+		{name: "both sides doubled up", entries: []int64{1000, 1000, -1000}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			journal := newReferenceJournal(models.LedgerJournalCapture, "txn_test", nil, "test", models.CurrencyUSD)
+			for _, amount := range tt.entries {
+				journal.Entries = append(journal.Entries, models.LedgerEntry{Amount: usd(amount), Currency: models.CurrencyUSD})
+			}
+
+			err := journal.checkBalanced()
+			if tt.wantErr && err == nil {
+				t.Errorf("checkBalanced() = nil, want error for entries %v", tt.entries)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("checkBalanced() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestLedgerJournalAddEntrySkipsZero(t *testing.T) {
+	journal := newReferenceJournal(models.LedgerJournalCapture, "txn_test", nil, "test", models.CurrencyUSD)
+	journal.addEntry(models.LedgerAccountPlatformFees, "", usd(0))
+	journal.addEntry(models.LedgerAccountRestaurant, "restaurant-1", usd(-500))
+	if len(journal.Entries) != 1 {
+		t.Fatalf("journal has %d entries, want 1", len(journal.Entries))
+	}
+	if entry := journal.Entries[0]; entry.OwnerID != "restaurant-1" || entry.Currency != models.CurrencyUSD {
+		t.Errorf("entry = %+v, want restaurant-1 in USD", entry)
+	}
+}
+
+func TestSplitOrderRevenue(t *testing.T) {
+	// Items 2000, delivery 300, service 100, tax 160 and tip 240 make a total of 2800
+	order := &models.Order{
+		Status:      models.OrderStatusConfirmed,
+		Currency:    models.CurrencyUSD,
+		Subtotal:    usd(2000),
+		DeliveryFee: usd(300),
+		ServiceFee:  usd(100),
+		Tax:         usd(160),
+		Tip:         usd(240),
+		Total:       usd(2800),
+	}
+	none := orderRevenue{restaurant: usd(0), fees: usd(0), tax: usd(0), tip: usd(0)}
+
+	tests := []struct {
+		name      string
+		order     *models.Order
+		amount    int64
+		collected orderRevenue
+		want      orderRevenue
+	}{
+		{
+			name:      "full capture",
+			order:     order,
+			amount:    2800,
+			collected: none,
+			want:      orderRevenue{restaurant: usd(2000), fees: usd(400), tax: usd(160), tip: usd(240)},
+		},
+		{
+			name:      "half capture splits proportionally",
+			order:     order,
+			amount:    1400,
+			collected: none,
+			want:      orderRevenue{restaurant: usd(1000), fees: usd(200), tax: usd(80), tip: usd(120)},
+		},
+		{
+			name:      "rounding left to the restaurant",
+			order:     order,
+			amount:    1001,
+			collected: none,
+			want:      orderRevenue{restaurant: usd(715), fees: usd(143), tax: usd(57), tip: usd(86)},
+		},
+		{
+			name:      "second capture takes what is left",
+			order:     order,
+			amount:    1400,
+			collected: orderRevenue{restaurant: usd(1000), fees: usd(200), tax: usd(80), tip: usd(120)},
+			want:      orderRevenue{restaurant: usd(1000), fees: usd(200), tax: usd(80), tip: usd(120)},
+		},
+		{
+			name:      "tip raised after collection goes to tips alone",
+			order:     &models.Order{Status: models.OrderStatusDelivered, Currency: models.CurrencyUSD, Subtotal: usd(2000), DeliveryFee: usd(300), ServiceFee: usd(100), Tax: usd(160), Tip: usd(500), Total: usd(3060)},
+			amount:    260,
+			collected: orderRevenue{restaurant: usd(2000), fees: usd(400), tax: usd(160), tip: usd(240)},
+			want:      orderRevenue{restaurant: usd(0), fees: usd(0), tax: usd(0), tip: usd(260)},
+		},
+		{
+			name:      "cancelled order fee goes to the restaurant",
+			order:     &models.Order{Status: models.OrderStatusCancelled, Currency: models.CurrencyUSD, Subtotal: usd(2000), DeliveryFee: usd(300), Tax: usd(160), Total: usd(2460)},
+			amount:    200,
+			collected: none,
+			want:      orderRevenue{restaurant: usd(200), fees: usd(0), tax: usd(0), tip: usd(0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitOrderRevenue(tt.order, usd(tt.amount), tt.collected)
+			if got != tt.want {
+				t.Errorf("splitOrderRevenue = %+v, want %+v", got, tt.want)
+			}
+			if total := got.total(); total.Amount != tt.amount {
+				t.Errorf("split adds up to %d, want %d", total.Amount, tt.amount)
+			}
+		})
+	}
+}
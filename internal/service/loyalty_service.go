@@ -0,0 +1,431 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+)
+
+const (
+	defaultEarnPointsPerUnit   = 10
+	defaultRedeemPointsPerUnit = 100
+	defaultMinRedeemPoints     = 500
+	defaultPointsExpiryDays    = 365
+	defaultTierWindowDays      = 365
+	defaultLoyaltyPageSize     = 50
+	maxLoyaltyPageSize         = 200
+
+	pointsExpiryPerAccrual = "per_accrual"
+	pointsExpiryInactivity = "inactivity"
+	pointsExpiryNone       = "none"
+)
+
+// loyaltyTiers lists the tiers from lowest to highest
+var loyaltyTiers = []models.LoyaltyTier{models.LoyaltyTierBronze, models.LoyaltyTierSilver, models.LoyaltyTierGold}
+
+// defaultTierConfigs apply when the config does not define the tiers
+var defaultTierConfigs = map[string]config.TierConfig{
+	string(models.LoyaltyTierBronze): {MinPoints: 0, EarnMultiplierBasisPoints: 10000},
+	string(models.LoyaltyTierSilver): {MinPoints: 2000, EarnMultiplierBasisPoints: 12500},
+	string(models.LoyaltyTierGold):   {MinPoints: 5000, EarnMultiplierBasisPoints: 15000, FreeDelivery: true},
+}
+
+type LoyaltyService interface {
+	GetLoyalty(userID string, limit, offset int) (*models.LoyaltyAccount, error)
+	SetMultiplier(restaurantID string, request *models.SetLoyaltyMultiplierRequest) (*models.LoyaltyMultiplier, error)
+	ApplyLoyalty(order *models.Order, lines []models.DiscountableLine, discount *models.PromotionDiscount) error
+	RedeemPoints(order *models.Order) error
+	RestorePoints(orderID string) error
+	AccrueOrder(order *models.Order) error
+}
+
+type loyaltyService struct {
+	loyaltyRepo    repository.LoyaltyRepository
+	userRepo       repository.UserRepository
+	restaurantRepo repository.RestaurantRepository
+	config         config.LoyaltyConfig
+}
+
+func NewLoyaltyService(loyaltyRepo repository.LoyaltyRepository, userRepo repository.UserRepository, restaurantRepo repository.RestaurantRepository, cfg config.LoyaltyConfig) LoyaltyService {
+	return &loyaltyService{
+		loyaltyRepo:    loyaltyRepo,
+		userRepo:       userRepo,
+		restaurantRepo: restaurantRepo,
+		config:         cfg,
+	}
+}
+
+func (s *loyaltyService) GetLoyalty(userID string, limit, offset int) (*models.LoyaltyAccount, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultLoyaltyPageSize
+	}
+	limit = min(limit, maxLoyaltyPageSize)
+	offset = max(offset, 0)
+
+	balance, err := s.balance(userID)
+	if err != nil {
+		return nil, err
+	}
+	tier, tierPoints, err := s.tier(userID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.loyaltyRepo.GetEntries(userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	tierConfig := s.tierConfig(tier)
+	account := &models.LoyaltyAccount{
+		UserID:       userID,
+		Balance:      balance,
+		BalanceValue: s.pointsValue(balance, models.DefaultCurrency),
+		Tier:         tier,
+		TierPoints:   tierPoints,
+		Perks: models.LoyaltyPerks{
+			EarnMultiplierBasisPoints: tierConfig.EarnMultiplierBasisPoints,
+			FreeDelivery:              tierConfig.FreeDelivery,
+		},
+		MinRedeemPoints: s.minRedeemPoints(),
+		History:         history,
+	}
+	for i, candidate := range loyaltyTiers[:len(loyaltyTiers)-1] {
+		if candidate == tier {
+			next := loyaltyTiers[i+1]
+			account.NextTier = &next
+			account.PointsToNextTier = s.tierConfig(next).MinPoints - tierPoints
+		}
+	}
+	if account.History == nil {
+		account.History = []models.LoyaltyEntry{}
+	}
+
+	lot, err := s.loyaltyRepo.GetNextExpiringLot(userID)
+	if err != nil {
+		return nil, err
+	}
+	if lot != nil {
+		account.NextExpiryAt = lot.ExpiresAt
+		account.NextExpiryPoints = lot.Remaining
+	}
+	if s.expiryPolicy() == pointsExpiryInactivity && balance > 0 {
+		lastActivity, err := s.loyaltyRepo.GetLastActivityAt(userID)
+		if err != nil {
+			return nil, err
+		}
+		if lastActivity != nil {
+			expiresAt := lastActivity.Add(s.expiryWindow())
+			account.NextExpiryAt = &expiresAt
+			account.NextExpiryPoints = balance
+		}
+	}
+	return account, nil
+}
+
+func (s *loyaltyService) SetMultiplier(restaurantID string, request *models.SetLoyaltyMultiplierRequest) (*models.LoyaltyMultiplier, error) {
+	if strings.TrimSpace(restaurantID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Restaurant ID is required", nil)
+	}
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Loyalty multiplier is required", nil)
+	}
+	if request.BasisPoints < 10000 || request.BasisPoints > 100000 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Points multiplier must be between 10000 and 100000 basis points", nil)
+	}
+	if request.StartsAt != nil && request.EndsAt != nil && !request.EndsAt.After(*request.StartsAt) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Points multiplier must end after it starts", nil)
+	}
+	if _, err := s.restaurantRepo.GetByID(restaurantID); err != nil {
+		return nil, err
+	}
+
+	multiplier := &models.LoyaltyMultiplier{
+		RestaurantID: restaurantID,
+		BasisPoints:  request.BasisPoints,
+		StartsAt:     request.StartsAt,
+		EndsAt:       request.EndsAt,
+		UpdatedBy:    request.AgentID,
+	}
+	if err := s.loyaltyRepo.SaveMultiplier(multiplier); err != nil {
+		return nil, err
+	}
+	return multiplier, nil
+}
+
+// ApplyLoyalty adds the customer's loyalty benefits to an order's discount: free delivery for tiers that have
+// it, and the points they chose to spend, taken off the items that promo codes left something of. Points are
+// only checked against the balance here; RedeemPoints spends them once the order is placed.
+func (s *loyaltyService) ApplyLoyalty(order *models.Order, lines []models.DiscountableLine, discount *models.PromotionDiscount) error {
+	if order.RedeemPoints < 0 {
+		return errors.NewHTTPError(http.StatusBadRequest, "Points to redeem cannot be negative", nil)
+	}
+
+	tier, _, err := s.tier(order.UserID)
+	if err != nil {
+		return err
+	}
+	if s.tierConfig(tier).FreeDelivery {
+		if waived := order.DeliveryFee.Sub(discount.Delivery).Max(models.Zero(order.Currency)); waived.IsPositive() {
+			discount.Delivery = discount.Delivery.Add(waived)
+			discount.Total = discount.Total.Add(waived)
+			discount.Lines = append(discount.Lines, models.DiscountLine{
+				Description: fmt.Sprintf("Free delivery for %s members", tier),
+				Type:        models.PromotionFreeDelivery,
+				Amount:      waived,
+			})
+		}
+	}
+
+	if order.RedeemPoints == 0 {
+		return nil
+	}
+	if order.RedeemPoints < s.minRedeemPoints() {
+		return errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("At least %d points must be redeemed at a time", s.minRedeemPoints()), nil)
+	}
+	balance, err := s.balance(order.UserID)
+	if err != nil {
+		return err
+	}
+	if order.RedeemPoints > balance {
+		return errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("You have %d points to redeem", balance), nil)
+	}
+
+	remaining := make([]models.Money, len(lines))
+	payable := models.Zero(order.Currency)
+	for i, line := range lines {
+		remaining[i] = line.Amount.Sub(discount.ItemDiscounts[i]).Max(models.Zero(order.Currency))
+		payable = payable.Add(remaining[i])
+	}
+	// Spend no more points than the items still cost
+	unit := currencyUnit(order.Currency)
+	order.RedeemPoints = min(order.RedeemPoints, payable.Amount*s.redeemPointsPerUnit()/unit.Amount)
+	value := s.pointsValue(order.RedeemPoints, order.Currency)
+	if !value.IsPositive() {
+		order.RedeemPoints = 0
+		return nil
+	}
+
+	for i, share := range allocateProportionally(value, remaining) {
+		discount.ItemDiscounts[i] = discount.ItemDiscounts[i].Add(share)
+	}
+	discount.Total = discount.Total.Add(value)
+	discount.Lines = append(discount.Lines, models.DiscountLine{
+		Description: fmt.Sprintf("%d loyalty points", order.RedeemPoints),
+		Type:        models.PromotionLoyaltyPoints,
+		Amount:      value,
+	})
+	return nil
+}
+
+// RedeemPoints spends the points on a priced order. It runs before the order is stored, like claiming a
+// promo code, so points spent on another order in the meantime fail the checkout.
+func (s *loyaltyService) RedeemPoints(order *models.Order) error {
+	if order.RedeemPoints <= 0 {
+		return nil
+	}
+	return s.loyaltyRepo.SpendPoints(&models.LoyaltyEntry{
+		ID:          utils.GenerateLoyaltyEntryID(),
+		UserID:      order.UserID,
+		Type:        models.LoyaltyEntryRedeem,
+		OrderID:     &order.ID,
+		Points:      -order.RedeemPoints,
+		Description: "Redeemed on order " + order.ID,
+	})
+}
+
+// RestorePoints gives back the points spent on an order that failed to be stored or was cancelled, as a
+// new lot so they do not expire straight away
+func (s *loyaltyService) RestorePoints(orderID string) error {
+	redeemed, err := s.loyaltyRepo.GetOrderEntry(models.LoyaltyEntryRedeem, orderID)
+	if err != nil || redeemed == nil {
+		return err
+	}
+	return s.loyaltyRepo.AddPoints(&models.LoyaltyEntry{
+		ID:          utils.GenerateLoyaltyEntryID(),
+		UserID:      redeemed.UserID,
+		Type:        models.LoyaltyEntryRestore,
+		OrderID:     &orderID,
+		Points:      -redeemed.Points,
+		Description: "Restored from cancelled order " + orderID,
+		ExpiresAt:   s.lotExpiry(time.Now()),
+	})
+}
+
+// AccrueOrder credits the points for a delivered, fully paid order: the configured rate on what the customer paid for
+// the items, raised by their tier's multiplier and any bonus the restaurant is running. Tips, fees and tax
+// earn nothing. Accruing the same order again has no effect.
+func (s *loyaltyService) AccrueOrder(order *models.Order) error {
+	spend := order.Subtotal.Sub(order.Discount.Sub(order.DiscountLines.OfType(models.PromotionFreeDelivery, order.Currency)))
+	if !spend.IsPositive() {
+		return nil
+	}
+
+	tier, _, err := s.tier(order.UserID)
+	if err != nil {
+		return err
+	}
+	points := spend.Amount * s.earnPointsPerUnit() / currencyUnit(order.Currency).Amount
+	points = points * s.tierConfig(tier).EarnMultiplierBasisPoints / 10000
+
+	now := time.Now()
+	multiplier, err := s.loyaltyRepo.GetMultiplier(order.RestaurantID)
+	if err != nil {
+		return err
+	}
+	description := "Earned on order " + order.ID
+	if multiplier != nil && multiplier.ActiveAt(now) && multiplier.BasisPoints > 10000 {
+		points = points * multiplier.BasisPoints / 10000
+		description += fmt.Sprintf(" (%s bonus at %s)", formatMultiplier(multiplier.BasisPoints), order.RestaurantName)
+	}
+	if points <= 0 {
+		return nil
+	}
+
+	return s.loyaltyRepo.AddPoints(&models.LoyaltyEntry{
+		ID:          utils.GenerateLoyaltyEntryID(),
+		UserID:      order.UserID,
+		Type:        models.LoyaltyEntryEarn,
+		OrderID:     &order.ID,
+		Points:      points,
+		Description: description,
+		ExpiresAt:   s.lotExpiry(now),
+	})
+}
+
+// balance books any points due to expire and returns what the user has left to spend
+func (s *loyaltyService) balance(userID string) (int64, error) {
+	now := time.Now()
+	expiry := &models.LoyaltyEntry{
+		ID:     utils.GenerateLoyaltyEntryID(),
+		UserID: userID,
+		Type:   models.LoyaltyEntryExpire,
+	}
+	switch s.expiryPolicy() {
+	case pointsExpiryPerAccrual:
+		expiry.Description = "Points expired"
+		if err := s.loyaltyRepo.ExpireLots(expiry, &now); err != nil {
+			return 0, err
+		}
+	case pointsExpiryInactivity:
+		lastActivity, err := s.loyaltyRepo.GetLastActivityAt(userID)
+		if err != nil {
+			return 0, err
+		}
+		if lastActivity != nil && now.Sub(*lastActivity) >= s.expiryWindow() {
+			expiry.Description = fmt.Sprintf("Points expired after %d days without activity", int(s.expiryWindow().Hours()/24))
+			if err := s.loyaltyRepo.ExpireLots(expiry, nil); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return s.loyaltyRepo.GetBalance(userID)
+}
+
+// tier works out the user's tier from the points they earned within the tier window
+func (s *loyaltyService) tier(userID string) (models.LoyaltyTier, int64, error) {
+	windowDays := s.config.TierWindowDays
+	if windowDays <= 0 {
+		windowDays = defaultTierWindowDays
+	}
+	earned, err := s.loyaltyRepo.SumEarnedSince(userID, time.Now().AddDate(0, 0, -windowDays))
+	if err != nil {
+		return models.LoyaltyTierBronze, 0, err
+	}
+	tier := models.LoyaltyTierBronze
+	for _, candidate := range loyaltyTiers {
+		if earned >= s.tierConfig(candidate).MinPoints {
+			tier = candidate
+		}
+	}
+	return tier, earned, nil
+}
+
+// tierConfig returns the configured threshold and perks of a tier, falling back to the defaults
+func (s *loyaltyService) tierConfig(tier models.LoyaltyTier) config.TierConfig {
+	tierConfig, ok := s.config.Tiers[string(tier)]
+	if !ok {
+		tierConfig = defaultTierConfigs[string(tier)]
+	}
+	if tierConfig.EarnMultiplierBasisPoints <= 0 {
+		tierConfig.EarnMultiplierBasisPoints = 10000
+	}
+	return tierConfig
+}
+
+// lotExpiry is when points added now expire, or nil when lots do not expire on their own
+func (s *loyaltyService) lotExpiry(now time.Time) *time.Time {
+	if s.expiryPolicy() != pointsExpiryPerAccrual {
+		return nil
+	}
+	expiresAt := now.Add(s.expiryWindow())
+	return &expiresAt
+}
+
+func (s *loyaltyService) expiryPolicy() string {
+	switch s.config.ExpiryPolicy {
+	case pointsExpiryInactivity, pointsExpiryNone:
+		return s.config.ExpiryPolicy
+	}
+	return pointsExpiryPerAccrual
+}
+
+func (s *loyaltyService) expiryWindow() time.Duration {
+	days := s.config.ExpiryDays
+	if days <= 0 {
+		days = defaultPointsExpiryDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// pointsValue is what a number of points takes off an order
+func (s *loyaltyService) pointsValue(points int64, currency models.Currency) models.Money {
+	return currencyUnit(currency).MulRat(points, s.redeemPointsPerUnit())
+}
+
+func (s *loyaltyService) earnPointsPerUnit() int64 {
+	if s.config.EarnPointsPerUnit > 0 {
+		return s.config.EarnPointsPerUnit
+	}
+	return defaultEarnPointsPerUnit
+}
+
+func (s *loyaltyService) redeemPointsPerUnit() int64 {
+	if s.config.RedeemPointsPerUnit > 0 {
+		return s.config.RedeemPointsPerUnit
+	}
+	return defaultRedeemPointsPerUnit
+}
+
+func (s *loyaltyService) minRedeemPoints() int64 {
+	if s.config.MinRedeemPoints > 0 {
+		return s.config.MinRedeemPoints
+	}
+	return defaultMinRedeemPoints
+}
+
+// currencyUnit is one whole unit of the currency, e.g. 1.00 USD
+func currencyUnit(currency models.Currency) models.Money {
+	amount := int64(1)
+	for range currency.Exponent() {
+		amount *= 10
+	}
+	return models.NewMoney(amount, currency)
+}
+
+// formatMultiplier renders basis points as a multiplier, e.g. 15000 as "1.5x"
+func formatMultiplier(basisPoints int64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%d.%04d", basisPoints/10000, basisPoints%10000), "0"), ".") + "x"
+}
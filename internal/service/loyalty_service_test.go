@@ -0,0 +1,158 @@
+package service
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+func newTestLoyalty(t *testing.T) *loyaltyService {
+	t.Helper()
+	openTestDB(t)
+	// Default rates: 10 points per 1.00 spent, 100 points take 1.00 off, at least 500 at a time
+	return NewLoyaltyService(repository.NewLoyaltyRepository(), repository.NewUserRepository(), repository.NewRestaurantRepository(), config.LoyaltyConfig{}).(*loyaltyService)
+}
+
+// lot adds points earned at some point in the past that expire at the given time
+func lot(t *testing.T, s *loyaltyService, id string, points int64, earnedAt time.Time, expiresAt time.Time) {
+	t.Helper()
+	if err := s.loyaltyRepo.AddPoints(&models.LoyaltyEntry{ID: id, UserID: "ana", Type: models.LoyaltyEntryEarn, Points: points, ExpiresAt: &expiresAt, CreatedAt: earnedAt}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAccrueOrder(t *testing.T) {
+	s := newTestLoyalty(t)
+	balance := func() int64 {
+		t.Helper()
+		points, err := s.balance("ana")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return points
+	}
+
+	// 20.00 of items earns 200 points, and only once however often delivery is reported
+	order := testOrder("o1", "ana", "r1")
+	for i := 0; i < 2; i++ {
+		if err := s.AccrueOrder(order); err != nil {
+			t.Fatalf("AccrueOrder error = %v", err)
+		}
+	}
+	if got := balance(); got != 200 {
+		t.Fatalf("balance after one 20.00 order = %d, want 200", got)
+	}
+
+	// A promo code discount earns nothing, but free delivery does not reduce the points
+	discounted := testOrder("o2", "ana", "r1")
+	discounted.Discount = usd(800)
+	discounted.DiscountLines = models.DiscountLinesArray{
+		{Code: "FIVEOFF", Type: models.PromotionFixed, Amount: usd(500)},
+		{Code: "SHIPFREE", Type: models.PromotionFreeDelivery, Amount: usd(300)},
+	}
+	if err := s.AccrueOrder(discounted); err != nil {
+		t.Fatal(err)
+	}
+	if got := balance(); got != 200+150 {
+		t.Errorf("balance after a 15.00 paid basket = %d, want 350", got)
+	}
+
+	// A double points week at the restaurant
+	if err := s.loyaltyRepo.SaveMultiplier(&models.LoyaltyMultiplier{RestaurantID: "r1", BasisPoints: 20000}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AccrueOrder(testOrder("o3", "ana", "r1")); err != nil {
+		t.Fatal(err)
+	}
+	if got := balance(); got != 350+400 {
+		t.Errorf("balance after a double points order = %d, want 750", got)
+	}
+
+	// Points from last year have expired
+	lot(t, s, "old", 1000, time.Now().AddDate(-1, 0, -1), time.Now().Add(-24*time.Hour))
+	if got := balance(); got != 750 {
+		t.Errorf("balance with an expired lot = %d, want 750", got)
+	}
+}
+
+func TestAccrueOrderAtHigherTiers(t *testing.T) {
+	s := newTestLoyalty(t)
+	yearAhead := time.Now().AddDate(1, 0, 0)
+
+	// 2500 points earned this year make Ana silver, earning 1.25x
+	lot(t, s, "spring", 2500, time.Now().AddDate(0, -3, 0), yearAhead)
+	if err := s.AccrueOrder(testOrder("o1", "ana", "r1")); err != nil {
+		t.Fatal(err)
+	}
+	if points, _ := s.balance("ana"); points != 2500+250 {
+		t.Errorf("silver balance = %d, want 2750", points)
+	}
+
+	// Another 3000 points reach gold, which waives the delivery fee at checkout
+	lot(t, s, "summer", 3000, time.Now().AddDate(0, -1, 0), yearAhead)
+	order := testOrder("o2", "ana", "r1")
+	discount := &models.PromotionDiscount{ItemDiscounts: []models.Money{usd(0)}, Delivery: usd(0), Total: usd(0)}
+	if err := s.ApplyLoyalty(order, []models.DiscountableLine{{Amount: order.Subtotal}}, discount); err != nil {
+		t.Fatal(err)
+	}
+	if discount.Delivery.Amount != 300 || len(discount.Lines) != 1 {
+		t.Errorf("gold discount = %+v, want the 3.00 delivery fee waived", discount)
+	}
+}
+
+func TestRedeemPoints(t *testing.T) {
+	s := newTestLoyalty(t)
+	lot(t, s, "soon", 400, time.Now().AddDate(0, -11, 0), time.Now().AddDate(0, 1, 0))
+	lot(t, s, "later", 800, time.Now().AddDate(0, -1, 0), time.Now().AddDate(0, 11, 0))
+	apply := func(order *models.Order) (*models.PromotionDiscount, error) {
+		discount := &models.PromotionDiscount{ItemDiscounts: []models.Money{usd(0)}, Delivery: usd(0), Total: usd(0)}
+		return discount, s.ApplyLoyalty(order, []models.DiscountableLine{{Amount: order.Subtotal}}, discount)
+	}
+
+	for _, points := range []int64{300, 1300} {
+		order := testOrder("o1", "ana", "r1")
+		order.RedeemPoints = points
+		_, err := apply(order)
+		rejectedAs(t, err, http.StatusBadRequest)
+	}
+
+	// Two checkouts spend 700 of the 1200 points at once; only one can have them
+	orders := []*models.Order{testOrder("o1", "ana", "r1"), testOrder("o2", "ana", "r1")}
+	var wg sync.WaitGroup
+	spent := make(chan *models.Order, len(orders))
+	for _, order := range orders {
+		order.RedeemPoints = 700
+		discount, err := apply(order)
+		if err != nil || discount.Total.Amount != 700 {
+			t.Fatalf("ApplyLoyalty with 700 points = %+v, %v; want 7.00 off", discount, err)
+		}
+		wg.Add(1)
+		go func(order *models.Order) {
+			defer wg.Done()
+			if err := s.RedeemPoints(order); err == nil {
+				spent <- order
+			}
+		}(order)
+	}
+	wg.Wait()
+	close(spent)
+	if len(spent) != 1 {
+		t.Fatalf("%d checkouts spent the same points", len(spent))
+	}
+	if points, _ := s.balance("ana"); points != 500 {
+		t.Errorf("balance after spending 700 = %d, want 500", points)
+	}
+
+	// Cancelling gives the points back
+	if err := s.RestorePoints((<-spent).ID); err != nil {
+		t.Fatal(err)
+	}
+	if points, _ := s.balance("ana"); points != 1200 {
+		t.Errorf("balance after the order was cancelled = %d, want 1200", points)
+	}
+}
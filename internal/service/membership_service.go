@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+	"dfood/pkg/logger"
+)
+
+// defaultMembershipRetryDays is when failed renewals are retried, in days after each failure, when the config sets no schedule
+var defaultMembershipRetryDays = []int{1, 3, 5}
+
+type MembershipService interface {
+	CreatePlan(request *models.CreateMembershipPlanRequest) (*models.MembershipPlan, error)
+	UpdatePlan(planID string, request *models.UpdateMembershipPlanRequest) (*models.MembershipPlan, error)
+	GetPlans(activeOnly bool) ([]models.MembershipPlan, error)
+	Subscribe(viewer models.OrderViewer, request *models.SubscribeMembershipRequest) (*models.Membership, error)
+	GetUserMembership(userID string) (*models.Membership, error)
+	CancelMembership(viewer models.OrderViewer, userID string) (*models.Membership, error)
+	ResumeMembership(viewer models.OrderViewer, userID string) (*models.Membership, error)
+	ApplyMembership(order *models.Order, discount *models.PromotionDiscount) error
+	BillDue() (*models.MembershipBillingRun, error)
+}
+
+type membershipService struct {
+	membershipRepo      repository.MembershipRepository
+	userRepo            repository.UserRepository
+	paymentRepo         repository.PaymentRepository
+	paymentService      PaymentService
+	notificationService NotificationService
+	config              config.MembershipConfig
+	// Billing runs one at a time so the background biller and an admin run cannot charge the same renewal twice
+	billing sync.Mutex
+}
+
+func NewMembershipService(membershipRepo repository.MembershipRepository, userRepo repository.UserRepository, paymentRepo repository.PaymentRepository, paymentService PaymentService, notificationService NotificationService, cfg config.MembershipConfig) MembershipService {
+	return &membershipService{
+		membershipRepo:      membershipRepo,
+		userRepo:            userRepo,
+		paymentRepo:         paymentRepo,
+		paymentService:      paymentService,
+		notificationService: notificationService,
+		config:              cfg,
+	}
+}
+
+func (s *membershipService) CreatePlan(request *models.CreateMembershipPlanRequest) (*models.MembershipPlan, error) {
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Membership plan is required", nil)
+	}
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Plan name is required", nil)
+	}
+
+	currency := models.DefaultCurrency
+	for _, amount := range []models.Money{request.Price, request.FreeDeliveryMinSubtotal} {
+		if amount.Currency != "" && amount.Currency != currency {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Membership plan amounts must be in "+string(currency), nil)
+		}
+	}
+	if !request.Price.IsPositive() {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Membership plans need a positive price", nil)
+	}
+	if request.FreeDeliveryMinSubtotal.IsNegative() {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Free delivery minimum cannot be negative", nil)
+	}
+	intervalMonths := request.IntervalMonths
+	if intervalMonths == 0 {
+		intervalMonths = 1
+	}
+	if intervalMonths < 0 || intervalMonths > 12 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Billing interval must be between 1 and 12 months", nil)
+	}
+	if request.TrialDays < 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Trial days cannot be negative", nil)
+	}
+	if request.ServiceFeeDiscountBasisPoints < 0 || request.ServiceFeeDiscountBasisPoints > 10000 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Service fee discount must be between 0 and 10000 basis points", nil)
+	}
+	if !request.FreeDelivery && request.ServiceFeeDiscountBasisPoints == 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Membership plans need free delivery or a service fee discount", nil)
+	}
+
+	plan := &models.MembershipPlan{
+		ID:                            utils.GenerateMembershipPlanID(),
+		Name:                          name,
+		Description:                   strings.TrimSpace(request.Description),
+		Price:                         models.NewMoney(request.Price.Amount, currency),
+		Currency:                      currency,
+		IntervalMonths:                intervalMonths,
+		TrialDays:                     request.TrialDays,
+		FreeDelivery:                  request.FreeDelivery,
+		FreeDeliveryMinSubtotal:       models.NewMoney(request.FreeDeliveryMinSubtotal.Amount, currency),
+		ServiceFeeDiscountBasisPoints: request.ServiceFeeDiscountBasisPoints,
+		IsActive:                      true,
+		CreatedBy:                     request.AgentID,
+	}
+	if err := s.membershipRepo.CreatePlan(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (s *membershipService) UpdatePlan(planID string, request *models.UpdateMembershipPlanRequest) (*models.MembershipPlan, error) {
+	if strings.TrimSpace(planID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Plan ID is required", nil)
+	}
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Membership plan changes are required", nil)
+	}
+	plan, err := s.membershipRepo.GetPlanByID(planID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if request.Name != nil {
+		name := strings.TrimSpace(*request.Name)
+		if name == "" {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Plan name cannot be empty", nil)
+		}
+		updates["name"] = name
+	}
+	if request.Description != nil {
+		updates["description"] = strings.TrimSpace(*request.Description)
+	}
+	if request.TrialDays != nil {
+		if *request.TrialDays < 0 {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Trial days cannot be negative", nil)
+		}
+		updates["trial_days"] = *request.TrialDays
+	}
+	if request.IsActive != nil {
+		updates["is_active"] = *request.IsActive
+	}
+	if len(updates) == 0 {
+		return plan, nil
+	}
+
+	if err := s.membershipRepo.UpdatePlan(plan.ID, updates); err != nil {
+		return nil, err
+	}
+	return s.membershipRepo.GetPlanByID(plan.ID)
+}
+
+func (s *membershipService) GetPlans(activeOnly bool) ([]models.MembershipPlan, error) {
+	return s.membershipRepo.ListPlans(activeOnly)
+}
+
+// Subscribe starts a membership. Plans with a trial start free for customers who have never been members;
+// otherwise the first period is charged straight away and nothing is created if the charge fails.
+// Customers can only subscribe themselves.
+func (s *membershipService) Subscribe(viewer models.OrderViewer, request *models.SubscribeMembershipRequest) (*models.Membership, error) {
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	if _, err := authorizeUser(s.userRepo, viewer, request.UserID); err != nil {
+		return nil, err
+	}
+	plan, err := s.membershipRepo.GetPlanByID(request.PlanID)
+	if err != nil {
+		return nil, err
+	}
+	if !plan.IsActive {
+		return nil, errors.NewHTTPError(http.StatusConflict, "The "+plan.Name+" plan is no longer offered", nil)
+	}
+	current, err := s.membershipRepo.GetCurrentByUserID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		return nil, errors.NewHTTPError(http.StatusConflict, "You are already a "+current.Plan.Name+" member", nil)
+	}
+	if err := s.checkCard(request.UserID, request.CardID); err != nil {
+		return nil, err
+	}
+
+	previous, err := s.membershipRepo.CountByUserID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	membership := &models.Membership{
+		ID:                 utils.GenerateMembershipID(),
+		UserID:             request.UserID,
+		PlanID:             plan.ID,
+		CardID:             request.CardID,
+		CurrentPeriodStart: now,
+	}
+	if plan.TrialDays > 0 && previous == 0 {
+		trialEndsAt := now.AddDate(0, 0, plan.TrialDays)
+		membership.Status = models.MembershipStatusTrialing
+		membership.CurrentPeriodEnd = trialEndsAt
+		membership.TrialEndsAt = &trialEndsAt
+	} else {
+		payment, err := s.paymentService.ChargeMembership(membership, plan.Price)
+		if err != nil {
+			return nil, err
+		}
+		membership.Status = models.MembershipStatusActive
+		membership.CurrentPeriodEnd = now.AddDate(0, plan.IntervalMonths, 0)
+		membership.LastPaymentID = &payment.ID
+	}
+
+	if err := s.membershipRepo.Create(membership); err != nil {
+		if membership.LastPaymentID != nil {
+			logger.Error("Failed to store membership after charging for it", "transaction_id", *membership.LastPaymentID, "user_id", request.UserID, "error", err)
+			s.refundCharge(*membership.LastPaymentID)
+		}
+		return nil, err
+	}
+	membership.Plan = *plan
+	return membership, nil
+}
+
+// checkCard makes sure the membership has a card to be charged with, now or when its trial ends
+func (s *membershipService) checkCard(userID string, cardID *string) error {
+	if cardID != nil && *cardID != "" {
+		card, err := s.paymentRepo.GetCardByID(*cardID)
+		if err != nil {
+			return err
+		}
+		if card.UserID != userID {
+			return errors.NewHTTPError(http.StatusForbidden, "Card does not belong to this user", nil)
+		}
+		return nil
+	}
+	cards, err := s.paymentRepo.GetUserCards(userID)
+	if err != nil {
+		return err
+	}
+	if len(cards) == 0 {
+		return errors.NewHTTPError(http.StatusBadRequest, "A saved card is required for a membership", nil)
+	}
+	return nil
+}
+
+func (s *membershipService) GetUserMembership(userID string) (*models.Membership, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+	membership, err := s.membershipRepo.GetCurrentByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, errors.NewHTTPError(http.StatusNotFound, "User has no membership", nil)
+	}
+	return membership, nil
+}
+
+// CancelMembership stops the membership from renewing. Its perks last until the end of the period already
+// paid for, or of the trial, which then ends without a charge.
+func (s *membershipService) CancelMembership(viewer models.OrderViewer, userID string) (*models.Membership, error) {
+	user, err := authorizeUser(s.userRepo, viewer, userID)
+	if err != nil {
+		return nil, err
+	}
+	membership, err := s.GetUserMembership(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if membership.CancelAtPeriodEnd {
+		return membership, nil
+	}
+
+	// Nothing more is collected for a renewal that could not be paid, so it ends now
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"cancel_at_period_end": true,
+		"cancelled_at":         now,
+	}
+	if membership.Status == models.MembershipStatusPastDue {
+		updates["status"] = models.MembershipStatusCancelled
+		updates["ended_at"] = now
+		updates["next_retry_at"] = nil
+	}
+	if err := s.membershipRepo.UpdateIfStatus(membership.ID, membership.Status, updates); err != nil {
+		return nil, err
+	}
+	return s.membershipRepo.GetByID(membership.ID)
+}
+
+// ResumeMembership takes back a cancellation before the membership has ended
+func (s *membershipService) ResumeMembership(viewer models.OrderViewer, userID string) (*models.Membership, error) {
+	user, err := authorizeUser(s.userRepo, viewer, userID)
+	if err != nil {
+		return nil, err
+	}
+	membership, err := s.GetUserMembership(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !membership.CancelAtPeriodEnd {
+		return membership, nil
+	}
+	if err := s.membershipRepo.UpdateIfStatus(membership.ID, membership.Status, map[string]interface{}{
+		"cancel_at_period_end": false,
+		"cancelled_at":         nil,
+	}); err != nil {
+		return nil, err
+	}
+	return s.membershipRepo.GetByID(membership.ID)
+}
+
+// ApplyMembership gives an order the perks of its customer's membership: free delivery on orders that reach the
+// plan's minimum, and the plan's share off the service fee. Both are paid for by the platform.
+func (s *membershipService) ApplyMembership(order *models.Order, discount *models.PromotionDiscount) error {
+	membership, err := s.membershipRepo.GetCurrentByUserID(order.UserID)
+	if err != nil {
+		return err
+	}
+	if membership == nil || membership.Plan.Currency != order.Currency {
+		return nil
+	}
+	plan := membership.Plan
+	order.MembershipID = &membership.ID
+
+	if plan.FreeDelivery && order.Subtotal.Cmp(plan.FreeDeliveryMinSubtotal) >= 0 {
+		if waived := order.DeliveryFee.Sub(discount.Delivery).Max(models.Zero(order.Currency)); waived.IsPositive() {
+			discount.Delivery = discount.Delivery.Add(waived)
+			discount.Total = discount.Total.Add(waived)
+			discount.Lines = append(discount.Lines, models.DiscountLine{
+				Description: fmt.Sprintf("Free delivery for %s members", plan.Name),
+				Type:        models.PromotionFreeDelivery,
+				Amount:      waived,
+			})
+		}
+	}
+	if plan.ServiceFeeDiscountBasisPoints > 0 {
+		order.ServiceFee = order.ServiceFee.Sub(order.ServiceFee.Percent(plan.ServiceFeeDiscountBasisPoints))
+	}
+	return nil
+}
+
+// BillDue renews every membership whose period has ended and retries past due renewals whose time has come.
+// Memberships cancelled by their customers end instead of renewing.
+func (s *membershipService) BillDue() (*models.MembershipBillingRun, error) {
+	s.billing.Lock()
+	defer s.billing.Unlock()
+
+	now := time.Now().UTC()
+	due, err := s.membershipRepo.GetDue(now)
+	if err != nil {
+		return nil, err
+	}
+	run := &models.MembershipBillingRun{}
+	for i := range due {
+		if err := s.renew(&due[i], now, run); err != nil {
+			logger.Error("Failed to renew membership", "membership_id", due[i].ID, "error", err)
+		}
+	}
+	if len(due) > 0 {
+		logger.Info("Billed memberships", "renewed", run.Renewed, "failed", run.Failed, "lapsed", run.Lapsed, "cancelled", run.Cancelled)
+	}
+	return run, nil
+}
+
+// renew charges the next period of a due membership, or ends it if its customer cancelled
+func (s *membershipService) renew(membership *models.Membership, now time.Time, run *models.MembershipBillingRun) error {
+	if membership.CancelAtPeriodEnd {
+		if err := s.membershipRepo.UpdateIfStatus(membership.ID, membership.Status, map[string]interface{}{
+			"status":   models.MembershipStatusCancelled,
+			"ended_at": membership.CurrentPeriodEnd,
+		}); err != nil {
+			return err
+		}
+		run.Cancelled++
+		return nil
+	}
+
+	payment, err := s.paymentService.ChargeMembership(membership, membership.Plan.Price)
+	if err != nil {
+		return s.recordFailedRenewal(membership, now, err, run)
+	}
+
+	// Periods follow on from each other, except that a renewal paid late, or long overdue, starts from now
+	start := membership.CurrentPeriodEnd
+	if membership.Status == models.MembershipStatusPastDue || !start.AddDate(0, membership.Plan.IntervalMonths, 0).After(now) {
+		start = now
+	}
+	if err := s.membershipRepo.UpdateIfStatus(membership.ID, membership.Status, map[string]interface{}{
+		"status":               models.MembershipStatusActive,
+		"current_period_start": start,
+		"current_period_end":   start.AddDate(0, membership.Plan.IntervalMonths, 0),
+		"failed_attempts":      0,
+		"next_retry_at":        nil,
+		"last_failure_reason":  nil,
+		"last_payment_id":      payment.ID,
+	}); err != nil {
+		// Someone else changed the membership while it was being charged, so the period was not extended
+		logger.Error("Failed to renew membership after charging for it", "membership_id", membership.ID, "transaction_id", payment.ID, "error", err)
+		s.refundCharge(payment.ID)
+		return err
+	}
+	run.Renewed++
+	return nil
+}
+
+// refundCharge gives back a membership payment that did not buy a period. Membership payments are not
+// tied to an order, so no caller identity is needed to refund them to the card.
+func (s *membershipService) refundCharge(transactionID string) {
+	if _, err := s.paymentService.ProcessRefund(models.OrderViewer{}, transactionID, models.Money{}, false); err != nil {
+		logger.Error("Failed to refund membership payment", "transaction_id", transactionID, "error", err)
+	}
+}
+
+// recordFailedRenewal schedules the next retry of a renewal that could not be charged and tells the customer,
+// or lets the membership lapse once every retry has failed
+func (s *membershipService) recordFailedRenewal(membership *models.Membership, now time.Time, chargeErr error, run *models.MembershipBillingRun) error {
+	reason, ok := errors.GetErrorMessage(chargeErr)
+	if !ok {
+		reason = "Payment failed"
+	}
+	retryDays := s.config.RetryDays
+	if len(retryDays) == 0 {
+		retryDays = defaultMembershipRetryDays
+	}
+
+	attempts := membership.FailedAttempts + 1
+	updates := map[string]interface{}{
+		"failed_attempts":     attempts,
+		"last_failure_reason": reason,
+	}
+	var title, body string
+	if attempts > len(retryDays) {
+		updates["status"] = models.MembershipStatusLapsed
+		updates["ended_at"] = now
+		updates["next_retry_at"] = nil
+		title = "Your membership has ended"
+		body = fmt.Sprintf("We could not collect %s for your %s membership, so it has ended. You can join again at any time.", membership.Plan.Price, membership.Plan.Name)
+	} else {
+		nextRetryAt := now.AddDate(0, 0, retryDays[attempts-1])
+		updates["status"] = models.MembershipStatusPastDue
+		updates["next_retry_at"] = nextRetryAt
+		title = "Membership payment failed"
+		body = fmt.Sprintf("We could not collect %s for your %s membership (%s). We will try again on %s; update your card to keep your perks.", membership.Plan.Price, membership.Plan.Name, reason, nextRetryAt.Format("January 2"))
+	}
+	if err := s.membershipRepo.UpdateIfStatus(membership.ID, membership.Status, updates); err != nil {
+		return err
+	}
+	if updates["status"] == models.MembershipStatusLapsed {
+		run.Lapsed++
+	} else {
+		run.Failed++
+	}
+
+	if err := s.notificationService.SendNotification(&models.Notification{
+		ID:     utils.GenerateNotificationID(),
+		UserID: membership.UserID,
+		Title:  title,
+		Body:   body,
+		Type:   "system",
+	}); err != nil {
+		logger.Error("Failed to notify user of membership payment failure", "membership_id", membership.ID, "error", err)
+	}
+	return nil
+}
+
+type MembershipBiller interface {
+	Start(ctx context.Context, interval time.Duration)
+}
+
+type membershipBiller struct {
+	membershipService MembershipService
+}
+
+func NewMembershipBiller(membershipService MembershipService) MembershipBiller {
+	return &membershipBiller{
+		membershipService: membershipService,
+	}
+}
+
+// Start bills due memberships every interval until ctx is cancelled
+func (b *membershipBiller) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := b.membershipService.BillDue(); err != nil {
+			logger.Error("Failed to bill memberships", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"dfood/internal/config"
+	"dfood/internal/database"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+func TestBillDueRetriesFailedRenewalsThenLapses(t *testing.T) {
+	payments := newTestPayments(t)
+	notifications := repository.NewNotificationRepository()
+	s := NewMembershipService(repository.NewMembershipRepository(), payments.userRepo, payments.paymentRepo, payments,
+		NewNotificationService(notifications, payments.userRepo), config.MembershipConfig{RetryDays: []int{1, 3, 5}}).(*membershipService)
+
+	seed(t, testUser("ana"), testUser("ben"), &models.MembershipPlan{ID: "plus", Name: "Plus", Price: usd(999), Currency: models.CurrencyUSD, IntervalMonths: 1, CreatedBy: "admin"})
+	anaCard := saveTestCard(t, payments, "ana", FakeCardDeclined)
+	benCard := saveTestCard(t, payments, "ben", FakeCardDeclined)
+	periodEnd := time.Now().UTC().Add(-time.Hour)
+	seed(t,
+		&models.Membership{ID: "m-ana", UserID: "ana", PlanID: "plus", Status: models.MembershipStatusActive, CardID: &anaCard.ID, CurrentPeriodStart: periodEnd.AddDate(0, -1, 0), CurrentPeriodEnd: periodEnd},
+		&models.Membership{ID: "m-ben", UserID: "ben", PlanID: "plus", Status: models.MembershipStatusActive, CardID: &benCard.ID, CurrentPeriodStart: periodEnd.AddDate(0, -1, 0), CurrentPeriodEnd: periodEnd},
+	)
+	membership := func(id string) *models.Membership {
+		t.Helper()
+		membership, err := s.membershipRepo.GetByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return membership
+	}
+	// retryNow brings a past due membership's next retry forward so the next run picks it up
+	retryNow := func(id string) {
+		t.Helper()
+		if err := database.DB.Model(&models.Membership{}).Where("id = ? AND status = ?", id, models.MembershipStatusPastDue).Update("next_retry_at", time.Now().UTC().Add(-time.Minute)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Both renewals fail; Ben then adds a card that works before the first retry
+	if run, err := s.BillDue(); err != nil || run.Failed != 2 {
+		t.Fatalf("first BillDue = %+v, %v; want two failed renewals", run, err)
+	}
+	goodCard := saveTestCard(t, payments, "ben", FakeCardSuccess)
+	if err := database.DB.Model(&models.Membership{}).Where("id = ?", "m-ben").Update("card_id", goodCard.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Ana's declined card is retried 1, 3 and 5 days after each failure, and the fourth failure ends it
+	for attempt, days := range []int{1, 3, 5} {
+		got := membership("m-ana")
+		if got.Status != models.MembershipStatusPastDue || got.FailedAttempts != attempt+1 || got.NextRetryAt == nil {
+			t.Fatalf("after %d failures: status %s, attempts %d, next retry %v", attempt+1, got.Status, got.FailedAttempts, got.NextRetryAt)
+		}
+		if wait := time.Until(*got.NextRetryAt).Round(time.Hour); wait != time.Duration(days)*24*time.Hour {
+			t.Errorf("retry %d is %s away, want %d days", attempt+1, wait, days)
+		}
+		retryNow("m-ana")
+		retryNow("m-ben")
+		if _, err := s.BillDue(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lapsed := membership("m-ana")
+	if lapsed.Status != models.MembershipStatusLapsed || lapsed.EndedAt == nil || lapsed.NextRetryAt != nil {
+		t.Errorf("after every retry failed: status %s, ended %v, next retry %v; want lapsed", lapsed.Status, lapsed.EndedAt, lapsed.NextRetryAt)
+	}
+	if sent, _ := notifications.GetByUserID("ana", 10, 0); len(sent) != 4 || sent[0].Title != "Your membership has ended" {
+		t.Errorf("ana was sent %d notifications, want three failures followed by the end of the membership", len(sent))
+	}
+
+	// Ben's retry went through on the new card: a fresh period from the retry, and nothing left to retry
+	renewed := membership("m-ben")
+	if renewed.Status != models.MembershipStatusActive || renewed.FailedAttempts != 0 || renewed.NextRetryAt != nil || renewed.LastPaymentID == nil {
+		t.Fatalf("after a successful retry: %+v", renewed)
+	}
+	if !renewed.CurrentPeriodStart.After(periodEnd) {
+		t.Errorf("period paid late starts %s, want from the retry rather than %s", renewed.CurrentPeriodStart, periodEnd)
+	}
+	if payment, _ := payments.paymentRepo.GetTransactionByID(*renewed.LastPaymentID); payment.Amount.Amount != 999 {
+		t.Errorf("renewal charged %s, want 9.99 once", payment.Amount)
+	}
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+const (
+	// openingHoursHorizonDays bounds how far ahead opens_at and closes_at are looked for
+	openingHoursHorizonDays = 14
+	holidayDateLayout       = "2006-01-02"
+)
+
+// openingSchedule answers whether a restaurant is open at a given time from its weekly hours, holiday
+// overrides and pause. Restaurants without weekly hours are open around the clock except on their holidays.
+type openingSchedule struct {
+	location    *time.Location
+	weekly      []models.OpeningHours
+	holidays    map[string][]models.HolidayHours // By date in the restaurant's time zone
+	pausedUntil *time.Time
+}
+
+// openInterval is one stretch of opening time, starting on the local date it belongs to
+type openInterval struct {
+	start time.Time
+	end   time.Time
+}
+
+// restaurantLocation resolves the time zone a restaurant's hours are given in
+func restaurantLocation(restaurant *models.Restaurant) *time.Location {
+	if restaurant.TimeZone == "" {
+		return time.Local
+	}
+	location, err := time.LoadLocation(restaurant.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
+
+// loadOpeningSchedules reads the hours of the restaurants, with their holiday overrides between from and to
+func loadOpeningSchedules(restaurantRepo repository.RestaurantRepository, restaurants []*models.Restaurant, from, to time.Time) (map[string]*openingSchedule, error) {
+	schedules := make(map[string]*openingSchedule, len(restaurants))
+	if len(restaurants) == 0 {
+		return schedules, nil
+	}
+
+	ids := make([]string, 0, len(restaurants))
+	for _, restaurant := range restaurants {
+		ids = append(ids, restaurant.ID)
+		schedules[restaurant.ID] = &openingSchedule{
+			location:    restaurantLocation(restaurant),
+			holidays:    make(map[string][]models.HolidayHours),
+			pausedUntil: restaurant.PausedUntil,
+		}
+	}
+
+	weekly, err := restaurantRepo.GetOpeningHours(ids...)
+	if err != nil {
+		return nil, err
+	}
+	for _, interval := range weekly {
+		schedule := schedules[interval.RestaurantID]
+		schedule.weekly = append(schedule.weekly, interval)
+	}
+
+	// Dates are local to each restaurant, so widen the range to cover every zone's idea of the day
+	holidays, err := restaurantRepo.GetHolidayHours(from.UTC().AddDate(0, 0, -2).Format(holidayDateLayout), to.UTC().AddDate(0, 0, 2).Format(holidayDateLayout), ids...)
+	if err != nil {
+		return nil, err
+	}
+	for _, holiday := range holidays {
+		schedule := schedules[holiday.RestaurantID]
+		schedule.holidays[holiday.Date] = append(schedule.holidays[holiday.Date], holiday)
+	}
+	return schedules, nil
+}
+
+// loadOpeningSchedule reads one restaurant's hours with the holiday overrides between from and to
+func loadOpeningSchedule(restaurantRepo repository.RestaurantRepository, restaurant *models.Restaurant, from, to time.Time) (*openingSchedule, error) {
+	schedules, err := loadOpeningSchedules(restaurantRepo, []*models.Restaurant{restaurant}, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return schedules[restaurant.ID], nil
+}
+
+// setOpenStatus fills in whether each restaurant is open now and when it next opens and closes
+func setOpenStatus(restaurantRepo repository.RestaurantRepository, restaurants ...*models.Restaurant) error {
+	now := time.Now()
+	schedules, err := loadOpeningSchedules(restaurantRepo, restaurants, now, now.AddDate(0, 0, 2*openingHoursHorizonDays))
+	if err != nil {
+		return err
+	}
+	for _, restaurant := range restaurants {
+		schedules[restaurant.ID].describe(restaurant, now)
+	}
+	return nil
+}
+
+// setOpenStatusAll is setOpenStatus for a slice of restaurants, updated in place
+func setOpenStatusAll(restaurantRepo repository.RestaurantRepository, restaurants []models.Restaurant) error {
+	pointers := make([]*models.Restaurant, len(restaurants))
+	for i := range restaurants {
+		pointers[i] = &restaurants[i]
+	}
+	return setOpenStatus(restaurantRepo, pointers...)
+}
+
+// describe sets the restaurant's computed open status as of now, with times in the restaurant's zone
+func (s *openingSchedule) describe(restaurant *models.Restaurant, now time.Time) {
+	restaurant.IsOpen = s.isOpenAt(now)
+	restaurant.OpensAt = nil
+	restaurant.ClosesAt = nil
+	if restaurant.IsOpen {
+		restaurant.ClosesAt = s.nextClosing(now)
+		if restaurant.ClosesAt != nil {
+			restaurant.OpensAt = s.nextOpening(*restaurant.ClosesAt)
+		}
+	} else {
+		restaurant.OpensAt = s.nextOpening(now)
+		if restaurant.OpensAt != nil {
+			restaurant.ClosesAt = s.nextClosing(*restaurant.OpensAt)
+		}
+	}
+
+	for _, moment := range []*time.Time{restaurant.OpensAt, restaurant.ClosesAt} {
+		if moment != nil {
+			*moment = moment.In(s.location)
+		}
+	}
+}
+
+// isOpenAt reports whether the restaurant takes orders at t
+func (s *openingSchedule) isOpenAt(t time.Time) bool {
+	if s.pausedUntil != nil && t.Before(*s.pausedUntil) {
+		return false
+	}
+	_, open := s.openUntil(t)
+	return open
+}
+
+// openUntil finds the latest end of the intervals covering t, ignoring any pause.
+// Intervals running past midnight belong to the day they start on, so the previous day is checked too.
+func (s *openingSchedule) openUntil(t time.Time) (time.Time, bool) {
+	local := t.In(s.location)
+	var until time.Time
+	open := false
+	for _, offset := range []int{-1, 0} {
+		for _, interval := range s.intervals(local.Year(), local.Month(), local.Day()+offset) {
+			if !t.Before(interval.start) && t.Before(interval.end) && interval.end.After(until) {
+				until = interval.end
+				open = true
+			}
+		}
+	}
+	return until, open
+}
+
+// nextClosing follows back-to-back intervals from t, when the restaurant is open, to the moment it closes
+func (s *openingSchedule) nextClosing(t time.Time) *time.Time {
+	limit := t.AddDate(0, 0, openingHoursHorizonDays)
+	current := t
+	for current.Before(limit) {
+		until, open := s.openUntil(current)
+		if !open {
+			return &current
+		}
+		current = until
+	}
+	return nil
+}
+
+// nextOpening finds the first moment after t, when the restaurant is closed, that it opens again
+func (s *openingSchedule) nextOpening(t time.Time) *time.Time {
+	from := t
+	if s.pausedUntil != nil && from.Before(*s.pausedUntil) {
+		from = *s.pausedUntil
+		if _, open := s.openUntil(from); open {
+			return &from
+		}
+	}
+
+	local := from.In(s.location)
+	for day := 0; day <= openingHoursHorizonDays; day++ {
+		var next *time.Time
+		for _, interval := range s.intervals(local.Year(), local.Month(), local.Day()+day) {
+			if interval.start.After(from) && (next == nil || interval.start.Before(*next)) {
+				start := interval.start
+				next = &start
+			}
+		}
+		if next != nil {
+			return next
+		}
+	}
+	return nil
+}
+
+// intervals lists the opening intervals starting on a local date. time.Date normalises the day,
+// so callers can step past the ends of months.
+func (s *openingSchedule) intervals(year int, month time.Month, day int) []openInterval {
+	date := time.Date(year, month, day, 0, 0, 0, 0, s.location)
+
+	if overrides, ok := s.holidays[date.Format(holidayDateLayout)]; ok {
+		var intervals []openInterval
+		for _, override := range overrides {
+			if override.IsClosed {
+				continue
+			}
+			intervals = appendOpenInterval(intervals, date, override.OpensAt, override.ClosesAt)
+		}
+		return intervals
+	}
+
+	if len(s.weekly) == 0 {
+		return []openInterval{{start: date, end: date.AddDate(0, 0, 1)}}
+	}
+	var intervals []openInterval
+	for _, interval := range s.weekly {
+		if interval.DayOfWeek == int(date.Weekday()) {
+			intervals = appendOpenInterval(intervals, date, interval.OpensAt, interval.ClosesAt)
+		}
+	}
+	return intervals
+}
+
+// appendOpenInterval adds the interval between two times of day on date; closing at or before the opening
+// time runs into the next day. Wall clock times are resolved in the date's zone so they hold across DST changes.
+func appendOpenInterval(intervals []openInterval, date time.Time, opensAt, closesAt string) []openInterval {
+	opens, err := parseClock(opensAt)
+	if err != nil {
+		return intervals
+	}
+	closes, err := parseClock(closesAt)
+	if err != nil {
+		return intervals
+	}
+
+	start := time.Date(date.Year(), date.Month(), date.Day(), opens/60, opens%60, 0, 0, date.Location())
+	closingDay := date.Day()
+	if closes <= opens {
+		closingDay++
+	}
+	end := time.Date(date.Year(), date.Month(), closingDay, closes/60, closes%60, 0, 0, date.Location())
+	return append(intervals, openInterval{start: start, end: end})
+}
+
+// parseClock converts an "HH:MM" time of day into minutes after midnight
+func parseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", value, err)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
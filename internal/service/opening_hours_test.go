@@ -0,0 +1,109 @@
+package service
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+func TestOpeningScheduleDescribe(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weekly := []models.OpeningHours{
+		{DayOfWeek: 0, OpensAt: "10:00", ClosesAt: "22:00"},
+		{DayOfWeek: 1, OpensAt: "11:00", ClosesAt: "14:00"},
+		{DayOfWeek: 1, OpensAt: "17:00", ClosesAt: "22:00"},
+		{DayOfWeek: 4, OpensAt: "17:00", ClosesAt: "22:00"},
+		{DayOfWeek: 5, OpensAt: "18:00", ClosesAt: "02:00"},
+		{DayOfWeek: 6, OpensAt: "18:00", ClosesAt: "02:00"},
+	}
+	holidays := map[string][]models.HolidayHours{
+		"2026-12-24": {{OpensAt: "10:00", ClosesAt: "15:00"}},
+		"2026-12-25": {{IsClosed: true}},
+	}
+	local := func(value string) time.Time {
+		moment, err := time.ParseInLocation("2006-01-02 15:04", value, newYork)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return moment
+	}
+
+	tests := []struct {
+		name        string
+		at          string
+		pausedUntil string
+		open        bool
+		opensAt     string
+		closesAt    string
+	}{
+		{name: "lunch", at: "2026-12-07 12:00", open: true, closesAt: "2026-12-07 14:00", opensAt: "2026-12-07 17:00"},
+		{name: "between lunch and dinner", at: "2026-12-07 15:00", opensAt: "2026-12-07 17:00", closesAt: "2026-12-07 22:00"},
+		{name: "dinner, already Tuesday in UTC", at: "2026-12-07 21:30", open: true, closesAt: "2026-12-07 22:00", opensAt: "2026-12-10 17:00"},
+		{name: "after midnight on the Friday late shift", at: "2026-12-12 01:30", open: true, closesAt: "2026-12-12 02:00", opensAt: "2026-12-12 18:00"},
+		{name: "before Christmas Eve short hours", at: "2026-12-24 09:00", opensAt: "2026-12-24 10:00", closesAt: "2026-12-24 15:00"},
+		{name: "Christmas Eve evening replaced by the short hours", at: "2026-12-24 18:00", opensAt: "2026-12-26 18:00", closesAt: "2026-12-27 02:00"},
+		{name: "Christmas night", at: "2026-12-25 19:00", opensAt: "2026-12-26 18:00", closesAt: "2026-12-27 02:00"},
+		{name: "no late shift carried over from Christmas", at: "2026-12-26 01:00", opensAt: "2026-12-26 18:00", closesAt: "2026-12-27 02:00"},
+		{name: "paused over lunch", at: "2026-12-07 12:00", pausedUntil: "2026-12-07 13:00", opensAt: "2026-12-07 13:00", closesAt: "2026-12-07 14:00"},
+		{name: "paused past the end of lunch", at: "2026-12-07 12:00", pausedUntil: "2026-12-07 15:00", opensAt: "2026-12-07 17:00", closesAt: "2026-12-07 22:00"},
+		{name: "day the clocks go forward", at: "2026-03-08 12:00", open: true, closesAt: "2026-03-08 22:00", opensAt: "2026-03-09 11:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := &openingSchedule{location: newYork, weekly: weekly, holidays: holidays}
+			if tt.pausedUntil != "" {
+				pausedUntil := local(tt.pausedUntil)
+				schedule.pausedUntil = &pausedUntil
+			}
+			restaurant := &models.Restaurant{}
+			// The caller's clock is in UTC; the answer comes back in the restaurant's zone
+			schedule.describe(restaurant, local(tt.at).UTC())
+
+			if restaurant.IsOpen != tt.open {
+				t.Errorf("IsOpen = %v, want %v", restaurant.IsOpen, tt.open)
+			}
+			for _, moment := range []struct {
+				field string
+				got   *time.Time
+				want  string
+			}{{"OpensAt", restaurant.OpensAt, tt.opensAt}, {"ClosesAt", restaurant.ClosesAt, tt.closesAt}} {
+				if moment.got == nil || moment.got.Location() != newYork || moment.got.Format("2006-01-02 15:04") != moment.want {
+					t.Errorf("%s = %v, want %s in New York", moment.field, moment.got, moment.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetOpenStatusLoadsHolidaysAndPause(t *testing.T) {
+	openTestDB(t)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Open around the clock in Tokyo, but paused for the next hour and closed all of the following day there
+	pausedUntil := time.Now().Add(time.Hour).Truncate(time.Second)
+	tomorrow := pausedUntil.In(tokyo).AddDate(0, 0, 1)
+	restaurant := &models.Restaurant{ID: "r1", Name: "Corner Deli", TimeZone: "Asia/Tokyo", PausedUntil: &pausedUntil}
+	seed(t, restaurant, &models.HolidayHours{RestaurantID: "r1", Date: tomorrow.Format(holidayDateLayout), IsClosed: true})
+
+	if err := setOpenStatus(repository.NewRestaurantRepository(), restaurant); err != nil {
+		t.Fatalf("setOpenStatus error = %v", err)
+	}
+	if restaurant.IsOpen {
+		t.Errorf("paused restaurant is open")
+	}
+	if restaurant.OpensAt == nil || !restaurant.OpensAt.Equal(pausedUntil) || restaurant.OpensAt.Location().String() != "Asia/Tokyo" {
+		t.Errorf("OpensAt = %v, want the end of the pause, %v, in Tokyo", restaurant.OpensAt, pausedUntil.In(tokyo))
+	}
+	midnight := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 0, 0, 0, 0, tokyo)
+	if restaurant.ClosesAt == nil || !restaurant.ClosesAt.Equal(midnight) {
+		t.Errorf("ClosesAt = %v, want midnight before the holiday, %v", restaurant.ClosesAt, midnight)
+	}
+}
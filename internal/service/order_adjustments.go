@@ -0,0 +1,197 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dfood/internal/models"
+	"dfood/pkg/errors"
+)
+
+// RemoveOrderItems lets the restaurant take items it cannot fulfil off an order before it leaves the kitchen.
+// Removed items keep their original prices, fees are left as they were, and tax is recalculated on what remains,
+// so the order total only ever goes down. The lower total is what gets captured from the customer's hold.
+// Only the restaurant's owner, support or an admin can do this.
+func (s *orderService) RemoveOrderItems(viewer models.OrderViewer, orderID string, request *models.RemoveOrderItemsRequest) (*models.Order, error) {
+	if strings.TrimSpace(orderID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Order ID is required", nil)
+	}
+	if request == nil || len(request.Items) == 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Items to remove are required", nil)
+	}
+
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeRestaurantOwner(s.userRepo, s.restaurantRepo, viewer, order.RestaurantID); err != nil {
+		return nil, err
+	}
+	switch order.Status {
+	case models.OrderStatusScheduled, models.OrderStatusPending, models.OrderStatusConfirmed, models.OrderStatusPreparing:
+	default:
+		return nil, errors.NewHTTPError(http.StatusConflict, "Items can no longer be removed from a "+string(order.Status)+" order", nil)
+	}
+	// Participant shares are fixed at checkout, so a group order has to be cancelled and placed again instead
+	if order.GroupOrderID != nil {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Items cannot be removed from a group order", nil)
+	}
+
+	items, err := removeItems(order.Items, request.Items)
+	if err != nil {
+		return nil, err
+	}
+	previousTotal := order.Total
+	if err := s.repriceItems(order, items); err != nil {
+		return nil, err
+	}
+	order.Total = order.Total.Min(previousTotal)
+
+	// Money already collected can only be given back as a refund
+	transactions, err := s.paymentService.GetOrderTransactions(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	collected := models.Zero(order.Currency)
+	for _, transaction := range transactions {
+		if transaction.IsCollected() {
+			collected = collected.Add(transaction.Amount)
+		}
+	}
+	if collected.Cmp(order.Total) > 0 {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Payment for this order has already been captured; refund the difference instead", nil)
+	}
+
+	if err := s.orderRepo.UpdateIfStatus(order.ID, order.Status, map[string]interface{}{
+		"items":          order.Items,
+		"subtotal":       order.Subtotal,
+		"tax":            order.Tax,
+		"tax_lines":      order.TaxLines,
+		"tip":            order.Tip,
+		"discount":       order.Discount,
+		"discount_lines": order.DiscountLines,
+		"total":          order.Total,
+	}); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// removeItems applies the removals to a copy of the order lines, dropping lines that reach zero
+func removeItems(items models.OrderItemsArray, removals []models.OrderItemRemoval) (models.OrderItemsArray, error) {
+	quantities := make([]int, len(items))
+	for i, item := range items {
+		quantities[i] = item.Quantity
+	}
+
+	seen := make(map[int]bool, len(removals))
+	for _, removal := range removals {
+		if removal.Line < 0 || removal.Line >= len(items) {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Order has no item line %d", removal.Line), nil)
+		}
+		if seen[removal.Line] {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Item line %d is listed more than once", removal.Line), nil)
+		}
+		seen[removal.Line] = true
+
+		switch {
+		case removal.Quantity == 0:
+			quantities[removal.Line] = 0
+		case removal.Quantity < 0 || removal.Quantity > quantities[removal.Line]:
+			return nil, errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Quantity to remove from %s must be between 1 and %d", items[removal.Line].FoodName, quantities[removal.Line]), nil)
+		default:
+			quantities[removal.Line] -= removal.Quantity
+		}
+	}
+
+	remaining := make(models.OrderItemsArray, 0, len(items))
+	for i, item := range items {
+		if quantities[i] == 0 {
+			continue
+		}
+		item.Quantity = quantities[i]
+		item.Total = item.UnitPrice.Mul(int64(item.Quantity))
+		remaining = append(remaining, item)
+	}
+	if len(remaining) == 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Cancel the order instead of removing every item", nil)
+	}
+	return remaining, nil
+}
+
+// repriceItems recomputes the subtotal, tax and total of an order for a new set of already priced lines.
+// Promo code discounts are kept as granted, except that items can never be discounted below zero.
+func (s *orderService) repriceItems(order *models.Order, items models.OrderItemsArray) error {
+	restaurant, err := s.restaurantRepo.GetByID(order.RestaurantID)
+	if err != nil {
+		return err
+	}
+	var address *models.Address
+	if order.DeliveryAddressID != nil {
+		if address, err = s.addressRepo.GetByID(*order.DeliveryAddressID); err != nil {
+			return err
+		}
+	}
+
+	subtotal := models.Zero(order.Currency)
+	taxableLines := make([]models.TaxableLine, 0, len(items)+1)
+	itemTotals := make([]models.Money, 0, len(items))
+	for _, item := range items {
+		food, err := s.foodRepo.GetByID(item.FoodID)
+		if err != nil {
+			return err
+		}
+		subtotal = subtotal.Add(item.Total)
+		taxableLines = append(taxableLines, models.TaxableLine{Category: food.TaxCategory, Amount: item.Total})
+		itemTotals = append(itemTotals, item.Total)
+	}
+
+	deliveryDiscount := order.DiscountLines.OfType(models.PromotionFreeDelivery, order.Currency)
+	itemDiscount := order.Discount.Sub(deliveryDiscount)
+	if itemDiscount.Cmp(subtotal) > 0 {
+		order.DiscountLines = shrinkItemDiscounts(order.DiscountLines, subtotal)
+		itemDiscount = subtotal
+		order.Discount = itemDiscount.Add(deliveryDiscount)
+	}
+	for i, share := range allocateProportionally(itemDiscount, itemTotals) {
+		taxableLines[i].Amount = taxableLines[i].Amount.Sub(share)
+	}
+	taxableLines = append(taxableLines, models.TaxableLine{Category: models.TaxCategoryDelivery, Amount: order.DeliveryFee.Add(order.SmallOrderFee).Add(order.ServiceFee).Sub(deliveryDiscount)})
+
+	// Tax at the rates in force when the order was placed
+	tax, err := s.taxService.CalculateTax(taxJurisdiction(restaurant, address), taxableLines, order.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	order.Items = items
+	order.Subtotal = subtotal
+	order.Tax = tax.Total
+	order.TaxLines = tax.Lines
+	// A tip chosen as a percentage follows the subtotal down
+	if order.TipBasisPoints != nil {
+		order.Tip = subtotal.Percent(*order.TipBasisPoints)
+	}
+	order.Total = subtotal.Add(order.DeliveryFee).Add(order.SmallOrderFee).Add(order.ServiceFee).Sub(order.Discount).Add(tax.Exclusive).Add(order.Tip)
+	return nil
+}
+
+// shrinkItemDiscounts scales the item discount lines down so together they come to limit
+func shrinkItemDiscounts(lines models.DiscountLinesArray, limit models.Money) models.DiscountLinesArray {
+	amounts := make([]models.Money, len(lines))
+	for i, line := range lines {
+		amounts[i] = models.Zero(limit.Currency)
+		if line.Type != models.PromotionFreeDelivery {
+			amounts[i] = line.Amount
+		}
+	}
+	shrunk := append(models.DiscountLinesArray(nil), lines...)
+	for i, share := range allocateProportionally(limit, amounts) {
+		if shrunk[i].Type != models.PromotionFreeDelivery {
+			shrunk[i].Amount = share
+		}
+	}
+	return shrunk
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+	"dfood/pkg/errors"
+	"dfood/pkg/logger"
+)
+
+// evaluateCancellation applies the cancellation policy to an order for the given actor:
+// free until the kitchen starts preparing the order, a configurable fee from then on,
+// and only support can cancel an order that is already on its way. Restaurants never charge a fee.
+func evaluateCancellation(cfg config.CancellationConfig, order *models.Order, actor models.CancellationActor, waiveFee bool) *models.CancellationDecision {
+	decision := &models.CancellationDecision{
+		Status:       order.Status,
+		Fee:          models.Zero(order.Currency),
+		RefundAmount: models.Zero(order.Currency),
+	}
+
+	switch order.Status {
+	case models.OrderStatusDelivered:
+		decision.Message = "Cannot cancel delivered order"
+		return decision
+	case models.OrderStatusCancelled:
+		decision.Message = "Order is already cancelled"
+		return decision
+	case models.OrderStatusScheduled, models.OrderStatusPending, models.OrderStatusConfirmed:
+		decision.FeeBasisPoints = 0
+	case models.OrderStatusPreparing:
+		decision.FeeBasisPoints = cfg.PreparingFeeBasisPoints
+	case models.OrderStatusOnTheWay:
+		if actor != models.CancelledBySupport {
+			decision.Message = "Order is already on its way; please contact support to cancel"
+			return decision
+		}
+		decision.FeeBasisPoints = cfg.OnTheWayFeeBasisPoints
+	default:
+		decision.Message = "Order cannot be cancelled in its current status"
+		return decision
+	}
+
+	// The restaurant is at fault when it cancels, and support may waive the fee
+	if actor == models.CancelledByRestaurant || (actor == models.CancelledBySupport && waiveFee) {
+		decision.FeeBasisPoints = 0
+	}
+
+	decision.Allowed = true
+	decision.Fee = order.Subtotal.Percent(decision.FeeBasisPoints).Min(order.Total)
+	decision.RefundAmount = order.Total.Sub(decision.Fee)
+	return decision
+}
+
+func (s *orderService) PreviewCancellation(viewer models.OrderViewer, orderID string, actor models.CancellationActor) (*models.CancellationDecision, error) {
+	if strings.TrimSpace(orderID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Order ID is required", nil)
+	}
+	if actor == "" {
+		actor = models.CancelledByCustomer
+	}
+	if !actor.IsValid() {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid cancellation actor, expected customer, restaurant or support", nil)
+	}
+
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeCancellation(viewer, order, actor); err != nil {
+		return nil, err
+	}
+	return evaluateCancellation(s.cancellationConfig, order, actor, false), nil
+}
+
+func (s *orderService) CancelOrder(viewer models.OrderViewer, orderID string, request *models.CancelOrderRequest) (*models.Order, error) {
+	if strings.TrimSpace(orderID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Order ID is required", nil)
+	}
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Cancellation details are required", nil)
+	}
+	if !request.CancelledBy.IsValid() {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid cancellation actor, expected customer, restaurant or support", nil)
+	}
+	if !request.Reason.IsValidFor(request.CancelledBy) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid cancellation reason for "+string(request.CancelledBy), nil)
+	}
+	if request.WaiveFee && request.CancelledBy != models.CancelledBySupport {
+		return nil, errors.NewHTTPError(http.StatusForbidden, "Only support can waive the cancellation fee", nil)
+	}
+
+	// Validate order exists
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeCancellation(viewer, order, request.CancelledBy); err != nil {
+		return nil, err
+	}
+
+	decision := evaluateCancellation(s.cancellationConfig, order, request.CancelledBy, request.WaiveFee)
+	if !decision.Allowed {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, decision.Message, nil)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":              models.OrderStatusCancelled,
+		"cancelled_by":        request.CancelledBy,
+		"cancellation_reason": request.Reason,
+		"cancellation_note":   request.Note,
+		"cancelled_at":        now,
+		"cancellation_fee":    decision.Fee,
+	}
+	if err := s.orderRepo.UpdateIfStatus(order.ID, order.Status, updates); err != nil {
+		return nil, err
+	}
+	order.Status = models.OrderStatusCancelled
+	order.CancelledBy = &request.CancelledBy
+	order.CancellationReason = &request.Reason
+	order.CancellationNote = request.Note
+	order.CancelledAt = &now
+	order.CancellationFee = decision.Fee
+
+	// A cancelled order does not count towards promo code limits, and its points go back to the customer
+	s.releaseDiscounts(order.ID)
+
+	// The cancellation stands even if the refund fails; a failed refund is flagged for follow-up.
+	// Holds that were never captured are voided, except for whatever the fee still needs.
+	releaseErr := s.paymentService.ReleaseOrder(order.ID, decision.Fee)
+	if releaseErr != nil {
+		logger.Error("Failed to release payments for cancelled order", "order_id", order.ID, "error", releaseErr)
+	}
+	refunded, refundStatus := s.refundOrder(viewer, order, decision.Fee, request.ToWallet)
+	if releaseErr != nil {
+		refundStatus = models.RefundStatusFailed
+	}
+	order.RefundAmount = refunded
+	order.RefundStatus = &refundStatus
+	if err := s.orderRepo.UpdateIfStatus(order.ID, models.OrderStatusCancelled, map[string]interface{}{
+		"refund_amount": refunded,
+		"refund_status": refundStatus,
+	}); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// authorizeCancellation checks that the authenticated caller may cancel the order as the given actor:
+// customers only their own orders, restaurants only orders placed with a restaurant they own,
+// and only support and admin staff may act as support
+func (s *orderService) authorizeCancellation(viewer models.OrderViewer, order *models.Order, actor models.CancellationActor) error {
+	if actor == models.CancelledBySupport {
+		if viewer.Role != models.RoleSupport && viewer.Role != models.RoleAdmin {
+			return errors.NewHTTPError(http.StatusForbidden, "Only support can cancel as support", nil)
+		}
+		return nil
+	}
+
+	user, err := callerUser(s.userRepo, viewer)
+	if err != nil {
+		return err
+	}
+
+	switch actor {
+	case models.CancelledByCustomer:
+		if order.UserID != user.ID {
+			return errors.NewHTTPError(http.StatusForbidden, "Order does not belong to user", nil)
+		}
+	case models.CancelledByRestaurant:
+		restaurant, err := s.restaurantRepo.GetByID(order.RestaurantID)
+		if err != nil {
+			return err
+		}
+		if restaurant.OwnerID == nil || *restaurant.OwnerID != user.ID {
+			return errors.NewHTTPError(http.StatusForbidden, "Only the restaurant's owner can cancel its orders", nil)
+		}
+	}
+	return nil
+}
+
+// refundOrder refunds everything collected for the order beyond the cancellation fee through PaymentService,
+// to the customer's wallet when toWallet is set
+func (s *orderService) refundOrder(viewer models.OrderViewer, order *models.Order, fee models.Money, toWallet bool) (models.Money, models.RefundStatus) {
+	refunded := models.Zero(order.Currency)
+
+	transactions, err := s.paymentService.GetOrderTransactions(order.ID)
+	if err != nil {
+		logger.Error("Failed to load payments for cancelled order", "order_id", order.ID, "error", err)
+		return refunded, models.RefundStatusFailed
+	}
+
+	// Refunds issued before the cancellation count towards what the customer is owed
+	remaining := fee.Neg()
+	for _, transaction := range transactions {
+		if transaction.CollectsFunds() && transaction.Status == models.PaymentStatusCompleted {
+			remaining = remaining.Add(transaction.Amount.Sub(transaction.RefundedAmount))
+		}
+	}
+	if !remaining.IsPositive() {
+		return refunded, models.RefundStatusNone
+	}
+
+	for _, transaction := range transactions {
+		if !transaction.CollectsFunds() || transaction.Status != models.PaymentStatusCompleted || !remaining.IsPositive() {
+			continue
+		}
+
+		refund := transaction.Amount.Sub(transaction.RefundedAmount).Min(remaining)
+		if !refund.IsPositive() {
+			continue
+		}
+		if _, err := s.paymentService.ProcessRefund(viewer, transaction.ID, refund, toWallet); err != nil {
+			logger.Error("Failed to refund cancelled order", "order_id", order.ID, "transaction_id", transaction.ID, "error", err)
+			return refunded, models.RefundStatusFailed
+		}
+		refunded = refunded.Add(refund)
+		remaining = remaining.Sub(refund)
+	}
+
+	return refunded, models.RefundStatusRefunded
+}
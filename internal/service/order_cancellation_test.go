@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+)
+
+func TestEvaluateCancellationFees(t *testing.T) {
+	cfg := config.CancellationConfig{PreparingFeeBasisPoints: 5000, OnTheWayFeeBasisPoints: 10000}
+	const refused = -1
+
+	// Fee kept from a 20.00 subtotal, 24.60 total order, by who cancels: customer, restaurant, support, support waiving the fee
+	grid := map[models.OrderStatus][4]int64{
+		models.OrderStatusScheduled: {0, 0, 0, 0},
+		models.OrderStatusPending:   {0, 0, 0, 0},
+		models.OrderStatusConfirmed: {0, 0, 0, 0},
+		models.OrderStatusPreparing: {1000, 0, 1000, 0},
+		models.OrderStatusOnTheWay:  {refused, refused, 2000, 0},
+		models.OrderStatusDelivered: {refused, refused, refused, refused},
+		models.OrderStatusCancelled: {refused, refused, refused, refused},
+	}
+	cancellers := [4]struct {
+		actor models.CancellationActor
+		waive bool
+	}{{models.CancelledByCustomer, false}, {models.CancelledByRestaurant, false}, {models.CancelledBySupport, false}, {models.CancelledBySupport, true}}
+
+	for status, fees := range grid {
+		for i, canceller := range cancellers {
+			order := testOrder("o1", "u1", "r1")
+			order.Status = status
+			decision := evaluateCancellation(cfg, order, canceller.actor, canceller.waive)
+
+			if fees[i] == refused {
+				if decision.Allowed || decision.Message == "" {
+					t.Errorf("%s cancelling a %s order (waive %v) = %+v, want refused with a reason", canceller.actor, status, canceller.waive, decision)
+				}
+				continue
+			}
+			if !decision.Allowed || decision.Fee.Amount != fees[i] || decision.RefundAmount.Amount != 2460-fees[i] {
+				t.Errorf("%s cancelling a %s order (waive %v) = %+v, want a fee of %d and the rest refunded", canceller.actor, status, canceller.waive, decision, fees[i])
+			}
+		}
+	}
+}
+
+func TestEvaluateCancellationFeeNeverExceedsTheTotal(t *testing.T) {
+	// A 20.00 basket paid down to 12.00 with a promotion; the full-subtotal fee is capped at what was paid
+	order := testOrder("o1", "u1", "r1")
+	order.Status = models.OrderStatusOnTheWay
+	order.Discount, order.Total = usd(1260), usd(1200)
+
+	decision := evaluateCancellation(config.CancellationConfig{OnTheWayFeeBasisPoints: 10000}, order, models.CancelledBySupport, false)
+	if !decision.Allowed || decision.Fee.Amount != 1200 || !decision.RefundAmount.IsZero() {
+		t.Errorf("decision = %+v, want the 12.00 paid kept as the fee and nothing refunded", decision)
+	}
+}
@@ -0,0 +1,425 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+)
+
+const (
+	quoteValidity          = 5 * time.Minute
+	defaultPrepMinutes     = 15
+	courierSpeedKmPerHour  = 20.0
+	defaultDeliveryMinutes = 30
+)
+
+// orderPricing carries what was resolved while pricing an order
+type orderPricing struct {
+	restaurant          *models.Restaurant
+	address             *models.Address
+	estimatedMinutes    int
+	estimatedDeliveryAt time.Time
+}
+
+// priceOrder validates the basket and fills in item prices, fees, tax and totals on the order.
+// CreateOrder and QuoteOrder both go through here so a quote always matches the order price.
+func (s *orderService) priceOrder(order *models.Order) (*orderPricing, error) {
+	// Validate restaurant exists
+	restaurant, err := s.restaurantRepo.GetByID(order.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+	order.RestaurantName = restaurant.Name
+
+	// Resolve the saved delivery address when one is referenced
+	address, err := s.resolveDeliveryAddress(order)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate order items and calculate totals
+	order.Currency = models.DefaultCurrency
+	subtotal := models.Zero(order.Currency)
+	var taxableLines []models.TaxableLine
+	var discountableLines []models.DiscountableLine
+	prepMinutes := 0
+	for i, item := range order.Items {
+		if strings.TrimSpace(item.FoodID) == "" {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Food ID is required for all items", nil)
+		}
+		if item.Quantity <= 0 {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Quantity must be greater than 0", nil)
+		}
+
+		// Validate food exists and is available
+		food, err := s.foodRepo.GetByID(item.FoodID)
+		if err != nil {
+			return nil, err
+		}
+		if !food.IsAvailable {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Food item is not available: "+food.Name, nil)
+		}
+		if food.RestaurantID != order.RestaurantID {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "All items must be from the same restaurant", nil)
+		}
+
+		// Validate modifier selections and resolve their prices
+		modifiers, modifiersTotal, err := resolveModifiers(food, item.Modifiers)
+		if err != nil {
+			return nil, err
+		}
+
+		// Update item details
+		order.Items[i].FoodName = food.Name
+		order.Items[i].Price = food.Price
+		order.Items[i].Modifiers = modifiers
+		order.Items[i].UnitPrice = food.Price.Add(modifiersTotal)
+		order.Items[i].Total = order.Items[i].UnitPrice.Mul(int64(item.Quantity))
+		subtotal = subtotal.Add(order.Items[i].Total)
+		taxableLines = append(taxableLines, models.TaxableLine{Category: food.TaxCategory, Amount: order.Items[i].Total})
+		discountableLines = append(discountableLines, models.DiscountableLine{Category: food.Category, Amount: order.Items[i].Total})
+
+		// Items are prepared in parallel, so the slowest one sets the kitchen time
+		if minutes := parseMinutes(food.PreparationTime); minutes > prepMinutes {
+			prepMinutes = minutes
+		}
+	}
+
+	// Set order totals
+	order.Subtotal = subtotal
+
+	// Delivery fees are always computed server-side from distance, zones and basket size
+	delivery, err := s.deliveryService.QuoteDelivery(restaurant, address, subtotal)
+	if err != nil {
+		return nil, err
+	}
+	order.DeliveryFee = delivery.Fee
+	order.SmallOrderFee = delivery.SmallOrderFee
+	order.DeliveryDistanceKm = delivery.DistanceKm
+	order.ServiceFee = s.serviceFee(subtotal)
+	order.MembershipID = nil
+
+	// Promo codes, membership perks and loyalty points come off before tax, so each item is taxed on what the customer actually pays for it
+	order.PromoCodes = normalizePromoCodes(order.PromoCodes)
+	discount, err := s.promotionService.EvaluatePromotions(order, discountableLines)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.membershipService.ApplyMembership(order, discount); err != nil {
+		return nil, err
+	}
+	if err := s.loyaltyService.ApplyLoyalty(order, discountableLines, discount); err != nil {
+		return nil, err
+	}
+	for i := range discountableLines {
+		taxableLines[i].Amount = taxableLines[i].Amount.Sub(discount.ItemDiscounts[i])
+	}
+	order.Discount = discount.Total
+	order.DiscountLines = discount.Lines
+	// Fees are taxed like delivery
+	taxableLines = append(taxableLines, models.TaxableLine{Category: models.TaxCategoryDelivery, Amount: order.DeliveryFee.Add(order.SmallOrderFee).Add(order.ServiceFee).Sub(discount.Delivery)})
+
+	// Tax is always computed server-side; any client-supplied value is discarded
+	tax, err := s.taxService.CalculateTax(taxJurisdiction(restaurant, address), taxableLines, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	order.Tax = tax.Total
+	order.TaxLines = tax.Lines
+
+	// The tip is the one amount the customer chooses; it is untaxed and paid to the courier in full
+	tip, err := resolveTip(order.Subtotal, order.Tip, order.TipBasisPoints)
+	if err != nil {
+		return nil, err
+	}
+	order.Tip = tip
+	order.Total = order.Subtotal.Add(order.DeliveryFee).Add(order.SmallOrderFee).Add(order.ServiceFee).Sub(order.Discount).Add(tax.Exclusive).Add(order.Tip)
+
+	minutes := estimateDeliveryMinutes(restaurant, prepMinutes, delivery.DistanceKm)
+	return &orderPricing{
+		restaurant:          restaurant,
+		address:             address,
+		estimatedMinutes:    minutes,
+		estimatedDeliveryAt: time.Now().Add(time.Duration(minutes) * time.Minute),
+	}, nil
+}
+
+func (s *orderService) QuoteOrder(request *models.QuoteOrderRequest) (*models.OrderQuote, error) {
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Quote request is required", nil)
+	}
+	if strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	if strings.TrimSpace(request.RestaurantID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Restaurant ID is required", nil)
+	}
+	if len(request.Items) == 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Order items are required", nil)
+	}
+
+	// Validate user exists
+	_, err := s.userRepo.GetByID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Price a transient order through the same path CreateOrder uses
+	order := &models.Order{
+		UserID:            request.UserID,
+		RestaurantID:      request.RestaurantID,
+		Items:             append(models.OrderItemsArray(nil), request.Items...),
+		DeliveryAddressID: request.DeliveryAddressID,
+		Tip:               request.Tip,
+		TipBasisPoints:    request.TipBasisPoints,
+		PromoCodes:        request.PromoCodes,
+		RedeemPoints:      request.RedeemPoints,
+	}
+	if request.PromoCode != nil {
+		order.PromoCodes = append(order.PromoCodes, *request.PromoCode)
+	}
+	pricing, err := s.priceOrder(order)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &models.OrderQuote{
+		ID:                       utils.GenerateQuoteID(),
+		UserID:                   order.UserID,
+		RestaurantID:             order.RestaurantID,
+		RestaurantName:           order.RestaurantName,
+		DeliveryAddressID:        order.DeliveryAddressID,
+		PromoCodes:               order.PromoCodes,
+		Fingerprint:              basketFingerprint(order),
+		Currency:                 order.Currency,
+		Items:                    order.Items,
+		Subtotal:                 order.Subtotal,
+		DeliveryFee:              order.DeliveryFee,
+		SmallOrderFee:            order.SmallOrderFee,
+		ServiceFee:               order.ServiceFee,
+		MembershipID:             order.MembershipID,
+		Discount:                 order.Discount,
+		DiscountLines:            order.DiscountLines,
+		Tax:                      order.Tax,
+		TaxLines:                 order.TaxLines,
+		Tip:                      order.Tip,
+		Total:                    order.Total,
+		DeliveryDistanceKm:       order.DeliveryDistanceKm,
+		EstimatedDeliveryMinutes: pricing.estimatedMinutes,
+		EstimatedDeliveryAt:      pricing.estimatedDeliveryAt,
+		ExpiresAt:                time.Now().Add(quoteValidity),
+	}
+	if err := s.quoteRepo.Create(quote); err != nil {
+		return nil, err
+	}
+	quote.QuoteID = utils.SignValue(quote.ID)
+
+	return quote, nil
+}
+
+// applyQuote replaces the freshly computed prices with those of a valid quote for the same basket.
+// CreateOrder claims the quote just before the order is stored.
+func (s *orderService) applyQuote(order *models.Order) error {
+	quoteID, ok := utils.VerifySignedValue(*order.QuoteID)
+	if !ok {
+		return errors.NewHTTPError(http.StatusBadRequest, "Invalid quote ID", nil)
+	}
+
+	quote, err := s.quoteRepo.GetByID(quoteID)
+	if err != nil {
+		return err
+	}
+	if quote.UserID != order.UserID {
+		return errors.NewHTTPError(http.StatusForbidden, "Quote does not belong to user", nil)
+	}
+	if time.Now().After(quote.ExpiresAt) {
+		return errors.NewHTTPError(http.StatusConflict, "Quote has expired, please request a new quote", nil)
+	}
+	if quote.Fingerprint != basketFingerprint(order) {
+		return errors.NewHTTPError(http.StatusConflict, "Basket has changed since the quote was issued, please request a new quote", nil)
+	}
+
+	order.QuoteID = &quote.ID
+	order.Items = quote.Items
+	order.Subtotal = quote.Subtotal
+	order.DeliveryFee = quote.DeliveryFee
+	order.SmallOrderFee = quote.SmallOrderFee
+	order.ServiceFee = quote.ServiceFee
+	order.MembershipID = quote.MembershipID
+	order.Discount = quote.Discount
+	order.DiscountLines = quote.DiscountLines
+	order.Tax = quote.Tax
+	order.TaxLines = quote.TaxLines
+	// The tip is not a price, so the one chosen at checkout replaces the quoted one
+	order.Total = quote.Total.Sub(quote.Tip).Add(order.Tip)
+	return nil
+}
+
+// basketFingerprint identifies what was priced, independent of item order and of any prices
+func basketFingerprint(order *models.Order) string {
+	lines := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		options := make([]string, 0, len(item.Modifiers))
+		for _, modifier := range item.Modifiers {
+			options = append(options, modifier.OptionID)
+		}
+		sort.Strings(options)
+		lines = append(lines, fmt.Sprintf("%s:%d:%s", item.FoodID, item.Quantity, strings.Join(options, ",")))
+	}
+	sort.Strings(lines)
+
+	addressID := ""
+	if order.DeliveryAddressID != nil {
+		addressID = *order.DeliveryAddressID
+	}
+	basket := order.RestaurantID + "|" + addressID + "|" + strings.Join(lines, ";")
+	if len(order.PromoCodes) > 0 {
+		codes := append([]string(nil), order.PromoCodes...)
+		sort.Strings(codes)
+		basket += "|" + strings.Join(codes, ",")
+	}
+	if order.RedeemPoints > 0 {
+		basket += "|points:" + strconv.FormatInt(order.RedeemPoints, 10)
+	}
+	sum := sha256.Sum256([]byte(basket))
+	return hex.EncodeToString(sum[:])
+}
+
+// serviceFee is the configured share of the subtotal, kept between the minimum and maximum fee
+func (s *orderService) serviceFee(subtotal models.Money) models.Money {
+	if s.serviceFeeConfig.BasisPoints <= 0 {
+		return models.Zero(subtotal.Currency)
+	}
+	fee := subtotal.Percent(s.serviceFeeConfig.BasisPoints).Max(models.NewMoney(s.serviceFeeConfig.MinFee, subtotal.Currency))
+	if s.serviceFeeConfig.MaxFee > 0 {
+		fee = fee.Min(models.NewMoney(s.serviceFeeConfig.MaxFee, subtotal.Currency))
+	}
+	return fee
+}
+
+var minutesPattern = regexp.MustCompile(`\d+`)
+
+// parseMinutes extracts the first number from free-form durations such as "15 mins" or "30-40 min"
+func parseMinutes(value string) int {
+	match := minutesPattern.FindString(value)
+	if match == "" {
+		return 0
+	}
+	minutes, _ := strconv.Atoi(match)
+	return minutes
+}
+
+// estimateDeliveryMinutes adds kitchen time to travel time, falling back to the restaurant's advertised time
+func estimateDeliveryMinutes(restaurant *models.Restaurant, prepMinutes int, distanceKm *float64) int {
+	if distanceKm == nil {
+		if minutes := parseMinutes(restaurant.DeliveryTime); minutes > 0 {
+			return max(minutes, prepMinutes)
+		}
+		return max(defaultDeliveryMinutes, prepMinutes)
+	}
+
+	if prepMinutes == 0 {
+		prepMinutes = defaultPrepMinutes
+	}
+	travelMinutes := int(*distanceKm/courierSpeedKmPerHour*60 + 0.5)
+	return prepMinutes + travelMinutes
+}
+
+// resolveDeliveryAddress loads the saved address referenced by the order, if any,
+// and fills in the free-form delivery address from it when the client left it blank
+func (s *orderService) resolveDeliveryAddress(order *models.Order) (*models.Address, error) {
+	if order.DeliveryAddressID == nil || strings.TrimSpace(*order.DeliveryAddressID) == "" {
+		order.DeliveryAddressID = nil
+		return nil, nil
+	}
+
+	address, err := s.addressRepo.GetByID(*order.DeliveryAddressID)
+	if err != nil {
+		return nil, err
+	}
+	if address.UserID != order.UserID {
+		return nil, errors.NewHTTPError(http.StatusForbidden, "Address does not belong to user", nil)
+	}
+
+	if strings.TrimSpace(order.DeliveryAddress) == "" {
+		order.DeliveryAddress = fmt.Sprintf("%s, %s, %s, %s %s", address.Address, address.Street, address.City, address.State, address.ZipCode)
+	}
+	return address, nil
+}
+
+// taxJurisdiction taxes at the delivery destination when known, otherwise at the restaurant
+func taxJurisdiction(restaurant *models.Restaurant, address *models.Address) models.TaxJurisdiction {
+	if address != nil {
+		return models.TaxJurisdiction{State: address.State, ZipCode: address.ZipCode}
+	}
+	return models.TaxJurisdiction{State: restaurant.State, ZipCode: restaurant.ZipCode}
+}
+
+// resolveModifiers validates the selected modifiers against the food's modifier groups
+// and returns the selections with server-side names and prices filled in
+func resolveModifiers(food *models.Food, selected []models.OrderItemModifier) ([]models.OrderItemModifier, models.Money, error) {
+	groups := make(map[string]*models.ModifierGroup, len(food.ModifierGroups))
+	options := make(map[string]*models.ModifierOption)
+	for i := range food.ModifierGroups {
+		group := &food.ModifierGroups[i]
+		groups[group.ID] = group
+		for j := range group.Options {
+			options[group.Options[j].ID] = &group.Options[j]
+		}
+	}
+
+	total := models.Zero(food.Price.Currency)
+	counts := make(map[string]int)
+	seen := make(map[string]bool)
+	resolved := make([]models.OrderItemModifier, 0, len(selected))
+	for _, selection := range selected {
+		option, exists := options[selection.OptionID]
+		if !exists {
+			return nil, models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "Invalid modifier option for "+food.Name, nil)
+		}
+		group := groups[option.GroupID]
+		if selection.GroupID != "" && selection.GroupID != group.ID {
+			return nil, models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "Modifier option does not belong to group: "+group.Name, nil)
+		}
+		if !option.IsAvailable {
+			return nil, models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "Modifier option is not available: "+option.Name, nil)
+		}
+		if seen[option.ID] {
+			return nil, models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "Modifier option selected more than once: "+option.Name, nil)
+		}
+		seen[option.ID] = true
+		counts[group.ID]++
+
+		resolved = append(resolved, models.OrderItemModifier{
+			GroupID:    group.ID,
+			GroupName:  group.Name,
+			OptionID:   option.ID,
+			OptionName: option.Name,
+			PriceDelta: option.PriceDelta,
+		})
+		total = total.Add(option.PriceDelta)
+	}
+
+	// Enforce min/max selections per group
+	for _, group := range groups {
+		count := counts[group.ID]
+		if count < group.EffectiveMinSelections() {
+			return nil, models.Money{}, errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s: select at least %d option(s) for %s", food.Name, group.EffectiveMinSelections(), group.Name), nil)
+		}
+		if group.MaxSelections > 0 && count > group.MaxSelections {
+			return nil, models.Money{}, errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s: select at most %d option(s) for %s", food.Name, group.MaxSelections, group.Name), nil)
+		}
+	}
+
+	return resolved, total, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"testing"
+
+	"dfood/internal/models"
+)
+
+// pizza has a required size, up to two paid toppings and any number of free sauces
+func pizza() *models.Food {
+	option := func(id, groupID, name string, delta int64, available bool) models.ModifierOption {
+		return models.ModifierOption{ID: id, GroupID: groupID, Name: name, PriceDelta: usd(delta), IsAvailable: available}
+	}
+	return &models.Food{
+		ID:    "food-pizza",
+		Name:  "Pizza",
+		Price: usd(1200),
+		ModifierGroups: []models.ModifierGroup{
+			{ID: "size", Name: "Size", IsRequired: true, MaxSelections: 1, Options: []models.ModifierOption{
+				option("size-s", "size", "Small", 0, true),
+				option("size-l", "size", "Large", 400, true),
+			}},
+			{ID: "toppings", Name: "Toppings", MaxSelections: 2, Options: []models.ModifierOption{
+				option("top-olive", "toppings", "Olives", 150, true),
+				option("top-ham", "toppings", "Ham", 250, true),
+				option("top-egg", "toppings", "Egg", 100, true),
+				option("top-truffle", "toppings", "Truffle", 900, false),
+			}},
+			{ID: "sauces", Name: "Sauces", MaxSelections: 0, Options: []models.ModifierOption{
+				option("sauce-bbq", "sauces", "BBQ", 0, true),
+				option("sauce-garlic", "sauces", "Garlic", 0, true),
+				option("sauce-chili", "sauces", "Chili", 0, true),
+			}},
+		},
+	}
+}
+
+func pick(optionIDs ...string) []models.OrderItemModifier {
+	selected := make([]models.OrderItemModifier, len(optionIDs))
+	for i, id := range optionIDs {
+		selected[i] = models.OrderItemModifier{OptionID: id}
+	}
+	return selected
+}
+
+func TestResolveModifiersPricesFromTheMenu(t *testing.T) {
+	// The client claims the large size is free; the menu price wins
+	selected := pick("size-l", "top-ham", "sauce-bbq", "sauce-garlic", "sauce-chili")
+	selected[0].PriceDelta = usd(0)
+	selected[0].OptionName = "Free upgrade"
+
+	resolved, total, err := resolveModifiers(pizza(), selected)
+	if err != nil {
+		t.Fatalf("resolveModifiers error = %v", err)
+	}
+	if total.Amount != 650 {
+		t.Errorf("modifier total = %d, want 650", total.Amount)
+	}
+	if len(resolved) != len(selected) {
+		t.Fatalf("resolved %d modifiers, want %d", len(resolved), len(selected))
+	}
+	if large := resolved[0]; large.PriceDelta.Amount != 400 || large.OptionName != "Large" || large.GroupName != "Size" {
+		t.Errorf("resolved size = %+v, want Large at 400 from the menu", large)
+	}
+}
+
+func TestResolveModifiersRejects(t *testing.T) {
+	cases := map[string][]models.OrderItemModifier{
+		"missing required size":      pick("top-ham"),
+		"two sizes":                  pick("size-s", "size-l"),
+		"three toppings":             pick("size-s", "top-olive", "top-ham", "top-egg"),
+		"unavailable topping":        pick("size-s", "top-truffle"),
+		"same option twice":          pick("size-s", "top-ham", "top-ham"),
+		"option from another food":   pick("size-s", "burger-cheese"),
+		"option claimed for a group": {{OptionID: "size-s"}, {GroupID: "size", OptionID: "top-ham"}},
+	}
+
+	for name, selected := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := resolveModifiers(pizza(), selected); err == nil {
+				t.Errorf("resolveModifiers(%v) = nil error", selected)
+			}
+		})
+	}
+}
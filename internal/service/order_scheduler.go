@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/pkg/errors"
+	"dfood/pkg/logger"
+)
+
+const (
+	defaultScheduleLeadMinutes = 45
+	defaultMaxScheduleDays     = 7
+)
+
+// scheduleOrder validates a pre-order's requested time and holds the order until the kitchen needs to start on it
+func (s *orderService) scheduleOrder(order *models.Order, pricing *orderPricing) error {
+	restaurant := pricing.restaurant
+	scheduledFor := *order.ScheduledFor
+	now := time.Now()
+
+	leadMinutes := restaurant.ScheduleLeadMinutes
+	if leadMinutes <= 0 {
+		leadMinutes = defaultScheduleLeadMinutes
+	}
+	leadMinutes = max(leadMinutes, pricing.estimatedMinutes)
+	if scheduledFor.Before(now.Add(time.Duration(leadMinutes) * time.Minute)) {
+		return errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Scheduled orders must be placed at least %d minutes in advance", leadMinutes), nil)
+	}
+
+	maxDays := restaurant.MaxScheduleDays
+	if maxDays <= 0 {
+		maxDays = defaultMaxScheduleDays
+	}
+	if scheduledFor.After(now.AddDate(0, 0, maxDays)) {
+		return errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Orders can be scheduled at most %d days in advance", maxDays), nil)
+	}
+
+	// The kitchen has to be open when it starts preparing the order.
+	// Times are stored in UTC so the scheduler's comparisons don't depend on the client's offset.
+	releaseAt := scheduledFor.Add(-time.Duration(pricing.estimatedMinutes) * time.Minute).UTC()
+	schedule, err := loadOpeningSchedule(s.restaurantRepo, restaurant, releaseAt, releaseAt)
+	if err != nil {
+		return err
+	}
+	if !schedule.isOpenAt(releaseAt) {
+		return errors.NewHTTPError(http.StatusBadRequest, restaurant.Name+" is closed at the requested time", nil)
+	}
+
+	scheduledFor = scheduledFor.UTC()
+	order.ScheduledFor = &scheduledFor
+	order.ReleaseAt = &releaseAt
+	order.EstimatedDeliveryAt = &scheduledFor
+	order.Status = models.OrderStatusScheduled
+	return nil
+}
+
+// ensureOpenNow refuses orders for right away while the restaurant is closed or paused
+func (s *orderService) ensureOpenNow(restaurant *models.Restaurant) error {
+	if err := setOpenStatus(s.restaurantRepo, restaurant); err != nil {
+		return err
+	}
+	if restaurant.IsOpen {
+		return nil
+	}
+
+	message := restaurant.Name + " is closed"
+	if restaurant.PausedUntil != nil && restaurant.PausedUntil.After(time.Now()) && restaurant.PauseReason != nil {
+		message += " (" + *restaurant.PauseReason + ")"
+	}
+	if restaurant.OpensAt != nil {
+		message += " and opens again " + restaurant.OpensAt.In(restaurantLocation(restaurant)).Format("Mon 15:04 MST")
+	}
+	return errors.NewHTTPError(http.StatusBadRequest, message, nil)
+}
+
+type OrderScheduler interface {
+	Start(ctx context.Context, interval time.Duration)
+	ReleaseDueOrders() (int64, error)
+}
+
+type orderScheduler struct {
+	orderRepo repository.OrderRepository
+}
+
+func NewOrderScheduler(orderRepo repository.OrderRepository) OrderScheduler {
+	return &orderScheduler{
+		orderRepo: orderRepo,
+	}
+}
+
+// Start releases due scheduled orders every interval until ctx is cancelled
+func (s *orderScheduler) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ReleaseDueOrders(); err != nil {
+			logger.Error("Failed to release scheduled orders", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReleaseDueOrders moves scheduled orders whose kitchen start time has arrived into the pending queue
+func (s *orderScheduler) ReleaseDueOrders() (int64, error) {
+	released, err := s.orderRepo.ReleaseScheduled(time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	if released > 0 {
+		logger.Info("Released scheduled orders", "count", released)
+	}
+	return released, nil
+}
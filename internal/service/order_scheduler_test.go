@@ -0,0 +1,88 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+func TestScheduleOrder(t *testing.T) {
+	openTestDB(t)
+	restaurant := &models.Restaurant{ID: "r1", Name: "Corner Deli", TimeZone: "UTC"}
+	for day := 0; day < 7; day++ {
+		seed(t, &models.OpeningHours{RestaurantID: "r1", DayOfWeek: day, OpensAt: "10:00", ClosesAt: "22:00"})
+	}
+	s := &orderService{restaurantRepo: repository.NewRestaurantRepository()}
+	pricing := &orderPricing{restaurant: restaurant, estimatedMinutes: 30}
+
+	now := time.Now().UTC()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	schedule := func(at time.Time) (*models.Order, error) {
+		// A client in another zone; the order must come back in UTC
+		requested := at.In(time.FixedZone("UTC-5", -5*60*60))
+		order := &models.Order{ScheduledFor: &requested, Status: models.OrderStatusPending}
+		return order, s.scheduleOrder(order, pricing)
+	}
+
+	order, err := schedule(tomorrow.Add(19 * time.Hour))
+	if err != nil {
+		t.Fatalf("scheduling for 19:00 tomorrow: %v", err)
+	}
+	if order.Status != models.OrderStatusScheduled {
+		t.Errorf("status = %s, want scheduled", order.Status)
+	}
+	if want := tomorrow.Add(18*time.Hour + 30*time.Minute); !order.ReleaseAt.Equal(want) || order.ReleaseAt.Location() != time.UTC {
+		t.Errorf("release at %v, want %v so the kitchen has its 30 minutes", order.ReleaseAt, want)
+	}
+	if order.ScheduledFor.Location() != time.UTC {
+		t.Errorf("scheduled for %v, want it stored in UTC", order.ScheduledFor)
+	}
+
+	// The kitchen would have to start at 09:45, before it opens, even though delivery falls in opening hours
+	_, err = schedule(tomorrow.Add(10*time.Hour + 15*time.Minute))
+	rejectedAs(t, err, http.StatusBadRequest)
+
+	// Less than the default 45 minutes of notice
+	_, err = schedule(now.Add(20 * time.Minute))
+	rejectedAs(t, err, http.StatusBadRequest)
+
+	// Past the default week ahead
+	_, err = schedule(tomorrow.AddDate(0, 0, 8).Add(12 * time.Hour))
+	rejectedAs(t, err, http.StatusBadRequest)
+
+	restaurant.MaxScheduleDays = 14
+	if _, err = schedule(tomorrow.AddDate(0, 0, 8).Add(12 * time.Hour)); err != nil {
+		t.Errorf("scheduling 9 days ahead with a 14 day limit: %v", err)
+	}
+}
+
+func TestReleaseDueOrders(t *testing.T) {
+	openTestDB(t)
+	now := time.Now().UTC()
+	due, later, pending := testOrder("due", "u1", "r1"), testOrder("later", "u1", "r1"), testOrder("pending", "u1", "r1")
+	for order, releaseAt := range map[*models.Order]time.Time{due: now.Add(-time.Minute), later: now.Add(time.Hour)} {
+		releaseAt := releaseAt
+		order.Status = models.OrderStatusScheduled
+		order.ReleaseAt = &releaseAt
+	}
+	seed(t, due, later, pending)
+	orderRepo := repository.NewOrderRepository()
+
+	released, err := NewOrderScheduler(orderRepo).ReleaseDueOrders()
+	if err != nil || released != 1 {
+		t.Fatalf("ReleaseDueOrders = %d, %v; want 1", released, err)
+	}
+	for id, want := range map[string]models.OrderStatus{"due": models.OrderStatusPending, "later": models.OrderStatusScheduled, "pending": models.OrderStatusPending} {
+		if order, err := orderRepo.GetByID(id); err != nil || order.Status != want {
+			t.Errorf("order %s = %v, %v; want status %s", id, order, err, want)
+		}
+	}
+
+	// Running again before the next order is due releases nothing
+	if released, err = NewOrderScheduler(orderRepo).ReleaseDueOrders(); err != nil || released != 0 {
+		t.Errorf("second ReleaseDueOrders = %d, %v; want 0", released, err)
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/pkg/errors"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	exportPageSize     = 500
+)
+
+// SearchOrders returns one page of orders matching the search parameters.
+// Customers are always limited to their own orders; support and admins can search everyone's.
+func (s *orderService) SearchOrders(viewer models.OrderViewer, params *models.OrderSearchParams) (*models.OrderSearchResult, error) {
+	filter, err := s.buildOrderFilter(viewer, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra order to learn whether there is a next page
+	pageSize := filter.Limit
+	filter.Limit++
+	orders, err := s.orderRepo.Search(*filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.OrderSearchResult{Orders: orders}
+	if len(orders) > pageSize {
+		result.Orders = orders[:pageSize]
+		cursor := encodeOrderCursor(&result.Orders[pageSize-1])
+		result.NextCursor = &cursor
+	}
+	return result, nil
+}
+
+// ExportOrdersCSV writes every order matching the search parameters to w as CSV, page by page
+func (s *orderService) ExportOrdersCSV(viewer models.OrderViewer, params *models.OrderSearchParams, w io.Writer) error {
+	filter, err := s.buildOrderFilter(viewer, params)
+	if err != nil {
+		return err
+	}
+	filter.Limit = exportPageSize
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"id", "created_at", "status", "user_id", "restaurant_id", "restaurant_name", "items",
+		"payment_method", "currency", "subtotal", "delivery_fee", "small_order_fee", "service_fee", "tax", "total",
+		"scheduled_for", "cancelled_at", "cancellation_reason",
+	}); err != nil {
+		return err
+	}
+
+	for {
+		orders, err := s.orderRepo.Search(*filter)
+		if err != nil {
+			return err
+		}
+		for i := range orders {
+			if err := writer.Write(orderCSVRecord(&orders[i])); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+
+		if len(orders) < filter.Limit {
+			return nil
+		}
+		last := orders[len(orders)-1]
+		filter.After = &models.OrderCursor{CreatedAt: last.CreatedAt, Total: last.Total.Amount, ID: last.ID}
+	}
+}
+
+// buildOrderFilter validates search parameters and scopes them to what the viewer may see
+func (s *orderService) buildOrderFilter(viewer models.OrderViewer, params *models.OrderSearchParams) (*models.OrderSearchFilter, error) {
+	if params == nil {
+		params = &models.OrderSearchParams{}
+	}
+
+	filter := &models.OrderSearchFilter{
+		UserID:        strings.TrimSpace(params.UserID),
+		RestaurantID:  strings.TrimSpace(params.RestaurantID),
+		From:          params.From,
+		To:            params.To,
+		PaymentMethod: strings.TrimSpace(params.PaymentMethod),
+		ItemQuery:     strings.TrimSpace(params.Query),
+		Sort:          params.Sort,
+		Limit:         params.Limit,
+	}
+
+	switch viewer.Role {
+	case models.RoleSupport, models.RoleAdmin:
+	case models.RoleCustomer:
+		user, err := s.userRepo.GetByEmail(viewer.Email)
+		if err != nil {
+			return nil, errors.NewHTTPError(http.StatusForbidden, "User not found for token", err)
+		}
+		filter.UserID = user.ID
+	default:
+		return nil, errors.NewHTTPError(http.StatusForbidden, "Insufficient permissions", nil)
+	}
+
+	for _, status := range params.Status {
+		for _, value := range strings.Split(string(status), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				filter.Statuses = append(filter.Statuses, models.OrderStatus(value))
+			}
+		}
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "from must be before to", nil)
+	}
+	if params.MinTotal != "" {
+		minTotal, err := models.ParseMoney(params.MinTotal, models.DefaultCurrency)
+		if err != nil {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid minTotal", err)
+		}
+		filter.MinTotal = &minTotal
+	}
+	if params.MaxTotal != "" {
+		maxTotal, err := models.ParseMoney(params.MaxTotal, models.DefaultCurrency)
+		if err != nil {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid maxTotal", err)
+		}
+		filter.MaxTotal = &maxTotal
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = models.OrderSortNewest
+	}
+	if !filter.Sort.IsValid() {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid sort, expected newest, oldest, total_desc or total_asc", nil)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSearchLimit
+	}
+	if filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+
+	if params.Cursor != "" {
+		cursor, err := decodeOrderCursor(params.Cursor)
+		if err != nil {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid cursor", err)
+		}
+		filter.After = cursor
+	}
+	return filter, nil
+}
+
+func encodeOrderCursor(order *models.Order) string {
+	data, _ := json.Marshal(models.OrderCursor{CreatedAt: order.CreatedAt, Total: order.Total.Amount, ID: order.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeOrderCursor(value string) (*models.OrderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor models.OrderCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.ID == "" {
+		return nil, fmt.Errorf("cursor has no order ID")
+	}
+	// Timestamps are stored in the server's zone, so compare in the same zone
+	cursor.CreatedAt = cursor.CreatedAt.In(time.Local)
+	return &cursor, nil
+}
+
+func orderCSVRecord(order *models.Order) []string {
+	items := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, fmt.Sprintf("%dx %s", item.Quantity, item.FoodName))
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	reason := ""
+	if order.CancellationReason != nil {
+		reason = string(*order.CancellationReason)
+	}
+
+	return []string{
+		order.ID,
+		order.CreatedAt.Format(time.RFC3339),
+		string(order.Status),
+		order.UserID,
+		order.RestaurantID,
+		order.RestaurantName,
+		strings.Join(items, "; "),
+		order.PaymentMethod,
+		string(order.Currency),
+		order.Subtotal.Decimal(),
+		order.DeliveryFee.Decimal(),
+		order.SmallOrderFee.Decimal(),
+		order.ServiceFee.Decimal(),
+		order.Tax.Decimal(),
+		order.Total.Decimal(),
+		formatTime(order.ScheduledFor),
+		formatTime(order.CancelledAt),
+		reason,
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+func TestSearchOrders(t *testing.T) {
+	openTestDB(t)
+	seed(t, testUser("ana"), testUser("ben"))
+
+	// Totals tie on purpose so the cursor has to fall back to the ID
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	for i, o := range []struct {
+		id, userID, item string
+		total            int64
+	}{
+		{"o1", "ana", "Sandwich", 1500},
+		{"o2", "ana", "Ramen", 2500},
+		{"o3", "ben", "Ramen", 2500},
+		{"o4", "ana", "Sandwich", 2500},
+		{"o5", "ana", "Pho", 900},
+		{"o6", "ben", "Pho", 3100},
+	} {
+		order := testOrder(o.id, o.userID, "r1")
+		order.Items[0].FoodName = o.item
+		order.Total = usd(o.total)
+		order.CreatedAt = start.Add(time.Duration(i) * time.Hour)
+		seed(t, order)
+	}
+	s := &orderService{orderRepo: repository.NewOrderRepository(), userRepo: repository.NewUserRepository()}
+	support := models.OrderViewer{Email: "agent@example.com", Role: models.RoleSupport}
+
+	// walk follows next cursors to the end and returns the order IDs in the order they were served
+	walk := func(viewer models.OrderViewer, params models.OrderSearchParams) []string {
+		t.Helper()
+		var ids []string
+		for page := 0; page < 10; page++ {
+			result, err := s.SearchOrders(viewer, &params)
+			if err != nil {
+				t.Fatalf("SearchOrders(%+v) error = %v", params, err)
+			}
+			for _, order := range result.Orders {
+				ids = append(ids, order.ID)
+			}
+			if result.NextCursor == nil {
+				return ids
+			}
+			params.Cursor = *result.NextCursor
+		}
+		t.Fatalf("SearchOrders(%+v) never ran out of pages", params)
+		return nil
+	}
+
+	if got, want := walk(support, models.OrderSearchParams{Sort: models.OrderSortTotalDesc, Limit: 2}), []string{"o6", "o4", "o3", "o2", "o1", "o5"}; !slices.Equal(got, want) {
+		t.Errorf("support by total, two per page = %v, want %v", got, want)
+	}
+	if got, want := walk(support, models.OrderSearchParams{Sort: models.OrderSortOldest, Limit: 4}), []string{"o1", "o2", "o3", "o4", "o5", "o6"}; !slices.Equal(got, want) {
+		t.Errorf("support oldest first = %v, want %v", got, want)
+	}
+	if got, want := walk(support, models.OrderSearchParams{Query: "ramen", MinTotal: "20.00", MaxTotal: "25.00"}), []string{"o3", "o2"}; !slices.Equal(got, want) {
+		t.Errorf("support searching ramen between 20 and 25 = %v, want %v", got, want)
+	}
+
+	// A customer asking for someone else's orders still only sees their own
+	ana := models.OrderViewer{Email: "ana@example.com", Role: models.RoleCustomer}
+	if got, want := walk(ana, models.OrderSearchParams{UserID: "ben", Limit: 3}), []string{"o5", "o4", "o2", "o1"}; !slices.Equal(got, want) {
+		t.Errorf("ana asking for ben's orders = %v, want only ana's own %v", got, want)
+	}
+
+	_, err := s.SearchOrders(models.OrderViewer{Email: "nobody@example.com", Role: models.RoleCustomer}, nil)
+	rejectedAs(t, err, http.StatusForbidden)
+	_, err = s.SearchOrders(support, &models.OrderSearchParams{Cursor: "not-a-cursor"})
+	rejectedAs(t, err, http.StatusBadRequest)
+}
@@ -1,36 +1,80 @@
 package service
 
 import (
+	"io"
 	"net/http"
 	"strings"
+	"time"
 
+	"dfood/internal/config"
 	"dfood/internal/models"
 	"dfood/internal/repository"
+	"dfood/internal/utils"
 	"dfood/pkg/errors"
+	"dfood/pkg/logger"
 )
 
 type OrderService interface {
 	CreateOrder(order *models.Order) (*models.Order, error)
-	GetUserOrders(userID string, limit, offset int) ([]models.Order, error)
+	QuoteOrder(request *models.QuoteOrderRequest) (*models.OrderQuote, error)
+	GetUserOrders(userID string, timeframe models.OrderTimeframe, limit, offset int) ([]models.Order, error)
 	GetOrderByID(orderID string) (*models.Order, error)
-	UpdateOrderStatus(orderID string, status models.OrderStatus) error
-	CancelOrder(orderID string) error
+	SearchOrders(viewer models.OrderViewer, params *models.OrderSearchParams) (*models.OrderSearchResult, error)
+	ExportOrdersCSV(viewer models.OrderViewer, params *models.OrderSearchParams, w io.Writer) error
+	UpdateOrderStatus(viewer models.OrderViewer, orderID string, request *models.UpdateOrderStatusRequest) error
+	AdjustTip(viewer models.OrderViewer, orderID string, request *models.AdjustTipRequest) (*models.Order, error)
+	RemoveOrderItems(viewer models.OrderViewer, orderID string, request *models.RemoveOrderItemsRequest) (*models.Order, error)
+	CancelOrder(viewer models.OrderViewer, orderID string, request *models.CancelOrderRequest) (*models.Order, error)
+	PreviewCancellation(viewer models.OrderViewer, orderID string, actor models.CancellationActor) (*models.CancellationDecision, error)
 	TrackOrder(orderID string) (*models.Order, error)
+	Reorder(orderID string, request *models.ReorderRequest) (*models.ReorderResult, error)
+	CreateOrderTemplate(request *models.CreateOrderTemplateRequest) (*models.OrderTemplate, error)
+	GetUserOrderTemplates(userID string) ([]models.OrderTemplate, error)
+	DeleteOrderTemplate(templateID string, request *models.DeleteOrderTemplateRequest) error
+	PlaceOrderTemplate(templateID string, request *models.PlaceOrderTemplateRequest) (*models.Order, error)
 }
 
 type orderService struct {
-	orderRepo      repository.OrderRepository
-	userRepo       repository.UserRepository
-	restaurantRepo repository.RestaurantRepository
-	foodRepo       repository.FoodRepository
+	orderRepo          repository.OrderRepository
+	userRepo           repository.UserRepository
+	restaurantRepo     repository.RestaurantRepository
+	foodRepo           repository.FoodRepository
+	addressRepo        repository.AddressRepository
+	taxService         TaxService
+	deliveryService    DeliveryService
+	quoteRepo          repository.OrderQuoteRepository
+	templateRepo       repository.OrderTemplateRepository
+	groupOrderRepo     repository.GroupOrderRepository
+	paymentService     PaymentService
+	promotionService   PromotionService
+	loyaltyService     LoyaltyService
+	referralService    ReferralService
+	membershipService  MembershipService
+	cancellationConfig config.CancellationConfig
+	tipConfig          config.TipConfig
+	serviceFeeConfig   config.ServiceFeeConfig
 }
 
-func NewOrderService(orderRepo repository.OrderRepository, userRepo repository.UserRepository, restaurantRepo repository.RestaurantRepository, foodRepo repository.FoodRepository) OrderService {
+func NewOrderService(orderRepo repository.OrderRepository, userRepo repository.UserRepository, restaurantRepo repository.RestaurantRepository, foodRepo repository.FoodRepository, addressRepo repository.AddressRepository, taxService TaxService, deliveryService DeliveryService, quoteRepo repository.OrderQuoteRepository, templateRepo repository.OrderTemplateRepository, groupOrderRepo repository.GroupOrderRepository, paymentService PaymentService, promotionService PromotionService, loyaltyService LoyaltyService, referralService ReferralService, membershipService MembershipService, cancellationConfig config.CancellationConfig, tipConfig config.TipConfig, serviceFeeConfig config.ServiceFeeConfig) OrderService {
 	return &orderService{
-		orderRepo:      orderRepo,
-		userRepo:       userRepo,
-		restaurantRepo: restaurantRepo,
-		foodRepo:       foodRepo,
+		orderRepo:          orderRepo,
+		userRepo:           userRepo,
+		restaurantRepo:     restaurantRepo,
+		foodRepo:           foodRepo,
+		addressRepo:        addressRepo,
+		taxService:         taxService,
+		deliveryService:    deliveryService,
+		quoteRepo:          quoteRepo,
+		templateRepo:       templateRepo,
+		groupOrderRepo:     groupOrderRepo,
+		paymentService:     paymentService,
+		promotionService:   promotionService,
+		loyaltyService:     loyaltyService,
+		referralService:    referralService,
+		membershipService:  membershipService,
+		cancellationConfig: cancellationConfig,
+		tipConfig:          tipConfig,
+		serviceFeeConfig:   serviceFeeConfig,
 	}
 }
 
@@ -49,7 +93,7 @@ func (s *orderService) CreateOrder(order *models.Order) (*models.Order, error) {
 	if len(order.Items) == 0 {
 		return nil, errors.NewHTTPError(http.StatusBadRequest, "Order items are required", nil)
 	}
-	if strings.TrimSpace(order.DeliveryAddress) == "" {
+	if strings.TrimSpace(order.DeliveryAddress) == "" && order.DeliveryAddressID == nil {
 		return nil, errors.NewHTTPError(http.StatusBadRequest, "Delivery address is required", nil)
 	}
 	if strings.TrimSpace(order.PaymentMethod) == "" {
@@ -62,63 +106,65 @@ func (s *orderService) CreateOrder(order *models.Order) (*models.Order, error) {
 		return nil, err
 	}
 
-	// Validate restaurant exists
-	restaurant, err := s.restaurantRepo.GetByID(order.RestaurantID)
+	// Price the basket server-side; client-supplied amounts are never trusted
+	pricing, err := s.priceOrder(order)
 	if err != nil {
 		return nil, err
 	}
-	order.RestaurantName = restaurant.Name
 
-	// Validate order items and calculate totals
-	var subtotal float64
-	for i, item := range order.Items {
-		if strings.TrimSpace(item.FoodID) == "" {
-			return nil, errors.NewHTTPError(http.StatusBadRequest, "Food ID is required for all items", nil)
-		}
-		if item.Quantity <= 0 {
-			return nil, errors.NewHTTPError(http.StatusBadRequest, "Quantity must be greater than 0", nil)
-		}
+	// Generate ID if not provided
+	if order.ID == "" {
+		order.ID = utils.GenerateOrderID()
+	}
 
-		// Validate food exists and is available
-		food, err := s.foodRepo.GetByID(item.FoodID)
-		if err != nil {
+	// Honor a previously issued quote so the price can't change mid-checkout
+	if order.QuoteID != nil {
+		if err := s.applyQuote(order); err != nil {
 			return nil, err
 		}
-		if !food.IsAvailable {
-			return nil, errors.NewHTTPError(http.StatusBadRequest, "Food item is not available: "+food.Name, nil)
-		}
-		if food.RestaurantID != order.RestaurantID {
-			return nil, errors.NewHTTPError(http.StatusBadRequest, "All items must be from the same restaurant", nil)
-		}
+	}
+	order.EstimatedDeliveryAt = &pricing.estimatedDeliveryAt
+	order.Status = models.OrderStatusPending
+	order.ReleaseAt = nil
 
-		// Update item details
-		order.Items[i].FoodName = food.Name
-		order.Items[i].Price = food.Price
-		order.Items[i].Total = food.Price * float64(item.Quantity)
-		subtotal += order.Items[i].Total
+	// Pre-orders wait as scheduled until the kitchen needs to start on them; anything else needs it open now
+	if order.ScheduledFor != nil {
+		if err := s.scheduleOrder(order, pricing); err != nil {
+			return nil, err
+		}
+	} else if err := s.ensureOpenNow(pricing.restaurant); err != nil {
+		return nil, err
 	}
 
-	// Set order totals
-	order.Subtotal = subtotal
-	if order.DeliveryFee < 0 {
-		order.DeliveryFee = restaurant.DeliveryFee
+	// Claim the quote and promo code uses and spend the points before the order is stored so each is used
+	// once and limits and balances hold under concurrent checkouts
+	if order.QuoteID != nil {
+		if err := s.quoteRepo.MarkUsed(*order.QuoteID, order.ID); err != nil {
+			return nil, err
+		}
 	}
-	if order.Tax <= 0 {
-		order.Tax = subtotal * 0.08 // 8% tax rate
+	if err := s.promotionService.RedeemPromotions(order); err != nil {
+		s.releaseQuote(order)
+		return nil, err
+	}
+	if err := s.loyaltyService.RedeemPoints(order); err != nil {
+		s.releaseDiscounts(order.ID)
+		s.releaseQuote(order)
+		return nil, err
 	}
-	order.Total = order.Subtotal + order.DeliveryFee + order.Tax
-	order.Status = models.OrderStatusPending
 
 	// Create order
 	err = s.orderRepo.Create(order)
 	if err != nil {
+		s.releaseDiscounts(order.ID)
+		s.releaseQuote(order)
 		return nil, err
 	}
 
 	return order, nil
 }
 
-func (s *orderService) GetUserOrders(userID string, limit, offset int) ([]models.Order, error) {
+func (s *orderService) GetUserOrders(userID string, timeframe models.OrderTimeframe, limit, offset int) ([]models.Order, error) {
 	if strings.TrimSpace(userID) == "" {
 		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
 	}
@@ -139,7 +185,68 @@ func (s *orderService) GetUserOrders(userID string, limit, offset int) ([]models
 		offset = 0
 	}
 
-	return s.orderRepo.GetByUserID(userID, limit, offset)
+	var orders []models.Order
+	switch timeframe {
+	case models.OrderTimeframeAll:
+		orders, err = s.orderRepo.GetByUserID(userID, limit, offset)
+	case models.OrderTimeframeUpcoming:
+		orders, err = s.orderRepo.GetByUserIDAndStatuses(userID, []models.OrderStatus{
+			models.OrderStatusScheduled,
+			models.OrderStatusPending,
+			models.OrderStatusConfirmed,
+			models.OrderStatusPreparing,
+			models.OrderStatusOnTheWay,
+		}, limit, offset)
+	case models.OrderTimeframePast:
+		orders, err = s.orderRepo.GetByUserIDAndStatuses(userID, []models.OrderStatus{
+			models.OrderStatusDelivered,
+			models.OrderStatusCancelled,
+		}, limit, offset)
+	default:
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid timeframe, expected upcoming or past", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachPortions(userID, orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// attachPortions shows the user's own part of any group orders in the list
+func (s *orderService) attachPortions(userID string, orders []models.Order) error {
+	var groupOrderIDs []string
+	for _, order := range orders {
+		if order.GroupOrderID != nil {
+			groupOrderIDs = append(groupOrderIDs, order.ID)
+		}
+	}
+	participants, err := s.groupOrderRepo.GetParticipantsByOrderIDs(userID, groupOrderIDs)
+	if err != nil {
+		return err
+	}
+
+	byOrder := make(map[string]*models.GroupOrderParticipant, len(participants))
+	for i := range participants {
+		byOrder[*participants[i].OrderID] = &participants[i]
+	}
+	for i := range orders {
+		participant, exists := byOrder[orders[i].ID]
+		if !exists {
+			continue
+		}
+		orders[i].Portion = &models.GroupOrderPortion{
+			GroupOrderID:  participant.GroupOrderID,
+			IsHost:        participant.IsHost,
+			Items:         participant.Items,
+			Subtotal:      participant.Subtotal,
+			Share:         participant.Share,
+			PaymentStatus: participant.PaymentStatus,
+		}
+	}
+	return nil
 }
 
 func (s *orderService) GetOrderByID(orderID string) (*models.Order, error) {
@@ -150,10 +257,16 @@ func (s *orderService) GetOrderByID(orderID string) (*models.Order, error) {
 	return s.orderRepo.GetByID(orderID)
 }
 
-func (s *orderService) UpdateOrderStatus(orderID string, status models.OrderStatus) error {
+// UpdateOrderStatus moves an order through the kitchen and delivery. Only the restaurant's owner, support or an
+// admin can do this, since confirming and delivering capture the payment and award loyalty points.
+func (s *orderService) UpdateOrderStatus(viewer models.OrderViewer, orderID string, request *models.UpdateOrderStatusRequest) error {
 	if strings.TrimSpace(orderID) == "" {
 		return errors.NewHTTPError(http.StatusBadRequest, "Order ID is required", nil)
 	}
+	if request == nil {
+		return errors.NewHTTPError(http.StatusBadRequest, "Order status is required", nil)
+	}
+	status := request.Status
 
 	// Validate status
 	validStatuses := map[models.OrderStatus]bool{
@@ -162,7 +275,9 @@ func (s *orderService) UpdateOrderStatus(orderID string, status models.OrderStat
 		models.OrderStatusPreparing: true,
 		models.OrderStatusOnTheWay:  true,
 		models.OrderStatusDelivered: true,
-		models.OrderStatusCancelled: true,
+	}
+	if status == models.OrderStatusCancelled {
+		return errors.NewHTTPError(http.StatusBadRequest, "Use the cancel endpoint to cancel an order", nil)
 	}
 	if !validStatuses[status] {
 		return errors.NewHTTPError(http.StatusBadRequest, "Invalid order status", nil)
@@ -173,35 +288,98 @@ func (s *orderService) UpdateOrderStatus(orderID string, status models.OrderStat
 	if err != nil {
 		return err
 	}
+	if err := authorizeRestaurantOwner(s.userRepo, s.restaurantRepo, viewer, order.RestaurantID); err != nil {
+		return err
+	}
 
 	// Validate status transition
 	if order.Status == models.OrderStatusDelivered || order.Status == models.OrderStatusCancelled {
 		return errors.NewHTTPError(http.StatusBadRequest, "Cannot update status of completed order", nil)
 	}
+	if status == models.OrderStatusDelivered && (order.Status == models.OrderStatusPending || order.Status == models.OrderStatusScheduled) {
+		return errors.NewHTTPError(http.StatusBadRequest, "Order must be confirmed before it can be delivered", nil)
+	}
 
-	return s.orderRepo.UpdateStatus(orderID, status)
-}
+	// Courier details travel with the status update; the courier ID is who the order's tip is settled to
+	updates := map[string]interface{}{"status": status}
+	if request.DeliveryPersonName != nil {
+		updates["delivery_person_name"] = *request.DeliveryPersonName
+	}
+	if request.DeliveryPersonPhone != nil {
+		updates["delivery_person_phone"] = *request.DeliveryPersonPhone
+	}
+	if request.CourierID != nil {
+		updates["courier_id"] = strings.TrimSpace(*request.CourierID)
+	}
+	if request.TrackingURL != nil {
+		updates["tracking_url"] = *request.TrackingURL
+	}
+	// Delivery time decides which settlement run pays the restaurant and courier for the order
+	if status == models.OrderStatusDelivered {
+		updates["delivered_at"] = time.Now()
+	}
+	if err := s.orderRepo.UpdateIfStatus(orderID, order.Status, updates); err != nil {
+		return err
+	}
 
-func (s *orderService) CancelOrder(orderID string) error {
-	if strings.TrimSpace(orderID) == "" {
-		return errors.NewHTTPError(http.StatusBadRequest, "Order ID is required", nil)
+	// The status change stands even if collecting the payment fails; failed captures are recorded for follow-up
+	if err := s.paymentService.CaptureOrder(orderID); err != nil {
+		logger.Error("Failed to capture order payments", "order_id", orderID, "status", status, "error", err)
 	}
+	if status == models.OrderStatusDelivered {
+		// Points and referral credits are only earned on orders that were actually paid for
+		paid, err := s.isOrderPaid(order)
+		if err != nil {
+			logger.Error("Failed to check payment for delivered order", "order_id", orderID, "error", err)
+		}
+		if !paid {
+			logger.Warn("Delivered order is not fully paid, skipping loyalty points and referral rewards", "order_id", orderID)
+			return nil
+		}
+		if err := s.loyaltyService.AccrueOrder(order); err != nil {
+			logger.Error("Failed to accrue loyalty points", "order_id", orderID, "error", err)
+		}
+		if err := s.referralService.RewardFirstOrder(order); err != nil {
+			logger.Error("Failed to reward referral", "order_id", orderID, "error", err)
+		}
+	}
+	return nil
+}
 
-	// Validate order exists
-	order, err := s.orderRepo.GetByID(orderID)
+// isOrderPaid reports whether the payments collected for the order cover its total
+func (s *orderService) isOrderPaid(order *models.Order) (bool, error) {
+	transactions, err := s.paymentService.GetOrderTransactions(order.ID)
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	collected := models.Zero(order.Currency)
+	for _, transaction := range transactions {
+		if transaction.IsCollected() {
+			collected = collected.Add(transaction.Amount)
+		}
 	}
+	return collected.Cmp(order.Total) >= 0, nil
+}
 
-	// Check if order can be cancelled
-	if order.Status == models.OrderStatusDelivered {
-		return errors.NewHTTPError(http.StatusBadRequest, "Cannot cancel delivered order", nil)
+// releaseQuote frees the quote claimed by an order that was not stored, so the customer can still use it
+func (s *orderService) releaseQuote(order *models.Order) {
+	if order.QuoteID == nil {
+		return
 	}
-	if order.Status == models.OrderStatusCancelled {
-		return errors.NewHTTPError(http.StatusBadRequest, "Order is already cancelled", nil)
+	if err := s.quoteRepo.Release(*order.QuoteID, order.ID); err != nil {
+		logger.Error("Failed to release order quote", "quote_id", *order.QuoteID, "order_id", order.ID, "error", err)
 	}
+}
 
-	return s.orderRepo.UpdateStatus(orderID, models.OrderStatusCancelled)
+// releaseDiscounts gives back the promo code uses and loyalty points of an order that will not go ahead
+func (s *orderService) releaseDiscounts(orderID string) {
+	if err := s.promotionService.ReleasePromotions(orderID); err != nil {
+		logger.Error("Failed to release promo codes", "order_id", orderID, "error", err)
+	}
+	if err := s.loyaltyService.RestorePoints(orderID); err != nil {
+		logger.Error("Failed to restore loyalty points", "order_id", orderID, "error", err)
+	}
 }
 
 func (s *orderService) TrackOrder(orderID string) (*models.Order, error) {
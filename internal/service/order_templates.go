@@ -0,0 +1,286 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+)
+
+// Reorder rebuilds a past order's basket against today's menu and quotes it.
+// Items that can no longer be ordered are left out and reported; price changes are reported too.
+func (s *orderService) Reorder(orderID string, request *models.ReorderRequest) (*models.ReorderResult, error) {
+	if strings.TrimSpace(orderID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Order ID is required", nil)
+	}
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != request.UserID {
+		return nil, errors.NewHTTPError(http.StatusForbidden, "Order does not belong to user", nil)
+	}
+
+	items, unavailable, repriced, err := s.rebuildItems(order.RestaurantID, order.Items)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.ReorderResult{
+		SourceOrderID: order.ID,
+		Items:         items,
+		Unavailable:   unavailable,
+		Repriced:      repriced,
+	}
+	if len(items) == 0 {
+		return result, nil
+	}
+
+	addressID := order.DeliveryAddressID
+	if request.DeliveryAddressID != nil {
+		addressID = request.DeliveryAddressID
+	}
+	quote, err := s.QuoteOrder(&models.QuoteOrderRequest{
+		UserID:            order.UserID,
+		RestaurantID:      order.RestaurantID,
+		Items:             items,
+		DeliveryAddressID: addressID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Items = quote.Items
+	result.Quote = quote
+
+	return result, nil
+}
+
+func (s *orderService) CreateOrderTemplate(request *models.CreateOrderTemplateRequest) (*models.OrderTemplate, error) {
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Order template is required", nil)
+	}
+	if strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	if strings.TrimSpace(request.Name) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Template name is required", nil)
+	}
+
+	// Validate user exists
+	_, err := s.userRepo.GetByID(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &models.OrderTemplate{
+		ID:                utils.GenerateOrderTemplateID(),
+		UserID:            request.UserID,
+		Name:              strings.TrimSpace(request.Name),
+		RestaurantID:      request.RestaurantID,
+		DeliveryAddress:   request.DeliveryAddress,
+		DeliveryAddressID: request.DeliveryAddressID,
+		PaymentMethod:     request.PaymentMethod,
+	}
+	items := request.Items
+
+	// Copy everything not given explicitly from the source order
+	if request.SourceOrderID != nil {
+		order, err := s.orderRepo.GetByID(*request.SourceOrderID)
+		if err != nil {
+			return nil, err
+		}
+		if order.UserID != request.UserID {
+			return nil, errors.NewHTTPError(http.StatusForbidden, "Order does not belong to user", nil)
+		}
+		template.SourceOrderID = &order.ID
+		template.RestaurantID = order.RestaurantID
+		if len(items) == 0 {
+			items = order.Items
+		}
+		if template.DeliveryAddress == "" && template.DeliveryAddressID == nil {
+			template.DeliveryAddress = order.DeliveryAddress
+			template.DeliveryAddressID = order.DeliveryAddressID
+		}
+		if template.PaymentMethod == "" {
+			template.PaymentMethod = order.PaymentMethod
+		}
+	}
+
+	if strings.TrimSpace(template.RestaurantID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Restaurant ID is required", nil)
+	}
+	if len(items) == 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Order items are required", nil)
+	}
+
+	restaurant, err := s.restaurantRepo.GetByID(template.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+	template.RestaurantName = restaurant.Name
+
+	// Only save templates that can actually be placed today
+	rebuilt, unavailable, _, err := s.rebuildItems(template.RestaurantID, items)
+	if err != nil {
+		return nil, err
+	}
+	if len(unavailable) > 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, unavailable[0].FoodName+": "+unavailable[0].Reason, nil)
+	}
+	template.Items = rebuilt
+
+	if err := s.templateRepo.Create(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (s *orderService) GetUserOrderTemplates(userID string) ([]models.OrderTemplate, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+
+	// Validate user exists
+	_, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.templateRepo.GetByUserID(userID)
+}
+
+func (s *orderService) DeleteOrderTemplate(templateID string, request *models.DeleteOrderTemplateRequest) error {
+	if strings.TrimSpace(templateID) == "" {
+		return errors.NewHTTPError(http.StatusBadRequest, "Template ID is required", nil)
+	}
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+
+	template, err := s.templateRepo.GetByID(templateID)
+	if err != nil {
+		return err
+	}
+	if template.UserID != request.UserID {
+		return errors.NewHTTPError(http.StatusForbidden, "Order template does not belong to user", nil)
+	}
+
+	return s.templateRepo.Delete(templateID)
+}
+
+// PlaceOrderTemplate places a new order from a saved template through the regular CreateOrder path
+func (s *orderService) PlaceOrderTemplate(templateID string, request *models.PlaceOrderTemplateRequest) (*models.Order, error) {
+	if strings.TrimSpace(templateID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Template ID is required", nil)
+	}
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+
+	template, err := s.templateRepo.GetByID(templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template.UserID != request.UserID {
+		return nil, errors.NewHTTPError(http.StatusForbidden, "Order template does not belong to user", nil)
+	}
+
+	order := &models.Order{
+		UserID:            template.UserID,
+		RestaurantID:      template.RestaurantID,
+		Items:             append(models.OrderItemsArray(nil), template.Items...),
+		DeliveryAddress:   template.DeliveryAddress,
+		DeliveryAddressID: template.DeliveryAddressID,
+		PaymentMethod:     template.PaymentMethod,
+		ScheduledFor:      request.ScheduledFor,
+	}
+	if request.DeliveryAddressID != nil {
+		order.DeliveryAddressID = request.DeliveryAddressID
+		order.DeliveryAddress = ""
+	}
+	if request.PaymentMethod != nil {
+		order.PaymentMethod = *request.PaymentMethod
+	}
+
+	created, err := s.CreateOrder(order)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.templateRepo.MarkPlaced(template.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// rebuildItems checks past order items against the current menu. Orderable items are returned
+// with today's names and prices; the rest are reported as unavailable, and price changes as repriced.
+func (s *orderService) rebuildItems(restaurantID string, items []models.OrderItem) ([]models.OrderItem, []models.ReorderItemIssue, []models.ReorderPriceChange, error) {
+	rebuilt := make([]models.OrderItem, 0, len(items))
+	unavailable := []models.ReorderItemIssue{}
+	repriced := []models.ReorderPriceChange{}
+	for _, item := range items {
+		issue := models.ReorderItemIssue{FoodID: item.FoodID, FoodName: item.FoodName}
+
+		food, err := s.foodRepo.GetByID(item.FoodID)
+		if err != nil {
+			if code, ok := errors.GetStatusCode(err); ok && code == http.StatusNotFound {
+				issue.Reason = "No longer on the menu"
+				unavailable = append(unavailable, issue)
+				continue
+			}
+			return nil, nil, nil, err
+		}
+		issue.FoodName = food.Name
+		if food.RestaurantID != restaurantID {
+			issue.Reason = "No longer on the menu"
+			unavailable = append(unavailable, issue)
+			continue
+		}
+		if !food.IsAvailable {
+			issue.Reason = "Currently unavailable"
+			unavailable = append(unavailable, issue)
+			continue
+		}
+
+		modifiers, modifiersTotal, err := resolveModifiers(food, item.Modifiers)
+		if err != nil {
+			message, _ := errors.GetErrorMessage(err)
+			issue.Reason = message
+			unavailable = append(unavailable, issue)
+			continue
+		}
+
+		current := models.OrderItem{
+			FoodID:              food.ID,
+			FoodName:            food.Name,
+			Price:               food.Price,
+			UnitPrice:           food.Price.Add(modifiersTotal),
+			Quantity:            item.Quantity,
+			Modifiers:           modifiers,
+			SpecialInstructions: item.SpecialInstructions,
+		}
+		current.Total = current.UnitPrice.Mul(int64(current.Quantity))
+		rebuilt = append(rebuilt, current)
+
+		// Orders placed before unit prices were recorded only carry the base price
+		previous := item.UnitPrice
+		if previous.IsZero() {
+			previous = item.Price
+		}
+		if previous != current.UnitPrice {
+			repriced = append(repriced, models.ReorderPriceChange{
+				FoodID:            food.ID,
+				FoodName:          food.Name,
+				PreviousUnitPrice: previous,
+				CurrentUnitPrice:  current.UnitPrice,
+			})
+		}
+	}
+	return rebuilt, unavailable, repriced, nil
+}
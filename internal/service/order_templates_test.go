@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+func TestRebuildItemsAgainstTodaysMenu(t *testing.T) {
+	openTestDB(t)
+	menuItem := func(id, restaurantID, name string, price int64) *models.Food {
+		return &models.Food{ID: id, RestaurantID: restaurantID, RestaurantName: restaurantID, Name: name, Price: usd(price), Category: "mains"}
+	}
+	margherita := pizza()
+	margherita.RestaurantID, margherita.RestaurantName, margherita.Category = "r1", "r1", "mains"
+	seed(t, margherita, menuItem("soda", "r1", "Soda", 250), menuItem("soup", "r1", "Soup", 600), menuItem("burger", "r2", "Burger", 900))
+	// IsAvailable defaults to true in the schema, so switch it off after the insert
+	if err := database.DB.Model(&models.Food{}).Where("id = ?", "soup").Update("is_available", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := &orderService{foodRepo: repository.NewFoodRepository()}
+
+	past := []models.OrderItem{
+		{FoodID: "food-pizza", FoodName: "Pizza", Quantity: 2, Price: usd(1200), UnitPrice: usd(1850), Modifiers: pick("size-l", "top-ham")},
+		{FoodID: "soda", FoodName: "Soda", Quantity: 3, Price: usd(200)}, // From before unit prices were recorded
+		{FoodID: "soup", FoodName: "Soup", Quantity: 1, Price: usd(600), UnitPrice: usd(600)},
+		{FoodID: "burger", FoodName: "Burger", Quantity: 1, Price: usd(900), UnitPrice: usd(900)},
+		{FoodID: "retired", FoodName: "Seasonal Salad", Quantity: 1, Price: usd(700), UnitPrice: usd(700)},
+		{FoodID: "food-pizza", FoodName: "Pizza", Quantity: 1, Price: usd(1200), UnitPrice: usd(1350), Modifiers: pick("size-s", "top-anchovy")},
+	}
+
+	items, unavailable, repriced, err := s.rebuildItems("r1", past)
+	if err != nil {
+		t.Fatalf("rebuildItems error = %v", err)
+	}
+
+	if len(items) != 2 || items[0].Total.Amount != 3700 || items[1].Total.Amount != 750 {
+		t.Errorf("rebuilt items = %+v, want 2 pizzas at 37.00 and 3 sodas at 7.50", items)
+	}
+	if len(repriced) != 1 || repriced[0].FoodID != "soda" || repriced[0].PreviousUnitPrice.Amount != 200 || repriced[0].CurrentUnitPrice.Amount != 250 {
+		t.Errorf("repriced = %+v, want only the soda going from 2.00 to 2.50", repriced)
+	}
+
+	reasons := map[string]string{}
+	for _, issue := range unavailable {
+		reasons[issue.FoodName] = issue.Reason
+	}
+	for name, reason := range map[string]string{"Soup": "Currently unavailable", "Burger": "No longer on the menu", "Seasonal Salad": "No longer on the menu"} {
+		if reasons[name] != reason {
+			t.Errorf("%s reported as %q, want %q", name, reasons[name], reason)
+		}
+	}
+	// The pizza with a topping that is gone is reported with the modifier error
+	if len(unavailable) != 4 || reasons["Pizza"] == "" {
+		t.Errorf("unavailable = %+v, want the soup, burger, salad and the pizza with a missing topping", unavailable)
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/pkg/errors"
+	"dfood/pkg/logger"
+)
+
+const (
+	maxTipBasisPoints          = 10000 // A percentage tip can be up to the whole subtotal
+	defaultTipAdjustmentWindow = 60 * time.Minute
+)
+
+// resolveTip works out a tip given either as a fixed amount or as basis points of the subtotal
+func resolveTip(subtotal, amount models.Money, basisPoints *int64) (models.Money, error) {
+	if basisPoints != nil {
+		if !amount.IsZero() {
+			return models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "Give the tip as an amount or a percentage, not both", nil)
+		}
+		if *basisPoints < 0 || *basisPoints > maxTipBasisPoints {
+			return models.Money{}, errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Tip percentage must be between 0 and %d basis points", maxTipBasisPoints), nil)
+		}
+		return subtotal.Percent(*basisPoints), nil
+	}
+
+	if amount.Currency != "" && amount.Currency != subtotal.Currency {
+		return models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "Tip currency must match the order currency ("+string(subtotal.Currency)+")", nil)
+	}
+	if amount.IsNegative() {
+		return models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "Tip cannot be negative", nil)
+	}
+	amount.Currency = subtotal.Currency
+	return amount, nil
+}
+
+// tipAdjustmentWindow is how long after delivery the customer can still change their tip
+func (s *orderService) tipAdjustmentWindow() time.Duration {
+	if s.tipConfig.AdjustmentWindowMinutes > 0 {
+		return time.Duration(s.tipConfig.AdjustmentWindowMinutes) * time.Minute
+	}
+	return defaultTipAdjustmentWindow
+}
+
+// AdjustTip changes the tip on an order until the adjustment window after delivery closes. A higher tip is
+// charged to the card or wallet the order was paid with; a lower one is only possible while the tip has not
+// been collected yet, since collected tips belong to the courier. Only the customer who placed the order can change it.
+func (s *orderService) AdjustTip(viewer models.OrderViewer, orderID string, request *models.AdjustTipRequest) (*models.Order, error) {
+	if strings.TrimSpace(orderID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Order ID is required", nil)
+	}
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Tip is required", nil)
+	}
+
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := callerUser(s.userRepo, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != user.ID {
+		return nil, errors.NewHTTPError(http.StatusForbidden, "Order does not belong to this user", nil)
+	}
+	switch order.Status {
+	case models.OrderStatusCancelled:
+		return nil, errors.NewHTTPError(http.StatusConflict, "Cannot change the tip on a cancelled order", nil)
+	case models.OrderStatusDelivered:
+		window := s.tipAdjustmentWindow()
+		if order.DeliveredAt == nil || time.Since(*order.DeliveredAt) > window {
+			return nil, errors.NewHTTPError(http.StatusConflict, fmt.Sprintf("Tips can only be changed within %d minutes of delivery", int(window.Minutes())), nil)
+		}
+	}
+
+	tip, err := resolveTip(order.Subtotal, request.Tip, request.TipBasisPoints)
+	if err != nil {
+		return nil, err
+	}
+	total := order.Total.Sub(order.Tip).Add(tip)
+
+	transactions, err := s.paymentService.GetOrderTransactions(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	collected := models.Zero(order.Currency)
+	for _, transaction := range transactions {
+		if transaction.IsCollected() {
+			collected = collected.Add(transaction.Amount)
+		}
+	}
+	if total.Cmp(collected) < 0 {
+		return nil, errors.NewHTTPError(http.StatusConflict, "The tip has already been paid and can only be raised", nil)
+	}
+
+	previous := map[string]interface{}{
+		"tip":              order.Tip,
+		"tip_basis_points": order.TipBasisPoints,
+		"total":            order.Total,
+	}
+	if err := s.orderRepo.UpdateIfStatus(order.ID, order.Status, map[string]interface{}{
+		"tip":              tip,
+		"tip_basis_points": request.TipBasisPoints,
+		"total":            total,
+	}); err != nil {
+		return nil, err
+	}
+
+	// Put the old tip back if the difference cannot be paid, so the total never exceeds what the customer committed
+	if err := s.paymentService.CoverOrderTotal(order.ID); err != nil {
+		if restoreErr := s.orderRepo.Update(order.ID, previous); restoreErr != nil {
+			logger.Error("Failed to restore tip after payment failed", "order_id", order.ID, "error", restoreErr)
+		}
+		return nil, err
+	}
+	return s.orderRepo.GetByID(order.ID)
+}
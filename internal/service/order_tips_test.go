@@ -0,0 +1,45 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"dfood/internal/config"
+	"dfood/internal/database"
+	"dfood/internal/models"
+)
+
+func TestAdjustTipAfterCheckout(t *testing.T) {
+	payments := newTestPayments(t)
+	paidCapture(t, payments, "o1")
+	seed(t, testUser("ben"))
+	s := &orderService{orderRepo: payments.orderRepo, userRepo: payments.userRepo, paymentService: payments, tipConfig: config.TipConfig{AdjustmentWindowMinutes: 30}}
+	ana := models.OrderViewer{Email: "ana@example.com", Role: models.RoleCustomer}
+	fifteenPercent := int64(1500)
+
+	_, err := s.AdjustTip(models.OrderViewer{Email: "ben@example.com", Role: models.RoleCustomer}, "o1", &models.AdjustTipRequest{Tip: usd(500)})
+	rejectedAs(t, err, http.StatusForbidden)
+	_, err = s.AdjustTip(ana, "o1", &models.AdjustTipRequest{Tip: usd(500), TipBasisPoints: &fifteenPercent})
+	rejectedAs(t, err, http.StatusBadRequest)
+
+	// 15% of the 20.00 subtotal is added to the 24.60 already captured and charged to the same card
+	order, err := s.AdjustTip(ana, "o1", &models.AdjustTipRequest{TipBasisPoints: &fifteenPercent})
+	if err != nil || order.Tip.Amount != 300 || order.Total.Amount != 2760 {
+		t.Fatalf("AdjustTip to 15%% = %+v, %v; want a 3.00 tip and a 27.60 total", order, err)
+	}
+	if paid, _ := payments.paidAmount(order); paid.Amount != 2760 {
+		t.Errorf("collected %s after raising the tip, want 27.60", paid)
+	}
+
+	// The raised tip has been collected, so it can no longer come down
+	_, err = s.AdjustTip(ana, "o1", &models.AdjustTipRequest{Tip: usd(100)})
+	rejectedAs(t, err, http.StatusConflict)
+
+	deliveredAt := time.Now().Add(-45 * time.Minute)
+	if err := database.DB.Model(&models.Order{}).Where("id = ?", "o1").Updates(map[string]interface{}{"status": models.OrderStatusDelivered, "delivered_at": deliveredAt}).Error; err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.AdjustTip(ana, "o1", &models.AdjustTipRequest{Tip: usd(600)})
+	rejectedAs(t, err, http.StatusConflict)
+}
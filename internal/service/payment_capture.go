@@ -0,0 +1,291 @@
+package service
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+	"dfood/pkg/logger"
+)
+
+// captureStatus is the order status at which held funds are collected
+func (s *paymentService) captureStatus() models.OrderStatus {
+	if models.OrderStatus(s.config.CaptureOn) == models.OrderStatusDelivered {
+		return models.OrderStatusDelivered
+	}
+	return models.OrderStatusConfirmed
+}
+
+// CaptureOrder collects the order's open authorizations once it has reached the capture status.
+// Only the current order total is captured, so items removed after checkout are never charged,
+// and holds that are no longer needed are voided. Before the capture status it does nothing.
+func (s *paymentService) CaptureOrder(orderID string) error {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return err
+	}
+	if !order.Status.HasReached(s.captureStatus()) {
+		return nil
+	}
+	return s.settleAuthorizations(order, order.Total)
+}
+
+// ReleaseOrder settles the open authorizations of a cancelled order: only what is needed to reach
+// keep (the cancellation fee) is captured, and every other hold is voided
+func (s *paymentService) ReleaseOrder(orderID string, keep models.Money) error {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return err
+	}
+	keep.Currency = order.Currency
+	return s.settleAuthorizations(order, keep)
+}
+
+// CoverOrderTotal pays the difference when an order's total rises after checkout, such as a raised tip,
+// from the card or wallet its customer last paid it with. The new hold is captured straight away once the
+// order has reached the capture status. Orders still at checkout are left for the customer to pay.
+func (s *paymentService) CoverOrderTotal(orderID string) error {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status == models.OrderStatusScheduled || order.Status == models.OrderStatusPending {
+		return nil
+	}
+
+	transactions, err := s.paymentRepo.GetTransactionsByOrderID(order.ID)
+	if err != nil {
+		return err
+	}
+	paid := models.Zero(order.Currency)
+	var source *models.PaymentTransaction
+	for i := range transactions {
+		transaction := &transactions[i]
+		held := transaction.Type == models.PaymentTypeAuthorization && transaction.Status == models.PaymentStatusAuthorized
+		if transaction.IsCollected() || held {
+			paid = paid.Add(transaction.Amount)
+		}
+		// Captures carry the card of their authorization, so any successful payment by the customer will do
+		if transaction.UserID == order.UserID && transaction.Type != models.PaymentTypeRefund && transaction.Type != models.PaymentTypeVoid &&
+			(transaction.IsCollected() || held || transaction.Status == models.PaymentStatusCaptured) {
+			source = transaction
+		}
+	}
+	due := order.Total.Sub(paid)
+	if !due.IsPositive() {
+		return nil
+	}
+	if source == nil {
+		return errors.NewHTTPError(http.StatusConflict, "Order has no payment to charge the difference to", nil)
+	}
+
+	if source.Type == models.PaymentTypeWallet {
+		_, err = s.payFromWallet(order, source.UserID, due)
+		return err
+	}
+	if source.Type == models.PaymentTypeGiftCard && source.GiftCardID != nil {
+		giftCard, err := s.giftCardRepo.GetByID(*source.GiftCardID)
+		if err != nil {
+			return err
+		}
+		_, err = s.payFromGiftCard(order, source.UserID, giftCard, due)
+		return err
+	}
+	_, err = s.authorizeCard(order, &models.PaymentTransaction{
+		UserID:          source.UserID,
+		PaymentMethodID: source.PaymentMethodID,
+		CardID:          source.CardID,
+	}, due)
+	if err != nil {
+		return err
+	}
+	if err := s.CaptureOrder(order.ID); err != nil {
+		logger.Error("Failed to capture order payments", "order_id", order.ID, "error", err)
+	}
+	return nil
+}
+
+// settleAuthorizations captures enough of the order's open authorizations to bring the collected
+// amount up to target and voids the rest. When the holds exceed what is due, each one is captured
+// in proportion to its size so that group order participants share any reduction fairly.
+func (s *paymentService) settleAuthorizations(order *models.Order, target models.Money) error {
+	transactions, err := s.paymentRepo.GetTransactionsByOrderID(order.ID)
+	if err != nil {
+		return err
+	}
+
+	collected := models.Zero(order.Currency)
+	held := models.Zero(order.Currency)
+	var open []models.PaymentTransaction
+	for _, transaction := range transactions {
+		switch {
+		case transaction.IsCollected():
+			collected = collected.Add(transaction.Amount)
+		case transaction.Type == models.PaymentTypeAuthorization && transaction.Status == models.PaymentStatusAuthorized:
+			open = append(open, transaction)
+			held = held.Add(transaction.Amount)
+		}
+	}
+	if len(open) == 0 {
+		return nil
+	}
+
+	due := target.Sub(collected).Max(models.Zero(order.Currency)).Min(held)
+	holds := make([]models.Money, len(open))
+	for i := range open {
+		holds[i] = open[i].Amount
+	}
+	amounts := captureAmounts(holds, held, due)
+
+	var firstErr error
+	for i := range open {
+		authorization := &open[i]
+		amount := amounts[i]
+		if amount.IsPositive() {
+			err = s.captureAuthorization(authorization, amount)
+		} else {
+			err = s.voidAuthorization(authorization)
+		}
+		if err != nil {
+			logger.Error("Failed to settle payment authorization", "order_id", order.ID, "transaction_id", authorization.ID, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// captureAmounts splits due across holds totalling held in proportion to their sizes. Each share is
+// rounded down and the minor units left over go one each to the holds with the largest remainders,
+// so the shares add up to exactly due and none is more than its hold.
+func captureAmounts(holds []models.Money, held, due models.Money) []models.Money {
+	amounts := make([]models.Money, len(holds))
+	if due.Cmp(held) >= 0 {
+		copy(amounts, holds)
+		return amounts
+	}
+
+	remainders := make([]int64, len(holds))
+	order := make([]int, len(holds))
+	leftover := due
+	for i, hold := range holds {
+		share := hold.Amount * due.Amount
+		amounts[i] = models.NewMoney(share/held.Amount, due.Currency)
+		remainders[i] = share % held.Amount
+		order[i] = i
+		leftover = leftover.Sub(amounts[i])
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for _, i := range order {
+		if !leftover.IsPositive() {
+			break
+		}
+		amounts[i] = amounts[i].Add(models.NewMoney(1, due.Currency))
+		leftover = leftover.Sub(models.NewMoney(1, due.Currency))
+	}
+	return amounts
+}
+
+// captureAuthorization collects amount from an authorization and records it as a capture row.
+// The authorization is claimed first so two status updates can never capture it twice.
+func (s *paymentService) captureAuthorization(authorization *models.PaymentTransaction, amount models.Money) error {
+	if err := s.paymentRepo.UpdateTransactionIfStatus(authorization.ID, models.PaymentStatusAuthorized, map[string]interface{}{
+		"status": models.PaymentStatusCaptured,
+	}); err != nil {
+		return err
+	}
+
+	capture := s.childTransaction(authorization, models.PaymentTypeCapture, amount)
+	if err := s.paymentRepo.CreateTransaction(capture); err != nil {
+		s.reopenAuthorization(authorization, models.PaymentStatusCaptured)
+		return err
+	}
+
+	result, err := s.gateway.Capture(*authorization.TransactionID, amount)
+	if err != nil {
+		s.failTransaction(capture, models.PaymentStatusFailed, "Payment gateway unavailable")
+		s.reopenAuthorization(authorization, models.PaymentStatusCaptured)
+		return errors.NewHTTPError(http.StatusBadGateway, "Payment gateway unavailable, please try again", err)
+	}
+	if !result.Succeeded() {
+		s.failTransaction(capture, models.PaymentStatusFailed, result.Message)
+		s.reopenAuthorization(authorization, models.PaymentStatusCaptured)
+		return errors.NewHTTPError(http.StatusPaymentRequired, result.Message, nil)
+	}
+
+	if err := s.paymentRepo.UpdateTransaction(capture.ID, map[string]interface{}{
+		"status":         models.PaymentStatusCompleted,
+		"transaction_id": result.ID,
+		"processed_at":   time.Now(),
+	}); err != nil {
+		return err
+	}
+	s.recordInLedger(capture, s.ledgerService.RecordCapture)
+	return nil
+}
+
+// voidAuthorization releases an authorization's hold and records it as a void row
+func (s *paymentService) voidAuthorization(authorization *models.PaymentTransaction) error {
+	if err := s.paymentRepo.UpdateTransactionIfStatus(authorization.ID, models.PaymentStatusAuthorized, map[string]interface{}{
+		"status": models.PaymentStatusVoided,
+	}); err != nil {
+		return err
+	}
+
+	void := s.childTransaction(authorization, models.PaymentTypeVoid, authorization.Amount)
+	if err := s.paymentRepo.CreateTransaction(void); err != nil {
+		s.reopenAuthorization(authorization, models.PaymentStatusVoided)
+		return err
+	}
+
+	result, err := s.gateway.Void(*authorization.TransactionID)
+	if err != nil {
+		s.failTransaction(void, models.PaymentStatusFailed, "Payment gateway unavailable")
+		s.reopenAuthorization(authorization, models.PaymentStatusVoided)
+		return errors.NewHTTPError(http.StatusBadGateway, "Payment gateway unavailable, please try again", err)
+	}
+	if !result.Succeeded() {
+		s.failTransaction(void, models.PaymentStatusFailed, result.Message)
+		s.reopenAuthorization(authorization, models.PaymentStatusVoided)
+		return errors.NewHTTPError(http.StatusPaymentRequired, result.Message, nil)
+	}
+
+	return s.paymentRepo.UpdateTransaction(void.ID, map[string]interface{}{
+		"status":         models.PaymentStatusCompleted,
+		"transaction_id": result.ID,
+		"processed_at":   time.Now(),
+	})
+}
+
+// childTransaction builds a pending capture or void row linked to its authorization
+func (s *paymentService) childTransaction(authorization *models.PaymentTransaction, transactionType string, amount models.Money) *models.PaymentTransaction {
+	return &models.PaymentTransaction{
+		ID:              utils.GeneratePaymentID(),
+		OrderID:         authorization.OrderID,
+		UserID:          authorization.UserID,
+		PaymentMethodID: authorization.PaymentMethodID,
+		CardID:          authorization.CardID,
+		Type:            transactionType,
+		Amount:          amount,
+		RefundedAmount:  models.Zero(authorization.Currency),
+		Currency:        authorization.Currency,
+		Status:          models.PaymentStatusPending,
+		ParentID:        &authorization.ID,
+	}
+}
+
+// reopenAuthorization hands a claimed authorization back after its capture or void failed
+func (s *paymentService) reopenAuthorization(authorization *models.PaymentTransaction, claimedAs string) {
+	err := s.paymentRepo.UpdateTransactionIfStatus(authorization.ID, claimedAs, map[string]interface{}{
+		"status": models.PaymentStatusAuthorized,
+	})
+	if err != nil {
+		logger.Error("Failed to reopen payment authorization", "transaction_id", authorization.ID, "error", err)
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+
+	"dfood/internal/models"
+)
+
+func TestCaptureAmounts(t *testing.T) {
+	tests := []struct {
+		name  string
+		holds []int64
+		due   int64
+		want  []int64
+	}{
+		{name: "captures everything when due covers the holds", holds: []int64{1500, 2500}, due: 4000, want: []int64{1500, 2500}},
+		{name: "single hold", holds: []int64{2000}, due: 1234, want: []int64{1234}},
+		{name: "even split", holds: []int64{1000, 1000}, due: 1000, want: []int64{500, 500}},
+		{name: "proportional split", holds: []int64{1000, 3000}, due: 2000, want: []int64{500, 1500}},
+		{name: "leftover to largest remainder", holds: []int64{1000, 1000, 1000}, due: 1000, want: []int64{334, 333, 333}},
+		{name: "leftover not always to the last hold", holds: []int64{2, 1}, due: 2, want: []int64{1, 1}},
+		{name: "small holds never over-captured", holds: []int64{1, 1, 1, 1, 1}, due: 3, want: []int64{1, 1, 1, 0, 0}},
+		{name: "small last hold not exceeded", holds: []int64{1, 1, 1, 1}, due: 2, want: []int64{1, 1, 0, 0}},
+		{name: "nothing due voids every hold", holds: []int64{700, 300}, due: 0, want: []int64{0, 0}},
+		{name: "uneven holds", holds: []int64{999, 1, 1000}, due: 1001, want: []int64{500, 1, 500}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holds := make([]models.Money, len(tt.holds))
+			held := models.Zero(models.CurrencyUSD)
+			for i, amount := range tt.holds {
+				holds[i] = models.NewMoney(amount, models.CurrencyUSD)
+				held = held.Add(holds[i])
+			}
+
+			got := captureAmounts(holds, held, models.NewMoney(tt.due, models.CurrencyUSD))
+			if len(got) != len(tt.want) {
+				t.Fatalf("captureAmounts returned %d amounts, want %d", len(got), len(tt.want))
+			}
+			total := int64(0)
+			for i, amount := range got {
+				if amount.Amount != tt.want[i] {
+					t.Errorf("amount %d = %d, want %d", i, amount.Amount, tt.want[i])
+				}
+				if amount.Amount < 0 || amount.Amount > tt.holds[i] {
+					t.Errorf("amount %d = %d, outside its hold of %d", i, amount.Amount, tt.holds[i])
+				}
+				total += amount.Amount
+			}
+			if wantTotal := min(tt.due, held.Amount); total != wantTotal {
+				t.Errorf("captures add up to %d, want %d", total, wantTotal)
+			}
+		})
+	}
+}
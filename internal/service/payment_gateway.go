@@ -0,0 +1,117 @@
+package service
+
+import (
+	"fmt"
+
+	"dfood/internal/config"
+	"dfood/internal/database"
+	"dfood/internal/models"
+)
+
+// GatewayStatus is the outcome of a payment gateway operation
+type GatewayStatus string
+
+const (
+	GatewayStatusSucceeded      GatewayStatus = "succeeded"
+	GatewayStatusDeclined       GatewayStatus = "declined"
+	GatewayStatusRequiresAction GatewayStatus = "requires_action" // e.g. 3-D Secure authentication
+)
+
+// Decline codes reported by gateways
+const (
+	DeclineCodeGeneric           = "card_declined"
+	DeclineCodeInsufficientFunds = "insufficient_funds"
+	DeclineCodeInvalidRequest    = "invalid_request"
+)
+
+// GatewayCard holds raw card details on their way to the gateway; it must never be persisted
+type GatewayCard struct {
+	Number         string
+	CVV            string
+	ExpiryMonth    int
+	ExpiryYear     int
+	CardholderName string
+}
+
+// GatewayCardToken is the gateway's reusable stand-in for a card
+type GatewayCardToken struct {
+	Token       string
+	Brand       models.CardBrand
+	Last4       string
+	Fingerprint string // Stable for a card number, so the same card saved twice can be spotted
+}
+
+// GatewayAuthorization describes a charge to place on hold against a tokenized card
+type GatewayAuthorization struct {
+	Reference string // Our transaction ID, used by gateways as an idempotency key
+	Amount    models.Money
+	CardToken string
+}
+
+// GatewayResult is a gateway's response to an operation.
+// Declines are results rather than errors; errors mean the gateway could not be reached or answered nonsense.
+type GatewayResult struct {
+	ID          string // Gateway reference for the authorization, capture, void or refund
+	Status      GatewayStatus
+	Amount      models.Money // Amount the operation applied to
+	DeclineCode string
+	Message     string
+}
+
+// Succeeded reports whether the operation went through
+func (r *GatewayResult) Succeeded() bool {
+	return r.Status == GatewayStatusSucceeded
+}
+
+// PaymentGateway is the boundary to an external card processor
+type PaymentGateway interface {
+	// Tokenize exchanges card details for a token that can be charged later
+	Tokenize(card GatewayCard) (*GatewayCardToken, error)
+	// Authorize places a hold for the amount on the card
+	Authorize(request GatewayAuthorization) (*GatewayResult, error)
+	// Capture collects up to the authorized amount; the remainder of the hold is released
+	Capture(authorizationID string, amount models.Money) (*GatewayResult, error)
+	// Void releases an authorization that has not been captured
+	Void(authorizationID string) (*GatewayResult, error)
+	// Refund returns part or all of a captured amount
+	Refund(authorizationID string, amount models.Money) (*GatewayResult, error)
+}
+
+// NewPaymentGateway builds the gateway selected in config
+func NewPaymentGateway(cfg config.PaymentConfig) (PaymentGateway, error) {
+	switch cfg.Gateway {
+	case "", "fake":
+		return NewFakeGateway(cfg.FakeWebhookURL, cfg.WebhookSecrets["fake"]), nil
+	default:
+		return nil, fmt.Errorf("unknown payment gateway %q", cfg.Gateway)
+	}
+}
+
+// LegacyCardTokenizer exchanges the card numbers saved before tokenization for gateway tokens
+// while the database is migrated
+func LegacyCardTokenizer(gateway PaymentGateway) database.CardTokenizer {
+	return func(card *models.Card, number, cvv string) error {
+		if !models.IsValidCardNumber(number) {
+			return fmt.Errorf("card number is invalid")
+		}
+		if card.ExpiryYear < 100 {
+			card.ExpiryYear += 2000
+		}
+
+		token, err := gateway.Tokenize(GatewayCard{
+			Number:         number,
+			CVV:            cvv,
+			ExpiryMonth:    card.ExpiryMonth,
+			ExpiryYear:     card.ExpiryYear,
+			CardholderName: card.CardholderName,
+		})
+		if err != nil {
+			return err
+		}
+		card.Token = token.Token
+		card.Brand = token.Brand
+		card.Last4 = token.Last4
+		card.Fingerprint = token.Fingerprint
+		return nil
+	}
+}
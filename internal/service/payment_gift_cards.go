@@ -0,0 +1,111 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+	"dfood/pkg/logger"
+)
+
+// PurchaseGiftCard charges a saved card for a new gift card. The code is returned once, here, for the
+// buyer to pass on; if the card cannot be stored after the charge, the charge is refunded.
+func (s *paymentService) PurchaseGiftCard(request *models.PurchaseGiftCardRequest) (*models.GiftCard, error) {
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	amount := request.Amount
+	if amount.Currency == "" {
+		amount.Currency = models.DefaultCurrency
+	}
+	if err := checkGiftCardAmount(amount, s.giftCardConfig); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.chargeCard(request.UserID, request.CardID, models.PaymentTypeGiftCardSale, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	expiryDays := s.giftCardConfig.ExpiryDays
+	if expiryDays <= 0 {
+		expiryDays = defaultGiftCardExpiryDays
+	}
+	giftCard := newGiftCard(models.GiftCardSourcePurchase, amount, time.Now().AddDate(0, 0, expiryDays))
+	giftCard.PurchaserID = &request.UserID
+	giftCard.PaymentID = &payment.ID
+	giftCard.RecipientEmail = request.RecipientEmail
+	giftCard.Message = request.Message
+	if err := s.giftCardRepo.Create(giftCard); err != nil {
+		logger.Error("Failed to store purchased gift card", "transaction_id", payment.ID, "error", err)
+		s.reverseCharge(payment, "Gift card could not be issued")
+		return nil, err
+	}
+	s.recordInLedger(payment, func(payment *models.PaymentTransaction) error {
+		return s.ledgerService.RecordGiftCardSale(payment, giftCard)
+	})
+
+	return giftCard, nil
+}
+
+// resolveGiftCard loads the gift card a code belongs to along with its balance, refusing cards that cannot be spent
+func (s *paymentService) resolveGiftCard(code string, currency models.Currency) (*models.GiftCard, error) {
+	giftCard, err := s.giftCardRepo.GetByCodeHash(giftCardCodeHash(code))
+	if err != nil {
+		return nil, err
+	}
+	if giftCard.Currency != currency {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Gift card is in "+string(giftCard.Currency)+", not the order currency ("+string(currency)+")", nil)
+	}
+	if giftCard.Balance, err = s.ledgerService.GetGiftCardBalance(giftCard); err != nil {
+		return nil, err
+	}
+	if err := checkGiftCardUsable(giftCard); err != nil {
+		return nil, err
+	}
+	return giftCard, nil
+}
+
+// payFromGiftCard collects amount from a gift card at once. Like a wallet payment, the ledger posting
+// is the payment itself, so a failure to post fails the payment.
+func (s *paymentService) payFromGiftCard(order *models.Order, userID string, giftCard *models.GiftCard, amount models.Money) (*models.PaymentTransaction, error) {
+	payment := &models.PaymentTransaction{
+		ID:              utils.GeneratePaymentID(),
+		OrderID:         order.ID,
+		UserID:          userID,
+		PaymentMethodID: models.GiftCardPaymentMethodID,
+		GiftCardID:      &giftCard.ID,
+		Type:            models.PaymentTypeGiftCard,
+		Amount:          amount,
+		RefundedAmount:  models.Zero(order.Currency),
+		Currency:        order.Currency,
+		Status:          models.PaymentStatusPending,
+	}
+	if err := s.paymentRepo.CreateTransaction(payment); err != nil {
+		return nil, err
+	}
+
+	if err := s.ledgerService.SpendGiftCard(payment); err != nil {
+		reason := "Gift card payment could not be recorded"
+		if statusCode, _ := errors.GetStatusCode(err); statusCode == http.StatusPaymentRequired {
+			reason = "Gift card balance too low"
+			err = errors.NewHTTPError(http.StatusPaymentRequired, "Gift card ending in "+giftCard.Last4+" does not cover "+amount.String(), err)
+		}
+		s.failTransaction(payment, models.PaymentStatusFailed, reason)
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.paymentRepo.UpdateTransaction(payment.ID, map[string]interface{}{
+		"status":       models.PaymentStatusCompleted,
+		"processed_at": now,
+	}); err != nil {
+		return nil, err
+	}
+	payment.Status = models.PaymentStatusCompleted
+	payment.ProcessedAt = &now
+	return payment, nil
+}
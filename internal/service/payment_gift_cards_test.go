@@ -0,0 +1,65 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"dfood/internal/models"
+)
+
+func TestGiftCardPaymentsNeverOverspendTheBalance(t *testing.T) {
+	s := newTestPayments(t)
+	seed(t, testUser("ana"), testUser("ben"))
+	saveTestCard(t, s, "ana", FakeCardSuccess)
+	giftCard, err := s.PurchaseGiftCard(&models.PurchaseGiftCardRequest{UserID: "ana", Amount: usd(5000)})
+	if err != nil {
+		t.Fatalf("PurchaseGiftCard error = %v", err)
+	}
+	balance := func() int64 {
+		t.Helper()
+		balance, err := s.ledgerService.GetGiftCardBalance(giftCard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return balance.Amount
+	}
+
+	// Five 24.60 orders race for a 50.00 card; each saw the full balance, but only two fit in it
+	var wg sync.WaitGroup
+	paid := make(chan string, 5)
+	for i := 0; i < 5; i++ {
+		orderID := fmt.Sprintf("o%d", i)
+		seed(t, testOrder(orderID, "ana", "r1"))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.ProcessPayment(&models.PaymentTransaction{OrderID: orderID, UserID: "ana", PaymentMethodID: models.GiftCardPaymentMethodID, GiftCardCode: giftCard.Code})
+			if err == nil {
+				paid <- orderID
+				return
+			}
+			rejectedAs(t, err, http.StatusPaymentRequired)
+		}()
+	}
+	wg.Wait()
+	close(paid)
+	if len(paid) != 2 || balance() != 80 {
+		t.Fatalf("%d orders paid from a 50.00 gift card, leaving %d; want 2 leaving 80", len(paid), balance())
+	}
+	for orderID := range paid {
+		if order, _ := s.orderRepo.GetByID(orderID); order.Status != models.OrderStatusConfirmed {
+			t.Errorf("order %s paid by gift card is %s, want confirmed", orderID, order.Status)
+		}
+	}
+
+	// The code is not tied to the buyer, but Ben's declined card means the 0.80 left stays on it
+	seed(t, testOrder("ben1", "ben", "r1"))
+	saveTestCard(t, s, "ben", FakeCardDeclined)
+	_, err = s.ProcessPayment(&models.PaymentTransaction{OrderID: "ben1", UserID: "ben", GiftCardCode: giftCard.Code})
+	rejectedAs(t, err, http.StatusPaymentRequired)
+	if got := balance(); got != 80 {
+		t.Errorf("balance after the card part was declined = %d, want 80", got)
+	}
+}
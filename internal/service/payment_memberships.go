@@ -0,0 +1,16 @@
+package service
+
+import (
+	"dfood/internal/models"
+)
+
+// ChargeMembership collects one period of a membership from its card, or the user's default card when it
+// has none, so a customer can fix a failing renewal by changing their default card
+func (s *paymentService) ChargeMembership(membership *models.Membership, amount models.Money) (*models.PaymentTransaction, error) {
+	payment, err := s.chargeCard(membership.UserID, membership.CardID, models.PaymentTypeMembership, amount)
+	if err != nil {
+		return nil, err
+	}
+	s.recordInLedger(payment, s.ledgerService.RecordMembershipFee)
+	return payment, nil
+}
@@ -2,62 +2,572 @@ package service
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
+	"dfood/internal/config"
 	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/internal/utils"
 	"dfood/pkg/errors"
+	"dfood/pkg/logger"
 )
 
+// paymentClaimTimeout is how long a payment in progress keeps other payments of the same order out. A claim
+// older than this was left behind by a request that never finished.
+const paymentClaimTimeout = 2 * time.Minute
+
 type PaymentService interface {
 	GetPaymentMethods() ([]models.PaymentMethod, error)
 	GetUserCards(userID string) ([]models.Card, error)
-	SaveCard(card *models.Card) error
+	SaveCard(request *models.SaveCardRequest) (*models.Card, error)
 	DeleteCard(cardID string) error
 	ProcessPayment(transaction *models.PaymentTransaction) (*models.PaymentTransaction, error)
 	GetTransactionDetails(transactionID string) (*models.PaymentTransaction, error)
-	ProcessRefund(transactionID string, amount float64) (*models.PaymentTransaction, error)
+	GetOrderTransactions(orderID string) ([]models.PaymentTransaction, error)
+	ProcessRefund(viewer models.OrderViewer, transactionID string, amount models.Money, toWallet bool) (*models.PaymentTransaction, error)
+	TopUpWallet(viewer models.OrderViewer, request *models.TopUpWalletRequest) (*models.PaymentTransaction, error)
+	PurchaseGiftCard(request *models.PurchaseGiftCardRequest) (*models.GiftCard, error)
+	ChargeMembership(membership *models.Membership, amount models.Money) (*models.PaymentTransaction, error)
+	CaptureOrder(orderID string) error
+	ReleaseOrder(orderID string, keep models.Money) error
+	CoverOrderTotal(orderID string) error
+	HandleWebhook(provider string, payload []byte, signature string) (*models.PaymentWebhookEvent, error)
 }
 
 type paymentService struct {
-	// TODO: Add repository dependencies when implemented
+	paymentRepo    repository.PaymentRepository
+	webhookRepo    repository.PaymentWebhookRepository
+	orderRepo      repository.OrderRepository
+	giftCardRepo   repository.GiftCardRepository
+	userRepo       repository.UserRepository
+	gateway        PaymentGateway
+	ledgerService  LedgerService
+	config         config.PaymentConfig
+	giftCardConfig config.GiftCardConfig
 }
 
-func NewPaymentService() PaymentService {
-	return &paymentService{}
+func NewPaymentService(paymentRepo repository.PaymentRepository, webhookRepo repository.PaymentWebhookRepository, orderRepo repository.OrderRepository, giftCardRepo repository.GiftCardRepository, userRepo repository.UserRepository, gateway PaymentGateway, ledgerService LedgerService, cfg config.PaymentConfig, giftCardConfig config.GiftCardConfig) PaymentService {
+	return &paymentService{
+		paymentRepo:    paymentRepo,
+		webhookRepo:    webhookRepo,
+		orderRepo:      orderRepo,
+		giftCardRepo:   giftCardRepo,
+		userRepo:       userRepo,
+		gateway:        gateway,
+		ledgerService:  ledgerService,
+		config:         cfg,
+		giftCardConfig: giftCardConfig,
+	}
 }
 
+// GetPaymentMethods lists the stored payment methods followed by the wallet
 func (s *paymentService) GetPaymentMethods() ([]models.PaymentMethod, error) {
-	// TODO: Implement payment methods retrieval logic
-	return nil, errors.NewHTTPError(http.StatusNotImplemented, "Get payment methods not implemented", nil)
+	methods, err := s.paymentRepo.GetPaymentMethods()
+	if err != nil {
+		return nil, err
+	}
+	return append(methods, models.WalletPaymentMethod()), nil
 }
 
 func (s *paymentService) GetUserCards(userID string) ([]models.Card, error) {
-	// TODO: Implement user cards retrieval logic
-	return nil, errors.NewHTTPError(http.StatusNotImplemented, "Get user cards not implemented", nil)
+	if strings.TrimSpace(userID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	return s.paymentRepo.GetUserCards(userID)
 }
 
-func (s *paymentService) SaveCard(card *models.Card) error {
-	// TODO: Implement card saving logic
-	// This should include encryption of sensitive data
-	return errors.NewHTTPError(http.StatusNotImplemented, "Save card not implemented", nil)
+// SaveCard validates the card, exchanges it for a gateway token and stores only the token and display details
+func (s *paymentService) SaveCard(request *models.SaveCardRequest) (*models.Card, error) {
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+
+	number := models.NormalizeCardNumber(request.Number)
+	if !models.IsValidCardNumber(number) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Card number is invalid", nil)
+	}
+	if len(request.CVV) < 3 || len(request.CVV) > 4 || strings.Trim(request.CVV, "0123456789") != "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Security code must be 3 or 4 digits", nil)
+	}
+	expiryYear := request.ExpiryYear
+	if expiryYear < 100 {
+		expiryYear += 2000
+	}
+	now := time.Now()
+	if request.ExpiryMonth < 1 || request.ExpiryMonth > 12 || expiryYear > now.Year()+20 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Expiry date is invalid", nil)
+	}
+	if models.CardExpired(request.ExpiryMonth, expiryYear, now) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Card has expired", nil)
+	}
+
+	token, err := s.gateway.Tokenize(GatewayCard{
+		Number:         number,
+		CVV:            request.CVV,
+		ExpiryMonth:    request.ExpiryMonth,
+		ExpiryYear:     expiryYear,
+		CardholderName: request.CardholderName,
+	})
+	if err != nil {
+		logger.Error("Payment gateway tokenization failed", "user_id", request.UserID, "error", err)
+		return nil, errors.NewHTTPError(http.StatusBadGateway, "Payment gateway unavailable, please try again", err)
+	}
+
+	existing, err := s.paymentRepo.GetCardByFingerprint(request.UserID, token.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.NewHTTPError(http.StatusConflict, "This card is already saved as "+string(existing.Brand)+" ending in "+existing.Last4, nil)
+	}
+
+	cards, err := s.paymentRepo.GetUserCards(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	card := &models.Card{
+		ID:              utils.GenerateCardID(),
+		UserID:          request.UserID,
+		PaymentMethodID: request.PaymentMethodID,
+		Token:           token.Token,
+		Brand:           token.Brand,
+		Last4:           token.Last4,
+		Fingerprint:     token.Fingerprint,
+		ExpiryMonth:     request.ExpiryMonth,
+		ExpiryYear:      expiryYear,
+		CardholderName:  strings.TrimSpace(request.CardholderName),
+		IsDefault:       request.IsDefault || len(cards) == 0,
+	}
+	if err := s.paymentRepo.CreateCard(card); err != nil {
+		return nil, err
+	}
+	if card.IsDefault && len(cards) > 0 {
+		if err := s.paymentRepo.SetDefaultCard(card.UserID, card.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return card, nil
 }
 
 func (s *paymentService) DeleteCard(cardID string) error {
-	// TODO: Implement card deletion logic
-	return errors.NewHTTPError(http.StatusNotImplemented, "Delete card not implemented", nil)
+	if strings.TrimSpace(cardID) == "" {
+		return errors.NewHTTPError(http.StatusBadRequest, "Card ID is required", nil)
+	}
+	return s.paymentRepo.DeleteCard(cardID)
 }
 
+// ProcessPayment pays part or all of the order's outstanding balance from the user's wallet, a hold on
+// a saved card, or both. The order moves to confirmed once its payments cover the total, and held card
+// funds are only captured when the order reaches the configured capture status.
 func (s *paymentService) ProcessPayment(transaction *models.PaymentTransaction) (*models.PaymentTransaction, error) {
-	// TODO: Implement payment processing logic
-	// This should integrate with payment gateways like Stripe, PayPal, etc.
-	return nil, errors.NewHTTPError(http.StatusNotImplemented, "Process payment not implemented", nil)
+	if transaction == nil || strings.TrimSpace(transaction.OrderID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Order ID is required", nil)
+	}
+	if strings.TrimSpace(transaction.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+
+	order, err := s.orderRepo.GetByID(transaction.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	// Group order participants pay their portion of the host's order
+	if order.UserID != transaction.UserID && order.GroupOrderID == nil {
+		return nil, errors.NewHTTPError(http.StatusForbidden, "Order does not belong to this user", nil)
+	}
+	if order.Status == models.OrderStatusCancelled || order.Status == models.OrderStatusDelivered {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Order is "+string(order.Status)+" and can no longer be paid", nil)
+	}
+
+	// The order is claimed before anything is worked out from its payments, so two concurrent payments
+	// cannot both authorize the same outstanding amount
+	now := time.Now()
+	if err := s.orderRepo.ClaimPayment(order.ID, now, now.Add(-paymentClaimTimeout)); err != nil {
+		return nil, err
+	}
+	defer s.releasePaymentClaim(order.ID)
+
+	paid, err := s.paidAmount(order)
+	if err != nil {
+		return nil, err
+	}
+	outstanding := order.Total.Sub(paid)
+	if !outstanding.IsPositive() {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Order is already paid", nil)
+	}
+
+	amount := transaction.Amount
+	amount.Currency = order.Currency
+	if amount.IsZero() {
+		amount = outstanding
+	}
+	if transaction.Currency != "" && transaction.Currency != order.Currency {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Payment currency must match the order currency ("+string(order.Currency)+")", nil)
+	}
+	if !amount.IsPositive() || amount.Cmp(outstanding) > 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Payment amount must be between 0 and the outstanding "+outstanding.String(), nil)
+	}
+
+	// A gift card or the wallet pays first: all of it when chosen as the payment method, or as much as it
+	// covers when combined with a card, which is then authorized for the rest
+	useGiftCard := transaction.PaymentMethodID == models.GiftCardPaymentMethodID || strings.TrimSpace(transaction.GiftCardCode) != ""
+	useWallet := transaction.PaymentMethodID == models.WalletPaymentMethodID || transaction.UseWallet
+	if useGiftCard && useWallet {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "A gift card can be combined with a card but not with the wallet", nil)
+	}
+	var giftCard *models.GiftCard
+	giftCardAmount := models.Zero(order.Currency)
+	if useGiftCard {
+		if strings.TrimSpace(transaction.GiftCardCode) == "" {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Gift card code is required", nil)
+		}
+		if giftCard, err = s.resolveGiftCard(transaction.GiftCardCode, order.Currency); err != nil {
+			return nil, err
+		}
+		giftCardAmount = giftCard.Balance.Min(amount)
+		if transaction.PaymentMethodID == models.GiftCardPaymentMethodID && giftCardAmount.Cmp(amount) < 0 {
+			return nil, errors.NewHTTPError(http.StatusPaymentRequired, "Gift card balance of "+giftCard.Balance.String()+" does not cover "+amount.String(), nil)
+		}
+	}
+	walletAmount := models.Zero(order.Currency)
+	if useWallet {
+		balance, err := s.ledgerService.GetWalletBalance(transaction.UserID, order.Currency)
+		if err != nil {
+			return nil, err
+		}
+		walletAmount = balance.Max(models.Zero(order.Currency)).Min(amount)
+		if transaction.PaymentMethodID == models.WalletPaymentMethodID && walletAmount.Cmp(amount) < 0 {
+			return nil, errors.NewHTTPError(http.StatusPaymentRequired, "Wallet balance of "+balance.String()+" does not cover "+amount.String(), nil)
+		}
+	}
+	cardAmount := amount.Sub(walletAmount).Sub(giftCardAmount)
+
+	// The card goes first so a decline leaves the wallet or gift card untouched; the hold is released if they then fall short
+	var authorization *models.PaymentTransaction
+	if cardAmount.IsPositive() {
+		if authorization, err = s.authorizeCard(order, transaction, cardAmount); err != nil {
+			return nil, err
+		}
+	}
+	releaseHold := func() {
+		if authorization != nil && authorization.Status == models.PaymentStatusAuthorized {
+			if voidErr := s.voidAuthorization(authorization); voidErr != nil {
+				logger.Error("Failed to release card hold after balance payment failed", "transaction_id", authorization.ID, "error", voidErr)
+			}
+		}
+	}
+	var balancePayment *models.PaymentTransaction
+	if walletAmount.IsPositive() {
+		if balancePayment, err = s.payFromWallet(order, transaction.UserID, walletAmount); err != nil {
+			releaseHold()
+			return nil, err
+		}
+	}
+	if giftCardAmount.IsPositive() {
+		if balancePayment, err = s.payFromGiftCard(order, transaction.UserID, giftCard, giftCardAmount); err != nil {
+			releaseHold()
+			return nil, err
+		}
+	}
+
+	s.confirmIfPaid(order)
+	// Holds placed after the order already reached the capture status are collected straight away
+	if err := s.CaptureOrder(order.ID); err != nil {
+		logger.Error("Failed to capture order payments", "order_id", order.ID, "error", err)
+	}
+
+	// Report whatever state the card authorization ended up in, or the wallet or gift card payment if no card was needed
+	if authorization != nil {
+		return s.paymentRepo.GetTransactionByID(authorization.ID)
+	}
+	return s.paymentRepo.GetTransactionByID(balancePayment.ID)
+}
+
+// authorizeCard places a hold for amount on the requested or default card
+func (s *paymentService) authorizeCard(order *models.Order, transaction *models.PaymentTransaction, amount models.Money) (*models.PaymentTransaction, error) {
+	card, err := s.resolveCard(transaction.UserID, transaction.CardID)
+	if err != nil {
+		return nil, err
+	}
+	if card.IsExpired(time.Now()) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Card ending in "+card.Last4+" has expired", nil)
+	}
+
+	paymentMethodID := transaction.PaymentMethodID
+	if paymentMethodID == "" || paymentMethodID == models.WalletPaymentMethodID || paymentMethodID == models.GiftCardPaymentMethodID {
+		paymentMethodID = card.PaymentMethodID
+	}
+	authorization := &models.PaymentTransaction{
+		ID:              utils.GeneratePaymentID(),
+		OrderID:         order.ID,
+		UserID:          transaction.UserID,
+		PaymentMethodID: paymentMethodID,
+		CardID:          &card.ID,
+		Type:            models.PaymentTypeAuthorization,
+		Amount:          amount,
+		RefundedAmount:  models.Zero(order.Currency),
+		Currency:        order.Currency,
+		Status:          models.PaymentStatusPending,
+	}
+	if err := s.paymentRepo.CreateTransaction(authorization); err != nil {
+		return nil, err
+	}
+
+	// Record the pending row first so a crash mid-authorization leaves a trace to reconcile against the gateway
+	result, err := s.gateway.Authorize(GatewayAuthorization{
+		Reference: authorization.ID,
+		Amount:    amount,
+		CardToken: card.Token,
+	})
+	if err != nil {
+		logger.Error("Payment gateway request failed", "transaction_id", authorization.ID, "error", err)
+		s.failTransaction(authorization, models.PaymentStatusFailed, "Payment gateway unavailable")
+		return nil, errors.NewHTTPError(http.StatusBadGateway, "Payment gateway unavailable, please try again", err)
+	}
+
+	switch result.Status {
+	case GatewayStatusRequiresAction:
+		s.failTransaction(authorization, models.PaymentStatusRequiresAction, result.Message)
+		return nil, errors.NewHTTPError(http.StatusPaymentRequired, result.Message, nil)
+	case GatewayStatusDeclined:
+		s.failTransaction(authorization, models.PaymentStatusFailed, result.Message)
+		return nil, errors.NewHTTPError(http.StatusPaymentRequired, result.Message, nil)
+	}
+
+	now := time.Now()
+	err = s.paymentRepo.UpdateTransactionIfStatus(authorization.ID, models.PaymentStatusPending, map[string]interface{}{
+		"status":         models.PaymentStatusAuthorized,
+		"transaction_id": result.ID,
+		"processed_at":   now,
+	})
+	if statusCode, _ := errors.GetStatusCode(err); err != nil && statusCode != http.StatusConflict {
+		return nil, err
+	}
+	// A conflict means a webhook for this authorization was handled first, which is fine either way
+	authorization.Status = models.PaymentStatusAuthorized
+	authorization.TransactionID = &result.ID
+	return authorization, nil
 }
 
 func (s *paymentService) GetTransactionDetails(transactionID string) (*models.PaymentTransaction, error) {
-	// TODO: Implement transaction details retrieval logic
-	return nil, errors.NewHTTPError(http.StatusNotImplemented, "Get transaction details not implemented", nil)
+	if strings.TrimSpace(transactionID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Transaction ID is required", nil)
+	}
+	return s.paymentRepo.GetTransactionByID(transactionID)
+}
+
+func (s *paymentService) GetOrderTransactions(orderID string) ([]models.PaymentTransaction, error) {
+	if strings.TrimSpace(orderID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Order ID is required", nil)
+	}
+	return s.paymentRepo.GetTransactionsByOrderID(orderID)
+}
+
+// ProcessRefund refunds part or all of a completed charge, capture, or wallet or gift card payment; a zero
+// amount refunds whatever remains. Wallet and gift card payments, and any payment when toWallet is set, are
+// credited to the customer's wallet at once instead of going back through the gateway. Only support can refund
+// a delivered order to the wallet, where the money could be spent again straight away.
+func (s *paymentService) ProcessRefund(viewer models.OrderViewer, transactionID string, amount models.Money, toWallet bool) (*models.PaymentTransaction, error) {
+	charge, err := s.GetTransactionDetails(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if !charge.CollectsFunds() || charge.Status != models.PaymentStatusCompleted {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Only completed charges with a remaining balance can be refunded", nil)
+	}
+	toWallet = toWallet || charge.Type == models.PaymentTypeWallet || charge.Type == models.PaymentTypeGiftCard
+	if toWallet && charge.OrderID != "" && !viewer.IsStaff() {
+		order, err := s.orderRepo.GetByID(charge.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		if order.Status == models.OrderStatusDelivered {
+			return nil, errors.NewHTTPError(http.StatusForbidden, "Only support can refund a delivered order to the wallet", nil)
+		}
+	}
+
+	refundable := charge.Amount.Sub(charge.RefundedAmount)
+	amount.Currency = charge.Currency
+	if amount.IsZero() {
+		amount = refundable
+	}
+	if !amount.IsPositive() || amount.Cmp(refundable) > 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Refund amount must be between 0 and the refundable "+refundable.String(), nil)
+	}
+
+	now := time.Now()
+	refund := &models.PaymentTransaction{
+		ID:              utils.GeneratePaymentID(),
+		OrderID:         charge.OrderID,
+		UserID:          charge.UserID,
+		PaymentMethodID: models.WalletPaymentMethodID,
+		Type:            models.PaymentTypeRefund,
+		Amount:          amount,
+		RefundedAmount:  models.Zero(charge.Currency),
+		Currency:        charge.Currency,
+		Status:          models.PaymentStatusCompleted,
+		ParentID:        &charge.ID,
+		ProcessedAt:     &now,
+	}
+	var gatewayChargeID string
+	if !toWallet {
+		if charge.TransactionID == nil {
+			return nil, errors.NewHTTPError(http.StatusConflict, "Charge has no gateway reference to refund against", nil)
+		}
+		if gatewayChargeID, err = s.gatewayChargeID(charge); err != nil {
+			return nil, err
+		}
+	}
+
+	// The amount is claimed first so concurrent refunds can never refund more than was charged
+	if err := s.paymentRepo.ClaimRefund(charge.ID, amount); err != nil {
+		return nil, err
+	}
+	if !toWallet {
+		result, err := s.gateway.Refund(gatewayChargeID, amount)
+		if err != nil {
+			s.releaseRefund(charge, amount)
+			logger.Error("Payment gateway refund failed", "transaction_id", charge.ID, "error", err)
+			return nil, errors.NewHTTPError(http.StatusBadGateway, "Payment gateway unavailable, please try again", err)
+		}
+		if !result.Succeeded() {
+			s.releaseRefund(charge, amount)
+			return nil, errors.NewHTTPError(http.StatusPaymentRequired, result.Message, nil)
+		}
+		refund.PaymentMethodID = charge.PaymentMethodID
+		refund.CardID = charge.CardID
+		refund.TransactionID = &result.ID
+	}
+
+	if err := s.paymentRepo.CreateTransaction(refund); err != nil {
+		return nil, err
+	}
+	s.recordInLedger(refund, s.ledgerService.RecordRefund)
+
+	return refund, nil
+}
+
+// releasePaymentClaim lets the next payment of the order go ahead
+func (s *paymentService) releasePaymentClaim(orderID string) {
+	if err := s.orderRepo.ReleasePayment(orderID); err != nil {
+		logger.Error("Failed to release order payment claim", "order_id", orderID, "error", err)
+	}
+}
+
+// releaseRefund gives back the refundable amount claimed for a refund that did not go through
+func (s *paymentService) releaseRefund(charge *models.PaymentTransaction, amount models.Money) {
+	if err := s.paymentRepo.ReleaseRefund(charge.ID, amount); err != nil {
+		logger.Error("Failed to release refund claim", "transaction_id", charge.ID, "amount", amount, "error", err)
+	}
+}
+
+// paidAmount sums what the customer has committed to an order: collected charges and captures
+// plus authorizations still holding funds
+func (s *paymentService) paidAmount(order *models.Order) (models.Money, error) {
+	paid := models.Zero(order.Currency)
+	transactions, err := s.paymentRepo.GetTransactionsByOrderID(order.ID)
+	if err != nil {
+		return paid, err
+	}
+	for _, transaction := range transactions {
+		if transaction.IsCollected() || (transaction.Type == models.PaymentTypeAuthorization && transaction.Status == models.PaymentStatusAuthorized) {
+			paid = paid.Add(transaction.Amount)
+		}
+	}
+	return paid, nil
+}
+
+// confirmIfPaid moves a pending order to confirmed once its payments cover the order total
+func (s *paymentService) confirmIfPaid(order *models.Order) {
+	if order.Status != models.OrderStatusPending {
+		return
+	}
+	paid, err := s.paidAmount(order)
+	if err != nil {
+		logger.Error("Failed to total order payments", "order_id", order.ID, "error", err)
+		return
+	}
+	if paid.Cmp(order.Total) < 0 {
+		return
+	}
+
+	err = s.orderRepo.UpdateIfStatus(order.ID, models.OrderStatusPending, map[string]interface{}{
+		"status": models.OrderStatusConfirmed,
+	})
+	if err != nil {
+		// The payment stands either way; the order simply moved on before we could confirm it
+		logger.Error("Failed to confirm paid order", "order_id", order.ID, "error", err)
+		return
+	}
+	order.Status = models.OrderStatusConfirmed
+}
+
+// resolveCard loads the requested card, or the user's default card when none is given
+func (s *paymentService) resolveCard(userID string, cardID *string) (*models.Card, error) {
+	if cardID != nil && *cardID != "" {
+		card, err := s.paymentRepo.GetCardByID(*cardID)
+		if err != nil {
+			return nil, err
+		}
+		if card.UserID != userID {
+			return nil, errors.NewHTTPError(http.StatusForbidden, "Card does not belong to this user", nil)
+		}
+		return card, nil
+	}
+
+	cards, err := s.paymentRepo.GetUserCards(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) == 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "A saved card is required to pay", nil)
+	}
+	// Cards come back default first
+	return &cards[0], nil
+}
+
+// gatewayChargeID returns the gateway ID that refunds of a charge or capture are issued against.
+// Captures are refunded through the authorization they collected.
+func (s *paymentService) gatewayChargeID(charge *models.PaymentTransaction) (string, error) {
+	if charge.Type != models.PaymentTypeCapture {
+		return *charge.TransactionID, nil
+	}
+	if charge.ParentID == nil {
+		return "", errors.NewHTTPError(http.StatusConflict, "Capture is not linked to an authorization", nil)
+	}
+	authorization, err := s.paymentRepo.GetTransactionByID(*charge.ParentID)
+	if err != nil {
+		return "", err
+	}
+	if authorization.TransactionID == nil {
+		return "", errors.NewHTTPError(http.StatusConflict, "Authorization has no gateway reference", nil)
+	}
+	return *authorization.TransactionID, nil
+}
+
+// recordInLedger posts a money movement that has already happened at the gateway. A posting failure
+// must not undo the payment, so it is logged and left for the ledger check to report.
+func (s *paymentService) recordInLedger(transaction *models.PaymentTransaction, record func(*models.PaymentTransaction) error) {
+	if err := record(transaction); err != nil {
+		logger.Error("Failed to post payment to the ledger", "transaction_id", transaction.ID, "type", transaction.Type, "error", err)
+	}
 }
 
-func (s *paymentService) ProcessRefund(transactionID string, amount float64) (*models.PaymentTransaction, error) {
-	// TODO: Implement refund processing logic
-	return nil, errors.NewHTTPError(http.StatusNotImplemented, "Process refund not implemented", nil)
+// failTransaction records a charge that did not complete
+func (s *paymentService) failTransaction(charge *models.PaymentTransaction, status, reason string) {
+	now := time.Now()
+	charge.Status = status
+	charge.FailureReason = &reason
+	charge.ProcessedAt = &now
+	err := s.paymentRepo.UpdateTransaction(charge.ID, map[string]interface{}{
+		"status":         status,
+		"failure_reason": reason,
+		"processed_at":   now,
+	})
+	if err != nil {
+		logger.Error("Failed to record failed payment", "transaction_id", charge.ID, "error", err)
+	}
 }
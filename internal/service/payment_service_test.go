@@ -0,0 +1,147 @@
+package service
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+// newTestPayments opens a test database and wires a payment service to it with the fake gateway.
+// Payments are captured as soon as an order is confirmed.
+func newTestPayments(t *testing.T) *paymentService {
+	t.Helper()
+	openTestDB(t)
+	orderRepo := repository.NewOrderRepository()
+	ledgerService := NewLedgerService(repository.NewLedgerRepository(), orderRepo, repository.NewPayoutRepository(), config.LedgerConfig{CommissionBasisPoints: 1500})
+	return NewPaymentService(
+		repository.NewPaymentRepository(),
+		repository.NewPaymentWebhookRepository(),
+		orderRepo,
+		repository.NewGiftCardRepository(),
+		repository.NewUserRepository(),
+		NewFakeGateway("", ""),
+		ledgerService,
+		config.PaymentConfig{CaptureOn: "confirmed"},
+		config.GiftCardConfig{MinAmount: 500, MaxAmount: 50000, ExpiryDays: 365},
+	).(*paymentService)
+}
+
+func saveTestCard(t *testing.T, s *paymentService, userID, number string) *models.Card {
+	t.Helper()
+	card, err := s.SaveCard(&models.SaveCardRequest{
+		UserID:          userID,
+		PaymentMethodID: "card",
+		Number:          number,
+		CVV:             "123",
+		ExpiryMonth:     12,
+		ExpiryYear:      time.Now().Year() + 2,
+		CardholderName:  "Test " + userID,
+	})
+	if err != nil {
+		t.Fatalf("SaveCard(%s) error = %v", number, err)
+	}
+	return card
+}
+
+// paidCapture pays a fresh 24.60 order in full with a card and returns the capture that collected it
+func paidCapture(t *testing.T, s *paymentService, orderID string) *models.PaymentTransaction {
+	t.Helper()
+	seed(t, testUser("ana"), testOrder(orderID, "ana", "r1"))
+	saveTestCard(t, s, "ana", FakeCardSuccess)
+
+	if _, err := s.ProcessPayment(&models.PaymentTransaction{OrderID: orderID, UserID: "ana"}); err != nil {
+		t.Fatalf("ProcessPayment error = %v", err)
+	}
+	transactions, err := s.GetOrderTransactions(orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range transactions {
+		if transactions[i].Type == models.PaymentTypeCapture {
+			return &transactions[i]
+		}
+	}
+	t.Fatalf("no capture among %+v", transactions)
+	return nil
+}
+
+func TestProcessPaymentDeclinedCardLeavesTheOrderUnpaid(t *testing.T) {
+	s := newTestPayments(t)
+	seed(t, testUser("ana"), testOrder("o1", "ana", "r1"))
+	saveTestCard(t, s, "ana", FakeCardDeclined)
+
+	_, err := s.ProcessPayment(&models.PaymentTransaction{OrderID: "o1", UserID: "ana"})
+	rejectedAs(t, err, http.StatusPaymentRequired)
+
+	order, _ := s.orderRepo.GetByID("o1")
+	if paid, _ := s.paidAmount(order); order.Status != models.OrderStatusPending || !paid.IsZero() {
+		t.Errorf("after a decline the order is %s with %s paid, want pending with nothing", order.Status, paid)
+	}
+	if order.PaymentClaimedAt != nil {
+		t.Errorf("payment claim left at %v after the decline", order.PaymentClaimedAt)
+	}
+}
+
+func TestProcessRefundNeverExceedsTheCharge(t *testing.T) {
+	s := newTestPayments(t)
+	capture := paidCapture(t, s, "o1")
+	staff := models.OrderViewer{Email: "agent@example.com", Role: models.RoleSupport}
+
+	if _, err := s.ProcessRefund(staff, capture.ID, usd(1000), false); err != nil {
+		t.Fatalf("refunding 10.00 of 24.60: %v", err)
+	}
+	_, err := s.ProcessRefund(staff, capture.ID, usd(1500), false)
+	rejectedAs(t, err, http.StatusBadRequest)
+
+	// A zero amount refunds the rest and closes the charge
+	rest, err := s.ProcessRefund(staff, capture.ID, models.Money{}, false)
+	if err != nil || rest.Amount.Amount != 1460 {
+		t.Fatalf("refunding the rest = %+v, %v; want 14.60", rest, err)
+	}
+	_, err = s.ProcessRefund(staff, capture.ID, usd(1), false)
+	rejectedAs(t, err, http.StatusConflict)
+
+	charge, _ := s.GetTransactionDetails(capture.ID)
+	if charge.Status != models.PaymentStatusRefunded || charge.RefundedAmount.Amount != 2460 {
+		t.Errorf("charge = %s with %s refunded, want refunded in full", charge.Status, charge.RefundedAmount)
+	}
+}
+
+func TestConcurrentRefundsClaimTheAmountOnce(t *testing.T) {
+	s := newTestPayments(t)
+	capture := paidCapture(t, s, "o1")
+	staff := models.OrderViewer{Email: "agent@example.com", Role: models.RoleSupport}
+
+	// Eight agents each refund 10.00 of a 24.60 charge at the same moment; only two can fit
+	var wg sync.WaitGroup
+	results := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.ProcessRefund(staff, capture.ID, usd(1000), true)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+		}
+	}
+	charge, _ := s.GetTransactionDetails(capture.ID)
+	if succeeded != 2 || charge.RefundedAmount.Amount != 2000 {
+		t.Errorf("%d refunds went through for %s, want 2 for 20.00", succeeded, charge.RefundedAmount)
+	}
+	if balance, _ := s.ledgerService.GetWalletBalance("ana", models.CurrencyUSD); balance.Amount != 2000 {
+		t.Errorf("wallet balance = %s, want the 20.00 refunded", balance)
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+	"dfood/pkg/logger"
+)
+
+// defaultWalletMaxTopUp caps a single top-up, in minor units of the default currency, when the config sets no limit
+const defaultWalletMaxTopUp = 50000
+
+// TopUpWallet charges one of the caller's saved cards and credits the amount to their wallet
+func (s *paymentService) TopUpWallet(viewer models.OrderViewer, request *models.TopUpWalletRequest) (*models.PaymentTransaction, error) {
+	if request == nil || strings.TrimSpace(request.UserID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	user, err := authorizeUser(s.userRepo, viewer, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	amount := request.Amount
+	if amount.Currency == "" {
+		amount.Currency = models.DefaultCurrency
+	}
+	maxTopUp := models.NewMoney(s.config.WalletMaxTopUp, models.DefaultCurrency)
+	if maxTopUp.IsZero() {
+		maxTopUp = models.NewMoney(defaultWalletMaxTopUp, models.DefaultCurrency)
+	}
+	if amount.Currency != maxTopUp.Currency {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Wallets can only be topped up in "+string(maxTopUp.Currency), nil)
+	}
+	if !amount.IsPositive() || amount.Cmp(maxTopUp) > 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Top-up amount must be between 0 and "+maxTopUp.String(), nil)
+	}
+
+	topUp, err := s.chargeCard(user.ID, request.CardID, models.PaymentTypeTopUp, amount)
+	if err != nil {
+		return nil, err
+	}
+	s.recordInLedger(topUp, s.ledgerService.RecordTopUp)
+
+	return topUp, nil
+}
+
+// chargeCard collects amount from a saved card at once for something other than an order. The hold is
+// captured straight away; if that fails it is voided so the customer is never charged for nothing.
+func (s *paymentService) chargeCard(userID string, cardID *string, paymentType string, amount models.Money) (*models.PaymentTransaction, error) {
+	card, err := s.resolveCard(userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card.IsExpired(time.Now()) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Card ending in "+card.Last4+" has expired", nil)
+	}
+
+	charge := &models.PaymentTransaction{
+		ID:              utils.GeneratePaymentID(),
+		UserID:          userID,
+		PaymentMethodID: card.PaymentMethodID,
+		CardID:          &card.ID,
+		Type:            paymentType,
+		Amount:          amount,
+		RefundedAmount:  models.Zero(amount.Currency),
+		Currency:        amount.Currency,
+		Status:          models.PaymentStatusPending,
+	}
+	if err := s.paymentRepo.CreateTransaction(charge); err != nil {
+		return nil, err
+	}
+
+	result, err := s.gateway.Authorize(GatewayAuthorization{
+		Reference: charge.ID,
+		Amount:    amount,
+		CardToken: card.Token,
+	})
+	if err != nil {
+		logger.Error("Payment gateway request failed", "transaction_id", charge.ID, "error", err)
+		s.failTransaction(charge, models.PaymentStatusFailed, "Payment gateway unavailable")
+		return nil, errors.NewHTTPError(http.StatusBadGateway, "Payment gateway unavailable, please try again", err)
+	}
+	if !result.Succeeded() {
+		s.failTransaction(charge, models.PaymentStatusFailed, result.Message)
+		return nil, errors.NewHTTPError(http.StatusPaymentRequired, result.Message, nil)
+	}
+
+	capture, err := s.gateway.Capture(result.ID, amount)
+	if err != nil || !capture.Succeeded() {
+		if _, voidErr := s.gateway.Void(result.ID); voidErr != nil {
+			logger.Error("Failed to release card hold", "transaction_id", charge.ID, "error", voidErr)
+		}
+		if err != nil {
+			s.failTransaction(charge, models.PaymentStatusFailed, "Payment gateway unavailable")
+			return nil, errors.NewHTTPError(http.StatusBadGateway, "Payment gateway unavailable, please try again", err)
+		}
+		s.failTransaction(charge, models.PaymentStatusFailed, capture.Message)
+		return nil, errors.NewHTTPError(http.StatusPaymentRequired, capture.Message, nil)
+	}
+
+	// Refunds of the charge go through the authorization, as they do for order captures
+	now := time.Now()
+	if err := s.paymentRepo.UpdateTransaction(charge.ID, map[string]interface{}{
+		"status":         models.PaymentStatusCompleted,
+		"transaction_id": result.ID,
+		"processed_at":   now,
+	}); err != nil {
+		return nil, err
+	}
+	charge.Status = models.PaymentStatusCompleted
+	charge.TransactionID = &result.ID
+	charge.ProcessedAt = &now
+	return charge, nil
+}
+
+// payFromWallet collects amount from the user's wallet at once. The ledger posting is the payment itself,
+// so unlike card movements a failure to post fails the payment.
+func (s *paymentService) payFromWallet(order *models.Order, userID string, amount models.Money) (*models.PaymentTransaction, error) {
+	payment := &models.PaymentTransaction{
+		ID:              utils.GeneratePaymentID(),
+		OrderID:         order.ID,
+		UserID:          userID,
+		PaymentMethodID: models.WalletPaymentMethodID,
+		Type:            models.PaymentTypeWallet,
+		Amount:          amount,
+		RefundedAmount:  models.Zero(order.Currency),
+		Currency:        order.Currency,
+		Status:          models.PaymentStatusPending,
+	}
+	if err := s.paymentRepo.CreateTransaction(payment); err != nil {
+		return nil, err
+	}
+
+	if err := s.ledgerService.SpendWallet(payment); err != nil {
+		reason := "Wallet payment could not be recorded"
+		if statusCode, _ := errors.GetStatusCode(err); statusCode == http.StatusPaymentRequired {
+			reason = "Wallet balance too low"
+		}
+		s.failTransaction(payment, models.PaymentStatusFailed, reason)
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.paymentRepo.UpdateTransaction(payment.ID, map[string]interface{}{
+		"status":       models.PaymentStatusCompleted,
+		"processed_at": now,
+	}); err != nil {
+		return nil, err
+	}
+	payment.Status = models.PaymentStatusCompleted
+	payment.ProcessedAt = &now
+	return payment, nil
+}
+
+// reverseCharge refunds a card charge in full when what it paid for could not be stored. Nothing was posted to
+// the ledger for it, so the charge is only marked refunded; if the gateway refund fails, the charge is left
+// completed for the ledger check to report.
+func (s *paymentService) reverseCharge(charge *models.PaymentTransaction, reason string) {
+	result, err := s.gateway.Refund(*charge.TransactionID, charge.Amount)
+	if err != nil || !result.Succeeded() {
+		logger.Error("Failed to refund charge for an unfinished purchase", "transaction_id", charge.ID, "error", err)
+		return
+	}
+	if err := s.paymentRepo.UpdateTransaction(charge.ID, map[string]interface{}{
+		"status":          models.PaymentStatusRefunded,
+		"refunded_amount": charge.Amount,
+		"failure_reason":  reason,
+	}); err != nil {
+		logger.Error("Failed to record refunded charge", "transaction_id", charge.ID, "gateway_refund_id", result.ID, "error", err)
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+)
+
+func TestWalletTopUpAndSpend(t *testing.T) {
+	s := newTestPayments(t)
+	seed(t, testUser("ana"), testUser("ben"), testOrder("o1", "ana", "r1"))
+	saveTestCard(t, s, "ana", FakeCardSuccess)
+	ana := models.OrderViewer{Email: "ana@example.com", Role: models.RoleCustomer}
+	balance := func() int64 {
+		t.Helper()
+		balance, err := s.ledgerService.GetWalletBalance("ana", models.CurrencyUSD)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return balance.Amount
+	}
+
+	// Ben cannot charge Ana's card by putting Ana's ID in the path
+	_, err := s.TopUpWallet(models.OrderViewer{Email: "ben@example.com", Role: models.RoleCustomer}, &models.TopUpWalletRequest{UserID: "ana", Amount: usd(1000)})
+	rejectedAs(t, err, http.StatusForbidden)
+
+	if _, err := s.TopUpWallet(ana, &models.TopUpWalletRequest{UserID: "ana", Amount: usd(1000)}); err != nil {
+		t.Fatalf("TopUpWallet error = %v", err)
+	}
+	if got := balance(); got != 1000 {
+		t.Fatalf("balance after topping up 10.00 = %d", got)
+	}
+
+	// Paying with the wallet alone needs the whole 24.60, and a shortfall takes nothing
+	_, err = s.ProcessPayment(&models.PaymentTransaction{OrderID: "o1", UserID: "ana", PaymentMethodID: models.WalletPaymentMethodID})
+	rejectedAs(t, err, http.StatusPaymentRequired)
+	if got := balance(); got != 1000 {
+		t.Errorf("balance after a refused wallet payment = %d, want 1000", got)
+	}
+
+	// Combined with the card, the wallet goes first and the card covers the rest
+	hold, err := s.ProcessPayment(&models.PaymentTransaction{OrderID: "o1", UserID: "ana", UseWallet: true})
+	if err != nil || hold.Amount.Amount != 1460 {
+		t.Fatalf("paying with wallet and card = %+v, %v; want 14.60 on the card", hold, err)
+	}
+	if got := balance(); got != 0 {
+		t.Errorf("balance after paying = %d, want 0", got)
+	}
+	if order, _ := s.orderRepo.GetByID("o1"); order.Status != models.OrderStatusConfirmed {
+		t.Errorf("order status = %s, want confirmed once fully paid", order.Status)
+	}
+}
+
+func TestWalletRefundOfADeliveredOrderNeedsSupport(t *testing.T) {
+	s := newTestPayments(t)
+	capture := paidCapture(t, s, "o1")
+	if err := database.DB.Model(&models.Order{}).Where("id = ?", "o1").Update("status", models.OrderStatusDelivered).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Money in the wallet can be spent at once, so a customer cannot send a delivered order's payment there
+	_, err := s.ProcessRefund(models.OrderViewer{Email: "ana@example.com", Role: models.RoleCustomer}, capture.ID, usd(500), true)
+	rejectedAs(t, err, http.StatusForbidden)
+
+	refund, err := s.ProcessRefund(models.OrderViewer{Email: "agent@example.com", Role: models.RoleSupport}, capture.ID, usd(500), true)
+	if err != nil || refund.PaymentMethodID != models.WalletPaymentMethodID {
+		t.Fatalf("support refund to the wallet = %+v, %v", refund, err)
+	}
+	if balance, _ := s.ledgerService.GetWalletBalance("ana", models.CurrencyUSD); balance.Amount != 500 {
+		t.Errorf("wallet balance = %s, want the 5.00 refunded", balance)
+	}
+}
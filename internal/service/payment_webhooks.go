@@ -0,0 +1,279 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+	"dfood/pkg/logger"
+)
+
+// defaultWebhookTolerance bounds the age of a signed webhook when config does not set one
+const defaultWebhookTolerance = 5 * time.Minute
+
+// HandleWebhook verifies a provider's webhook, records it once per event ID and applies it.
+// Events that fail to apply are answered with an error so the provider delivers them again.
+func (s *paymentService) HandleWebhook(provider string, payload []byte, signature string) (*models.PaymentWebhookEvent, error) {
+	secret := s.config.WebhookSecrets[provider]
+	if secret == "" {
+		return nil, errors.NewHTTPError(http.StatusNotFound, "Unknown payment provider", nil)
+	}
+
+	tolerance := time.Duration(s.config.WebhookToleranceSeconds) * time.Second
+	if tolerance <= 0 {
+		tolerance = defaultWebhookTolerance
+	}
+	if err := verifyWebhookSignature(secret, signature, payload, time.Now(), tolerance); err != nil {
+		return nil, errors.NewHTTPError(http.StatusUnauthorized, "Invalid webhook signature", err)
+	}
+
+	var body models.WebhookPayload
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid webhook payload", err)
+	}
+	if body.ID == "" || body.Type == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Webhook event ID and type are required", nil)
+	}
+
+	event := &models.PaymentWebhookEvent{
+		ID:       utils.GenerateWebhookEventID(),
+		Provider: provider,
+		EventID:  body.ID,
+		Type:     body.Type,
+		Payload:  string(payload),
+		Status:   models.WebhookEventReceived,
+	}
+	created, err := s.webhookRepo.CreateIfAbsent(event)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		if event, err = s.webhookRepo.GetByEventID(provider, body.ID); err != nil {
+			return nil, err
+		}
+		// Retried or replayed deliveries of a handled event are acknowledged without being applied again
+		if event.Status == models.WebhookEventProcessed || event.Status == models.WebhookEventIgnored {
+			return event, nil
+		}
+	}
+
+	status, dispatchErr := s.dispatchWebhook(&body)
+	now := time.Now()
+	updates := map[string]interface{}{"status": status, "processed_at": now, "error": nil}
+	if dispatchErr != nil {
+		status = models.WebhookEventFailed
+		message := dispatchErr.Error()
+		updates["status"] = status
+		updates["error"] = message
+		event.Error = &message
+		logger.Error("Failed to apply payment webhook", "provider", provider, "event_id", body.ID, "type", body.Type, "error", dispatchErr)
+	}
+	if err := s.webhookRepo.Update(event.ID, updates); err != nil {
+		return nil, err
+	}
+	event.Status = status
+	event.ProcessedAt = &now
+
+	if dispatchErr != nil {
+		return nil, errors.NewHTTPError(http.StatusInternalServerError, "Failed to process webhook event", dispatchErr)
+	}
+	return event, nil
+}
+
+// dispatchWebhook applies an event and reports whether it changed anything
+func (s *paymentService) dispatchWebhook(payload *models.WebhookPayload) (models.WebhookEventStatus, error) {
+	switch payload.Type {
+	case models.WebhookChargeSucceeded:
+		return s.onChargeSucceeded(&payload.Data)
+	case models.WebhookChargeFailed:
+		return s.onChargeFailed(&payload.Data)
+	case models.WebhookRefundSucceeded:
+		return s.onRefundSucceeded(&payload.Data)
+	case models.WebhookRefundFailed:
+		return s.onRefundFailed(&payload.Data)
+	case models.WebhookDisputeOpened:
+		return s.onDisputeOpened(&payload.Data)
+	default:
+		return models.WebhookEventIgnored, nil
+	}
+}
+
+func (s *paymentService) onChargeSucceeded(data *models.WebhookEventData) (models.WebhookEventStatus, error) {
+	charge, err := s.findWebhookTransaction(data.Reference, data.ID)
+	if charge == nil || err != nil {
+		return models.WebhookEventIgnored, err
+	}
+	if charge.Status != models.PaymentStatusPending && charge.Status != models.PaymentStatusRequiresAction {
+		return models.WebhookEventIgnored, nil
+	}
+	// Top-ups are captured and credited within the request that made them
+	if charge.Type == models.PaymentTypeTopUp {
+		return models.WebhookEventIgnored, nil
+	}
+
+	// Authorizations confirmed out of band still wait for the order to reach the capture status
+	status := models.PaymentStatusCompleted
+	if charge.Type == models.PaymentTypeAuthorization {
+		status = models.PaymentStatusAuthorized
+	}
+	err = s.paymentRepo.UpdateTransactionIfStatus(charge.ID, charge.Status, map[string]interface{}{
+		"status":         status,
+		"transaction_id": data.ID,
+		"failure_reason": nil,
+		"processed_at":   time.Now(),
+	})
+	if err != nil {
+		return models.WebhookEventFailed, err
+	}
+
+	order, err := s.orderRepo.GetByID(charge.OrderID)
+	if err != nil {
+		return models.WebhookEventFailed, err
+	}
+	s.confirmIfPaid(order)
+	if err := s.CaptureOrder(order.ID); err != nil {
+		logger.Error("Failed to capture order payments", "order_id", order.ID, "error", err)
+	}
+	return models.WebhookEventProcessed, nil
+}
+
+func (s *paymentService) onChargeFailed(data *models.WebhookEventData) (models.WebhookEventStatus, error) {
+	charge, err := s.findWebhookTransaction(data.Reference, data.ID)
+	if charge == nil || err != nil {
+		return models.WebhookEventIgnored, err
+	}
+	if charge.Status != models.PaymentStatusPending && charge.Status != models.PaymentStatusRequiresAction {
+		return models.WebhookEventIgnored, nil
+	}
+
+	err = s.paymentRepo.UpdateTransactionIfStatus(charge.ID, charge.Status, map[string]interface{}{
+		"status":         models.PaymentStatusFailed,
+		"failure_reason": data.FailureMessage,
+		"processed_at":   time.Now(),
+	})
+	if err != nil {
+		return models.WebhookEventFailed, err
+	}
+	return models.WebhookEventProcessed, nil
+}
+
+func (s *paymentService) onRefundSucceeded(data *models.WebhookEventData) (models.WebhookEventStatus, error) {
+	refund, err := s.findWebhookTransaction("", data.ID)
+	if refund == nil || err != nil || refund.Status != models.PaymentStatusPending {
+		return models.WebhookEventIgnored, err
+	}
+
+	err = s.paymentRepo.UpdateTransactionIfStatus(refund.ID, models.PaymentStatusPending, map[string]interface{}{
+		"status":       models.PaymentStatusCompleted,
+		"processed_at": time.Now(),
+	})
+	if err != nil {
+		return models.WebhookEventFailed, err
+	}
+	return models.WebhookEventProcessed, nil
+}
+
+// onRefundFailed reverses a refund the processor could not deliver: the charge becomes refundable
+// again and a cancelled order's refund is flagged for follow-up
+func (s *paymentService) onRefundFailed(data *models.WebhookEventData) (models.WebhookEventStatus, error) {
+	refund, err := s.findWebhookTransaction("", data.ID)
+	if refund == nil || err != nil {
+		return models.WebhookEventIgnored, err
+	}
+	if refund.Type != models.PaymentTypeRefund || refund.Status == models.PaymentStatusFailed {
+		return models.WebhookEventIgnored, nil
+	}
+
+	err = s.paymentRepo.UpdateTransactionIfStatus(refund.ID, refund.Status, map[string]interface{}{
+		"status":         models.PaymentStatusFailed,
+		"failure_reason": data.FailureMessage,
+		"processed_at":   time.Now(),
+	})
+	if err != nil {
+		return models.WebhookEventFailed, err
+	}
+	s.recordInLedger(refund, s.ledgerService.RecordRefundReversal)
+
+	if refund.ParentID != nil {
+		if err := s.paymentRepo.ReleaseRefund(*refund.ParentID, refund.Amount); err != nil {
+			return models.WebhookEventFailed, err
+		}
+	}
+
+	order, err := s.orderRepo.GetByID(refund.OrderID)
+	if err != nil {
+		return models.WebhookEventFailed, err
+	}
+	if order.RefundStatus != nil && *order.RefundStatus == models.RefundStatusRefunded {
+		err := s.orderRepo.Update(order.ID, map[string]interface{}{
+			"refund_status": models.RefundStatusFailed,
+			"refund_amount": order.RefundAmount.Sub(refund.Amount).Max(models.Zero(order.Currency)),
+		})
+		if err != nil {
+			return models.WebhookEventFailed, err
+		}
+	}
+	return models.WebhookEventProcessed, nil
+}
+
+func (s *paymentService) onDisputeOpened(data *models.WebhookEventData) (models.WebhookEventStatus, error) {
+	charge, err := s.findWebhookTransaction(data.Reference, data.ChargeID)
+	if charge == nil || err != nil {
+		return models.WebhookEventIgnored, err
+	}
+	// Disputes name the authorization, but the money in question sits on its capture
+	if charge.Type == models.PaymentTypeAuthorization {
+		if charge, err = s.completedCapture(charge); charge == nil || err != nil {
+			return models.WebhookEventIgnored, err
+		}
+	}
+	if charge.Status == models.PaymentStatusDisputed {
+		return models.WebhookEventIgnored, nil
+	}
+
+	now := time.Now()
+	if err := s.paymentRepo.UpdateTransaction(charge.ID, map[string]interface{}{"status": models.PaymentStatusDisputed}); err != nil {
+		return models.WebhookEventFailed, err
+	}
+	if err := s.orderRepo.Update(charge.OrderID, map[string]interface{}{"disputed_at": now}); err != nil {
+		return models.WebhookEventFailed, err
+	}
+	return models.WebhookEventProcessed, nil
+}
+
+// findWebhookTransaction looks a transaction up by our ID, falling back to the gateway's ID.
+// Transactions we have no record of, such as ones made outside the app, yield nil.
+func (s *paymentService) findWebhookTransaction(reference, gatewayID string) (*models.PaymentTransaction, error) {
+	var transaction *models.PaymentTransaction
+	var err error
+	switch {
+	case reference != "":
+		transaction, err = s.paymentRepo.GetTransactionByID(reference)
+	case gatewayID != "":
+		transaction, err = s.paymentRepo.GetTransactionByGatewayID(gatewayID)
+	default:
+		return nil, nil
+	}
+	if statusCode, _ := errors.GetStatusCode(err); statusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	return transaction, err
+}
+
+// completedCapture finds the capture that collected an authorization, or nil if none went through
+func (s *paymentService) completedCapture(authorization *models.PaymentTransaction) (*models.PaymentTransaction, error) {
+	transactions, err := s.paymentRepo.GetTransactionsByOrderID(authorization.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range transactions {
+		transaction := &transactions[i]
+		if transaction.Type == models.PaymentTypeCapture && transaction.IsCollected() && transaction.ParentID != nil && *transaction.ParentID == authorization.ID {
+			return transaction, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,438 @@
+package service
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+	"dfood/pkg/logger"
+)
+
+const (
+	defaultPayoutPageSize = 20
+	maxPayoutPageSize     = 100
+)
+
+type PayoutService interface {
+	SetCommissionRule(restaurantID string, request *models.SetCommissionRuleRequest) (*models.CommissionRule, error)
+	RunSettlement(request *models.SettlePayoutsRequest) ([]models.RestaurantPayout, error)
+	MarkPaid(payoutID string) (*models.RestaurantPayout, error)
+	GetRestaurantPayouts(viewer models.OrderViewer, restaurantID string, limit, offset int) ([]models.RestaurantPayout, error)
+	GetPayout(viewer models.OrderViewer, restaurantID, payoutID string) (*models.RestaurantPayout, error)
+	WriteStatementCSV(viewer models.OrderViewer, restaurantID, payoutID string, w io.Writer) error
+}
+
+type payoutService struct {
+	payoutRepo     repository.PayoutRepository
+	restaurantRepo repository.RestaurantRepository
+	orderRepo      repository.OrderRepository
+	userRepo       repository.UserRepository
+	ledgerRepo     repository.LedgerRepository
+	ledgerService  LedgerService
+}
+
+func NewPayoutService(payoutRepo repository.PayoutRepository, restaurantRepo repository.RestaurantRepository, orderRepo repository.OrderRepository, userRepo repository.UserRepository, ledgerRepo repository.LedgerRepository, ledgerService LedgerService) PayoutService {
+	return &payoutService{
+		payoutRepo:     payoutRepo,
+		restaurantRepo: restaurantRepo,
+		orderRepo:      orderRepo,
+		userRepo:       userRepo,
+		ledgerRepo:     ledgerRepo,
+		ledgerService:  ledgerService,
+	}
+}
+
+// orderTakings is what the ledger holds for one order: the restaurant's share of the payments collected,
+// our commission on it, and the payments themselves
+type orderTakings struct {
+	sales      models.Money
+	commission models.Money
+	collected  models.Money
+}
+
+// SetCommissionRule replaces the commission a restaurant pays from its next captured payment on
+func (s *payoutService) SetCommissionRule(restaurantID string, request *models.SetCommissionRuleRequest) (*models.CommissionRule, error) {
+	if strings.TrimSpace(restaurantID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Restaurant ID is required", nil)
+	}
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Commission rule is required", nil)
+	}
+	if request.BasisPoints < 0 || request.BasisPoints > 10000 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Commission must be between 0 and 10000 basis points", nil)
+	}
+	if request.FixedFee.IsNegative() {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Fixed fee cannot be negative", nil)
+	}
+	if _, err := s.restaurantRepo.GetByID(restaurantID); err != nil {
+		return nil, err
+	}
+
+	fixedFee := request.FixedFee
+	if fixedFee.Currency == "" {
+		fixedFee.Currency = models.DefaultCurrency
+	}
+	rule := &models.CommissionRule{
+		RestaurantID: restaurantID,
+		BasisPoints:  request.BasisPoints,
+		FixedFee:     fixedFee,
+		Currency:     fixedFee.Currency,
+		UpdatedBy:    request.AgentID,
+	}
+	if err := s.payoutRepo.SaveCommissionRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// RunSettlement issues payout statements for everything delivered, cancelled or refunded before the
+// period end that earlier runs have not settled. A restaurant whose refunds outweigh its takings gets
+// no payout; its items roll into the next run. Tips on the delivered orders go to their couriers in full.
+func (s *payoutService) RunSettlement(request *models.SettlePayoutsRequest) ([]models.RestaurantPayout, error) {
+	now := time.Now()
+	periodEnd := now
+	restaurantID := ""
+	if request != nil {
+		if request.PeriodEnd != nil {
+			periodEnd = *request.PeriodEnd
+		}
+		restaurantID = strings.TrimSpace(request.RestaurantID)
+	}
+	if periodEnd.After(now) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Period end cannot be in the future", nil)
+	}
+
+	restaurantIDs := []string{restaurantID}
+	if restaurantID == "" {
+		var err error
+		if restaurantIDs, err = s.payoutRepo.GetUnsettledRestaurantIDs(periodEnd); err != nil {
+			return nil, err
+		}
+	} else if _, err := s.restaurantRepo.GetByID(restaurantID); err != nil {
+		return nil, err
+	}
+
+	if err := s.settleTips(restaurantID, periodEnd); err != nil {
+		return nil, err
+	}
+
+	payouts := make([]models.RestaurantPayout, 0, len(restaurantIDs))
+	for _, id := range restaurantIDs {
+		settled, err := s.settleRestaurant(id, periodEnd)
+		if err != nil {
+			return payouts, err
+		}
+		payouts = append(payouts, settled...)
+	}
+	return payouts, nil
+}
+
+// settleTips moves the unsettled tips of orders delivered before the period end to their couriers'
+// ledger accounts. Tips are never part of a restaurant payout and carry no commission.
+func (s *payoutService) settleTips(restaurantID string, periodEnd time.Time) error {
+	orders, err := s.payoutRepo.GetOrdersWithUnsettledTips(restaurantID, periodEnd)
+	if err != nil {
+		return err
+	}
+	for i := range orders {
+		if err := s.ledgerService.SettleTips(&orders[i]); err != nil {
+			return err
+		}
+	}
+	if len(orders) > 0 {
+		logger.Info("Settled courier tips", "orders", len(orders), "period_end", periodEnd)
+	}
+	return nil
+}
+
+// settleRestaurant builds one payout per currency from the restaurant's unsettled orders and refunds
+func (s *payoutService) settleRestaurant(restaurantID string, periodEnd time.Time) ([]models.RestaurantPayout, error) {
+	orders, err := s.payoutRepo.GetUnsettledOrders(restaurantID, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := s.payoutRepo.GetUnsettledRefunds(restaurantID, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	byCurrency := make(map[models.Currency]*models.RestaurantPayout)
+	var currencies []models.Currency
+	payoutFor := func(currency models.Currency) *models.RestaurantPayout {
+		payout, ok := byCurrency[currency]
+		if !ok {
+			zero := models.Zero(currency)
+			payout = &models.RestaurantPayout{
+				ID:           utils.GeneratePayoutID(),
+				RestaurantID: restaurantID,
+				PeriodEnd:    periodEnd,
+				Currency:     currency,
+				Sales:        zero,
+				Commission:   zero,
+				Refunds:      zero,
+				Net:          zero,
+				Status:       models.PayoutStatusPending,
+			}
+			byCurrency[currency] = payout
+			currencies = append(currencies, currency)
+		}
+		return payout
+	}
+	addLine := func(payout *models.RestaurantPayout, line models.PayoutLine) {
+		line.ID = utils.GeneratePayoutLineID()
+		line.PayoutID = payout.ID
+		line.Currency = payout.Currency
+		line.Net = line.Sales.Sub(line.Commission).Sub(line.Refunds)
+		payout.Sales = payout.Sales.Add(line.Sales)
+		payout.Commission = payout.Commission.Add(line.Commission)
+		payout.Refunds = payout.Refunds.Add(line.Refunds)
+		payout.Net = payout.Net.Add(line.Net)
+		payout.Lines = append(payout.Lines, line)
+	}
+
+	for i := range orders {
+		order := &orders[i]
+		takings, err := s.orderTakings(order, restaurantID)
+		if err != nil {
+			return nil, err
+		}
+		payout := payoutFor(order.Currency)
+		addLine(payout, models.PayoutLine{
+			Kind:       models.PayoutLineOrder,
+			Reference:  order.ID,
+			OrderID:    order.ID,
+			Sales:      takings.sales,
+			Commission: takings.commission,
+			Refunds:    models.Zero(order.Currency),
+			OccurredAt: orderSettledAt(order),
+		})
+		payout.OrderCount++
+	}
+
+	// A refund costs the restaurant the part of it that was its share when the payment was collected,
+	// and we give back the commission taken on that part
+	refundsByID := make(map[string]*models.PaymentTransaction, len(refunds))
+	for i := range refunds {
+		refund := &refunds[i]
+		refundsByID[refund.ID] = refund
+		order, err := s.orderRepo.GetByID(refund.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		takings, err := s.orderTakings(order, restaurantID)
+		if err != nil {
+			return nil, err
+		}
+		share := models.Zero(refund.Currency)
+		commission := models.Zero(refund.Currency)
+		if takings.collected.IsPositive() {
+			share = takings.sales.MulRat(refund.Amount.Amount, takings.collected.Amount)
+			commission = takings.commission.MulRat(refund.Amount.Amount, takings.collected.Amount)
+		}
+		addLine(payoutFor(refund.Currency), models.PayoutLine{
+			Kind:       models.PayoutLineRefund,
+			Reference:  refund.ID,
+			OrderID:    refund.OrderID,
+			Sales:      models.Zero(refund.Currency),
+			Commission: commission.Neg(),
+			Refunds:    share,
+			OccurredAt: refund.CreatedAt,
+		})
+	}
+
+	payouts := make([]models.RestaurantPayout, 0, len(currencies))
+	for _, currency := range currencies {
+		payout := byCurrency[currency]
+		if !payout.Net.IsPositive() {
+			continue
+		}
+		if err := s.payoutRepo.Create(payout); err != nil {
+			return payouts, err
+		}
+		for _, line := range payout.Lines {
+			if line.Kind != models.PayoutLineRefund {
+				continue
+			}
+			err := s.ledgerService.RecordRefundShare(refundsByID[line.Reference], restaurantID, line.Refunds, line.Commission.Neg())
+			if err != nil {
+				logger.Error("Failed to post refund share to the ledger", "payout_id", payout.ID, "refund_id", line.Reference, "error", err)
+			}
+		}
+		payouts = append(payouts, *payout)
+	}
+	return payouts, nil
+}
+
+// orderTakings sums the restaurant's capture and commission entries for an order
+func (s *payoutService) orderTakings(order *models.Order, restaurantID string) (orderTakings, error) {
+	takings := orderTakings{
+		sales:      models.Zero(order.Currency),
+		commission: models.Zero(order.Currency),
+		collected:  models.Zero(order.Currency),
+	}
+	journals, err := s.ledgerRepo.GetJournalsByOrderID(order.ID)
+	if err != nil {
+		return takings, err
+	}
+	for _, journal := range journals {
+		if journal.Currency != order.Currency {
+			continue
+		}
+		for _, entry := range journal.Entries {
+			switch {
+			case journal.Type == models.LedgerJournalCapture && entry.Amount.IsPositive():
+				takings.collected = takings.collected.Add(entry.Amount)
+			case journal.Type == models.LedgerJournalCapture && entry.AccountType == models.LedgerAccountRestaurant && entry.OwnerID == restaurantID:
+				takings.sales = takings.sales.Sub(entry.Amount)
+			case journal.Type == models.LedgerJournalCommission && entry.AccountType == models.LedgerAccountRestaurant && entry.OwnerID == restaurantID:
+				takings.commission = takings.commission.Add(entry.Amount)
+			}
+		}
+	}
+	return takings, nil
+}
+
+// orderSettledAt is when an order finished, which decides the settlement run it falls into
+func orderSettledAt(order *models.Order) time.Time {
+	switch {
+	case order.DeliveredAt != nil:
+		return *order.DeliveredAt
+	case order.CancelledAt != nil:
+		return *order.CancelledAt
+	}
+	return order.UpdatedAt
+}
+
+// MarkPaid records that a payout's transfer has been made and clears it from what we owe the restaurant
+func (s *payoutService) MarkPaid(payoutID string) (*models.RestaurantPayout, error) {
+	if strings.TrimSpace(payoutID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Payout ID is required", nil)
+	}
+	payout, err := s.payoutRepo.GetByID(payoutID)
+	if err != nil {
+		return nil, err
+	}
+	if payout.Status != models.PayoutStatusPending {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Payout is already "+string(payout.Status), nil)
+	}
+
+	now := time.Now()
+	if err := s.payoutRepo.UpdateIfStatus(payout.ID, models.PayoutStatusPending, map[string]interface{}{
+		"status":  models.PayoutStatusPaid,
+		"paid_at": now,
+	}); err != nil {
+		return nil, err
+	}
+	payout.Status = models.PayoutStatusPaid
+	payout.PaidAt = &now
+	if err := s.ledgerService.RecordPayout(payout); err != nil {
+		logger.Error("Failed to post payout to the ledger", "payout_id", payout.ID, "error", err)
+	}
+	return payout, nil
+}
+
+func (s *payoutService) GetRestaurantPayouts(viewer models.OrderViewer, restaurantID string, limit, offset int) ([]models.RestaurantPayout, error) {
+	if err := s.authorizeViewer(viewer, restaurantID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultPayoutPageSize
+	}
+	limit = min(limit, maxPayoutPageSize)
+	offset = max(offset, 0)
+	return s.payoutRepo.GetByRestaurantID(restaurantID, limit, offset)
+}
+
+func (s *payoutService) GetPayout(viewer models.OrderViewer, restaurantID, payoutID string) (*models.RestaurantPayout, error) {
+	if err := s.authorizeViewer(viewer, restaurantID); err != nil {
+		return nil, err
+	}
+	payout, err := s.payoutRepo.GetByID(payoutID)
+	if err != nil {
+		return nil, err
+	}
+	if payout.RestaurantID != restaurantID {
+		return nil, errors.NewHTTPError(http.StatusNotFound, "Payout not found", nil)
+	}
+	return payout, nil
+}
+
+// WriteStatementCSV writes a payout statement to w: one row per order or refund followed by a total row
+func (s *payoutService) WriteStatementCSV(viewer models.OrderViewer, restaurantID, payoutID string, w io.Writer) error {
+	payout, err := s.GetPayout(viewer, restaurantID, payoutID)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"payout_id", "kind", "reference", "order_id", "occurred_at", "currency", "sales", "commission", "refunds", "net",
+	}); err != nil {
+		return err
+	}
+	for _, line := range payout.Lines {
+		if err := writer.Write([]string{
+			payout.ID,
+			string(line.Kind),
+			line.Reference,
+			line.OrderID,
+			line.OccurredAt.Format(time.RFC3339),
+			string(line.Currency),
+			line.Sales.Decimal(),
+			line.Commission.Decimal(),
+			line.Refunds.Decimal(),
+			line.Net.Decimal(),
+		}); err != nil {
+			return err
+		}
+	}
+	if err := writer.Write([]string{
+		payout.ID,
+		"total",
+		"",
+		"",
+		payout.PeriodEnd.Format(time.RFC3339),
+		string(payout.Currency),
+		payout.Sales.Decimal(),
+		payout.Commission.Decimal(),
+		payout.Refunds.Decimal(),
+		payout.Net.Decimal(),
+	}); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// authorizeViewer lets support and admin staff see any restaurant's payouts and other users only
+// the payouts of a restaurant they own
+func (s *payoutService) authorizeViewer(viewer models.OrderViewer, restaurantID string) error {
+	if strings.TrimSpace(restaurantID) == "" {
+		return errors.NewHTTPError(http.StatusBadRequest, "Restaurant ID is required", nil)
+	}
+	restaurant, err := s.restaurantRepo.GetByID(restaurantID)
+	if err != nil {
+		return err
+	}
+
+	switch viewer.Role {
+	case models.RoleSupport, models.RoleAdmin:
+		return nil
+	case models.RoleCustomer:
+		user, err := s.userRepo.GetByEmail(viewer.Email)
+		if err != nil {
+			return errors.NewHTTPError(http.StatusForbidden, "User not found for token", err)
+		}
+		if restaurant.OwnerID == nil || *restaurant.OwnerID != user.ID {
+			return errors.NewHTTPError(http.StatusForbidden, "Only the restaurant's owner can see its payouts", nil)
+		}
+		return nil
+	}
+	return errors.NewHTTPError(http.StatusForbidden, "Insufficient permissions", nil)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"dfood/internal/database"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+func TestRunSettlementNetsCommissionAndRefunds(t *testing.T) {
+	payments := newTestPayments(t)
+	seed(t, &models.Restaurant{ID: "r1", Name: "Corner Deli", DeliveryFee: usd(300)})
+	payouts := NewPayoutService(repository.NewPayoutRepository(), repository.NewRestaurantRepository(), payments.orderRepo, payments.userRepo, repository.NewLedgerRepository(), payments.ledgerService)
+
+	// 10% plus 0.50 an order replaces the 15% default
+	if _, err := payouts.SetCommissionRule("r1", &models.SetCommissionRuleRequest{BasisPoints: 1000, FixedFee: usd(50), AgentID: "agent"}); err != nil {
+		t.Fatalf("SetCommissionRule error = %v", err)
+	}
+	capture := paidCapture(t, payments, "o1")
+	deliveredAt := time.Now().Add(-time.Minute)
+	if err := database.DB.Model(&models.Order{}).Where("id = ?", "o1").Updates(map[string]interface{}{"status": models.OrderStatusDelivered, "delivered_at": deliveredAt}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Half the 24.60 is refunded, which takes half of the restaurant's 20.00 share back along with half the commission
+	if _, err := payments.ProcessRefund(models.OrderViewer{Role: models.RoleSupport}, capture.ID, usd(1230), false); err != nil {
+		t.Fatalf("ProcessRefund error = %v", err)
+	}
+
+	settled, err := payouts.RunSettlement(&models.SettlePayoutsRequest{RestaurantID: "r1"})
+	if err != nil || len(settled) != 1 {
+		t.Fatalf("RunSettlement = %+v, %v; want one payout", settled, err)
+	}
+	payout := settled[0]
+	if payout.Sales.Amount != 2000 || payout.Commission.Amount != 125 || payout.Refunds.Amount != 1000 || payout.Net.Amount != 875 {
+		t.Errorf("payout sales %s, commission %s, refunds %s, net %s; want 20.00, 1.25, 10.00 and 8.75",
+			payout.Sales, payout.Commission, payout.Refunds, payout.Net)
+	}
+	if len(payout.Lines) != 2 || payout.OrderCount != 1 {
+		t.Errorf("payout has %d lines for %d orders, want an order line and a refund line", len(payout.Lines), payout.OrderCount)
+	}
+
+	// Everything is settled now, so a second run pays nothing twice
+	if again, err := payouts.RunSettlement(&models.SettlePayoutsRequest{RestaurantID: "r1"}); err != nil || len(again) != 0 {
+		t.Errorf("second RunSettlement = %+v, %v; want no payouts", again, err)
+	}
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+)
+
+const (
+	defaultPromotionPageSize = 20
+	maxPromotionPageSize     = 100
+)
+
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+type PromotionService interface {
+	CreatePromotion(request *models.CreatePromotionRequest) (*models.Promotion, error)
+	UpdatePromotion(promotionID string, request *models.UpdatePromotionRequest) (*models.Promotion, error)
+	GetPromotions(activeOnly bool, limit, offset int) ([]models.Promotion, error)
+	GetPromotion(promotionID string) (*models.Promotion, error)
+	EvaluatePromotions(order *models.Order, lines []models.DiscountableLine) (*models.PromotionDiscount, error)
+	RedeemPromotions(order *models.Order) error
+	ReleasePromotions(orderID string) error
+}
+
+type promotionService struct {
+	promotionRepo  repository.PromotionRepository
+	orderRepo      repository.OrderRepository
+	restaurantRepo repository.RestaurantRepository
+}
+
+func NewPromotionService(promotionRepo repository.PromotionRepository, orderRepo repository.OrderRepository, restaurantRepo repository.RestaurantRepository) PromotionService {
+	return &promotionService{
+		promotionRepo:  promotionRepo,
+		orderRepo:      orderRepo,
+		restaurantRepo: restaurantRepo,
+	}
+}
+
+func (s *promotionService) CreatePromotion(request *models.CreatePromotionRequest) (*models.Promotion, error) {
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Promotion is required", nil)
+	}
+	code := strings.ToUpper(strings.TrimSpace(request.Code))
+	if !promoCodePattern.MatchString(code) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Promo code must be 3 to 32 letters, digits, dashes or underscores", nil)
+	}
+
+	currency := models.DefaultCurrency
+	for _, amount := range []models.Money{request.Amount, request.MaxDiscount, request.MinSubtotal} {
+		if amount.Currency != "" && amount.Currency != currency {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Promotion amounts must be in "+string(currency), nil)
+		}
+		if amount.IsNegative() {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Promotion amounts cannot be negative", nil)
+		}
+	}
+
+	promotion := &models.Promotion{
+		ID:             utils.GeneratePromotionID(),
+		Code:           code,
+		Description:    strings.TrimSpace(request.Description),
+		Type:           request.Type,
+		Amount:         models.NewMoney(request.Amount.Amount, currency),
+		MaxDiscount:    models.NewMoney(request.MaxDiscount.Amount, currency),
+		MinSubtotal:    models.NewMoney(request.MinSubtotal.Amount, currency),
+		Currency:       currency,
+		FirstOrderOnly: request.FirstOrderOnly,
+		Stackable:      request.Stackable,
+		MaxUses:        request.MaxUses,
+		MaxUsesPerUser: request.MaxUsesPerUser,
+		StartsAt:       request.StartsAt,
+		EndsAt:         request.EndsAt,
+		IsActive:       true,
+		CreatedBy:      request.AgentID,
+	}
+	switch request.Type {
+	case models.PromotionPercentage:
+		if request.BasisPoints <= 0 || request.BasisPoints > 10000 {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Percentage promotions need between 1 and 10000 basis points", nil)
+		}
+		promotion.BasisPoints = request.BasisPoints
+		promotion.Amount = models.Zero(currency)
+	case models.PromotionFixed:
+		if !request.Amount.IsPositive() {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Fixed promotions need a positive amount", nil)
+		}
+		promotion.MaxDiscount = models.Zero(currency)
+	case models.PromotionFreeDelivery:
+		promotion.Amount = models.Zero(currency)
+		promotion.MaxDiscount = models.Zero(currency)
+	default:
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid promotion type, expected percentage, fixed or free_delivery", nil)
+	}
+	if request.MaxUses < 0 || request.MaxUsesPerUser < 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Usage limits cannot be negative", nil)
+	}
+	if request.StartsAt != nil && request.EndsAt != nil && !request.EndsAt.After(*request.StartsAt) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Promotion must end after it starts", nil)
+	}
+
+	if request.RestaurantID != nil && strings.TrimSpace(*request.RestaurantID) != "" {
+		restaurantID := strings.TrimSpace(*request.RestaurantID)
+		if _, err := s.restaurantRepo.GetByID(restaurantID); err != nil {
+			return nil, err
+		}
+		promotion.RestaurantID = &restaurantID
+	}
+	if request.Category != nil && strings.TrimSpace(*request.Category) != "" {
+		category := strings.TrimSpace(*request.Category)
+		promotion.Category = &category
+	}
+
+	if err := s.promotionRepo.Create(promotion); err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+func (s *promotionService) UpdatePromotion(promotionID string, request *models.UpdatePromotionRequest) (*models.Promotion, error) {
+	if strings.TrimSpace(promotionID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Promotion ID is required", nil)
+	}
+	if request == nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Promotion changes are required", nil)
+	}
+	promotion, err := s.promotionRepo.GetByID(promotionID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if request.Description != nil {
+		updates["description"] = strings.TrimSpace(*request.Description)
+	}
+	if request.MaxUses != nil {
+		if *request.MaxUses < 0 {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Usage limits cannot be negative", nil)
+		}
+		updates["max_uses"] = *request.MaxUses
+	}
+	if request.MaxUsesPerUser != nil {
+		if *request.MaxUsesPerUser < 0 {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Usage limits cannot be negative", nil)
+		}
+		updates["max_uses_per_user"] = *request.MaxUsesPerUser
+	}
+	startsAt, endsAt := promotion.StartsAt, promotion.EndsAt
+	if request.StartsAt != nil {
+		startsAt = request.StartsAt
+		updates["starts_at"] = *request.StartsAt
+	}
+	if request.EndsAt != nil {
+		endsAt = request.EndsAt
+		updates["ends_at"] = *request.EndsAt
+	}
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Promotion must end after it starts", nil)
+	}
+	if request.IsActive != nil {
+		updates["is_active"] = *request.IsActive
+	}
+	if len(updates) == 0 {
+		return promotion, nil
+	}
+
+	if err := s.promotionRepo.Update(promotion.ID, updates); err != nil {
+		return nil, err
+	}
+	return s.promotionRepo.GetByID(promotion.ID)
+}
+
+func (s *promotionService) GetPromotions(activeOnly bool, limit, offset int) ([]models.Promotion, error) {
+	if limit <= 0 {
+		limit = defaultPromotionPageSize
+	}
+	limit = min(limit, maxPromotionPageSize)
+	offset = max(offset, 0)
+	return s.promotionRepo.List(activeOnly, limit, offset)
+}
+
+func (s *promotionService) GetPromotion(promotionID string) (*models.Promotion, error) {
+	if strings.TrimSpace(promotionID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Promotion ID is required", nil)
+	}
+	return s.promotionRepo.GetByID(promotionID)
+}
+
+// promotionOrder is the sequence in which stacked promotions apply: percentages first so that a fixed
+// amount is never scaled, and free delivery last as it touches only the delivery fee
+var promotionOrder = map[models.PromotionType]int{
+	models.PromotionPercentage:   0,
+	models.PromotionFixed:        1,
+	models.PromotionFreeDelivery: 2,
+}
+
+// EvaluatePromotions checks the order's promo codes against their rules and works out the discount, spread
+// over the given item lines so that tax can be charged on the discounted amounts. Nothing is recorded;
+// RedeemPromotions claims the uses once the order is placed.
+func (s *promotionService) EvaluatePromotions(order *models.Order, lines []models.DiscountableLine) (*models.PromotionDiscount, error) {
+	discount := &models.PromotionDiscount{
+		ItemDiscounts: make([]models.Money, len(lines)),
+		Delivery:      models.Zero(order.Currency),
+		Total:         models.Zero(order.Currency),
+	}
+	for i := range discount.ItemDiscounts {
+		discount.ItemDiscounts[i] = models.Zero(order.Currency)
+	}
+	if len(order.PromoCodes) == 0 {
+		return discount, nil
+	}
+
+	promotions := make([]*models.Promotion, 0, len(order.PromoCodes))
+	for _, code := range order.PromoCodes {
+		promotion, err := s.promotionRepo.GetByCode(code)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkEligible(promotion, order); err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+	if len(promotions) > 1 {
+		for _, promotion := range promotions {
+			if !promotion.Stackable {
+				return nil, errors.NewHTTPError(http.StatusBadRequest, "Promo code "+promotion.Code+" cannot be combined with other codes", nil)
+			}
+		}
+	}
+	sort.SliceStable(promotions, func(i, j int) bool {
+		return promotionOrder[promotions[i].Type] < promotionOrder[promotions[j].Type]
+	})
+
+	for _, promotion := range promotions {
+		amount, err := applyPromotion(promotion, order, lines, discount)
+		if err != nil {
+			return nil, err
+		}
+		discount.Lines = append(discount.Lines, models.DiscountLine{
+			PromotionID: promotion.ID,
+			Code:        promotion.Code,
+			Description: promotion.Description,
+			Type:        promotion.Type,
+			Amount:      amount,
+		})
+		discount.Total = discount.Total.Add(amount)
+	}
+	return discount, nil
+}
+
+// checkEligible applies a promotion's status, validity window, scope, basket and usage rules to the order
+func (s *promotionService) checkEligible(promotion *models.Promotion, order *models.Order) error {
+	now := time.Now()
+	switch {
+	case !promotion.IsActive:
+		return errors.NewHTTPError(http.StatusBadRequest, "Promo code "+promotion.Code+" is no longer available", nil)
+	case promotion.StartsAt != nil && now.Before(*promotion.StartsAt):
+		return errors.NewHTTPError(http.StatusBadRequest, "Promo code "+promotion.Code+" is not valid yet", nil)
+	case promotion.EndsAt != nil && !now.Before(*promotion.EndsAt):
+		return errors.NewHTTPError(http.StatusBadRequest, "Promo code "+promotion.Code+" has expired", nil)
+	case promotion.Currency != order.Currency:
+		return errors.NewHTTPError(http.StatusBadRequest, "Promo code "+promotion.Code+" is not valid for "+string(order.Currency)+" orders", nil)
+	case promotion.RestaurantID != nil && *promotion.RestaurantID != order.RestaurantID:
+		return errors.NewHTTPError(http.StatusBadRequest, "Promo code "+promotion.Code+" does not apply to this restaurant", nil)
+	case order.Subtotal.Cmp(promotion.MinSubtotal) < 0:
+		return errors.NewHTTPError(http.StatusBadRequest, "Promo code "+promotion.Code+" needs a subtotal of at least "+promotion.MinSubtotal.String(), nil)
+	case promotion.MaxUses > 0 && promotion.UsedCount >= promotion.MaxUses:
+		return errors.NewHTTPError(http.StatusBadRequest, "Promo code "+promotion.Code+" has reached its usage limit", nil)
+	}
+
+	if promotion.FirstOrderOnly {
+		placed, err := s.orderRepo.CountPlacedByUserID(order.UserID)
+		if err != nil {
+			return err
+		}
+		if placed > 0 {
+			return errors.NewHTTPError(http.StatusBadRequest, "Promo code "+promotion.Code+" is only valid on a first order", nil)
+		}
+	}
+	if promotion.MaxUsesPerUser > 0 {
+		used, err := s.promotionRepo.CountUserRedemptions(promotion.ID, order.UserID)
+		if err != nil {
+			return err
+		}
+		if used >= int64(promotion.MaxUsesPerUser) {
+			return errors.NewHTTPError(http.StatusBadRequest, "You have already used promo code "+promotion.Code, nil)
+		}
+	}
+	return nil
+}
+
+// applyPromotion adds one promotion's discount to the running totals and returns its amount. Item discounts
+// are taken from what earlier promotions left of the eligible lines, so stacked codes never go below zero.
+func applyPromotion(promotion *models.Promotion, order *models.Order, lines []models.DiscountableLine, discount *models.PromotionDiscount) (models.Money, error) {
+	if promotion.Type == models.PromotionFreeDelivery {
+		waived := order.DeliveryFee.Sub(discount.Delivery).Max(models.Zero(order.Currency))
+		discount.Delivery = discount.Delivery.Add(waived)
+		return waived, nil
+	}
+
+	remaining := make([]models.Money, len(lines))
+	eligible := models.Zero(order.Currency)
+	for i, line := range lines {
+		remaining[i] = models.Zero(order.Currency)
+		if promotion.Category != nil && !strings.EqualFold(*promotion.Category, line.Category) {
+			continue
+		}
+		remaining[i] = line.Amount.Sub(discount.ItemDiscounts[i]).Max(models.Zero(order.Currency))
+		eligible = eligible.Add(remaining[i])
+	}
+	if !eligible.IsPositive() {
+		return models.Money{}, errors.NewHTTPError(http.StatusBadRequest, "Promo code "+promotion.Code+" does not apply to any items in the basket", nil)
+	}
+
+	var amount models.Money
+	if promotion.Type == models.PromotionPercentage {
+		amount = eligible.Percent(promotion.BasisPoints)
+		if promotion.MaxDiscount.IsPositive() {
+			amount = amount.Min(promotion.MaxDiscount)
+		}
+	} else {
+		amount = promotion.Amount.Min(eligible)
+	}
+
+	for i, share := range allocateProportionally(amount, remaining) {
+		discount.ItemDiscounts[i] = discount.ItemDiscounts[i].Add(share)
+	}
+	return amount, nil
+}
+
+// allocateProportionally splits amount over the weights in proportion to their size; the last non-zero
+// weight absorbs the rounding so the shares add up to exactly amount
+func allocateProportionally(amount models.Money, weights []models.Money) []models.Money {
+	shares := make([]models.Money, len(weights))
+	total := models.Zero(amount.Currency)
+	last := -1
+	for i, weight := range weights {
+		shares[i] = models.Zero(amount.Currency)
+		if weight.IsPositive() {
+			total = total.Add(weight)
+			last = i
+		}
+	}
+	if last < 0 {
+		return shares
+	}
+
+	allocated := models.Zero(amount.Currency)
+	for i, weight := range weights {
+		if !weight.IsPositive() {
+			continue
+		}
+		if i == last {
+			shares[i] = amount.Sub(allocated)
+			break
+		}
+		shares[i] = amount.MulRat(weight.Amount, total.Amount)
+		allocated = allocated.Add(shares[i])
+	}
+	return shares
+}
+
+// RedeemPromotions records the uses of the promotions on a priced order. It runs before the order is stored,
+// in the same way a quote is claimed, so a code that has just run out fails the checkout instead of being
+// granted past its limit.
+func (s *promotionService) RedeemPromotions(order *models.Order) error {
+	redemptions := make([]models.PromotionRedemption, 0, len(order.DiscountLines))
+	for _, line := range order.DiscountLines {
+		// Tier perks and loyalty points are discount lines too, but no promo code stands behind them
+		if line.PromotionID == "" {
+			continue
+		}
+		redemptions = append(redemptions, models.PromotionRedemption{
+			ID:          utils.GeneratePromotionRedemptionID(),
+			PromotionID: line.PromotionID,
+			OrderID:     order.ID,
+			UserID:      order.UserID,
+			Code:        line.Code,
+			Discount:    line.Amount,
+			Currency:    order.Currency,
+		})
+	}
+	if len(redemptions) == 0 {
+		return nil
+	}
+	return s.promotionRepo.Redeem(redemptions)
+}
+
+// ReleasePromotions gives back the promotion uses of an order that failed to be stored or was cancelled
+func (s *promotionService) ReleasePromotions(orderID string) error {
+	return s.promotionRepo.ReleaseByOrderID(orderID)
+}
+
+// normalizePromoCodes upper-cases the given codes and drops blanks and repeats
+func normalizePromoCodes(codes []string) []string {
+	seen := make(map[string]bool, len(codes))
+	normalized := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		normalized = append(normalized, code)
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
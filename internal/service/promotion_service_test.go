@@ -0,0 +1,134 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+func newTestPromotions(t *testing.T, promotions ...*models.Promotion) *promotionService {
+	t.Helper()
+	openTestDB(t)
+	for _, promotion := range promotions {
+		promotion.ID, promotion.Currency, promotion.IsActive = "promo-"+promotion.Code, models.CurrencyUSD, true
+		seed(t, promotion)
+	}
+	return NewPromotionService(repository.NewPromotionRepository(), repository.NewOrderRepository(), repository.NewRestaurantRepository()).(*promotionService)
+}
+
+// redeemConcurrently redeems code once for each order, all at the same moment, and returns how many went through
+func redeemConcurrently(t *testing.T, s *promotionService, code string, orders ...*models.Order) int {
+	t.Helper()
+	var wg sync.WaitGroup
+	succeeded := make(chan *models.Order, len(orders))
+	for _, order := range orders {
+		order.DiscountLines = models.DiscountLinesArray{{PromotionID: "promo-" + code, Code: code, Type: models.PromotionFixed, Amount: usd(500)}}
+		wg.Add(1)
+		go func(order *models.Order) {
+			defer wg.Done()
+			err := s.RedeemPromotions(order)
+			if err == nil {
+				succeeded <- order
+				return
+			}
+			rejectedAs(t, err, http.StatusConflict)
+		}(order)
+	}
+	wg.Wait()
+	close(succeeded)
+	return len(succeeded)
+}
+
+func usedCount(t *testing.T, s *promotionService, code string) int {
+	t.Helper()
+	promotion, err := s.promotionRepo.GetByCode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return promotion.UsedCount
+}
+
+func TestRedeemPromotionsStopsAtTheUsageLimit(t *testing.T) {
+	s := newTestPromotions(t, &models.Promotion{Code: "LUNCH", Type: models.PromotionFixed, Amount: usd(500), MaxUses: 3})
+
+	orders := make([]*models.Order, 10)
+	for i := range orders {
+		orders[i] = testOrder(fmt.Sprintf("o%d", i), fmt.Sprintf("user%d", i), "r1")
+	}
+	if won := redeemConcurrently(t, s, "LUNCH", orders...); won != 3 || usedCount(t, s, "LUNCH") != 3 {
+		t.Fatalf("%d of 10 checkouts redeemed a 3-use code, used count %d", won, usedCount(t, s, "LUNCH"))
+	}
+
+	// Cancelling an order gives its use back to the next customer
+	for _, order := range orders {
+		if err := s.ReleasePromotions(order.ID); err != nil {
+			t.Fatalf("ReleasePromotions(%s) error = %v", order.ID, err)
+		}
+		if usedCount(t, s, "LUNCH") == 2 {
+			break
+		}
+	}
+	if won := redeemConcurrently(t, s, "LUNCH", testOrder("late", "late-user", "r1")); won != 1 {
+		t.Errorf("redeeming a released use failed")
+	}
+}
+
+func TestRedeemPromotionsOncePerUser(t *testing.T) {
+	s := newTestPromotions(t, &models.Promotion{Code: "WELCOME", Type: models.PromotionFixed, Amount: usd(500), MaxUsesPerUser: 1})
+
+	// Two tabs checking out at once both passed EvaluatePromotions; only one may keep the code
+	if won := redeemConcurrently(t, s, "WELCOME", testOrder("o1", "ana", "r1"), testOrder("o2", "ana", "r1")); won != 1 {
+		t.Fatalf("ana redeemed a once-per-user code %d times", won)
+	}
+
+	next := testOrder("o3", "ana", "r1")
+	next.PromoCodes = models.StringArray{"WELCOME"}
+	_, err := s.EvaluatePromotions(next, []models.DiscountableLine{{Amount: usd(2000)}})
+	rejectedAs(t, err, http.StatusBadRequest)
+
+	other := testOrder("o4", "ben", "r1")
+	other.PromoCodes = models.StringArray{"WELCOME"}
+	if _, err := s.EvaluatePromotions(other, []models.DiscountableLine{{Amount: usd(2000)}}); err != nil {
+		t.Errorf("ben's first use of WELCOME = %v", err)
+	}
+}
+
+func TestEvaluatePromotionsStacking(t *testing.T) {
+	drinks := "Drinks"
+	s := newTestPromotions(t,
+		&models.Promotion{Code: "FIVEOFF", Type: models.PromotionFixed, Amount: usd(500), Stackable: true},
+		&models.Promotion{Code: "THIRSTY", Type: models.PromotionPercentage, BasisPoints: 5000, Category: &drinks, Stackable: true},
+		&models.Promotion{Code: "SHIPFREE", Type: models.PromotionFreeDelivery, Stackable: true},
+		&models.Promotion{Code: "SOLO", Type: models.PromotionFixed, Amount: usd(300)},
+		&models.Promotion{Code: "BIGBASKET", Type: models.PromotionFixed, Amount: usd(300), MinSubtotal: usd(5000)},
+	)
+	lines := []models.DiscountableLine{{Category: "mains", Amount: usd(2000)}, {Category: "drinks", Amount: usd(600)}}
+	order := testOrder("o1", "ana", "r1")
+	order.Subtotal = usd(2600)
+
+	// Half off drinks comes off first, then 5.00 spread over what is left, then the delivery fee
+	order.PromoCodes = models.StringArray{"FIVEOFF", "SHIPFREE", "THIRSTY"}
+	discount, err := s.EvaluatePromotions(order, lines)
+	if err != nil {
+		t.Fatalf("EvaluatePromotions error = %v", err)
+	}
+	if discount.Total.Amount != 300+500+300 || discount.Delivery.Amount != 300 {
+		t.Errorf("discount = %s with %s off delivery, want 11.00 with 3.00 off delivery", discount.Total, discount.Delivery)
+	}
+	if items := discount.ItemDiscounts[0].Add(discount.ItemDiscounts[1]); items.Amount != 800 || discount.ItemDiscounts[1].Amount > 600 {
+		t.Errorf("item discounts = %v, want 8.00 in total and no more than the 6.00 of drinks", discount.ItemDiscounts)
+	}
+	if len(discount.Lines) != 3 || discount.Lines[0].Code != "THIRSTY" {
+		t.Errorf("discount lines = %+v, want percentage first", discount.Lines)
+	}
+
+	for _, codes := range [][]string{{"SOLO", "FIVEOFF"}, {"BIGBASKET"}} {
+		order.PromoCodes = codes
+		_, err := s.EvaluatePromotions(order, lines)
+		rejectedAs(t, err, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+	"dfood/internal/utils"
+	"dfood/pkg/errors"
+	"dfood/pkg/logger"
+)
+
+// defaultReferralCredit is paid to each side of a referral, in minor units of the default currency, when the config leaves it out
+const defaultReferralCredit = 1000
+
+type ReferralService interface {
+	ResolveReferrer(code string) (*models.User, error)
+	RecordReferral(referee, referrer *models.User) (*models.Referral, error)
+	RewardFirstOrder(order *models.Order) error
+	GetUserReferrals(userID string) (*models.UserReferrals, error)
+	GetReport(params *models.ReferralReportParams) (*models.ReferralReport, error)
+}
+
+type referralService struct {
+	referralRepo  repository.ReferralRepository
+	userRepo      repository.UserRepository
+	addressRepo   repository.AddressRepository
+	paymentRepo   repository.PaymentRepository
+	ledgerService LedgerService
+	config        config.ReferralConfig
+}
+
+func NewReferralService(referralRepo repository.ReferralRepository, userRepo repository.UserRepository, addressRepo repository.AddressRepository, paymentRepo repository.PaymentRepository, ledgerService LedgerService, cfg config.ReferralConfig) ReferralService {
+	return &referralService{
+		referralRepo:  referralRepo,
+		userRepo:      userRepo,
+		addressRepo:   addressRepo,
+		paymentRepo:   paymentRepo,
+		ledgerService: ledgerService,
+		config:        cfg,
+	}
+}
+
+// ResolveReferrer finds the user a referral code belongs to
+func (s *referralService) ResolveReferrer(code string) (*models.User, error) {
+	code = normalizeReferralCode(code)
+	if code == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Referral code is required", nil)
+	}
+	referrer, err := s.userRepo.GetByReferralCode(code)
+	if err != nil {
+		if status, ok := errors.GetStatusCode(err); ok && status == http.StatusNotFound {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Invalid referral code", nil)
+		}
+		return nil, err
+	}
+	return referrer, nil
+}
+
+// RecordReferral links a newly registered user to their referrer. Accounts that share a device or phone
+// number with an existing account are recorded as rejected straight away, so they are never rewarded.
+func (s *referralService) RecordReferral(referee, referrer *models.User) (*models.Referral, error) {
+	referral := &models.Referral{
+		ID:             utils.GenerateReferralID(),
+		ReferrerID:     referrer.ID,
+		RefereeID:      referee.ID,
+		Code:           *referrer.ReferralCode,
+		Status:         models.ReferralStatusPending,
+		ReferrerCredit: models.Zero(models.DefaultCurrency),
+		RefereeCredit:  models.Zero(models.DefaultCurrency),
+		Currency:       models.DefaultCurrency,
+	}
+
+	reason, err := s.checkRegistration(referee)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		referral.Status = models.ReferralStatusRejected
+		referral.RejectionReason = &reason
+		logger.Info("Referral rejected at registration", "referral_id", referral.ID, "referrer_id", referrer.ID, "referee_id", referee.ID, "reason", reason)
+	}
+
+	if err := s.referralRepo.Create(referral); err != nil {
+		return nil, err
+	}
+	return referral, nil
+}
+
+// checkRegistration returns the fraud check the new account fails, if any
+func (s *referralService) checkRegistration(referee *models.User) (string, error) {
+	if referee.DeviceID != nil {
+		count, err := s.userRepo.CountOthersByDeviceID(*referee.DeviceID, referee.ID)
+		if err != nil {
+			return "", err
+		}
+		if count > 0 {
+			return models.ReferralFraudSameDevice, nil
+		}
+	}
+	if referee.PhoneNumberIndex != "" {
+		count, err := s.userRepo.CountOthersByPhoneNumber(referee.PhoneNumberIndex, referee.ID)
+		if err != nil {
+			return "", err
+		}
+		if count > 0 {
+			return models.ReferralFraudSamePhone, nil
+		}
+	}
+	return "", nil
+}
+
+// RewardFirstOrder credits both users when a referred user's first paid order is delivered, unless the order
+// shows the two accounts belong to the same person or the referrer has reached their reward cap
+func (s *referralService) RewardFirstOrder(order *models.Order) error {
+	referral, err := s.referralRepo.GetByRefereeID(order.UserID)
+	if err != nil {
+		return err
+	}
+	if referral == nil || referral.Status != models.ReferralStatusPending {
+		return nil
+	}
+
+	reason, err := s.checkFirstOrder(referral, order)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		logger.Info("Referral rejected on first order", "referral_id", referral.ID, "order_id", order.ID, "reason", reason)
+		return s.referralRepo.UpdateIfStatus(referral.ID, models.ReferralStatusPending, map[string]interface{}{
+			"status":           models.ReferralStatusRejected,
+			"rejection_reason": reason,
+			"order_id":         order.ID,
+		})
+	}
+
+	if maxRewards := s.config.MaxRewardsPerReferrer; maxRewards > 0 {
+		rewarded, err := s.referralRepo.CountByReferrerID(referral.ReferrerID, models.ReferralStatusRewarded)
+		if err != nil {
+			return err
+		}
+		if rewarded >= int64(maxRewards) {
+			return s.referralRepo.UpdateIfStatus(referral.ID, models.ReferralStatusPending, map[string]interface{}{
+				"status":   models.ReferralStatusCapped,
+				"order_id": order.ID,
+			})
+		}
+	}
+
+	// The ledger posts each credit once, so crediting before the status change cannot pay twice
+	// and a failed status change leaves nothing unpaid
+	referral.OrderID = &order.ID
+	referral.ReferrerCredit = referralCredit(s.config.ReferrerCredit)
+	referral.RefereeCredit = referralCredit(s.config.RefereeCredit)
+	if err := s.ledgerService.CreditReferral(referral); err != nil {
+		return err
+	}
+	return s.referralRepo.UpdateIfStatus(referral.ID, models.ReferralStatusPending, map[string]interface{}{
+		"status":          models.ReferralStatusRewarded,
+		"order_id":        order.ID,
+		"referrer_credit": referral.ReferrerCredit,
+		"referee_credit":  referral.RefereeCredit,
+		"rewarded_at":     time.Now(),
+	})
+}
+
+// checkFirstOrder returns the fraud check the referred user's first order fails, if any
+func (s *referralService) checkFirstOrder(referral *models.Referral, order *models.Order) (string, error) {
+	addresses, err := s.addressRepo.GetByUserID(referral.ReferrerID)
+	if err != nil {
+		return "", err
+	}
+	destination := normalizeAddress(order.DeliveryAddress)
+	for _, address := range addresses {
+		formatted := fmt.Sprintf("%s, %s, %s, %s %s", address.Address, address.Street, address.City, address.State, address.ZipCode)
+		if normalizeAddress(formatted) == destination {
+			return models.ReferralFraudSameAddress, nil
+		}
+	}
+
+	referrerCards, err := s.paymentRepo.GetUserCards(referral.ReferrerID)
+	if err != nil {
+		return "", err
+	}
+	if len(referrerCards) == 0 {
+		return "", nil
+	}
+	refereeCards, err := s.paymentRepo.GetUserCards(referral.RefereeID)
+	if err != nil {
+		return "", err
+	}
+	fingerprints := make(map[string]bool, len(referrerCards))
+	for _, card := range referrerCards {
+		fingerprints[card.Fingerprint] = true
+	}
+	for _, card := range refereeCards {
+		if fingerprints[card.Fingerprint] {
+			return models.ReferralFraudSameCard, nil
+		}
+	}
+	return "", nil
+}
+
+// GetUserReferrals returns the user's referral code, handing one out to accounts made before the program, and who used it
+func (s *referralService) GetUserReferrals(userID string) (*models.UserReferrals, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ReferralCode == nil || *user.ReferralCode == "" {
+		code := utils.GenerateInviteCode()
+		if err := s.userRepo.UpdateField(userID, "referral_code", code); err != nil {
+			return nil, err
+		}
+		user.ReferralCode = &code
+	}
+
+	referrals, err := s.referralRepo.GetByReferrerID(userID)
+	if err != nil {
+		return nil, err
+	}
+	var rewarded int64
+	for _, referral := range referrals {
+		if referral.Status == models.ReferralStatusRewarded {
+			rewarded++
+		}
+	}
+	return &models.UserReferrals{
+		UserID:     userID,
+		Code:       *user.ReferralCode,
+		Rewarded:   rewarded,
+		MaxRewards: s.config.MaxRewardsPerReferrer,
+		Referrals:  referrals,
+	}, nil
+}
+
+// GetReport sums up the referrals registered in the period, per referrer and overall
+func (s *referralService) GetReport(params *models.ReferralReportParams) (*models.ReferralReport, error) {
+	if params == nil {
+		params = &models.ReferralReportParams{}
+	}
+	if params.From != nil && params.To != nil && !params.From.Before(*params.To) {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Report start must be before its end", nil)
+	}
+	rows, err := s.referralRepo.GetReportRows(params.From, params.To)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ReferralReport{
+		From:        params.From,
+		To:          params.To,
+		Rejections:  map[string]int64{},
+		CreditsPaid: models.Zero(models.DefaultCurrency),
+		Referrers:   []models.ReferrerPerformance{},
+	}
+	byReferrer := map[string]*models.ReferrerPerformance{}
+	for _, row := range rows {
+		performance := byReferrer[row.ReferrerID]
+		if performance == nil {
+			performance = &models.ReferrerPerformance{ReferrerID: row.ReferrerID, CreditsPaid: models.Zero(models.DefaultCurrency)}
+			byReferrer[row.ReferrerID] = performance
+		}
+		performance.Referred += row.Referrals
+		switch row.Status {
+		case models.ReferralStatusPending:
+			performance.Pending += row.Referrals
+		case models.ReferralStatusRewarded:
+			performance.Rewarded += row.Referrals
+		case models.ReferralStatusRejected:
+			performance.Rejected += row.Referrals
+			report.Rejections[row.RejectionReason] += row.Referrals
+		case models.ReferralStatusCapped:
+			performance.Capped += row.Referrals
+		}
+		credits := models.NewMoney(row.CreditsPaid, row.Currency)
+		performance.CreditsPaid = performance.CreditsPaid.Add(credits)
+		report.CreditsPaid = report.CreditsPaid.Add(credits)
+	}
+
+	for _, performance := range byReferrer {
+		report.Referred += performance.Referred
+		report.Rewarded += performance.Rewarded
+		report.Referrers = append(report.Referrers, *performance)
+	}
+	sort.Slice(report.Referrers, func(i, j int) bool {
+		a, b := report.Referrers[i], report.Referrers[j]
+		if a.Rewarded != b.Rewarded {
+			return a.Rewarded > b.Rewarded
+		}
+		if a.Referred != b.Referred {
+			return a.Referred > b.Referred
+		}
+		return a.ReferrerID < b.ReferrerID
+	})
+	if report.Referred > 0 {
+		report.ConversionRate = float64(report.Rewarded) / float64(report.Referred)
+	}
+	return report, nil
+}
+
+// referralCredit is a configured referral credit, or the default when the config leaves it out. A credit set
+// to 0 turns that side of the reward off; negative credits are treated the same way.
+func referralCredit(amount *int64) models.Money {
+	if amount == nil {
+		return models.NewMoney(defaultReferralCredit, models.DefaultCurrency)
+	}
+	return models.NewMoney(max(*amount, 0), models.DefaultCurrency)
+}
+
+// normalizeReferralCode accepts codes typed in any case and with surrounding spaces
+func normalizeReferralCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// normalizeAddress compares addresses regardless of case and spacing
+func normalizeAddress(address string) string {
+	return strings.Join(strings.Fields(strings.ToLower(address)), " ")
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"testing"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+func TestReferralCredit(t *testing.T) {
+	zero, negative, custom := int64(0), int64(-500), int64(250)
+
+	if got := referralCredit(nil); got.Amount != defaultReferralCredit {
+		t.Errorf("unset credit = %d, want the default %d", got.Amount, defaultReferralCredit)
+	}
+	if got := referralCredit(&zero); !got.IsZero() {
+		t.Errorf("credit set to 0 = %d, want 0", got.Amount)
+	}
+	if got := referralCredit(&negative); !got.IsZero() {
+		t.Errorf("negative credit = %d, want 0", got.Amount)
+	}
+	if got := referralCredit(&custom); got.Amount != custom {
+		t.Errorf("credit set to %d = %d", custom, got.Amount)
+	}
+}
+
+// referralFixture has Ana, whose code is ANA-1, referring Ben, who has just placed a first order
+type referralFixture struct {
+	payments  *paymentService
+	referrals *referralService
+	order     *models.Order
+}
+
+func newReferralFixture(t *testing.T, cfg config.ReferralConfig) *referralFixture {
+	t.Helper()
+	payments := newTestPayments(t)
+	code := "ANA-1"
+	ana, ben := testUser("ana"), testUser("ben")
+	ana.ReferralCode = &code
+	seed(t, ana, ben)
+
+	referrals := NewReferralService(repository.NewReferralRepository(), payments.userRepo, repository.NewAddressRepository(), payments.paymentRepo, payments.ledgerService, cfg).(*referralService)
+	if referral, err := referrals.RecordReferral(ben, ana); err != nil || referral.Status != models.ReferralStatusPending {
+		t.Fatalf("RecordReferral = %+v, %v; want a pending referral", referral, err)
+	}
+
+	order := testOrder("o1", "ben", "r1")
+	order.DeliveryAddress = "Flat 2,  9 Elm St, Springfield, IL 62701"
+	order.Status = models.OrderStatusDelivered
+	return &referralFixture{payments: payments, referrals: referrals, order: order}
+}
+
+// outcome rewards Ben's first order and reports the referral's status and reason along with both wallet balances
+func (f *referralFixture) outcome(t *testing.T) (models.ReferralStatus, string, int64, int64) {
+	t.Helper()
+	if err := f.referrals.RewardFirstOrder(f.order); err != nil {
+		t.Fatalf("RewardFirstOrder error = %v", err)
+	}
+	referral, err := f.referrals.referralRepo.GetByRefereeID("ben")
+	if err != nil || referral == nil {
+		t.Fatalf("GetByRefereeID = %v, %v", referral, err)
+	}
+	reason := ""
+	if referral.RejectionReason != nil {
+		reason = *referral.RejectionReason
+	}
+	ana, _ := f.payments.ledgerService.GetWalletBalance("ana", models.CurrencyUSD)
+	ben, _ := f.payments.ledgerService.GetWalletBalance("ben", models.CurrencyUSD)
+	return referral.Status, reason, ana.Amount, ben.Amount
+}
+
+func TestRewardFirstOrderCreditsBothUsersOnce(t *testing.T) {
+	f := newReferralFixture(t, config.ReferralConfig{})
+	f.outcome(t)
+	// Delivery can be reported again; the credits are not paid twice
+	status, _, ana, ben := f.outcome(t)
+	if status != models.ReferralStatusRewarded || ana != defaultReferralCredit || ben != defaultReferralCredit {
+		t.Errorf("referral %s with wallets %d and %d, want rewarded with %d each", status, ana, ben, defaultReferralCredit)
+	}
+}
+
+func TestRewardFirstOrderRejectsTheReferrersOwnAddress(t *testing.T) {
+	f := newReferralFixture(t, config.ReferralConfig{})
+	seed(t, &models.Address{ID: "home", UserID: "ana", Address: "Flat 2", Street: "9 Elm St", City: "Springfield", State: "IL", ZipCode: "62701"})
+
+	status, reason, ana, ben := f.outcome(t)
+	if status != models.ReferralStatusRejected || reason != models.ReferralFraudSameAddress || ana != 0 || ben != 0 {
+		t.Errorf("referral %s (%s) with wallets %d and %d, want rejected for the same address and nothing paid", status, reason, ana, ben)
+	}
+}
+
+func TestRewardFirstOrderRejectsASharedCard(t *testing.T) {
+	f := newReferralFixture(t, config.ReferralConfig{})
+	saveTestCard(t, f.payments, "ana", FakeCardSuccess)
+	saveTestCard(t, f.payments, "ben", FakeCardSuccess)
+
+	status, reason, ana, ben := f.outcome(t)
+	if status != models.ReferralStatusRejected || reason != models.ReferralFraudSameCard || ana != 0 || ben != 0 {
+		t.Errorf("referral %s (%s) with wallets %d and %d, want rejected for the same card and nothing paid", status, reason, ana, ben)
+	}
+}
+
+func TestRewardFirstOrderStopsAtTheReferrersCap(t *testing.T) {
+	f := newReferralFixture(t, config.ReferralConfig{MaxRewardsPerReferrer: 1})
+	seed(t, &models.Referral{ID: "earlier", ReferrerID: "ana", RefereeID: "cat", Code: "ANA-1", Status: models.ReferralStatusRewarded, Currency: models.CurrencyUSD})
+
+	status, _, ana, ben := f.outcome(t)
+	if status != models.ReferralStatusCapped || ana != 0 || ben != 0 {
+		t.Errorf("referral %s with wallets %d and %d, want capped and nothing paid", status, ana, ben)
+	}
+}
+
+func TestRecordReferralRejectsASharedDevice(t *testing.T) {
+	f := newReferralFixture(t, config.ReferralConfig{})
+	device := "device-1"
+	cat := testUser("cat")
+	cat.DeviceID = &device
+	dan := testUser("dan")
+	dan.DeviceID = &device
+	seed(t, cat, dan)
+
+	ana, _ := f.payments.userRepo.GetByID("ana")
+	referral, err := f.referrals.RecordReferral(dan, ana)
+	if err != nil || referral.Status != models.ReferralStatusRejected || referral.RejectionReason == nil || *referral.RejectionReason != models.ReferralFraudSameDevice {
+		t.Errorf("RecordReferral for a second account on cat's device = %+v, %v; want rejected for the same device", referral, err)
+	}
+}
@@ -1,8 +1,10 @@
 package service
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"dfood/internal/models"
 	"dfood/internal/repository"
@@ -17,17 +19,25 @@ type RestaurantService interface {
 	SearchRestaurants(query string, limit, offset int) ([]models.Restaurant, error)
 	GetRestaurantsByCategory(category string, limit, offset int) ([]models.Restaurant, error)
 	GetRestaurantMenu(restaurantID string, limit, offset int) ([]models.Food, error)
+	GetOpeningSchedule(restaurantID string) (*models.OpeningSchedule, error)
+	SetOpeningHours(viewer models.OrderViewer, restaurantID string, request *models.SetOpeningHoursRequest) (*models.OpeningSchedule, error)
+	SetHolidayHours(viewer models.OrderViewer, restaurantID string, request *models.SetHolidayHoursRequest) (*models.OpeningSchedule, error)
+	DeleteHolidayHours(viewer models.OrderViewer, restaurantID, date string) (*models.OpeningSchedule, error)
+	PauseRestaurant(viewer models.OrderViewer, restaurantID string, request *models.PauseRestaurantRequest) (*models.Restaurant, error)
+	ResumeRestaurant(viewer models.OrderViewer, restaurantID string) (*models.Restaurant, error)
 }
 
 type restaurantService struct {
 	restaurantRepo repository.RestaurantRepository
 	foodRepo       repository.FoodRepository
+	userRepo       repository.UserRepository
 }
 
-func NewRestaurantService(restaurantRepo repository.RestaurantRepository, foodRepo repository.FoodRepository) RestaurantService {
+func NewRestaurantService(restaurantRepo repository.RestaurantRepository, foodRepo repository.FoodRepository, userRepo repository.UserRepository) RestaurantService {
 	return &restaurantService{
 		restaurantRepo: restaurantRepo,
 		foodRepo:       foodRepo,
+		userRepo:       userRepo,
 	}
 }
 
@@ -42,7 +52,7 @@ func (s *restaurantService) GetAllRestaurants(limit, offset int) ([]models.Resta
 		offset = 0
 	}
 
-	return s.restaurantRepo.GetAll(limit, offset)
+	return s.withOpenStatus(s.restaurantRepo.GetAll(limit, offset))
 }
 
 func (s *restaurantService) GetRestaurantByID(id string) (*models.Restaurant, error) {
@@ -50,7 +60,14 @@ func (s *restaurantService) GetRestaurantByID(id string) (*models.Restaurant, er
 		return nil, errors.NewHTTPError(http.StatusBadRequest, "Restaurant ID is required", nil)
 	}
 
-	return s.restaurantRepo.GetByID(id)
+	restaurant, err := s.restaurantRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := setOpenStatus(s.restaurantRepo, restaurant); err != nil {
+		return nil, err
+	}
+	return restaurant, nil
 }
 
 func (s *restaurantService) GetPopularRestaurants(limit int) ([]models.Restaurant, error) {
@@ -61,7 +78,7 @@ func (s *restaurantService) GetPopularRestaurants(limit int) ([]models.Restauran
 		limit = 50 // Max limit
 	}
 
-	return s.restaurantRepo.GetPopular(limit)
+	return s.withOpenStatus(s.restaurantRepo.GetPopular(limit))
 }
 
 func (s *restaurantService) GetNearbyRestaurants(latitude, longitude float64, radius float64, limit int) ([]models.Restaurant, error) {
@@ -84,7 +101,7 @@ func (s *restaurantService) GetNearbyRestaurants(latitude, longitude float64, ra
 		limit = 50 // Max limit
 	}
 
-	return s.restaurantRepo.GetNearby(latitude, longitude, radius, limit)
+	return s.withOpenStatus(s.restaurantRepo.GetNearby(latitude, longitude, radius, limit))
 }
 
 func (s *restaurantService) SearchRestaurants(query string, limit, offset int) ([]models.Restaurant, error) {
@@ -101,7 +118,7 @@ func (s *restaurantService) SearchRestaurants(query string, limit, offset int) (
 		offset = 0
 	}
 
-	return s.restaurantRepo.Search(query, limit, offset)
+	return s.withOpenStatus(s.restaurantRepo.Search(query, limit, offset))
 }
 
 func (s *restaurantService) GetRestaurantsByCategory(category string, limit, offset int) ([]models.Restaurant, error) {
@@ -118,7 +135,7 @@ func (s *restaurantService) GetRestaurantsByCategory(category string, limit, off
 		offset = 0
 	}
 
-	return s.restaurantRepo.GetByCategory(category, limit, offset)
+	return s.withOpenStatus(s.restaurantRepo.GetByCategory(category, limit, offset))
 }
 
 func (s *restaurantService) GetRestaurantMenu(restaurantID string, limit, offset int) ([]models.Food, error) {
@@ -144,3 +161,266 @@ func (s *restaurantService) GetRestaurantMenu(restaurantID string, limit, offset
 
 	return s.foodRepo.GetByRestaurant(restaurantID, limit, offset)
 }
+
+// withOpenStatus fills in the computed open status of restaurants just read from the repository
+func (s *restaurantService) withOpenStatus(restaurants []models.Restaurant, err error) ([]models.Restaurant, error) {
+	if err != nil {
+		return nil, err
+	}
+	if err := setOpenStatusAll(s.restaurantRepo, restaurants); err != nil {
+		return nil, err
+	}
+	return restaurants, nil
+}
+
+func (s *restaurantService) GetOpeningSchedule(restaurantID string) (*models.OpeningSchedule, error) {
+	restaurant, err := s.GetRestaurantByID(restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	weekly, err := s.restaurantRepo.GetOpeningHours(restaurant.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Holidays are listed from yesterday so an override still running past midnight shows up
+	today := time.Now().In(restaurantLocation(restaurant))
+	holidays, err := s.restaurantRepo.GetHolidayHours(today.AddDate(0, 0, -1).Format(holidayDateLayout), "9999-12-31", restaurant.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeZone := restaurant.TimeZone
+	if timeZone == "" {
+		timeZone = time.Local.String()
+	}
+	schedule := &models.OpeningSchedule{
+		RestaurantID: restaurant.ID,
+		TimeZone:     timeZone,
+		IsOpen:       restaurant.IsOpen,
+		OpensAt:      restaurant.OpensAt,
+		ClosesAt:     restaurant.ClosesAt,
+		Weekly:       weekly,
+		Holidays:     holidays,
+	}
+	if restaurant.PausedUntil != nil && restaurant.PausedUntil.After(time.Now()) {
+		schedule.PausedUntil = restaurant.PausedUntil
+		schedule.PauseReason = restaurant.PauseReason
+	}
+	return schedule, nil
+}
+
+// SetOpeningHours replaces the restaurant's weekly hours and optionally its time zone
+func (s *restaurantService) SetOpeningHours(viewer models.OrderViewer, restaurantID string, request *models.SetOpeningHoursRequest) (*models.OpeningSchedule, error) {
+	restaurant, err := s.authorizeManager(viewer, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeZone := restaurant.TimeZone
+	if request.TimeZone != nil {
+		timeZone = strings.TrimSpace(*request.TimeZone)
+		if _, err := time.LoadLocation(timeZone); err != nil || timeZone == "" {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Unknown time zone: "+timeZone, err)
+		}
+	}
+
+	hours := make([]models.OpeningHours, 0, len(request.Hours))
+	for _, interval := range request.Hours {
+		if interval.DayOfWeek < 0 || interval.DayOfWeek > 6 {
+			return nil, errors.NewHTTPError(http.StatusBadRequest, "Day of week must be between 0 (Sunday) and 6 (Saturday)", nil)
+		}
+		if err := validateOpeningInterval(interval); err != nil {
+			return nil, err
+		}
+		hours = append(hours, models.OpeningHours{
+			RestaurantID: restaurant.ID,
+			DayOfWeek:    interval.DayOfWeek,
+			OpensAt:      interval.OpensAt,
+			ClosesAt:     interval.ClosesAt,
+		})
+	}
+	if err := validateNoOverlap(hours); err != nil {
+		return nil, err
+	}
+
+	if err := s.restaurantRepo.ReplaceOpeningHours(restaurant.ID, timeZone, hours); err != nil {
+		return nil, err
+	}
+	return s.GetOpeningSchedule(restaurant.ID)
+}
+
+// SetHolidayHours replaces the restaurant's hours on one date
+func (s *restaurantService) SetHolidayHours(viewer models.OrderViewer, restaurantID string, request *models.SetHolidayHoursRequest) (*models.OpeningSchedule, error) {
+	restaurant, err := s.authorizeManager(viewer, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := time.Parse(holidayDateLayout, request.Date)
+	if err != nil {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Date must be formatted as YYYY-MM-DD", err)
+	}
+	today := time.Now().In(restaurantLocation(restaurant)).Format(holidayDateLayout)
+	if request.Date < today {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Holiday hours cannot be set for past dates", nil)
+	}
+	if request.Closed && len(request.Hours) > 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "A closed day cannot have opening hours", nil)
+	}
+	if !request.Closed && len(request.Hours) == 0 {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Give the day's opening hours or mark it closed", nil)
+	}
+
+	var hours []models.HolidayHours
+	if request.Closed {
+		hours = append(hours, models.HolidayHours{
+			RestaurantID: restaurant.ID,
+			Date:         request.Date,
+			IsClosed:     true,
+			Note:         request.Note,
+		})
+	}
+	weekday := int(date.Weekday())
+	overlapCheck := make([]models.OpeningHours, 0, len(request.Hours))
+	for _, interval := range request.Hours {
+		if err := validateOpeningInterval(interval); err != nil {
+			return nil, err
+		}
+		hours = append(hours, models.HolidayHours{
+			RestaurantID: restaurant.ID,
+			Date:         request.Date,
+			OpensAt:      interval.OpensAt,
+			ClosesAt:     interval.ClosesAt,
+			Note:         request.Note,
+		})
+		overlapCheck = append(overlapCheck, models.OpeningHours{DayOfWeek: weekday, OpensAt: interval.OpensAt, ClosesAt: interval.ClosesAt})
+	}
+	if err := validateNoOverlap(overlapCheck); err != nil {
+		return nil, err
+	}
+
+	if err := s.restaurantRepo.ReplaceHolidayHours(restaurant.ID, request.Date, hours); err != nil {
+		return nil, err
+	}
+	return s.GetOpeningSchedule(restaurant.ID)
+}
+
+// DeleteHolidayHours puts a date back on the restaurant's weekly hours
+func (s *restaurantService) DeleteHolidayHours(viewer models.OrderViewer, restaurantID, date string) (*models.OpeningSchedule, error) {
+	restaurant, err := s.authorizeManager(viewer, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.restaurantRepo.DeleteHolidayHours(restaurant.ID, date); err != nil {
+		return nil, err
+	}
+	return s.GetOpeningSchedule(restaurant.ID)
+}
+
+// PauseRestaurant stops the restaurant taking orders for the next few minutes; scheduled orders due in that
+// time are refused too
+func (s *restaurantService) PauseRestaurant(viewer models.OrderViewer, restaurantID string, request *models.PauseRestaurantRequest) (*models.Restaurant, error) {
+	restaurant, err := s.authorizeManager(viewer, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	pausedUntil := time.Now().Add(time.Duration(request.Minutes) * time.Minute).UTC()
+	var reason *string
+	if trimmed := strings.TrimSpace(request.Reason); trimmed != "" {
+		reason = &trimmed
+	}
+	if err := s.restaurantRepo.Update(restaurant.ID, map[string]interface{}{
+		"paused_until": pausedUntil,
+		"pause_reason": reason,
+	}); err != nil {
+		return nil, err
+	}
+	return s.GetRestaurantByID(restaurant.ID)
+}
+
+// ResumeRestaurant ends a pause early
+func (s *restaurantService) ResumeRestaurant(viewer models.OrderViewer, restaurantID string) (*models.Restaurant, error) {
+	restaurant, err := s.authorizeManager(viewer, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	if restaurant.PausedUntil == nil || !restaurant.PausedUntil.After(time.Now()) {
+		return nil, errors.NewHTTPError(http.StatusConflict, "Restaurant is not paused", nil)
+	}
+
+	if err := s.restaurantRepo.Update(restaurant.ID, map[string]interface{}{
+		"paused_until": nil,
+		"pause_reason": nil,
+	}); err != nil {
+		return nil, err
+	}
+	return s.GetRestaurantByID(restaurant.ID)
+}
+
+// authorizeManager lets admins manage any restaurant's hours and other users only those of a restaurant they own
+func (s *restaurantService) authorizeManager(viewer models.OrderViewer, restaurantID string) (*models.Restaurant, error) {
+	if strings.TrimSpace(restaurantID) == "" {
+		return nil, errors.NewHTTPError(http.StatusBadRequest, "Restaurant ID is required", nil)
+	}
+	restaurant, err := s.restaurantRepo.GetByID(restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch viewer.Role {
+	case models.RoleAdmin:
+		return restaurant, nil
+	case models.RoleCustomer:
+		user, err := s.userRepo.GetByEmail(viewer.Email)
+		if err != nil {
+			return nil, errors.NewHTTPError(http.StatusForbidden, "User not found for token", err)
+		}
+		if restaurant.OwnerID == nil || *restaurant.OwnerID != user.ID {
+			return nil, errors.NewHTTPError(http.StatusForbidden, "Only the restaurant's owner can change its hours", nil)
+		}
+		return restaurant, nil
+	}
+	return nil, errors.NewHTTPError(http.StatusForbidden, "Insufficient permissions", nil)
+}
+
+func validateOpeningInterval(interval models.OpeningInterval) error {
+	if _, err := parseClock(interval.OpensAt); err != nil {
+		return errors.NewHTTPError(http.StatusBadRequest, "Opening times must be formatted as HH:MM", err)
+	}
+	if _, err := parseClock(interval.ClosesAt); err != nil {
+		return errors.NewHTTPError(http.StatusBadRequest, "Closing times must be formatted as HH:MM", err)
+	}
+	return nil
+}
+
+// validateNoOverlap rejects intervals that overlap, counting those that run past midnight into the next day
+func validateNoOverlap(hours []models.OpeningHours) error {
+	const week = 7 * 24 * 60
+	type span struct{ start, end int }
+	spans := make([]span, 0, len(hours))
+	for _, interval := range hours {
+		opens, _ := parseClock(interval.OpensAt)
+		closes, _ := parseClock(interval.ClosesAt)
+		start := interval.DayOfWeek*24*60 + opens
+		length := closes - opens
+		if length <= 0 {
+			length += 24 * 60
+		}
+		spans = append(spans, span{start: start, end: start + length})
+	}
+
+	for i := range spans {
+		for j := i + 1; j < len(spans); j++ {
+			// Compare on a circular week so Saturday night can run into Sunday morning
+			for _, shift := range []int{-week, 0, week} {
+				if spans[i].start < spans[j].end+shift && spans[j].start+shift < spans[i].end {
+					return errors.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Opening intervals %s-%s and %s-%s overlap",
+						hours[i].OpensAt, hours[i].ClosesAt, hours[j].OpensAt, hours[j].ClosesAt), nil)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+	"dfood/internal/repository"
+)
+
+type TaxService interface {
+	CalculateTax(jurisdiction models.TaxJurisdiction, lines []models.TaxableLine, at time.Time) (*models.TaxResult, error)
+}
+
+type taxService struct {
+	taxRuleRepo repository.TaxRuleRepository
+	config      config.TaxConfig
+}
+
+func NewTaxService(taxRuleRepo repository.TaxRuleRepository, cfg config.TaxConfig) TaxService {
+	return &taxService{
+		taxRuleRepo: taxRuleRepo,
+		config:      cfg,
+	}
+}
+
+// taxBucket accumulates the taxable base for one rule at one inclusive rate
+type taxBucket struct {
+	rule          *models.TaxRule
+	inclusiveRate int64
+	base          models.Money
+}
+
+func (s *taxService) CalculateTax(jurisdiction models.TaxJurisdiction, lines []models.TaxableLine, at time.Time) (*models.TaxResult, error) {
+	currency := models.DefaultCurrency
+	if len(lines) > 0 && lines[0].Amount.Currency != "" {
+		currency = lines[0].Amount.Currency
+	}
+	result := &models.TaxResult{
+		Lines:     models.TaxLinesArray{},
+		Inclusive: models.Zero(currency),
+		Exclusive: models.Zero(currency),
+		Total:     models.Zero(currency),
+	}
+
+	rules, err := s.taxRuleRepo.GetApplicable(jurisdiction, at)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 && s.config.DefaultRateBasisPoints > 0 {
+		rules = []models.TaxRule{s.defaultRule()}
+	}
+	if len(rules) == 0 {
+		return result, nil
+	}
+
+	// Group each line's amount under the rules that apply to its category
+	var buckets []*taxBucket
+	index := make(map[string]*taxBucket)
+	for _, line := range lines {
+		if !line.Amount.IsPositive() {
+			continue
+		}
+
+		applicable := selectTaxRules(rules, line.Category)
+		var inclusiveRate int64
+		for _, rule := range applicable {
+			if rule.IsInclusive {
+				inclusiveRate += rule.RateBasisPoints
+			}
+		}
+
+		for _, rule := range applicable {
+			key := fmt.Sprintf("%s|%s|%d", rule.ID, line.Category, inclusiveRate)
+			bucket, exists := index[key]
+			if !exists {
+				bucket = &taxBucket{rule: rule, inclusiveRate: inclusiveRate, base: models.Zero(currency)}
+				index[key] = bucket
+				buckets = append(buckets, bucket)
+			}
+			bucket.base = bucket.base.Add(line.Amount)
+		}
+	}
+
+	// Compute the tax once per bucket to keep rounding to one step per line of the breakdown
+	for _, bucket := range buckets {
+		line := models.TaxLine{
+			Name:            bucket.rule.Name,
+			RateBasisPoints: bucket.rule.RateBasisPoints,
+			Category:        bucket.rule.Category,
+			TaxableAmount:   bucket.base,
+			IsInclusive:     bucket.rule.IsInclusive,
+		}
+		if bucket.rule.IsInclusive {
+			// Prices already contain the tax: extract it from the gross amount
+			line.Amount = bucket.base.MulRat(bucket.rule.RateBasisPoints, 10000+bucket.inclusiveRate)
+			result.Inclusive = result.Inclusive.Add(line.Amount)
+		} else {
+			line.Amount = bucket.base.Percent(bucket.rule.RateBasisPoints)
+			result.Exclusive = result.Exclusive.Add(line.Amount)
+		}
+		result.Lines = append(result.Lines, line)
+	}
+	result.Total = result.Inclusive.Add(result.Exclusive)
+
+	return result, nil
+}
+
+// defaultRule builds the configured fallback rule used when no jurisdiction rule matches
+func (s *taxService) defaultRule() models.TaxRule {
+	name := s.config.DefaultName
+	if name == "" {
+		name = "Sales Tax"
+	}
+	return models.TaxRule{
+		ID:              "default",
+		Name:            name,
+		RateBasisPoints: s.config.DefaultRateBasisPoints,
+		IsInclusive:     s.config.DefaultPricesIncludeTax,
+		IsActive:        true,
+	}
+}
+
+// selectTaxRules picks, for each tax name, the most specific rule that applies to the category.
+// Delivery fees are only taxed by rules that target the delivery category explicitly.
+func selectTaxRules(rules []models.TaxRule, category string) []*models.TaxRule {
+	best := make(map[string]*models.TaxRule)
+	var names []string
+	for i := range rules {
+		rule := &rules[i]
+		switch {
+		case rule.Category == category:
+		case rule.Category == models.TaxCategoryStandard && category != models.TaxCategoryDelivery:
+		default:
+			continue
+		}
+
+		current, exists := best[rule.Name]
+		if !exists {
+			names = append(names, rule.Name)
+		}
+		if !exists || rule.Specificity() > current.Specificity() {
+			best[rule.Name] = rule
+		}
+	}
+
+	selected := make([]*models.TaxRule, 0, len(names))
+	for _, name := range names {
+		selected = append(selected, best[name])
+	}
+	return selected
+}
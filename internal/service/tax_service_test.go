@@ -0,0 +1,118 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"dfood/internal/config"
+	"dfood/internal/models"
+)
+
+// staticTaxRules returns the same rules for every jurisdiction, as if the repository had already matched them
+type staticTaxRules []models.TaxRule
+
+func (r staticTaxRules) GetApplicable(models.TaxJurisdiction, time.Time) ([]models.TaxRule, error) {
+	return append([]models.TaxRule(nil), r...), nil
+}
+
+func (r staticTaxRules) Create(*models.TaxRule) error { return nil }
+
+func calculateTax(t *testing.T, rules staticTaxRules, cfg config.TaxConfig, lines ...models.TaxableLine) *models.TaxResult {
+	t.Helper()
+	result, err := NewTaxService(rules, cfg).CalculateTax(models.TaxJurisdiction{State: "CA", ZipCode: "94103"}, lines, time.Now())
+	if err != nil {
+		t.Fatalf("CalculateTax error = %v", err)
+	}
+	if sum := result.Inclusive.Add(result.Exclusive); sum != result.Total {
+		t.Errorf("inclusive %s + exclusive %s != total %s", result.Inclusive, result.Exclusive, result.Total)
+	}
+	return result
+}
+
+func taxableFood(amount int64) models.TaxableLine {
+	return models.TaxableLine{Category: models.TaxCategoryStandard, Amount: usd(amount)}
+}
+
+func TestCalculateTaxStacksDifferentTaxes(t *testing.T) {
+	rules := staticTaxRules{
+		{ID: "state", Name: "State Tax", State: "CA", RateBasisPoints: 600},
+		{ID: "city", Name: "City Tax", State: "CA", ZipCode: "94103", RateBasisPoints: 225},
+	}
+
+	result := calculateTax(t, rules, config.TaxConfig{}, taxableFood(1000))
+
+	// 6% of 10.00 is 0.60; 2.25% is 0.225, which rounds half to even
+	if result.Exclusive.Amount != 82 || len(result.Lines) != 2 {
+		t.Errorf("tax = %d over %d lines, want 82 over 2", result.Exclusive.Amount, len(result.Lines))
+	}
+}
+
+func TestCalculateTaxMostSpecificRuleWins(t *testing.T) {
+	rules := staticTaxRules{
+		{ID: "state", Name: "Sales Tax", State: "CA", RateBasisPoints: 725},
+		{ID: "zip", Name: "Sales Tax", State: "CA", ZipCode: "94103", RateBasisPoints: 863},
+	}
+
+	// Two rules share a name, so only the zip rule applies; 8.63% of 20.00 is 1.726
+	result := calculateTax(t, rules, config.TaxConfig{}, taxableFood(2000))
+	if result.Total.Amount != 173 || len(result.Lines) != 1 || result.Lines[0].RateBasisPoints != 863 {
+		t.Errorf("tax = %+v, want 1.73 from the zip rule alone", result)
+	}
+}
+
+func TestCalculateTaxCategoryRuleOverridesStandard(t *testing.T) {
+	rules := staticTaxRules{
+		{ID: "state", Name: "Sales Tax", State: "CA", RateBasisPoints: 725},
+		{ID: "alcohol", Name: "Sales Tax", State: "CA", Category: models.TaxCategoryAlcohol, RateBasisPoints: 1000},
+		{ID: "grocery", Name: "Sales Tax", State: "CA", Category: models.TaxCategoryGrocery, RateBasisPoints: 0},
+	}
+
+	result := calculateTax(t, rules, config.TaxConfig{},
+		taxableFood(2000),
+		models.TaxableLine{Category: models.TaxCategoryAlcohol, Amount: usd(1500)},
+		models.TaxableLine{Category: models.TaxCategoryGrocery, Amount: usd(800)},
+	)
+
+	if result.Total.Amount != 145+150 {
+		t.Errorf("tax = %d, want 145 on food, 150 on alcohol and nothing on groceries", result.Total.Amount)
+	}
+}
+
+func TestCalculateTaxLeavesDeliveryUntaxedWithoutADeliveryRule(t *testing.T) {
+	delivery := models.TaxableLine{Category: models.TaxCategoryDelivery, Amount: usd(500)}
+
+	untaxed := calculateTax(t, staticTaxRules{{ID: "state", Name: "Sales Tax", RateBasisPoints: 800}}, config.TaxConfig{}, delivery)
+	if !untaxed.Total.IsZero() {
+		t.Errorf("delivery taxed %s by a standard rule", untaxed.Total)
+	}
+
+	taxed := calculateTax(t, staticTaxRules{{ID: "delivery", Name: "Sales Tax", Category: models.TaxCategoryDelivery, RateBasisPoints: 800}}, config.TaxConfig{}, delivery)
+	if taxed.Total.Amount != 40 {
+		t.Errorf("delivery tax = %d, want 40 from the delivery rule", taxed.Total.Amount)
+	}
+}
+
+func TestCalculateTaxExtractsInclusiveTax(t *testing.T) {
+	rules := staticTaxRules{
+		{ID: "vat", Name: "VAT", RateBasisPoints: 2000, IsInclusive: true},
+		{ID: "levy", Name: "Levy", RateBasisPoints: 500, IsInclusive: true},
+	}
+
+	// 12.50 holds 25% of tax in total, 2.00 of VAT and 0.50 of levy
+	result := calculateTax(t, rules, config.TaxConfig{}, taxableFood(1250))
+	if result.Inclusive.Amount != 250 || !result.Exclusive.IsZero() {
+		t.Errorf("inclusive = %s, exclusive = %s, want 2.50 inclusive only", result.Inclusive, result.Exclusive)
+	}
+}
+
+func TestCalculateTaxFallsBackToTheConfiguredRate(t *testing.T) {
+	result := calculateTax(t, nil, config.TaxConfig{DefaultRateBasisPoints: 800}, taxableFood(1000))
+	if result.Total.Amount != 80 || len(result.Lines) != 1 || result.Lines[0].Name != "Sales Tax" {
+		t.Errorf("fallback tax = %+v, want one 0.80 Sales Tax line", result)
+	}
+
+	none := calculateTax(t, nil, config.TaxConfig{}, taxableFood(1000))
+	if !none.Total.IsZero() || len(none.Lines) != 0 {
+		t.Errorf("tax without rules or a default rate = %+v, want none", none)
+	}
+}
@@ -45,6 +45,15 @@ func (s *userService) GetProfile(userID string) (*models.User, error) {
 	return user, nil
 }
 
+// editableProfileFields are the user columns customers can change on their own profile
+var editableProfileFields = map[string]bool{
+	"first_name":        true,
+	"last_name":         true,
+	"phone_number":      true,
+	"bio":               true,
+	"profile_image_url": true,
+}
+
 func (s *userService) UpdateProfile(userID string, updates map[string]interface{}) error {
 	if strings.TrimSpace(userID) == "" {
 		return errors.NewHTTPError(http.StatusBadRequest, "User ID is required", nil)
@@ -54,17 +63,19 @@ func (s *userService) UpdateProfile(userID string, updates map[string]interface{
 		return errors.NewHTTPError(http.StatusBadRequest, "No updates provided", nil)
 	}
 
+	// Only profile fields can be changed this way; the email is checked below
+	for field := range updates {
+		if field != "email" && !editableProfileFields[field] {
+			return errors.NewHTTPError(http.StatusBadRequest, "Field "+field+" cannot be updated", nil)
+		}
+	}
+
 	// Validate user exists
 	_, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		return err
 	}
 
-	// Remove sensitive fields that shouldn't be updated this way
-	delete(updates, "password")
-	delete(updates, "id")
-	delete(updates, "created_at")
-
 	// Validate email if being updated
 	if email, exists := updates["email"]; exists {
 		emailStr, ok := email.(string)
@@ -92,15 +103,7 @@ func (s *userService) UpdateProfileField(userID, field string, value interface{}
 	}
 
 	// Validate field is allowed to be updated
-	allowedFields := map[string]bool{
-		"first_name":        true,
-		"last_name":         true,
-		"phone_number":      true,
-		"bio":               true,
-		"profile_image_url": true,
-	}
-
-	if !allowedFields[field] {
+	if !editableProfileFields[field] {
 		return errors.NewHTTPError(http.StatusBadRequest, "Field cannot be updated", nil)
 	}
 
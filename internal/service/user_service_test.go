@@ -0,0 +1,59 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"dfood/internal/database"
+	"dfood/internal/encryption"
+	"dfood/internal/repository"
+	"dfood/pkg/errors"
+)
+
+// Fields outside the allow-list are refused before the user is even looked up
+func TestUpdateProfileRefusesProtectedFields(t *testing.T) {
+	service := &userService{}
+	for _, field := range []string{"role", "password", "id", "email_verified", "referral_code", "fcm_token", "created_at", "phone_number_index"} {
+		err := service.UpdateProfile("user-1", map[string]interface{}{"first_name": "Ada", field: "x"})
+		if status, _ := errors.GetStatusCode(err); status != http.StatusBadRequest {
+			t.Errorf("UpdateProfile with %s = %v, want 400", field, err)
+		}
+		err = service.UpdateProfileField("user-1", field, "x")
+		if status, _ := errors.GetStatusCode(err); status != http.StatusBadRequest {
+			t.Errorf("UpdateProfileField(%s) = %v, want 400", field, err)
+		}
+	}
+}
+
+// A profile update passes the phone number as a map value, which GORM writes without the field serializer
+func TestUpdateProfileKeepsThePhoneNumberEncrypted(t *testing.T) {
+	openTestDB(t)
+	seed(t, testUser("ana"), testUser("ben"))
+	userRepo := repository.NewUserRepository()
+	service := NewUserService(userRepo)
+
+	if err := service.UpdateProfile("ana", map[string]interface{}{"phone_number": "+15550100", "bio": "Hungry"}); err != nil {
+		t.Fatalf("UpdateProfile error = %v", err)
+	}
+
+	var stored struct{ PhoneNumber, PhoneNumberIndex string }
+	if err := database.DB.Table("users").Select("phone_number, phone_number_index").Where("id = ?", "ana").Scan(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !encryption.IsEncrypted(stored.PhoneNumber) || strings.Contains(stored.PhoneNumber, "5550100") {
+		t.Errorf("phone number stored as %q, want it encrypted", stored.PhoneNumber)
+	}
+	if stored.PhoneNumberIndex != encryption.BlindIndex("phone_number", "+15550100") {
+		t.Errorf("blind index was not refreshed with the new number")
+	}
+
+	ana, err := userRepo.GetByID("ana")
+	if err != nil || ana.PhoneNumber != "+15550100" || ana.Bio == nil || *ana.Bio != "Hungry" {
+		t.Errorf("reloaded user = %+v, %v; want the new number decrypted", ana, err)
+	}
+	// The referral fraud guard finds the number through its blind index
+	if count, err := userRepo.CountOthersByPhoneNumber(stored.PhoneNumberIndex, "ben"); err != nil || count != 1 {
+		t.Errorf("users other than ben with ana's number = %d, %v; want 1", count, err)
+	}
+}
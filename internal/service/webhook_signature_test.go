@@ -0,0 +1,62 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	const secret = "whsec_test"
+	const tolerance = 5 * time.Minute
+	payload := []byte(`{"type":"charge.succeeded","data":{"id":"auth_1"}}`)
+	signedAt := time.Unix(1792300000, 0)
+	valid := SignWebhook(secret, signedAt, payload)
+	mac := webhookMAC(secret, strconv.FormatInt(signedAt.Unix(), 10), payload)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		now     time.Time
+		wantErr bool
+	}{
+		{name: "valid", header: valid, now: signedAt},
+		{name: "valid within tolerance", header: valid, now: signedAt.Add(tolerance)},
+		{name: "valid with clock skew", header: valid, now: signedAt.Add(-tolerance)},
+		{name: "spaces around entries", header: "t=1792300000, v1=" + mac, now: signedAt},
+		{name: "rotated secret", header: "t=1792300000,v1=" + webhookMAC("whsec_old", "1792300000", payload) + ",v1=" + mac, now: signedAt},
+		{name: "unknown scheme ignored", header: "t=1792300000,v0=deadbeef,v1=" + mac, now: signedAt},
+		{name: "too old", header: valid, now: signedAt.Add(tolerance + time.Second), wantErr: true},
+		{name: "too far in the future", header: valid, now: signedAt.Add(-tolerance - time.Second), wantErr: true},
+		{name: "wrong secret", secret: "whsec_other", header: valid, now: signedAt, wantErr: true},
+		{name: "tampered body", header: valid, payload: []byte(`{"type":"charge.succeeded","data":{"id":"auth_2"}}`), now: signedAt, wantErr: true},
+		{name: "timestamp changed", header: "t=1792300001,v1=" + mac, now: signedAt, wantErr: true},
+		{name: "missing timestamp", header: "v1=" + mac, now: signedAt, wantErr: true},
+		{name: "missing signature", header: "t=1792300000", now: signedAt, wantErr: true},
+		{name: "non-numeric timestamp", header: "t=yesterday,v1=" + mac, now: signedAt, wantErr: true},
+		{name: "empty header", header: "", now: signedAt, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := secret
+			if tt.secret != "" {
+				key = tt.secret
+			}
+			body := payload
+			if tt.payload != nil {
+				body = tt.payload
+			}
+
+			err := verifyWebhookSignature(key, tt.header, body, tt.now, tolerance)
+			if tt.wantErr && err == nil {
+				t.Errorf("verifyWebhookSignature(%q) = nil, want error", tt.header)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("verifyWebhookSignature(%q) error = %v", tt.header, err)
+			}
+		})
+	}
+}
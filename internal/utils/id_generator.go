@@ -38,3 +38,129 @@ func GenerateRestaurantID() string {
 func GenerateFoodID() string {
 	return "food-" + GenerateID()
 }
+
+// GenerateQuoteID generates an order-quote-specific ID
+func GenerateQuoteID() string {
+	return "quote-" + GenerateID()
+}
+
+// GeneratePaymentID generates a payment-transaction-specific ID
+func GeneratePaymentID() string {
+	return "txn-" + GenerateID()
+}
+
+// GenerateWebhookEventID generates a stored-webhook-event-specific ID
+func GenerateWebhookEventID() string {
+	return "whe-" + GenerateID()
+}
+
+// GenerateLedgerJournalID generates a ledger-journal-specific ID
+func GenerateLedgerJournalID() string {
+	return "jnl-" + GenerateID()
+}
+
+// GenerateLedgerEntryID generates a ledger-entry-specific ID
+func GenerateLedgerEntryID() string {
+	return "led-" + GenerateID()
+}
+
+// GenerateCardID generates a saved-card-specific ID
+func GenerateCardID() string {
+	return "card-" + GenerateID()
+}
+
+// GenerateGroupOrderID generates a group-order-specific ID
+func GenerateGroupOrderID() string {
+	return "group-" + GenerateID()
+}
+
+// inviteCodeAlphabet leaves out characters that are easy to confuse when read aloud (0/O, 1/I)
+const inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateInviteCode generates a short, human-shareable invite code
+func GenerateInviteCode() string {
+	randomBytes := make([]byte, 8)
+	rand.Read(randomBytes)
+
+	code := make([]byte, len(randomBytes))
+	for i, b := range randomBytes {
+		code[i] = inviteCodeAlphabet[int(b)%len(inviteCodeAlphabet)]
+	}
+	return string(code)
+}
+
+// GenerateOrderTemplateID generates an order-template-specific ID
+func GenerateOrderTemplateID() string {
+	return "template-" + GenerateID()
+}
+
+// GeneratePayoutID generates a restaurant-payout-specific ID
+func GeneratePayoutID() string {
+	return "payout-" + GenerateID()
+}
+
+// GeneratePayoutLineID generates a payout-line-specific ID
+func GeneratePayoutLineID() string {
+	return "pol-" + GenerateID()
+}
+
+// GeneratePromotionID generates a promotion-specific ID
+func GeneratePromotionID() string {
+	return "promo-" + GenerateID()
+}
+
+// GeneratePromotionRedemptionID generates a promotion-redemption-specific ID
+func GeneratePromotionRedemptionID() string {
+	return "redeem-" + GenerateID()
+}
+
+// GenerateLoyaltyEntryID generates a loyalty-points-entry-specific ID
+func GenerateLoyaltyEntryID() string {
+	return "points-" + GenerateID()
+}
+
+// GenerateReferralID generates a referral-specific ID
+func GenerateReferralID() string {
+	return "referral-" + GenerateID()
+}
+
+// GenerateGiftCardID generates a gift-card-specific ID
+func GenerateGiftCardID() string {
+	return "gift-" + GenerateID()
+}
+
+// GenerateGiftCardBatchID generates an ID shared by a batch of promotional gift cards
+func GenerateGiftCardBatchID() string {
+	return "giftbatch-" + GenerateID()
+}
+
+// GenerateMembershipPlanID generates a membership-plan-specific ID
+func GenerateMembershipPlanID() string {
+	return "plan-" + GenerateID()
+}
+
+// GenerateMembershipID generates a membership-specific ID
+func GenerateMembershipID() string {
+	return "membership-" + GenerateID()
+}
+
+// GenerateNotificationID generates a notification-specific ID
+func GenerateNotificationID() string {
+	return "notif-" + GenerateID()
+}
+
+// GenerateGiftCardCode generates a gift card code in four groups of four, e.g. "K7QM-2XRD-9FTB-HW3N".
+// The 16 characters carry 80 random bits, so codes cannot be guessed.
+func GenerateGiftCardCode() string {
+	randomBytes := make([]byte, 16)
+	rand.Read(randomBytes)
+
+	code := make([]byte, 0, len(randomBytes)+3)
+	for i, b := range randomBytes {
+		if i > 0 && i%4 == 0 {
+			code = append(code, '-')
+		}
+		code = append(code, inviteCodeAlphabet[int(b)%len(inviteCodeAlphabet)])
+	}
+	return string(code)
+}
@@ -15,7 +15,7 @@ func RotateSecretKey(newSecret string) {
 	tokenBlacklist = make(map[string]bool)
 }
 
-func GenerateJwtToken(email string, isRefresh bool) (string, error) {
+func GenerateJwtToken(email, role string, isRefresh bool) (string, error) {
 	var expirationTime time.Time
 	if isRefresh {
 		expirationTime = time.Now().Add(7 * 24 * time.Hour)
@@ -23,8 +23,9 @@ func GenerateJwtToken(email string, isRefresh bool) (string, error) {
 		expirationTime = time.Now().Add(15 * time.Minute)
 	}
 	claims := &jwt.MapClaims{
-		"sub": email,
-		"exp": expirationTime.Unix(),
+		"sub":  email,
+		"role": role,
+		"exp":  expirationTime.Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtKey)
@@ -36,9 +37,9 @@ func ValidateToken(tokenStr string) (*jwt.MapClaims, error) {
 		return nil, jwt.ErrTokenInvalidClaims
 	}
 
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtKey, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
 		return nil, err
 	}
@@ -86,9 +87,9 @@ func InvalidateAllUserTokens(email string) {
 
 // ValidateTokenWithoutBlacklistCheck validates token without checking blacklist
 func ValidateTokenWithoutBlacklistCheck(tokenStr string) (*jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtKey, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// SignValue appends an HMAC-SHA256 signature to a value so it can be handed to clients
+// and verified later without a lookup, e.g. "quote-123.<signature>"
+func SignValue(value string) string {
+	return value + "." + signature(value)
+}
+
+// VerifySignedValue checks a value produced by SignValue and returns the original value
+func VerifySignedValue(signed string) (string, bool) {
+	index := strings.LastIndex(signed, ".")
+	if index <= 0 {
+		return "", false
+	}
+
+	value, sig := signed[:index], signed[index+1:]
+	if !hmac.Equal([]byte(sig), []byte(signature(value))) {
+		return "", false
+	}
+	return value, true
+}
+
+func signature(value string) string {
+	mac := hmac.New(sha256.New, jwtKey)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}